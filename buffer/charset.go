@@ -0,0 +1,40 @@
+package buffer
+
+// cp1252 maps the characters 128-159 of the CP-1252 charset to their unicode
+// counterparts. A zero value denotes an undefined character.
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// DecodeCharacter converts a CP-1252 encoded character to its unicode counterpart.
+func DecodeCharacter(value byte) rune {
+	if value >= 128 && value < 160 {
+		character := cp1252[value-128]
+		if character == 0 {
+			return '?'
+		}
+
+		return character
+	}
+
+	return rune(value)
+}
+
+// EncodeCharacter converts a unicode character to its CP-1252 encoded counterpart.
+// Characters that cannot be represented are encoded as a question mark.
+func EncodeCharacter(value rune) byte {
+	if (value > 0 && value < 128) || (value >= 160 && value <= 255) {
+		return byte(value)
+	}
+
+	for index, character := range cp1252 {
+		if character != 0 && character == value {
+			return byte(index + 128)
+		}
+	}
+
+	return '?'
+}
@@ -0,0 +1,155 @@
+package buffer
+
+// Cursor reads values from a byte array like a Reader, but rather than failing every
+// read, it remembers the first error it encounters and yields zero values from then
+// on. This suits formats that consist of long lists of fields, of which the errors are
+// checked for once all fields are read.
+type Cursor struct {
+	reader *Reader
+	err    error
+}
+
+// NewCursor constructs a new Cursor that starts reading at the start of the given data.
+func NewCursor(data []byte) *Cursor {
+	return &Cursor{reader: NewReader(data)}
+}
+
+// Err returns the first error that the cursor encountered, if any.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Position returns the index of the next byte to read.
+func (c *Cursor) Position() int {
+	return c.reader.Position()
+}
+
+// IsReadable returns whether there are bytes left to read and no read has failed.
+func (c *Cursor) IsReadable() bool {
+	return c.err == nil && c.reader.IsReadable()
+}
+
+// Seek moves the cursor to the specified index.
+func (c *Cursor) Seek(position int) {
+	if c.err == nil {
+		c.err = c.reader.Seek(position)
+	}
+}
+
+// Skip skips the specified amount of bytes.
+func (c *Cursor) Skip(amount int) {
+	if c.err == nil {
+		c.err = c.reader.Skip(amount)
+	}
+}
+
+// read performs the given read unless an earlier read failed, and remembers its error.
+func (c *Cursor) read(read func() (int, error)) int {
+	if c.err != nil {
+		return 0
+	}
+
+	value, err := read()
+	c.err = err
+
+	return value
+}
+
+// PeekByte reads the byte at the current index without advancing it.
+func (c *Cursor) PeekByte() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.PeekByte()
+		return int(value), err
+	})
+}
+
+// UInt8 reads an unsigned byte.
+func (c *Cursor) UInt8() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadByte()
+		return int(value), err
+	})
+}
+
+// Int8 reads a signed byte.
+func (c *Cursor) Int8() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadInt8()
+		return int(value), err
+	})
+}
+
+// Bool reads a byte that is true if it is one.
+func (c *Cursor) Bool() bool {
+	return c.UInt8() == 1
+}
+
+// UInt16 reads an unsigned 16-bit integer.
+func (c *Cursor) UInt16() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadUInt16()
+		return int(value), err
+	})
+}
+
+// Int16 reads a signed 16-bit integer.
+func (c *Cursor) Int16() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadInt16()
+		return int(value), err
+	})
+}
+
+// UInt24 reads an unsigned 24-bit integer.
+func (c *Cursor) UInt24() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadUInt24()
+		return int(value), err
+	})
+}
+
+// Int32 reads a signed 32-bit integer.
+func (c *Cursor) Int32() int {
+	return c.read(func() (int, error) {
+		value, err := c.reader.ReadInt32()
+		return int(value), err
+	})
+}
+
+// Smart reads an unsigned value of one or two bytes.
+func (c *Cursor) Smart() int {
+	return c.read(c.reader.ReadSmart)
+}
+
+// SignedSmart reads a signed value of one or two bytes.
+func (c *Cursor) SignedSmart() int {
+	return c.read(c.reader.ReadSignedSmart)
+}
+
+// NullableBigSmart reads a value of two or four bytes that may also be -1.
+func (c *Cursor) NullableBigSmart() int {
+	return c.read(c.reader.ReadNullableBigSmart)
+}
+
+// CString reads a null-terminated string.
+func (c *Cursor) CString() string {
+	if c.err != nil {
+		return ""
+	}
+
+	value, err := c.reader.ReadCString()
+	c.err = err
+
+	return value
+}
+
+// FirstError returns the first error that any of the given cursors encountered.
+func FirstError(cursors ...*Cursor) error {
+	for _, c := range cursors {
+		if c.err != nil {
+			return c.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package buffer
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrOutOfBounds is returned when a read exceeds the bounds of the underlying data.
+var ErrOutOfBounds = errors.New("index out of bounds")
+
+// Reader reads values from a byte array in the big-endian byte order used by the
+// cache formats. Unlike a bytecat.Iterator, reading past the end of the array never
+// panics but returns ErrOutOfBounds instead.
+type Reader struct {
+	data     []byte
+	position int
+}
+
+// NewReader constructs a new Reader that starts reading at the start of the given data.
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// Position returns the index of the next byte to read.
+func (r *Reader) Position() int {
+	return r.position
+}
+
+// Seek moves the reader to the specified index. May return an error.
+func (r *Reader) Seek(position int) error {
+	if position < 0 || position > len(r.data) {
+		return ErrOutOfBounds
+	}
+
+	r.position = position
+	return nil
+}
+
+// Length returns the total amount of bytes of the underlying data.
+func (r *Reader) Length() int {
+	return len(r.data)
+}
+
+// ReadableBytes returns the amount of bytes the reader has left to read.
+func (r *Reader) ReadableBytes() int {
+	return len(r.data) - r.position
+}
+
+// IsReadable returns whether the reader has any bytes left to read.
+func (r *Reader) IsReadable() bool {
+	return r.position < len(r.data)
+}
+
+// CanRead returns whether the reader has at least the specified amount of bytes left.
+func (r *Reader) CanRead(amount int) bool {
+	return r.position+amount <= len(r.data)
+}
+
+// Skip skips the specified amount of bytes. May return an error.
+func (r *Reader) Skip(amount int) error {
+	if !r.CanRead(amount) {
+		return ErrOutOfBounds
+	}
+
+	r.position += amount
+	return nil
+}
+
+// PeekByte reads the byte at the current index without advancing it. May return an error.
+func (r *Reader) PeekByte() (byte, error) {
+	if !r.CanRead(1) {
+		return 0, ErrOutOfBounds
+	}
+
+	return r.data[r.position], nil
+}
+
+// ReadBytes reads the specified amount of bytes into a new byte array. May return an error.
+func (r *Reader) ReadBytes(amount int) ([]byte, error) {
+	if amount < 0 || !r.CanRead(amount) {
+		return nil, ErrOutOfBounds
+	}
+
+	value := make([]byte, amount)
+	copy(value, r.data[r.position:])
+
+	r.position += amount
+	return value, nil
+}
+
+// ReadByte reads a single unsigned byte. May return an error.
+func (r *Reader) ReadByte() (byte, error) {
+	if !r.CanRead(1) {
+		return 0, ErrOutOfBounds
+	}
+
+	value := r.data[r.position]
+	r.position++
+
+	return value, nil
+}
+
+// ReadInt8 reads a single signed byte. May return an error.
+func (r *Reader) ReadInt8() (int8, error) {
+	value, err := r.ReadByte()
+	return int8(value), err
+}
+
+// ReadBool reads a single byte and returns whether it equals 1. May return an error.
+func (r *Reader) ReadBool() (bool, error) {
+	value, err := r.ReadByte()
+	return value == 1, err
+}
+
+// ReadUInt16 reads an unsigned 16-bit integer. May return an error.
+func (r *Reader) ReadUInt16() (uint16, error) {
+	if !r.CanRead(2) {
+		return 0, ErrOutOfBounds
+	}
+
+	value := uint16(r.data[r.position])<<8 | uint16(r.data[r.position+1])
+	r.position += 2
+
+	return value, nil
+}
+
+// ReadInt16 reads a signed 16-bit integer. May return an error.
+func (r *Reader) ReadInt16() (int16, error) {
+	value, err := r.ReadUInt16()
+	return int16(value), err
+}
+
+// ReadUInt24 reads an unsigned 24-bit integer. May return an error.
+func (r *Reader) ReadUInt24() (uint32, error) {
+	if !r.CanRead(3) {
+		return 0, ErrOutOfBounds
+	}
+
+	value := uint32(r.data[r.position])<<16 | uint32(r.data[r.position+1])<<8 | uint32(r.data[r.position+2])
+	r.position += 3
+
+	return value, nil
+}
+
+// ReadUInt32 reads an unsigned 32-bit integer. May return an error.
+func (r *Reader) ReadUInt32() (uint32, error) {
+	if !r.CanRead(4) {
+		return 0, ErrOutOfBounds
+	}
+
+	value := uint32(r.data[r.position])<<24 | uint32(r.data[r.position+1])<<16 |
+		uint32(r.data[r.position+2])<<8 | uint32(r.data[r.position+3])
+	r.position += 4
+
+	return value, nil
+}
+
+// ReadInt32 reads a signed 32-bit integer. May return an error.
+func (r *Reader) ReadInt32() (int32, error) {
+	value, err := r.ReadUInt32()
+	return int32(value), err
+}
+
+// ReadUInt64 reads an unsigned 64-bit integer. May return an error.
+func (r *Reader) ReadUInt64() (uint64, error) {
+	high, err := r.ReadUInt32()
+	if err != nil {
+		return 0, err
+	}
+
+	low, err := r.ReadUInt32()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(high)<<32 | uint64(low), nil
+}
+
+// ReadInt64 reads a signed 64-bit integer. May return an error.
+func (r *Reader) ReadInt64() (int64, error) {
+	value, err := r.ReadUInt64()
+	return int64(value), err
+}
+
+// ReadSmart reads an unsigned value of either one or two bytes, depending on
+// whether the most significant bit of the first byte is set. The result is
+// in the range of 0-32767. May return an error.
+func (r *Reader) ReadSmart() (int, error) {
+	peek, err := r.PeekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if peek < 128 {
+		value, err := r.ReadByte()
+		return int(value), err
+	}
+
+	value, err := r.ReadUInt16()
+	return int(value) - 32768, err
+}
+
+// ReadSignedSmart reads a signed value of either one or two bytes. The result
+// is in the range of -16384-16383. May return an error.
+func (r *Reader) ReadSignedSmart() (int, error) {
+	peek, err := r.PeekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if peek < 128 {
+		value, err := r.ReadByte()
+		return int(value) - 64, err
+	}
+
+	value, err := r.ReadUInt16()
+	return int(value) - 49152, err
+}
+
+// ReadSmartMinusOne reads a smart value where the encoded value of zero
+// represents -1. May return an error.
+func (r *Reader) ReadSmartMinusOne() (int, error) {
+	peek, err := r.PeekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if peek < 128 {
+		value, err := r.ReadByte()
+		return int(value) - 1, err
+	}
+
+	value, err := r.ReadUInt16()
+	return int(value) - 32769, err
+}
+
+// ReadIncrementalSmart reads a sum of smart values where every value of 32767
+// indicates that another smart value follows. May return an error.
+func (r *Reader) ReadIncrementalSmart() (int, error) {
+	var total int
+
+	for {
+		value, err := r.ReadSmart()
+		if err != nil {
+			return 0, err
+		}
+
+		if value != 32767 {
+			return total + value, nil
+		}
+
+		total += 32767
+	}
+}
+
+// ReadBigSmart reads an unsigned value of either two or four bytes, depending
+// on whether the most significant bit of the first byte is set. May return an error.
+func (r *Reader) ReadBigSmart() (int, error) {
+	peek, err := r.PeekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if peek < 128 {
+		value, err := r.ReadUInt16()
+		return int(value), err
+	}
+
+	value, err := r.ReadUInt32()
+	return int(value & 0x7FFFFFFF), err
+}
+
+// ReadNullableBigSmart reads a big smart value where the two-byte value of
+// 32767 represents -1, as used by model and sprite ids. May return an error.
+func (r *Reader) ReadNullableBigSmart() (int, error) {
+	peek, err := r.PeekByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if peek < 128 {
+		value, err := r.ReadUInt16()
+		if value == 32767 {
+			return -1, err
+		}
+
+		return int(value), err
+	}
+
+	value, err := r.ReadUInt32()
+	return int(value & 0x7FFFFFFF), err
+}
+
+// ReadVarInt reads an unsigned value that is encoded in groups of 7 bits, most
+// significant group first, where the most significant bit of each byte indicates
+// whether another group follows. May return an error.
+func (r *Reader) ReadVarInt() (int, error) {
+	var value int
+
+	for i := 0; i < 5; i++ {
+		group, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		value = value<<7 | int(group&0x7F)
+		if group < 128 {
+			return value, nil
+		}
+	}
+
+	return 0, errors.New("variable length integer is too long")
+}
+
+// ReadCString reads a zero-terminated string of CP-1252 encoded characters.
+// May return an error.
+func (r *Reader) ReadCString() (string, error) {
+	var bldr strings.Builder
+
+	for {
+		character, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		if character == 0 {
+			return bldr.String(), nil
+		}
+
+		bldr.WriteRune(DecodeCharacter(character))
+	}
+}
+
+// ReadVersionedCString reads a zero-terminated string that is preceded by
+// a version byte that must be zero. May return an error.
+func (r *Reader) ReadVersionedCString() (string, error) {
+	version, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	if version != 0 {
+		return "", errors.New("unsupported versioned string")
+	}
+
+	return r.ReadCString()
+}
@@ -0,0 +1,142 @@
+package buffer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadSmart(t *testing.T) {
+	itr := NewReader([]byte{0x7F, 0x80, 0x80, 0xFF, 0xFF})
+
+	expected := []int{127, 128, 32767}
+	for _, value := range expected {
+		smart, err := itr.ReadSmart()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if smart != value {
+			t.Errorf("expected smart value %v but got %v", value, smart)
+		}
+	}
+
+	if itr.IsReadable() {
+		t.Error("expected all bytes to be read")
+	}
+}
+
+func TestReadBigSmart(t *testing.T) {
+	itr := NewReader([]byte{0x7F, 0xFF, 0x80, 0x01, 0x00, 0x00})
+
+	value, err := itr.ReadNullableBigSmart()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != -1 {
+		t.Errorf("expected nullable big smart value -1 but got %v", value)
+	}
+
+	if value, err = itr.ReadBigSmart(); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != 65536 {
+		t.Errorf("expected big smart value 65536 but got %v", value)
+	}
+}
+
+func TestReadCString(t *testing.T) {
+	itr := NewReader([]byte{'a', 0x80, 'b', 0, 'c'})
+
+	value, err := itr.ReadCString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "a€b" {
+		t.Errorf("expected a€b but got %v", value)
+	}
+
+	if _, err := itr.ReadCString(); err != ErrOutOfBounds {
+		t.Error("expected an unterminated string to fail")
+	}
+}
+
+func TestReadOutOfBounds(t *testing.T) {
+	itr := NewReader([]byte{1, 2, 3})
+
+	if _, err := itr.ReadUInt32(); err != ErrOutOfBounds {
+		t.Error("expected reading past the end to fail")
+	}
+
+	if _, err := itr.ReadUInt24(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := itr.ReadByte(); err != ErrOutOfBounds {
+		t.Error("expected reading past the end to fail")
+	}
+}
+
+func TestCursorRemembersFirstError(t *testing.T) {
+	c := NewCursor([]byte{0xFF, 0x01, 0x02, 0x03})
+
+	if value := c.Int8(); value != -1 {
+		t.Errorf("expected signed byte -1 but got %v", value)
+	}
+
+	if value := c.UInt16(); value != 0x0102 {
+		t.Errorf("expected 16-bit value 258 but got %v", value)
+	}
+
+	if value := c.UInt16(); value != 0 || c.Err() != ErrOutOfBounds {
+		t.Error("expected reading past the end to fail")
+	}
+
+	// the byte that is left is not read once the cursor failed
+	if value := c.UInt8(); value != 0 || c.IsReadable() {
+		t.Error("expected the cursor to yield zero values after failing")
+	}
+
+	if FirstError(NewCursor(nil), c) != ErrOutOfBounds {
+		t.Error("expected the first error of the cursors")
+	}
+}
+
+// varInts pairs values of two or more groups with their encoding by the client.
+var varInts = []struct {
+	value   int
+	encoded []byte
+}{
+	{127, []byte{0x7F}},
+	{128, []byte{0x81, 0x00}},
+	{300, []byte{0x82, 0x2C}},
+	{1 << 21, []byte{0x81, 0x80, 0x80, 0x00}},
+}
+
+func TestReadVarInt(t *testing.T) {
+	for _, test := range varInts {
+		value, err := NewReader(test.encoded).ReadVarInt()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value != test.value {
+			t.Errorf("expected variable length integer %v but got %v", test.value, value)
+		}
+	}
+}
+
+func TestWriteVarInt(t *testing.T) {
+	for _, test := range varInts {
+		w := NewWriter()
+		if err := w.WriteVarInt(test.value); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(w.Bytes(), test.encoded) {
+			t.Errorf("expected %v to be encoded as %v but got %v", test.value, test.encoded, w.Bytes())
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package buffer
+
+import "fmt"
+
+// Writer writes values into a growing byte array in the big-endian byte order used
+// by the cache formats.
+type Writer struct {
+	data []byte
+}
+
+// NewWriter constructs a new Writer with an empty byte array.
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Bytes returns the bytes that have been written so far.
+func (w *Writer) Bytes() []byte {
+	return w.data
+}
+
+// Length returns the amount of bytes that have been written so far.
+func (w *Writer) Length() int {
+	return len(w.data)
+}
+
+// WriteBytes writes the given bytes as they are.
+func (w *Writer) WriteBytes(value []byte) *Writer {
+	w.data = append(w.data, value...)
+	return w
+}
+
+// WriteByte writes a single byte.
+func (w *Writer) WriteByte(value byte) error {
+	w.data = append(w.data, value)
+	return nil
+}
+
+// WriteInt8 writes the lower 8 bits of the given value.
+func (w *Writer) WriteInt8(value int) *Writer {
+	w.data = append(w.data, byte(value))
+	return w
+}
+
+// WriteBool writes a single byte of either 1 or 0.
+func (w *Writer) WriteBool(value bool) *Writer {
+	if value {
+		return w.WriteInt8(1)
+	}
+
+	return w.WriteInt8(0)
+}
+
+// WriteInt16 writes the lower 16 bits of the given value.
+func (w *Writer) WriteInt16(value int) *Writer {
+	w.data = append(w.data, byte(value>>8), byte(value))
+	return w
+}
+
+// WriteInt24 writes the lower 24 bits of the given value.
+func (w *Writer) WriteInt24(value int) *Writer {
+	w.data = append(w.data, byte(value>>16), byte(value>>8), byte(value))
+	return w
+}
+
+// WriteInt32 writes the lower 32 bits of the given value.
+func (w *Writer) WriteInt32(value int) *Writer {
+	w.data = append(w.data, byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+	return w
+}
+
+// WriteInt64 writes the given 64-bit value.
+func (w *Writer) WriteInt64(value int64) *Writer {
+	w.WriteInt32(int(value >> 32))
+	return w.WriteInt32(int(value))
+}
+
+// WriteSmart writes an unsigned value in the range of 0-32767 as either one or two
+// bytes. May return an error.
+func (w *Writer) WriteSmart(value int) error {
+	if value < 0 || value > 32767 {
+		return fmt.Errorf("smart value %v out of range (0-32767)", value)
+	}
+
+	if value < 128 {
+		w.WriteInt8(value)
+	} else {
+		w.WriteInt16(value + 32768)
+	}
+
+	return nil
+}
+
+// WriteSignedSmart writes a signed value in the range of -16384-16383 as either
+// one or two bytes. May return an error.
+func (w *Writer) WriteSignedSmart(value int) error {
+	if value < -16384 || value > 16383 {
+		return fmt.Errorf("signed smart value %v out of range (-16384-16383)", value)
+	}
+
+	if value >= -64 && value < 64 {
+		w.WriteInt8(value + 64)
+	} else {
+		w.WriteInt16(value + 49152)
+	}
+
+	return nil
+}
+
+// WriteSmartMinusOne writes a smart value where -1 is encoded as zero. May return an error.
+func (w *Writer) WriteSmartMinusOne(value int) error {
+	return w.WriteSmart(value + 1)
+}
+
+// WriteIncrementalSmart writes the given value as a sum of smart values. May return an error.
+func (w *Writer) WriteIncrementalSmart(value int) error {
+	if value < 0 {
+		return fmt.Errorf("incremental smart value %v is negative", value)
+	}
+
+	for value >= 32767 {
+		w.WriteInt16(32767 + 32768)
+		value -= 32767
+	}
+
+	return w.WriteSmart(value)
+}
+
+// WriteBigSmart writes a non-negative value as either two or four bytes. May return an error.
+func (w *Writer) WriteBigSmart(value int) error {
+	if value < 0 || value > 0x7FFFFFFF {
+		return fmt.Errorf("big smart value %v out of range", value)
+	}
+
+	if value < 32768 {
+		w.WriteInt16(value)
+	} else {
+		w.WriteInt32(value | -0x80000000)
+	}
+
+	return nil
+}
+
+// WriteNullableBigSmart writes a big smart value where -1 is encoded as the
+// two-byte value of 32767. May return an error.
+func (w *Writer) WriteNullableBigSmart(value int) error {
+	if value == -1 {
+		w.WriteInt16(32767)
+		return nil
+	}
+
+	if value == 32767 {
+		w.WriteInt32(value | -0x80000000)
+		return nil
+	}
+
+	return w.WriteBigSmart(value)
+}
+
+// WriteVarInt writes a non-negative value in groups of 7 bits, most significant
+// group first. May return an error.
+func (w *Writer) WriteVarInt(value int) error {
+	if value < 0 {
+		return fmt.Errorf("variable length integer %v is negative", value)
+	}
+
+	shift := uint(0)
+	for value>>(shift+7) != 0 {
+		shift += 7
+	}
+
+	for ; shift > 0; shift -= 7 {
+		w.WriteInt8(value>>shift&0x7F | 0x80)
+	}
+
+	w.WriteInt8(value & 0x7F)
+	return nil
+}
+
+// WriteCString writes the given string as CP-1252 encoded characters followed by
+// a zero terminator.
+func (w *Writer) WriteCString(value string) *Writer {
+	for _, character := range value {
+		w.data = append(w.data, EncodeCharacter(character))
+	}
+
+	return w.WriteInt8(0)
+}
+
+// WriteVersionedCString writes the given string preceded by a zero version byte.
+func (w *Writer) WriteVersionedCString(value string) *Writer {
+	return w.WriteInt8(0).WriteCString(value)
+}
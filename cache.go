@@ -4,17 +4,30 @@ import (
 	"errors"
 	"github.com/sinoz/gokira/crypto"
 	"log"
+	"sync"
 )
 
 const (
 	releaseManifestIdx = 255
+
+	// AllIndices is the index count to pass to LoadCache and LoadFileBundle to load
+	// every index file of a cache, regardless of its revision.
+	AllIndices = releaseManifestIdx
 )
 
+// ErrLabelNotFound is returned when no folder of an archive is labelled with a given name.
+var ErrLabelNotFound = errors.New("could not find an entry going by the specified name in the specified archive")
+
 // Cache is a file store that can serve information found within the contents of the FileBundle.
 type Cache struct {
 	bundle   *FileBundle
 	mappings *indexTable
 	archives map[int]*Archive
+
+	revisionLock     sync.Mutex
+	revision         int
+	detection        sync.Once
+	detectedRevision RevisionRange
 }
 
 // LoadCache loads a FileBundle from the specified path and wraps it into an instance of a Cache.
@@ -105,6 +118,26 @@ func (cache *Cache) GetFolderManifest(archiveId, folderId int) (*FolderManifest,
 	return archiveManifest.FolderReferences[folderId], nil
 }
 
+// GetFolderPacks fetches the specified unencrypted folder and splits it up into the packs
+// that are listed in the folder's manifest. May return an error.
+func (cache *Cache) GetFolderPacks(archiveId, folderId int) ([]*Pack, error) {
+	manifest, err := cache.GetFolderManifest(archiveId, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest == nil {
+		return nil, errors.New("specified folder does not exist")
+	}
+
+	folder, err := cache.GetUnencryptedFolder(archiveId, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return folder.GetPacks(manifest)
+}
+
 func (cache *Cache) GetFolderManifestByName(archiveId int, target string) (*FolderManifest, error) {
 	archiveManifest, err := cache.GetArchiveManifest(archiveId)
 	if err != nil {
@@ -112,6 +145,10 @@ func (cache *Cache) GetFolderManifestByName(archiveId int, target string) (*Fold
 	}
 
 	for _, manifest := range archiveManifest.FolderReferences {
+		if manifest == nil {
+			continue
+		}
+
 		currentNameHash := manifest.LabelHash
 		targetNameHash := uint32(crypto.Djb2(target))
 
@@ -120,7 +157,7 @@ func (cache *Cache) GetFolderManifestByName(archiveId int, target string) (*Fold
 		}
 	}
 
-	return nil, errors.New("could not find an entry going by the specified name in the specified archive")
+	return nil, ErrLabelNotFound
 }
 
 // ArchiveCount returns the amount of archives this storage has available. This does not
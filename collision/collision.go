@@ -0,0 +1,291 @@
+// Package collision generates the collision flags of the tiles of the map from its
+// terrain and its locations, identical to the flags of the collision maps of the client,
+// for servers to validate and find the paths of movement with.
+package collision
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/mapdata"
+)
+
+// The collision flags of a tile. The flags of walls mark the sides or corners of a tile
+// that a wall blocks movement through.
+const (
+	WallNorthWest = 0x1
+	WallNorth     = 0x2
+	WallNorthEast = 0x4
+	WallEast      = 0x8
+	WallSouthEast = 0x10
+	WallSouth     = 0x20
+	WallSouthWest = 0x40
+	WallWest      = 0x80
+
+	// Object marks a tile that a location occupies.
+	Object = 0x100
+
+	// The flags of walls and objects that also block projectiles, which are the flags
+	// above shifted to the left by ProjectileShift.
+	ProjectileWallNorthWest = WallNorthWest << ProjectileShift
+	ProjectileWallNorth     = WallNorth << ProjectileShift
+	ProjectileWallNorthEast = WallNorthEast << ProjectileShift
+	ProjectileWallEast      = WallEast << ProjectileShift
+	ProjectileWallSouthEast = WallSouthEast << ProjectileShift
+	ProjectileWallSouth     = WallSouth << ProjectileShift
+	ProjectileWallSouthWest = WallSouthWest << ProjectileShift
+	ProjectileWallWest      = WallWest << ProjectileShift
+	ProjectileObject        = Object << ProjectileShift
+
+	// FloorDecoration marks a tile of which the floor decoration blocks movement.
+	FloorDecoration = 0x40000
+
+	// Floor marks a tile of which the terrain blocks movement.
+	Floor = 0x200000
+
+	// Unloaded marks a tile of a region of which the terrain has not been added, which
+	// blocks movement like the tiles beyond the scene of the client do.
+	Unloaded = 0x1000000
+)
+
+// ProjectileShift is the amount of bits the flags of walls and objects that also block
+// projectiles are shifted by.
+const ProjectileShift = 9
+
+// The shapes of locations, see mapdata.Location.
+const (
+	straightWallShape    = 0
+	diagonalCornerShape  = 1
+	wallCornerShape      = 2
+	rectangleCornerShape = 3
+	diagonalWallShape    = 9
+	centrepieceShape     = 10
+	diagonalObjectShape  = 11
+	roofShape            = 12
+	floorDecorationShape = 22
+)
+
+// ErrUnknownLoc is returned when a location refers to a LocType that does not exist.
+var ErrUnknownLoc = errors.New("location refers to an unknown loc type")
+
+// Map holds the collision flags of the tiles of the regions of the map, keyed by the id
+// of the region.
+type Map struct {
+	Regions map[int]*Region
+}
+
+// Region holds the collision flags of each tile of a region, indexed by plane, x and y.
+type Region struct {
+	Id    int
+	Flags [mapdata.Planes][mapdata.Size][mapdata.Size]int
+}
+
+// NewMap constructs an empty Map.
+func NewMap() *Map {
+	return &Map{Regions: make(map[int]*Region)}
+}
+
+// Generate decodes every region of which the XTEA keys are given from the given Cache and
+// generates their collision flags. Regions of which the terrain does not exist are
+// skipped. May return an error.
+func Generate(cache *gokira.Cache, keys map[int][4]int) (*Map, error) {
+	locs, err := config.GetLocTypes(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	collisions := NewMap()
+	for regionId, regionKeys := range keys {
+		region, err := mapdata.Load(cache, regionId, regionKeys)
+		if err == gokira.ErrLabelNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := collisions.AddRegion(region, locs); err != nil {
+			return nil, err
+		}
+	}
+
+	return collisions, nil
+}
+
+// Flags returns the collision flags of the tile at the given absolute coordinates, which
+// are Unloaded for the tiles of regions that have not been added.
+func (m *Map) Flags(plane, x, y int) int {
+	if x < 0 || y < 0 {
+		return Unloaded
+	}
+
+	region, ok := m.Regions[mapdata.RegionOf(x, y)]
+	if !ok {
+		return Unloaded
+	}
+
+	return region.Flags[plane][x%mapdata.Size][y%mapdata.Size]
+}
+
+// AddFlags adds the given collision flags to the tile at the given absolute coordinates.
+func (m *Map) AddFlags(plane, x, y, flags int) {
+	if x < 0 || y < 0 {
+		return
+	}
+
+	region := m.region(mapdata.RegionOf(x, y))
+	region.Flags[plane][x%mapdata.Size][y%mapdata.Size] |= flags
+}
+
+// region returns the Region of the given id, constructing it with every tile Unloaded if
+// it does not exist yet.
+func (m *Map) region(id int) *Region {
+	if region, ok := m.Regions[id]; ok {
+		return region
+	}
+
+	region := &Region{Id: id}
+	for plane := 0; plane < mapdata.Planes; plane++ {
+		for x := 0; x < mapdata.Size; x++ {
+			for y := 0; y < mapdata.Size; y++ {
+				region.Flags[plane][x][y] = Unloaded
+			}
+		}
+	}
+
+	m.Regions[id] = region
+	return region
+}
+
+// AddRegion adds the collision flags of the terrain and the locations of the given region,
+// of which the locations are defined by the given loc types, indexed by id. Locations may
+// add flags to the tiles of neighbouring regions. May return an error.
+func (m *Map) AddRegion(region *mapdata.Region, locs []*config.LocType) error {
+	regionX, regionY := mapdata.SplitRegionId(region.Id)
+	baseX, baseY := regionX*mapdata.Size, regionY*mapdata.Size
+
+	flags := m.region(region.Id)
+	for plane := 0; plane < mapdata.Planes; plane++ {
+		for x := 0; x < mapdata.Size; x++ {
+			for y := 0; y < mapdata.Size; y++ {
+				flags.Flags[plane][x][y] &^= Unloaded
+
+				if region.Terrain.Tiles[plane][x][y].Settings&mapdata.BlockedFlag == 0 {
+					continue
+				}
+
+				if level := collisionPlane(region.Terrain, plane, x, y); level >= 0 {
+					m.AddFlags(level, baseX+x, baseY+y, Floor)
+				}
+			}
+		}
+	}
+
+	for _, location := range region.Locations {
+		if location.Id >= len(locs) || locs[location.Id] == nil {
+			return ErrUnknownLoc
+		}
+
+		level := collisionPlane(region.Terrain, location.Plane, location.X, location.Y)
+		if level < 0 {
+			continue
+		}
+
+		m.AddLocation(level, baseX+location.X, baseY+location.Y, location.Type, location.Orientation, locs[location.Id])
+	}
+
+	return nil
+}
+
+// collisionPlane returns the plane of which the collision flags apply to the tile at the
+// given coordinates of the given plane, which is the plane below if the tile lies below
+// a bridge, or -1 if the tile is part of the ground below a bridge.
+func collisionPlane(terrain *mapdata.Terrain, plane, x, y int) int {
+	if terrain.Tiles[1][x][y].Settings&mapdata.BridgeFlag != 0 {
+		return plane - 1
+	}
+
+	return plane
+}
+
+// AddLocation adds the collision flags of a location of the given loc type, shape and
+// orientation at the given absolute coordinates, like the client does.
+func (m *Map) AddLocation(plane, x, y, shape, orientation int, loc *config.LocType) {
+	switch {
+	case shape == floorDecorationShape:
+		if loc.InteractType == 1 {
+			m.AddFlags(plane, x, y, FloorDecoration)
+		}
+
+	case loc.InteractType == 0:
+		return
+
+	case shape <= rectangleCornerShape:
+		m.addWall(plane, x, y, shape, orientation, loc.BlocksProjectile)
+
+	case shape == diagonalWallShape || shape == centrepieceShape || shape == diagonalObjectShape || shape >= roofShape:
+		sizeX, sizeY := loc.SizeX, loc.SizeY
+		if orientation == 1 || orientation == 3 {
+			sizeX, sizeY = sizeY, sizeX
+		}
+
+		flags := Object
+		if loc.BlocksProjectile {
+			flags |= ProjectileObject
+		}
+
+		for dx := 0; dx < sizeX; dx++ {
+			for dy := 0; dy < sizeY; dy++ {
+				m.AddFlags(plane, x+dx, y+dy, flags)
+			}
+		}
+	}
+}
+
+// wallFlag is a collision flag that a wall adds to the tile at an offset from its own.
+type wallFlag struct {
+	dx, dy int
+	flag   int
+}
+
+// wallFlags are the flags that walls add, indexed by shape and orientation. Rectangle
+// corners add the same flags as diagonal corners.
+var wallFlags = [3][4][]wallFlag{
+	straightWallShape: {
+		{{0, 0, WallWest}, {-1, 0, WallEast}},
+		{{0, 0, WallNorth}, {0, 1, WallSouth}},
+		{{0, 0, WallEast}, {1, 0, WallWest}},
+		{{0, 0, WallSouth}, {0, -1, WallNorth}},
+	},
+	diagonalCornerShape: {
+		{{0, 0, WallNorthWest}, {-1, 1, WallSouthEast}},
+		{{0, 0, WallNorthEast}, {1, 1, WallSouthWest}},
+		{{0, 0, WallSouthEast}, {1, -1, WallNorthWest}},
+		{{0, 0, WallSouthWest}, {-1, -1, WallNorthEast}},
+	},
+	wallCornerShape: {
+		{{0, 0, WallWest | WallNorth}, {-1, 0, WallEast}, {0, 1, WallSouth}},
+		{{0, 0, WallNorth | WallEast}, {0, 1, WallSouth}, {1, 0, WallWest}},
+		{{0, 0, WallEast | WallSouth}, {1, 0, WallWest}, {0, -1, WallNorth}},
+		{{0, 0, WallSouth | WallWest}, {0, -1, WallNorth}, {-1, 0, WallEast}},
+	},
+}
+
+// addWall adds the flags of a wall of the given shape and orientation at the given
+// absolute coordinates.
+func (m *Map) addWall(plane, x, y, shape, orientation int, blocksProjectile bool) {
+	if shape == rectangleCornerShape {
+		shape = diagonalCornerShape
+	}
+
+	for _, wall := range wallFlags[shape][orientation&3] {
+		flag := wall.flag
+		if blocksProjectile {
+			flag |= wall.flag << ProjectileShift
+		}
+
+		m.AddFlags(plane, x+wall.dx, y+wall.dy, flag)
+	}
+}
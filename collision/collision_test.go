@@ -0,0 +1,184 @@
+package collision
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+	"github.com/sinoz/gokira/mapdata"
+)
+
+// testLocation is a location of the test region.
+type testLocation struct {
+	plane, x, y, shape, orientation int
+}
+
+// loadTestCache builds the fixture cache that holds the terrain and the locations of
+// region 50, 50 along with the loc types of the locations.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	terrain := buffer.NewWriter()
+	for plane := 0; plane < mapdata.Planes; plane++ {
+		for x := 0; x < mapdata.Size; x++ {
+			for y := 0; y < mapdata.Size; y++ {
+				switch {
+				case plane == 0 && x == 3 && y == 3:
+					terrain.WriteInt16(49 + mapdata.BlockedFlag)
+				case plane == 1 && x == 5 && y == 5:
+					terrain.WriteInt16(49 + mapdata.BlockedFlag + mapdata.BridgeFlag)
+				}
+
+				terrain.WriteInt16(0)
+			}
+		}
+	}
+
+	// the locations of each loc type, in the order of their positions
+	groups := [][]testLocation{
+		{{0, 5, 5, 10, 0}, {0, 10, 10, 0, 0}, {0, 20, 20, 2, 1}, {1, 5, 5, 10, 0}},
+		{{0, 63, 20, 10, 1}},
+		{{0, 1, 1, 22, 0}},
+		{{0, 2, 2, 10, 0}},
+	}
+
+	locations := buffer.NewWriter()
+	for _, group := range groups {
+		locations.WriteSmart(1)
+
+		previous := 0
+		for _, l := range group {
+			position := l.plane<<12 | l.x<<6 | l.y
+			locations.WriteSmart(position - previous + 1)
+			locations.WriteInt8(l.shape<<2 | l.orientation)
+			previous = position
+		}
+
+		locations.WriteSmart(0)
+	}
+
+	locations.WriteSmart(0)
+
+	builder := cachetest.New()
+
+	// loc type 1 is two by three tiles large and lets projectiles pass, loc type 2
+	// blocks movement only and loc type 3 blocks nothing
+	builder.Add(2, 6, map[int][]byte{
+		0: {0},
+		1: {14, 2, 15, 3, 18, 0},
+		2: {27, 0},
+		3: {17, 0},
+	})
+
+	builder.AddFile(mapdata.Archive, 0, terrain.Bytes()).Named("m50_50")
+	builder.AddFile(mapdata.Archive, 1, locations.Bytes()).Named("l50_50")
+
+	return builder.Build(t)
+}
+
+func generateTestMap(t *testing.T) *Map {
+	cache := loadTestCache(t)
+
+	m, err := Generate(cache, map[int][4]int{
+		mapdata.RegionId(50, 50): {},
+		mapdata.RegionId(1, 1):   {},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestGenerate(t *testing.T) {
+	m := generateTestMap(t)
+
+	const baseX, baseY = 50 * mapdata.Size, 50 * mapdata.Size
+
+	expected := map[[3]int]int{
+		{0, 3, 3}:   Floor,
+		{0, 5, 5}:   Floor | Object | ProjectileObject,
+		{1, 5, 5}:   0,
+		{0, 10, 10}: WallWest | ProjectileWallWest,
+		{0, 9, 10}:  WallEast | ProjectileWallEast,
+		{0, 20, 20}: WallNorth | WallEast | ProjectileWallNorth | ProjectileWallEast,
+		{0, 20, 21}: WallSouth | ProjectileWallSouth,
+		{0, 21, 20}: WallWest | ProjectileWallWest,
+		{0, 63, 20}: Object,
+		{0, 65, 21}: Object | Unloaded,
+		{0, 66, 21}: Unloaded,
+		{0, 63, 22}: 0,
+		{0, 1, 1}:   FloorDecoration,
+		{0, 2, 2}:   0,
+	}
+
+	for coordinates, flags := range expected {
+		plane, x, y := coordinates[0], baseX+coordinates[1], baseY+coordinates[2]
+		if actual := m.Flags(plane, x, y); actual != flags {
+			t.Errorf("expected flags %#x at %v but got %#x", flags, coordinates, actual)
+		}
+	}
+
+	if len(m.Regions) != 2 || m.Regions[mapdata.RegionId(51, 50)] == nil {
+		t.Errorf("expected the location to spill into the neighbouring region")
+	}
+
+	if flags := m.Flags(0, 0, 0); flags != Unloaded {
+		t.Errorf("expected tiles of missing regions to be unloaded but got %#x", flags)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	m := generateTestMap(t)
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(m, read) {
+		t.Errorf("expected the map to survive a round trip")
+	}
+
+	if _, err := Read(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Errorf("expected an error reading an empty buffer")
+	}
+}
+
+func TestReadRejectsVersion1(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMap().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decompressor, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(decompressor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a version 1 file stored the flags of its tiles in 24 bits
+	data[len(magic)] = 1
+
+	var old bytes.Buffer
+	compressor := gzip.NewWriter(&old)
+	compressor.Write(data)
+	compressor.Close()
+
+	if _, err := Read(&old); err != ErrFormat {
+		t.Errorf("expected %v but got %v", ErrFormat, err)
+	}
+}
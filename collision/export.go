@@ -0,0 +1,139 @@
+package collision
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/sinoz/gokira/mapdata"
+)
+
+// magic identifies the binary format of a Map, followed by its version. Version 2
+// widened the flags of a tile from 24 to 32 bits.
+const (
+	magic   = "GKCM"
+	version = 2
+)
+
+// ErrFormat is returned when the data that a Map is read from is not of the binary
+// format of Write.
+var ErrFormat = errors.New("unrecognized collision map format")
+
+// Write writes the Map in a compact binary format that Read reads back. The format is
+// gzip compressed and consists of the magic "GKCM", a version byte and a 32-bit count of
+// regions, of which each consists of its 16-bit id, a byte with a bit set for each plane
+// of which any tile has a flag and, for each of those planes, the flags of its tiles as
+// 32-bit values ordered by x and then by y. Every value is big endian. May return an error.
+func (m *Map) Write(w io.Writer) error {
+	compressor := gzip.NewWriter(w)
+	writer := bufio.NewWriter(compressor)
+
+	ids := make([]int, 0, len(m.Regions))
+	for id := range m.Regions {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	header := make([]byte, len(magic)+5)
+	copy(header, magic)
+	header[len(magic)] = version
+	binary.BigEndian.PutUint32(header[len(magic)+1:], uint32(len(ids)))
+	writer.Write(header)
+
+	for _, id := range ids {
+		region := m.Regions[id]
+
+		mask := 0
+		for plane := 0; plane < mapdata.Planes; plane++ {
+			if !region.empty(plane) {
+				mask |= 1 << uint(plane)
+			}
+		}
+
+		writer.Write([]byte{byte(id >> 8), byte(id), byte(mask)})
+
+		for plane := 0; plane < mapdata.Planes; plane++ {
+			if mask&(1<<uint(plane)) == 0 {
+				continue
+			}
+
+			for x := 0; x < mapdata.Size; x++ {
+				for y := 0; y < mapdata.Size; y++ {
+					flags := region.Flags[plane][x][y]
+					writer.Write([]byte{byte(flags >> 24), byte(flags >> 16), byte(flags >> 8), byte(flags)})
+				}
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	return compressor.Close()
+}
+
+// Read reads a Map in the binary format of Write. May return an error.
+func Read(r io.Reader) (*Map, error) {
+	decompressor, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(decompressor)
+
+	header := make([]byte, len(magic)+5)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		return nil, ErrFormat
+	}
+
+	m := NewMap()
+	count := int(binary.BigEndian.Uint32(header[len(magic)+1:]))
+
+	tiles := make([]byte, mapdata.Size*mapdata.Size*4)
+	for i := 0; i < count; i++ {
+		attributes := make([]byte, 3)
+		if _, err := io.ReadFull(reader, attributes); err != nil {
+			return nil, ErrFormat
+		}
+
+		region := &Region{Id: int(attributes[0])<<8 | int(attributes[1])}
+		m.Regions[region.Id] = region
+
+		for plane := 0; plane < mapdata.Planes; plane++ {
+			if attributes[2]&(1<<uint(plane)) == 0 {
+				continue
+			}
+
+			if _, err := io.ReadFull(reader, tiles); err != nil {
+				return nil, ErrFormat
+			}
+
+			for x := 0; x < mapdata.Size; x++ {
+				for y := 0; y < mapdata.Size; y++ {
+					offset := (x*mapdata.Size + y) * 4
+					region.Flags[plane][x][y] = int(binary.BigEndian.Uint32(tiles[offset:]))
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// empty returns whether none of the tiles of the given plane of the Region has a flag.
+func (region *Region) empty(plane int) bool {
+	for x := 0; x < mapdata.Size; x++ {
+		for y := 0; y < mapdata.Size; y++ {
+			if region.Flags[plane][x][y] != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
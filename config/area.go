@@ -0,0 +1,173 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// AreaType is the definition of an element of the map, such as the icon of a map function
+// that a loc shows on the minimap and the world map.
+type AreaType struct {
+	Id int
+
+	// SpriteId is the id of the sprite that marks the area on the map, or -1.
+	// HoverSpriteId is the id of the sprite that replaces it while it is hovered, or -1.
+	SpriteId      int
+	HoverSpriteId int
+
+	// Name is the label that is drawn next to the area on the world map.
+	Name string
+
+	// TextColor is the 24-bit RGB color of the Name and TextSize selects its font.
+	TextColor int
+	TextSize  int
+
+	// Actions are the right-click options of the area on the world map.
+	Actions [5]string
+
+	// Category is the category of map functions the area is filtered by, or -1.
+	Category int
+}
+
+// GetAreaTypes decodes every AreaType in the given Cache, indexed by id. May return an error.
+func GetAreaTypes(cache *gokira.Cache) ([]*AreaType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, AreaFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*AreaType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeAreaType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetAreaType decodes the AreaType of the specified id. May return an error.
+func GetAreaType(cache *gokira.Cache, id int) (*AreaType, error) {
+	data, err := findPack(cache, ConfigArchive, AreaFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeAreaType(id, data, cache.Revision())
+}
+
+// AreaTable is the opcode Table of the AreaType. The polygon of opcode 15 is decoded
+// but not kept, as it is only drawn by the world map.
+var AreaTable = NewTable("area", newAreaType,
+	Op(1, "SpriteId", NullableBigSmart),
+	Op(2, "HoverSpriteId", NullableBigSmart),
+	Op(3, "Name", CString),
+	Op(4, "TextColor", U24),
+	Op(5, "", U24),
+	Op(6, "TextSize", U8),
+	Op(7, "", U8),
+	Op(8, "", U8),
+	Op(10, "Actions[0]", CString),
+	Op(11, "Actions[1]", CString),
+	Op(12, "Actions[2]", CString),
+	Op(13, "Actions[3]", CString),
+	Op(14, "Actions[4]", CString),
+	Op(15, "", polygon{}),
+	Op(17, "", CString),
+	Op(18, "", NullableBigSmart),
+	Op(19, "Category", U16),
+	Op(21, "", I32),
+	Op(22, "", I32),
+	Op(23, "", Tuple(U8, U8, U8)),
+	Op(24, "", Tuple(I16, I16)),
+	Op(25, "", NullableBigSmart),
+	Op(28, "", U8),
+	Op(29, "", U8),
+	Op(30, "", U8),
+)
+
+// DecodeAreaType decodes an AreaType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeAreaType(id int, data []byte, revision int) (*AreaType, error) {
+	value, err := AreaTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	area := value.(*AreaType)
+	area.Id = id
+
+	return area, nil
+}
+
+// newAreaType constructs an AreaType with its default values.
+func newAreaType() interface{} {
+	return &AreaType{
+		SpriteId:      -1,
+		HoverSpriteId: -1,
+		Category:      -1,
+	}
+}
+
+// polygon is the Codec of the outline of an area on the world map: a list of points,
+// an unused integer, a list of colors and a byte for each of the points.
+type polygon struct{}
+
+func (polygon) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]interface{}, 2*int(count))
+	for i := range points {
+		value, err := itr.ReadInt16()
+		if err != nil {
+			return nil, err
+		}
+
+		points[i] = int(value)
+	}
+
+	if _, err := itr.ReadInt32(); err != nil {
+		return nil, err
+	}
+
+	colors, err := Array(U8, I32).Decode(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]interface{}, count)
+	for i := range flags {
+		value, err := itr.ReadInt8()
+		if err != nil {
+			return nil, err
+		}
+
+		flags[i] = int(value)
+	}
+
+	return []interface{}{points, colors, flags}, nil
+}
+
+func (polygon) Encode(w *buffer.Writer, value interface{}) error {
+	values := value.([]interface{})
+	points, flags := asList(values[0]), asList(values[2])
+
+	w.WriteInt8(len(flags))
+	for _, point := range points {
+		w.WriteInt16(point.(int))
+	}
+
+	w.WriteInt32(0)
+	if err := Array(U8, I32).Encode(w, values[1]); err != nil {
+		return err
+	}
+
+	for _, flag := range flags {
+		w.WriteInt8(flag.(int))
+	}
+
+	return nil
+}
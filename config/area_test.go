@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestDecodeAreaType(t *testing.T) {
+	data := []byte{
+		1, 0x04, 0x5A,
+		3, 'B', 'a', 'n', 'k', 0,
+		10, 'V', 'i', 'e', 'w', 0,
+		15, 2, 0, 1, 0, 2, 0xFF, 0xFF, 0, 3, 0, 0, 0, 0, 1, 0, 0, 0, 7, 1, 2,
+		19, 0, 5,
+		0,
+	}
+
+	area, err := DecodeAreaType(12, data, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if area.Id != 12 || area.SpriteId != 1114 || area.HoverSpriteId != -1 || area.Name != "Bank" {
+		t.Errorf("unexpected area %+v", area)
+	}
+
+	if area.Actions[0] != "View" || area.Category != 5 {
+		t.Errorf("unexpected area %+v", area)
+	}
+}
@@ -0,0 +1,608 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Codec reads and writes the payload that follows an opcode. Decoded values are
+// always one of int, string, bool, []interface{} or map[int]interface{}, which are
+// converted to and from the type of the struct field an Opcode is bound to.
+type Codec interface {
+	Decode(itr *buffer.Reader) (interface{}, error)
+	Encode(w *buffer.Writer, value interface{}) error
+}
+
+// matcher is implemented by codecs that are only able to encode particular values,
+// which lets several opcodes share a field.
+type matcher interface {
+	Matches(value interface{}) bool
+}
+
+// scalar is a Codec of a single numeric or string value.
+type scalar struct {
+	decode func(itr *buffer.Reader) (interface{}, error)
+	encode func(w *buffer.Writer, value interface{}) error
+}
+
+func (codec scalar) Decode(itr *buffer.Reader) (interface{}, error) {
+	return codec.decode(itr)
+}
+
+func (codec scalar) Encode(w *buffer.Writer, value interface{}) error {
+	return codec.encode(w, value)
+}
+
+var (
+	// U8 is an unsigned byte.
+	U8 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadByte()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt8(value)
+			return nil
+		}),
+	}
+
+	// I8 is a signed byte.
+	I8 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadInt8()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt8(value)
+			return nil
+		}),
+	}
+
+	// U16 is an unsigned 16-bit integer.
+	U16 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadUInt16()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt16(value)
+			return nil
+		}),
+	}
+
+	// I16 is a signed 16-bit integer.
+	I16 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadInt16()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt16(value)
+			return nil
+		}),
+	}
+
+	// NullableU16 is an unsigned 16-bit integer where 65535 represents -1.
+	NullableU16 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return readNullableUInt16(itr)
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt16(value)
+			return nil
+		}),
+	}
+
+	// U24 is an unsigned 24-bit integer.
+	U24 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadUInt24()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt24(value)
+			return nil
+		}),
+	}
+
+	// I32 is a signed 32-bit integer.
+	I32 Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			value, err := itr.ReadInt32()
+			return int(value), err
+		},
+		encode: intEncoder(func(w *buffer.Writer, value int) error {
+			w.WriteInt32(value)
+			return nil
+		}),
+	}
+
+	// Smart is an unsigned value of one or two bytes.
+	Smart Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadSmart()
+		},
+		encode: intEncoder((*buffer.Writer).WriteSmart),
+	}
+
+	// BigSmart is an unsigned value of two or four bytes.
+	BigSmart Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadBigSmart()
+		},
+		encode: intEncoder((*buffer.Writer).WriteBigSmart),
+	}
+
+	// NullableBigSmart is a value of two or four bytes that may also be -1.
+	NullableBigSmart Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadNullableBigSmart()
+		},
+		encode: intEncoder((*buffer.Writer).WriteNullableBigSmart),
+	}
+
+	// VarInt is an unsigned value that is encoded in groups of 7 bits.
+	VarInt Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadVarInt()
+		},
+		encode: intEncoder((*buffer.Writer).WriteVarInt),
+	}
+
+	// CString is a zero-terminated string.
+	CString Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadCString()
+		},
+		encode: stringEncoder(func(w *buffer.Writer, value string) {
+			w.WriteCString(value)
+		}),
+	}
+
+	// VersionedCString is a zero-terminated string preceded by a zero byte.
+	VersionedCString Codec = scalar{
+		decode: func(itr *buffer.Reader) (interface{}, error) {
+			return itr.ReadVersionedCString()
+		},
+		encode: stringEncoder(func(w *buffer.Writer, value string) {
+			w.WriteVersionedCString(value)
+		}),
+	}
+
+	// Params is a map of integer or string parameters keyed by their parameter id.
+	Params Codec = params{}
+)
+
+// intEncoder adapts the given function to an encoder of int values.
+func intEncoder(encode func(w *buffer.Writer, value int) error) func(w *buffer.Writer, value interface{}) error {
+	return func(w *buffer.Writer, value interface{}) error {
+		number, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("expected an int value but got %T", value)
+		}
+
+		return encode(w, number)
+	}
+}
+
+// stringEncoder adapts the given function to an encoder of string values.
+func stringEncoder(encode func(w *buffer.Writer, value string)) func(w *buffer.Writer, value interface{}) error {
+	return func(w *buffer.Writer, value interface{}) error {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string value but got %T", value)
+		}
+
+		encode(w, text)
+		return nil
+	}
+}
+
+// flag is a Codec without a payload. The presence of its opcode sets a field to a value.
+type flag struct {
+	value interface{}
+}
+
+// Flag produces a Codec without a payload that decodes into the given value, which
+// must be an int or a bool.
+func Flag(value interface{}) Codec {
+	return flag{value: value}
+}
+
+func (codec flag) Decode(itr *buffer.Reader) (interface{}, error) {
+	return codec.value, nil
+}
+
+func (codec flag) Encode(w *buffer.Writer, value interface{}) error {
+	return nil
+}
+
+func (codec flag) Matches(value interface{}) bool {
+	return value == codec.value
+}
+
+// scaled is a Codec of an integer that is multiplied by a factor after decoding.
+type scaled struct {
+	codec  Codec
+	factor int
+}
+
+// Scaled produces a Codec of which the decoded value of the given Codec is multiplied
+// by the given factor.
+func Scaled(codec Codec, factor int) Codec {
+	return scaled{codec: codec, factor: factor}
+}
+
+func (codec scaled) Decode(itr *buffer.Reader) (interface{}, error) {
+	value, err := codec.codec.Decode(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(int) * codec.factor, nil
+}
+
+func (codec scaled) Encode(w *buffer.Writer, value interface{}) error {
+	number, ok := value.(int)
+	if !ok {
+		return fmt.Errorf("expected an int value but got %T", value)
+	}
+
+	return codec.codec.Encode(w, number/codec.factor)
+}
+
+// array is a Codec of a list of values that is preceded by its length.
+type array struct {
+	count   Codec
+	element Codec
+}
+
+// Array produces a Codec of a list of values of the element Codec, preceded by
+// the amount of values in the list.
+func Array(count, element Codec) Codec {
+	return array{count: count, element: element}
+}
+
+func (codec array) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := codec.count.Decode(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, count.(int))
+	for i := range values {
+		if values[i], err = codec.element.Decode(itr); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (codec array) Encode(w *buffer.Writer, value interface{}) error {
+	values, _ := value.([]interface{})
+	if err := codec.count.Encode(w, len(values)); err != nil {
+		return err
+	}
+
+	for _, element := range values {
+		if err := codec.element.Encode(w, element); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tuple is a Codec of several consecutive values that are each bound to their own field.
+type tuple struct {
+	codecs []Codec
+}
+
+// Tuple produces a Codec of several consecutive values, one for each given Codec.
+// An Opcode with a Tuple lists a field for each of the values.
+func Tuple(codecs ...Codec) Codec {
+	return tuple{codecs: codecs}
+}
+
+func (codec tuple) Decode(itr *buffer.Reader) (interface{}, error) {
+	values := make([]interface{}, len(codec.codecs))
+	for i, element := range codec.codecs {
+		value, err := element.Decode(itr)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func (codec tuple) Encode(w *buffer.Writer, value interface{}) error {
+	values := value.([]interface{})
+	for i, element := range codec.codecs {
+		if err := element.Encode(w, values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (codec tuple) Matches(value interface{}) bool {
+	values := value.([]interface{})
+	for i, element := range codec.codecs {
+		if m, ok := element.(matcher); ok && !m.Matches(values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// zip is a Codec of a list of tuples of which each column is bound to its own field.
+type zip struct {
+	count  Codec
+	codecs []Codec
+}
+
+// Zip produces a Codec of a list of tuples that is preceded by its length, such as
+// a list of recolors where each original color is followed by its replacement. An
+// Opcode with a Zip lists a field for each column, which holds a list of values.
+func Zip(count Codec, codecs ...Codec) Codec {
+	return zip{count: count, codecs: codecs}
+}
+
+func (codec zip) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := codec.count.Decode(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]interface{}, len(codec.codecs))
+	for column := range columns {
+		columns[column] = make([]interface{}, count.(int))
+	}
+
+	for row := 0; row < count.(int); row++ {
+		for column, element := range codec.codecs {
+			value, err := element.Decode(itr)
+			if err != nil {
+				return nil, err
+			}
+
+			columns[column].([]interface{})[row] = value
+		}
+	}
+
+	return columns, nil
+}
+
+func (codec zip) Encode(w *buffer.Writer, value interface{}) error {
+	if !codec.Matches(value) {
+		return errors.New("columns of a zipped list differ in length")
+	}
+
+	columns := value.([]interface{})
+	count := len(asList(columns[0]))
+
+	if err := codec.count.Encode(w, count); err != nil {
+		return err
+	}
+
+	for row := 0; row < count; row++ {
+		for column, element := range codec.codecs {
+			if err := element.Encode(w, asList(columns[column])[row]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (codec zip) Matches(value interface{}) bool {
+	columns := value.([]interface{})
+	for _, column := range columns {
+		if len(asList(column)) != len(asList(columns[0])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mapping is a Codec of a map of integer keys to values, preceded by its size.
+type mapping struct {
+	count Codec
+	key   Codec
+	value Codec
+}
+
+// Map produces a Codec of a map that is preceded by its size, where each key is
+// immediately followed by its value.
+func Map(count, key, value Codec) Codec {
+	return mapping{count: count, key: key, value: value}
+}
+
+func (codec mapping) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := codec.count.Decode(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int]interface{}, count.(int))
+	for i := 0; i < count.(int); i++ {
+		key, err := codec.key.Decode(itr)
+		if err != nil {
+			return nil, err
+		}
+
+		if values[key.(int)], err = codec.value.Decode(itr); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (codec mapping) Encode(w *buffer.Writer, value interface{}) error {
+	values, _ := value.(map[int]interface{})
+	if err := codec.count.Encode(w, len(values)); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(values) {
+		if err := codec.key.Encode(w, key); err != nil {
+			return err
+		}
+
+		if err := codec.value.Encode(w, values[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// params is the Codec of a map of parameters where each value is either a string or
+// an integer, as indicated by a flag in front of each key.
+type params struct{}
+
+func (codec params) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int]interface{}, count)
+	for i := 0; i < int(count); i++ {
+		isString, err := itr.ReadBool()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := itr.ReadUInt24()
+		if err != nil {
+			return nil, err
+		}
+
+		if isString {
+			values[int(key)], err = itr.ReadCString()
+		} else {
+			var value int32
+			value, err = itr.ReadInt32()
+			values[int(key)] = int(value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (codec params) Encode(w *buffer.Writer, value interface{}) error {
+	values, _ := value.(map[int]interface{})
+	w.WriteInt8(len(values))
+
+	for _, key := range sortedKeys(values) {
+		switch param := values[key].(type) {
+		case string:
+			w.WriteBool(true).WriteInt24(key).WriteCString(param)
+		case int:
+			w.WriteBool(false).WriteInt24(key).WriteInt32(param)
+		default:
+			return fmt.Errorf("unsupported param value of type %T", param)
+		}
+	}
+
+	return nil
+}
+
+// transforms is the Codec of the variable ids and the ids of the types a config type
+// transforms into, depending on the value of the variable.
+type transforms struct {
+	extended bool
+}
+
+// Transforms produces the Codec of a varbit id, a varp id and a list of transforms of
+// which the last one is the fallback for values out of range. The fallback is only
+// encoded in the extended variant. An Opcode with Transforms lists three fields.
+func Transforms(extended bool) Codec {
+	return transforms{extended: extended}
+}
+
+func (codec transforms) Decode(itr *buffer.Reader) (interface{}, error) {
+	varbitId, varpId, ids, err := readTransforms(itr, codec.extended)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, len(ids))
+	for i, id := range ids {
+		list[i] = id
+	}
+
+	return []interface{}{varbitId, varpId, list}, nil
+}
+
+func (codec transforms) Encode(w *buffer.Writer, value interface{}) error {
+	values := value.([]interface{})
+	list := asList(values[2])
+
+	if len(list) < 2 {
+		return errors.New("transforms require at least a single transform and a fallback")
+	}
+
+	w.WriteInt16(values[0].(int)).WriteInt16(values[1].(int))
+	if codec.extended {
+		w.WriteInt16(list[len(list)-1].(int))
+	}
+
+	w.WriteInt8(len(list) - 2)
+	for _, transform := range list[:len(list)-1] {
+		w.WriteInt16(transform.(int))
+	}
+
+	return nil
+}
+
+func (codec transforms) Matches(value interface{}) bool {
+	list := asList(value.([]interface{})[2])
+	return codec.extended || (len(list) > 0 && list[len(list)-1] == -1)
+}
+
+// asList converts a decoded list, which may be nil, to a slice.
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// sortedKeys returns the keys of the given map in ascending order, so that encoding
+// the same map always produces the same bytes.
+func sortedKeys(values map[int]interface{}) []int {
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Ints(keys)
+	return keys
+}
+
+// isEmpty returns whether the given decoded value is nil or an empty list or map.
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	return (kind == reflect.Slice || kind == reflect.Map) && reflect.ValueOf(value).Len() == 0
+}
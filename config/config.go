@@ -0,0 +1,128 @@
+// Package config decodes the definitions, or config types, that are stored within the
+// config archive and the texture archive of the cache.
+package config
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+const (
+	// ConfigArchive is the archive that holds a folder for each kind of config type.
+	ConfigArchive = 2
+
+	// TextureArchive is the archive that holds the texture definitions.
+	TextureArchive = 9
+
+	// DBTableIndexArchive is the archive that holds a folder of column indexes for
+	// each db table.
+	DBTableIndexArchive = 21
+)
+
+const (
+	UnderlayFolder  = 1
+	IdentikitFolder = 3
+	OverlayFolder   = 4
+	InventoryFolder = 5
+	LocFolder       = 6
+	EnumFolder      = 8
+	NpcFolder       = 9
+	ItemFolder      = 10
+	HitsplatFolder  = 32
+	HealthBarFolder = 33
+	AreaFolder      = 35
+	DBRowFolder     = 38
+	DBTableFolder   = 39
+
+	// TextureFolder is the folder within the TextureArchive that holds every texture.
+	TextureFolder = 0
+)
+
+// ErrNotFound is returned when a config type of the requested id does not exist.
+var ErrNotFound = errors.New("config type does not exist")
+
+// capacity returns the length of a slice that can hold each of the given packs by id.
+func capacity(packs []*gokira.Pack) int {
+	var count int
+
+	for _, pack := range packs {
+		if pack.Id >= count {
+			count = pack.Id + 1
+		}
+	}
+
+	return count
+}
+
+// findPack fetches the data of the pack of the specified id within the specified folder.
+// May return an error.
+func findPack(cache *gokira.Cache, archiveId, folderId, packId int) ([]byte, error) {
+	packs, err := cache.GetFolderPacks(archiveId, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pack := range packs {
+		if pack.Id == packId {
+			return pack.Data, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// readTransforms reads the varbit and varp ids and the ids of the types a config type
+// transforms into depending on the value of the variable. The last transform is the
+// fallback for values out of range, which is only encoded in the extended variant.
+// May return an error.
+func readTransforms(itr *buffer.Reader, extended bool) (int, int, []int, error) {
+	varbitId, err := readNullableUInt16(itr)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	varpId, err := readNullableUInt16(itr)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	fallback := -1
+	if extended {
+		if fallback, err = readNullableUInt16(itr); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	count, err := itr.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	transforms := make([]int, int(count)+2)
+	for i := 0; i <= int(count); i++ {
+		if transforms[i], err = readNullableUInt16(itr); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	transforms[count+1] = fallback
+
+	return varbitId, varpId, transforms, nil
+}
+
+// readNullableUInt16 reads an unsigned 16-bit integer where the value of 65535
+// represents -1. May return an error.
+func readNullableUInt16(itr *buffer.Reader) (int, error) {
+	value, err := itr.ReadUInt16()
+	if err != nil {
+		return 0, err
+	}
+
+	if value == 0xFFFF {
+		return -1, nil
+	}
+
+	return int(value), nil
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/sinoz/gokira"
+)
+
+// loadTestCache loads the fixture cache that holds a config folder for each of
+// the inventory, identikit, hitsplat, health bar, db row and db table types,
+// along with the indexes of a db table.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	bundle, err := gokira.LoadFileBundle("testdata/cache", 22)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := gokira.NewCache(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
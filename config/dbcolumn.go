@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// columns is the Codec of the columns of a db row or of the schema of a db table. Each
+// column lists the ids of the ScriptVarTypes of its tuples, followed by its values.
+// The values of a column are a flattened list of tuples. A db table only encodes the
+// values of columns that have a default.
+type columns struct {
+	defaults bool
+}
+
+func (codec columns) Decode(itr *buffer.Reader) (interface{}, error) {
+	count, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]interface{}, count)
+	values := make([]interface{}, count)
+
+	for {
+		setting, err := itr.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		if setting == 255 {
+			break
+		}
+
+		column := int(setting)
+		hasValues := true
+
+		if codec.defaults {
+			column = int(setting & 0x7F)
+			hasValues = setting&0x80 != 0
+		}
+
+		if column >= len(types) {
+			return nil, fmt.Errorf("column %v out of bounds of %v columns", column, len(types))
+		}
+
+		tupleTypes, err := readColumnTypes(itr)
+		if err != nil {
+			return nil, err
+		}
+
+		types[column] = tupleTypes
+		if !hasValues {
+			continue
+		}
+
+		if values[column], err = readColumnValues(itr, tupleTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{types, values}, nil
+}
+
+func (codec columns) Encode(w *buffer.Writer, value interface{}) error {
+	fields := value.([]interface{})
+	types, values := asList(fields[0]), asList(fields[1])
+
+	w.WriteInt8(len(types))
+	for column, tupleTypes := range types {
+		if tupleTypes == nil {
+			continue
+		}
+
+		var columnValues interface{}
+		if column < len(values) {
+			columnValues = values[column]
+		}
+
+		setting := column
+		if codec.defaults && columnValues != nil {
+			setting |= 0x80
+		}
+
+		w.WriteInt8(setting)
+		if err := writeColumnTypes(w, asList(tupleTypes)); err != nil {
+			return err
+		}
+
+		if !codec.defaults || columnValues != nil {
+			if err := writeColumnValues(w, asList(tupleTypes), asList(columnValues)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.WriteInt8(255)
+	return nil
+}
+
+// readColumnTypes reads the ids of the ScriptVarTypes of the tuples of a column.
+// May return an error.
+func readColumnTypes(itr *buffer.Reader) ([]interface{}, error) {
+	count, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]interface{}, count)
+	for i := range types {
+		if types[i], err = itr.ReadSmart(); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// readColumnValues reads the tuples of a column of the given types as a flattened
+// list of values. May return an error.
+func readColumnValues(itr *buffer.Reader, types []interface{}) ([]interface{}, error) {
+	count, err := itr.ReadSmart()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, count*len(types))
+	for i := 0; i < count; i++ {
+		for _, varType := range types {
+			value, err := readValue(itr, baseTypeOf(varType.(int)))
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+// readValue reads a single value of the given BaseVarType. Long values are decoded
+// as an int. May return an error.
+func readValue(itr *buffer.Reader, baseType BaseVarType) (interface{}, error) {
+	switch baseType {
+	case BaseString:
+		return itr.ReadCString()
+
+	case BaseLong:
+		value, err := itr.ReadInt64()
+		return int(value), err
+
+	default:
+		value, err := itr.ReadInt32()
+		return int(value), err
+	}
+}
+
+// writeColumnTypes writes the ids of the ScriptVarTypes of the tuples of a column.
+// May return an error.
+func writeColumnTypes(w *buffer.Writer, types []interface{}) error {
+	w.WriteInt8(len(types))
+	for _, varType := range types {
+		if err := w.WriteSmart(varType.(int)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeColumnValues writes the flattened tuples of a column of the given types.
+// May return an error.
+func writeColumnValues(w *buffer.Writer, types, values []interface{}) error {
+	if len(types) == 0 || len(values)%len(types) != 0 {
+		return fmt.Errorf("%v values do not form tuples of %v types", len(values), len(types))
+	}
+
+	if err := w.WriteSmart(len(values) / len(types)); err != nil {
+		return err
+	}
+
+	for i, value := range values {
+		if err := writeValue(w, baseTypeOf(types[i%len(types)].(int)), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeValue writes a single value of the given BaseVarType. May return an error.
+func writeValue(w *buffer.Writer, baseType BaseVarType, value interface{}) error {
+	switch baseType {
+	case BaseString:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string value but got %T", value)
+		}
+
+		w.WriteCString(text)
+
+	default:
+		number, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("expected an int value but got %T", value)
+		}
+
+		if baseType == BaseLong {
+			w.WriteInt64(int64(number))
+		} else {
+			w.WriteInt32(number)
+		}
+	}
+
+	return nil
+}
+
+// tuples splits the flattened values of a column into tuples of the given size, or
+// returns nil if the column has no values.
+func tuples(values []interface{}, size int) [][]interface{} {
+	if values == nil || size == 0 {
+		return nil
+	}
+
+	result := make([][]interface{}, 0, len(values)/size)
+	for i := 0; i+size <= len(values); i += size {
+		result = append(result, values[i:i+size])
+	}
+
+	return result
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// DBRowType is the definition of a row of a db table. A row holds a list of tuples
+// for each of its columns.
+type DBRowType struct {
+	Id int
+
+	// TableId is the id of the DBTableType the row belongs to.
+	TableId int
+
+	// Types holds the ids of the ScriptVarTypes of the tuples of each column, and Values
+	// the flattened tuples of each column. Both are nil for columns the row omits.
+	Types  [][]int
+	Values [][]interface{}
+}
+
+// GetDBRowTypes decodes every DBRowType in the given Cache, indexed by id. May return an error.
+func GetDBRowTypes(cache *gokira.Cache) ([]*DBRowType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, DBRowFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*DBRowType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeDBRowType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetDBRowType decodes the DBRowType of the specified id. May return an error.
+func GetDBRowType(cache *gokira.Cache, id int) (*DBRowType, error) {
+	data, err := findPack(cache, ConfigArchive, DBRowFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeDBRowType(id, data, cache.Revision())
+}
+
+// DBRowTable is the opcode Table of the DBRowType.
+var DBRowTable = NewTable("dbrow", newDBRowType,
+	Op(3, "Types,Values", columns{defaults: false}),
+	Op(4, "TableId", VarInt),
+)
+
+// DecodeDBRowType decodes a DBRowType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeDBRowType(id int, data []byte, revision int) (*DBRowType, error) {
+	value, err := DBRowTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	row := value.(*DBRowType)
+	row.Id = id
+
+	return row, nil
+}
+
+// newDBRowType constructs a DBRowType with its default values.
+func newDBRowType() interface{} {
+	return &DBRowType{TableId: -1}
+}
+
+// Tuples returns the tuples of the specified column, or nil if the row omits the column.
+func (row *DBRowType) Tuples(column int) [][]interface{} {
+	if column < 0 || column >= len(row.Values) || column >= len(row.Types) {
+		return nil
+	}
+
+	return tuples(row.Values[column], len(row.Types[column]))
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// DBTableType is the definition of the schema of a db table, a table of rows that
+// client scripts query by the values of their columns.
+type DBTableType struct {
+	Id int
+
+	// Types holds the ids of the ScriptVarTypes of the tuples of each column, and
+	// Defaults the flattened default tuples of each column, or nil if a column has
+	// no default.
+	Types    [][]int
+	Defaults [][]interface{}
+}
+
+// GetDBTableTypes decodes every DBTableType in the given Cache, indexed by id. May return an error.
+func GetDBTableTypes(cache *gokira.Cache) ([]*DBTableType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, DBTableFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*DBTableType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeDBTableType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetDBTableType decodes the DBTableType of the specified id. May return an error.
+func GetDBTableType(cache *gokira.Cache, id int) (*DBTableType, error) {
+	data, err := findPack(cache, ConfigArchive, DBTableFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeDBTableType(id, data, cache.Revision())
+}
+
+// DBTableTable is the opcode Table of the DBTableType.
+var DBTableTable = NewTable("dbtable", newDBTableType,
+	Op(1, "Types,Defaults", columns{defaults: true}),
+)
+
+// DecodeDBTableType decodes a DBTableType of the given id from the given data, using the
+// layout of the given revision. May return an error.
+func DecodeDBTableType(id int, data []byte, revision int) (*DBTableType, error) {
+	value, err := DBTableTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	table := value.(*DBTableType)
+	table.Id = id
+
+	return table, nil
+}
+
+// newDBTableType constructs a DBTableType with its default values.
+func newDBTableType() interface{} {
+	return &DBTableType{}
+}
+
+// DefaultTuples returns the default tuples of the specified column, or nil if the
+// column has no default.
+func (table *DBTableType) DefaultTuples(column int) [][]interface{} {
+	if column < 0 || column >= len(table.Defaults) || column >= len(table.Types) {
+		return nil
+	}
+
+	return tuples(table.Defaults[column], len(table.Types[column]))
+}
+
+// DBTableIndex is an index of the rows of a db table by the values of a single column.
+// The index holds a map for each element of the tuples of the column, of which only
+// the first is used for lookups by the client.
+type DBTableIndex struct {
+	Types []BaseVarType
+
+	// Rows maps the values of each tuple element to the ids of the rows that hold it.
+	// Keys are either an int or a string, depending on the BaseVarType of the element.
+	Rows []map[interface{}][]int
+}
+
+// DecodeDBTableIndex decodes a DBTableIndex from the given data. May return an error.
+func DecodeDBTableIndex(data []byte) (*DBTableIndex, error) {
+	itr := buffer.NewReader(data)
+
+	size, err := itr.ReadVarInt()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &DBTableIndex{
+		Types: make([]BaseVarType, size),
+		Rows:  make([]map[interface{}][]int, size),
+	}
+
+	for i := 0; i < size; i++ {
+		baseType, err := itr.ReadVarInt()
+		if err != nil {
+			return nil, err
+		}
+
+		if baseType > int(BaseString) {
+			return nil, fmt.Errorf("unknown base type %v", baseType)
+		}
+
+		keyCount, err := itr.ReadVarInt()
+		if err != nil {
+			return nil, err
+		}
+
+		index.Types[i] = BaseVarType(baseType)
+		index.Rows[i] = make(map[interface{}][]int, keyCount)
+
+		for j := 0; j < keyCount; j++ {
+			key, err := readValue(itr, index.Types[i])
+			if err != nil {
+				return nil, err
+			}
+
+			rowCount, err := itr.ReadVarInt()
+			if err != nil {
+				return nil, err
+			}
+
+			rows := make([]int, rowCount)
+			for k := range rows {
+				if rows[k], err = itr.ReadVarInt(); err != nil {
+					return nil, err
+				}
+			}
+
+			index.Rows[i][key] = rows
+		}
+	}
+
+	return index, nil
+}
+
+// DBTable is a db table along with its rows and the indexes of its columns.
+type DBTable struct {
+	Type *DBTableType
+
+	// Rows holds the rows of the table in ascending order of their ids.
+	Rows []*DBRowType
+
+	rows    map[int]*DBRowType
+	indexes map[int]*DBTableIndex
+}
+
+// LoadDBTable loads the db table of the specified id, along with each of its rows and
+// the indexes of its columns. Tables of which the cache holds no indexes are queried
+// by scanning their rows instead. May return an error.
+func LoadDBTable(cache *gokira.Cache, tableId int) (*DBTable, error) {
+	tableType, err := GetDBTableType(cache, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	rowTypes, err := GetDBRowTypes(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &DBTable{Type: tableType, rows: make(map[int]*DBRowType)}
+	for _, row := range rowTypes {
+		if row != nil && row.TableId == tableId {
+			table.Rows = append(table.Rows, row)
+			table.rows[row.Id] = row
+		}
+	}
+
+	if table.indexes, err = loadDBTableIndexes(cache, tableId); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// loadDBTableIndexes decodes the indexes of the columns of the specified db table, keyed
+// by column. The first pack of the folder of a table is the master index of all of its
+// rows, which is followed by the index of each indexed column. Returns nil if the cache
+// holds no indexes of the table. May return an error.
+func loadDBTableIndexes(cache *gokira.Cache, tableId int) (map[int]*DBTableIndex, error) {
+	if _, err := cache.GetArchive(DBTableIndexArchive); err != nil {
+		return nil, nil
+	}
+
+	manifest, err := cache.GetArchiveManifest(DBTableIndexArchive)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableId >= len(manifest.FolderReferences) || manifest.FolderReferences[tableId] == nil {
+		return nil, nil
+	}
+
+	packs, err := cache.GetFolderPacks(DBTableIndexArchive, tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[int]*DBTableIndex, len(packs))
+	for _, pack := range packs {
+		if pack.Id == 0 {
+			continue
+		}
+
+		if indexes[pack.Id-1], err = DecodeDBTableIndex(pack.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return indexes, nil
+}
+
+// Row returns the row of the specified id, or nil if the table has no such row.
+func (table *DBTable) Row(id int) *DBRowType {
+	return table.rows[id]
+}
+
+// Lookup returns the rows of which the first element of a tuple of the specified column
+// equals the given value, which must be an int or a string. Rows that omit the column
+// are matched against its default. May return an error.
+func (table *DBTable) Lookup(column int, value interface{}) ([]*DBRowType, error) {
+	if column < 0 || column >= len(table.Type.Types) || table.Type.Types[column] == nil {
+		return nil, fmt.Errorf("table %v has no column %v", table.Type.Id, column)
+	}
+
+	switch value.(type) {
+	case int, string:
+	default:
+		return nil, errors.New("lookup values must be an int or a string")
+	}
+
+	if index, ok := table.indexes[column]; ok && len(index.Rows) > 0 {
+		var rows []*DBRowType
+		for _, id := range index.Rows[0][value] {
+			if row := table.rows[id]; row != nil {
+				rows = append(rows, row)
+			}
+		}
+
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+		return rows, nil
+	}
+
+	var rows []*DBRowType
+	for _, row := range table.Rows {
+		columnTuples := row.Tuples(column)
+		if columnTuples == nil {
+			columnTuples = table.Type.DefaultTuples(column)
+		}
+
+		for _, tuple := range columnTuples {
+			if len(tuple) > 0 && tuple[0] == value {
+				rows = append(rows, row)
+				break
+			}
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestGetDBRowType(t *testing.T) {
+	row, err := GetDBRowType(loadTestCache(t), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if row.TableId != 0 || len(row.Types) != 2 || row.Types[1] != nil {
+		t.Fatalf("unexpected row %+v", row)
+	}
+
+	expected := [][]interface{}{{5, "axe"}, {7, "staff"}}
+	if tuples := row.Tuples(0); !reflect.DeepEqual(tuples, expected) {
+		t.Errorf("expected tuples %v but got %v", expected, tuples)
+	}
+
+	if row.Tuples(1) != nil {
+		t.Error("expected the omitted column to have no tuples")
+	}
+}
+
+func TestGetDBTableType(t *testing.T) {
+	table, err := GetDBTableType(loadTestCache(t), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(table.Types, [][]int{{0, 36}, {0}}) {
+		t.Errorf("unexpected column types %v", table.Types)
+	}
+
+	if table.DefaultTuples(0) != nil {
+		t.Error("expected column 0 to have no default")
+	}
+
+	if defaults := table.DefaultTuples(1); !reflect.DeepEqual(defaults, [][]interface{}{{10}}) {
+		t.Errorf("unexpected defaults %v", defaults)
+	}
+}
+
+func TestDBRowTableRoundTrip(t *testing.T) {
+	row := &DBRowType{
+		TableId: 300,
+		Types:   [][]int{nil, {0, 36}},
+		Values:  [][]interface{}{nil, {1, "a", 2, "b"}},
+	}
+
+	data, err := DBRowTable.Encode(row, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{3, 2, 1, 2, 0, 36, 2, 0, 0, 0, 1, 'a', 0, 0, 0, 0, 2, 'b', 0, 255, 4, 0x82, 0x2C, 0}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %v but got %v", expected, data)
+	}
+
+	decoded, err := DecodeDBRowType(0, data, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, row) {
+		t.Errorf("expected %+v but got %+v", row, decoded)
+	}
+}
+
+func TestDecodeDBTableIndex(t *testing.T) {
+	data := []byte{2, 0, 1, 0, 0, 0, 5, 2, 1, 3, 2, 1, 'x', 0, 1, 0x81, 0x00}
+
+	index, err := DecodeDBTableIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(index.Types, []BaseVarType{BaseInteger, BaseString}) {
+		t.Errorf("unexpected types %v", index.Types)
+	}
+
+	if !reflect.DeepEqual(index.Rows[0][5], []int{1, 3}) || !reflect.DeepEqual(index.Rows[1]["x"], []int{128}) {
+		t.Errorf("unexpected rows %v", index.Rows)
+	}
+}
+
+func TestDBTableLookup(t *testing.T) {
+	cache := loadTestCache(t)
+
+	table, err := LoadDBTable(cache, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Rows) != 3 || table.indexes != nil {
+		t.Fatalf("expected 3 unindexed rows but got %v", len(table.Rows))
+	}
+
+	rows, err := table.Lookup(0, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0].Id != 1 || rows[1].Id != 2 {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	if rows, _ = table.Lookup(1, 10); len(rows) != 2 || rows[0].Id != 1 {
+		t.Errorf("expected rows without column 1 to match its default but got %v", rows)
+	}
+
+	if _, err := table.Lookup(2, 0); err == nil {
+		t.Error("expected a lookup of a missing column to fail")
+	}
+
+	indexed, err := LoadDBTable(cache, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows, err = indexed.Lookup(0, 5); err != nil || len(rows) != 1 || rows[0] != indexed.Row(3) {
+		t.Errorf("unexpected indexed rows %v (%v)", rows, err)
+	}
+}
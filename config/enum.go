@@ -0,0 +1,84 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// EnumType is the definition of an enum, a map of keys to either integer or string
+// values that is used by client scripts and the server alike.
+type EnumType struct {
+	Id int
+
+	// KeyType and ValueType are the characters of the script types of the
+	// keys and the values, such as 'i' for integers and 's' for strings.
+	KeyType   byte
+	ValueType byte
+
+	// DefaultString and DefaultInt are the values of keys that are not mapped.
+	DefaultString string
+	DefaultInt    int
+
+	StringValues map[int]string
+	IntValues    map[int]int
+}
+
+// GetEnumTypes decodes every EnumType in the given Cache, indexed by id. May return an error.
+func GetEnumTypes(cache *gokira.Cache) ([]*EnumType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, EnumFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*EnumType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeEnumType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetEnumType decodes the EnumType of the specified id. May return an error.
+func GetEnumType(cache *gokira.Cache, id int) (*EnumType, error) {
+	data, err := findPack(cache, ConfigArchive, EnumFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeEnumType(id, data, cache.Revision())
+}
+
+// EnumTable is the opcode Table of the EnumType.
+var EnumTable = NewTable("enum", newEnumType,
+	Op(1, "KeyType", U8),
+	Op(2, "ValueType", U8),
+	Op(3, "DefaultString", CString),
+	Op(4, "DefaultInt", I32),
+	Op(5, "StringValues", Map(U16, I32, CString)),
+	Op(6, "IntValues", Map(U16, I32, I32)),
+)
+
+// DecodeEnumType decodes an EnumType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeEnumType(id int, data []byte, revision int) (*EnumType, error) {
+	value, err := EnumTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	enum := value.(*EnumType)
+	enum.Id = id
+
+	return enum, nil
+}
+
+// newEnumType constructs an EnumType with its default values.
+func newEnumType() interface{} {
+	return &EnumType{DefaultString: "null"}
+}
+
+// Size returns the amount of keys that are mapped to a value.
+func (enum *EnumType) Size() int {
+	return len(enum.StringValues) + len(enum.IntValues)
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// HealthBarType is the definition of a health bar that is drawn on top of an entity.
+type HealthBarType struct {
+	Id int
+
+	// StartOpacity and EndOpacity are the opacities the health bar fades between.
+	StartOpacity int
+	EndOpacity   int
+
+	// FadeStartCycle is the cycle at which the health bar starts to fade out, or -1.
+	FadeStartCycle int
+
+	// DisplayCycles is the amount of client cycles the health bar remains visible.
+	DisplayCycles int
+
+	// FrontSprite and BackSprite are the ids of the sprites that represent the
+	// remaining and the lost health, or -1 to draw plain rectangles instead.
+	FrontSprite int
+	BackSprite  int
+
+	// Width is the width of the health bar, in pixels.
+	Width int
+
+	// WidthPadding is the amount of pixels of the sprites that is not part of the bar.
+	WidthPadding int
+}
+
+// GetHealthBarTypes decodes every HealthBarType in the given Cache, indexed by id. May return an error.
+func GetHealthBarTypes(cache *gokira.Cache) ([]*HealthBarType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, HealthBarFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*HealthBarType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeHealthBarType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetHealthBarType decodes the HealthBarType of the specified id. May return an error.
+func GetHealthBarType(cache *gokira.Cache, id int) (*HealthBarType, error) {
+	data, err := findPack(cache, ConfigArchive, HealthBarFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeHealthBarType(id, data, cache.Revision())
+}
+
+// HealthBarTable is the opcode Table of the HealthBarType.
+var HealthBarTable = NewTable("health bar", newHealthBarType,
+	Op(1, "", U16),
+	Op(2, "StartOpacity", U8),
+	Op(3, "EndOpacity", U8),
+	Op(4, "FadeStartCycle", Flag(0)),
+	Op(5, "DisplayCycles", U16),
+	Op(6, "", U8),
+	Op(7, "FrontSprite", NullableBigSmart),
+	Op(8, "BackSprite", NullableBigSmart),
+	Op(11, "FadeStartCycle", U16),
+	Op(14, "Width", U8),
+	Op(15, "WidthPadding", U8),
+)
+
+// DecodeHealthBarType decodes a HealthBarType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeHealthBarType(id int, data []byte, revision int) (*HealthBarType, error) {
+	value, err := HealthBarTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	healthBar := value.(*HealthBarType)
+	healthBar.Id = id
+
+	return healthBar, nil
+}
+
+// newHealthBarType constructs a HealthBarType with its default values.
+func newHealthBarType() interface{} {
+	return &HealthBarType{
+		StartOpacity:   255,
+		EndOpacity:     255,
+		FadeStartCycle: -1,
+		DisplayCycles:  70,
+		FrontSprite:    -1,
+		BackSprite:     -1,
+		Width:          30,
+	}
+}
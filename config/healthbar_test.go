@@ -0,0 +1,18 @@
+package config
+
+import "testing"
+
+func TestGetHealthBarType(t *testing.T) {
+	healthBar, err := GetHealthBarType(loadTestCache(t), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if healthBar.StartOpacity != 200 || healthBar.EndOpacity != 255 || healthBar.DisplayCycles != 70 {
+		t.Errorf("unexpected health bar %+v", healthBar)
+	}
+
+	if healthBar.FrontSprite != 2176 || healthBar.BackSprite != 2177 || healthBar.Width != 32 || healthBar.WidthPadding != 1 {
+		t.Errorf("unexpected health bar %+v", healthBar)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// HitsplatType is the definition of a hitsplat that is drawn on top of an entity that
+// takes damage or is healed.
+type HitsplatType struct {
+	Id int
+
+	// FontId is the id of the font the amount is drawn with, or -1.
+	FontId int
+
+	// TextColor is the 24-bit RGB color of the amount.
+	TextColor int
+
+	// IconSprite, LeftSprite, MiddleSprite and RightSprite are the ids of the
+	// sprites the hitsplat is composed of, or -1. The middle sprite is repeated
+	// to fit the width of the amount.
+	IconSprite   int
+	LeftSprite   int
+	MiddleSprite int
+	RightSprite  int
+
+	// ScrollToOffsetX and ScrollToOffsetY are the offsets the hitsplat moves
+	// towards during its lifetime.
+	ScrollToOffsetX int
+	ScrollToOffsetY int
+
+	// Format is the text that is drawn where %1 is substituted by the amount.
+	Format string
+
+	// DisplayCycles is the amount of client cycles the hitsplat remains visible.
+	DisplayCycles int
+
+	// FadeStartCycle is the cycle at which the hitsplat starts to fade out, or -1.
+	FadeStartCycle int
+
+	// Comparison decides how the hitsplat is prioritized over other hitsplats, or -1.
+	Comparison int
+
+	// TextOffsetY is the vertical offset of the text relative to the sprites.
+	TextOffsetY int
+
+	// VarbitId and VarpId are the variables whose value selects one of the
+	// Transforms, or -1.
+	VarbitId   int
+	VarpId     int
+	Transforms []int
+}
+
+// GetHitsplatTypes decodes every HitsplatType in the given Cache, indexed by id. May return an error.
+func GetHitsplatTypes(cache *gokira.Cache) ([]*HitsplatType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, HitsplatFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*HitsplatType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeHitsplatType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetHitsplatType decodes the HitsplatType of the specified id. May return an error.
+func GetHitsplatType(cache *gokira.Cache, id int) (*HitsplatType, error) {
+	data, err := findPack(cache, ConfigArchive, HitsplatFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeHitsplatType(id, data, cache.Revision())
+}
+
+// HitsplatTable is the opcode Table of the HitsplatType.
+var HitsplatTable = NewTable("hitsplat", newHitsplatType,
+	Op(1, "FontId", NullableBigSmart),
+	Op(2, "TextColor", U24),
+	Op(3, "IconSprite", NullableBigSmart),
+	Op(4, "LeftSprite", NullableBigSmart),
+	Op(5, "MiddleSprite", NullableBigSmart),
+	Op(6, "RightSprite", NullableBigSmart),
+	Op(7, "ScrollToOffsetX", I16),
+	Op(8, "Format", VersionedCString),
+	Op(9, "DisplayCycles", U16),
+	Op(10, "ScrollToOffsetY", I16),
+	Op(11, "FadeStartCycle", Flag(0)),
+	Op(12, "Comparison", U8),
+	Op(13, "TextOffsetY", I16),
+	Op(14, "FadeStartCycle", U16),
+	Op(17, "VarbitId,VarpId,Transforms", Transforms(false)),
+	Op(18, "VarbitId,VarpId,Transforms", Transforms(true)),
+)
+
+// DecodeHitsplatType decodes a HitsplatType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeHitsplatType(id int, data []byte, revision int) (*HitsplatType, error) {
+	value, err := HitsplatTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	hitsplat := value.(*HitsplatType)
+	hitsplat.Id = id
+
+	return hitsplat, nil
+}
+
+// newHitsplatType constructs a HitsplatType with its default values.
+func newHitsplatType() interface{} {
+	return &HitsplatType{
+		FontId:         -1,
+		TextColor:      0xFFFFFF,
+		IconSprite:     -1,
+		LeftSprite:     -1,
+		MiddleSprite:   -1,
+		RightSprite:    -1,
+		DisplayCycles:  70,
+		FadeStartCycle: -1,
+		Comparison:     -1,
+		VarbitId:       -1,
+		VarpId:         -1,
+	}
+}
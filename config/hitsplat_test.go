@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestGetHitsplatTypes(t *testing.T) {
+	hitsplats, err := GetHitsplatTypes(loadTestCache(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	damage := hitsplats[0]
+	if damage.FontId != 494 || damage.TextColor != 0xFFFFFF || damage.MiddleSprite != 1358 || damage.LeftSprite != -1 {
+		t.Errorf("unexpected hitsplat %+v", damage)
+	}
+
+	if damage.Format != "%1" || damage.DisplayCycles != 60 || damage.FadeStartCycle != 0 {
+		t.Errorf("unexpected hitsplat %+v", damage)
+	}
+
+	transforming := hitsplats[1]
+	if transforming.VarbitId != -1 || transforming.VarpId != 1500 || transforming.Comparison != 2 {
+		t.Errorf("unexpected hitsplat %+v", transforming)
+	}
+
+	expected := []int{0, -1, 7}
+	if len(transforming.Transforms) != len(expected) {
+		t.Fatalf("expected transforms %v but got %v", expected, transforming.Transforms)
+	}
+
+	for i, transform := range expected {
+		if transforming.Transforms[i] != transform {
+			t.Errorf("expected transforms %v but got %v", expected, transforming.Transforms)
+		}
+	}
+}
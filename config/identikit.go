@@ -0,0 +1,105 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// Body parts an IdentikitType can be designed for. Female kits use the same
+// body parts with an offset of 7.
+const (
+	BodyPartHead = iota
+	BodyPartJaw
+	BodyPartTorso
+	BodyPartArms
+	BodyPartHands
+	BodyPartLegs
+	BodyPartFeet
+)
+
+// IdentikitType is the definition of a kit a player picks during character design,
+// such as a hairstyle or a type of shirt.
+type IdentikitType struct {
+	Id int
+
+	// BodyPart is the body part the kit is worn on, or -1. Kits for a female
+	// character have their body part offset by 7.
+	BodyPart int
+
+	// Models are the ids of the models the kit is composed of.
+	Models []int
+
+	// Selectable is whether a player is able to pick the kit during character design.
+	Selectable bool
+
+	RecolorFrom   []int
+	RecolorTo     []int
+	RetextureFrom []int
+	RetextureTo   []int
+
+	// ChatheadModels are the ids of the models that are shown in dialogues, or -1.
+	ChatheadModels [5]int
+}
+
+// GetIdentikitTypes decodes every IdentikitType in the given Cache, indexed by id. May return an error.
+func GetIdentikitTypes(cache *gokira.Cache) ([]*IdentikitType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, IdentikitFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*IdentikitType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeIdentikitType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetIdentikitType decodes the IdentikitType of the specified id. May return an error.
+func GetIdentikitType(cache *gokira.Cache, id int) (*IdentikitType, error) {
+	data, err := findPack(cache, ConfigArchive, IdentikitFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeIdentikitType(id, data, cache.Revision())
+}
+
+// IdentikitTable is the opcode Table of the IdentikitType.
+var IdentikitTable = NewTable("identikit", newIdentikitType,
+	Op(1, "BodyPart", U8),
+	Op(2, "Models", Array(U8, U16)),
+	Op(3, "Selectable", Flag(false)),
+	Op(40, "RecolorFrom,RecolorTo", Zip(U8, U16, U16)),
+	Op(41, "RetextureFrom,RetextureTo", Zip(U8, U16, U16)),
+	Op(60, "ChatheadModels[0]", U16),
+	Op(61, "ChatheadModels[1]", U16),
+	Op(62, "ChatheadModels[2]", U16),
+	Op(63, "ChatheadModels[3]", U16),
+	Op(64, "ChatheadModels[4]", U16),
+)
+
+// DecodeIdentikitType decodes an IdentikitType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeIdentikitType(id int, data []byte, revision int) (*IdentikitType, error) {
+	value, err := IdentikitTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	kit := value.(*IdentikitType)
+	kit.Id = id
+
+	return kit, nil
+}
+
+// newIdentikitType constructs an IdentikitType with its default values.
+func newIdentikitType() interface{} {
+	return &IdentikitType{
+		BodyPart:       -1,
+		Selectable:     true,
+		ChatheadModels: [5]int{-1, -1, -1, -1, -1},
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestGetIdentikitTypes(t *testing.T) {
+	kits, err := GetIdentikitTypes(loadTestCache(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kits) != 2 {
+		t.Fatalf("expected 2 kits but got %v", len(kits))
+	}
+
+	hair := kits[0]
+	if hair.BodyPart != BodyPartHead || !hair.Selectable || len(hair.Models) != 2 || hair.Models[1] != 231 {
+		t.Errorf("unexpected kit %+v", hair)
+	}
+
+	if hair.ChatheadModels[0] != 240 || hair.ChatheadModels[1] != -1 {
+		t.Errorf("unexpected chathead models %v", hair.ChatheadModels)
+	}
+
+	torso := kits[1]
+	if torso.BodyPart != BodyPartTorso+7 || torso.Selectable {
+		t.Errorf("unexpected kit %+v", torso)
+	}
+
+	if len(torso.RecolorFrom) != 1 || torso.RecolorFrom[0] != 6798 || torso.RecolorTo[0] != 8741 {
+		t.Errorf("unexpected recolors %v -> %v", torso.RecolorFrom, torso.RecolorTo)
+	}
+}
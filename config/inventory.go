@@ -0,0 +1,60 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// InventoryType is the definition of an inventory, such as the player's backpack,
+// equipment or bank.
+type InventoryType struct {
+	Id int
+
+	// Size is the amount of slots in the inventory.
+	Size int
+}
+
+// GetInventoryTypes decodes every InventoryType in the given Cache, indexed by id. May return an error.
+func GetInventoryTypes(cache *gokira.Cache) ([]*InventoryType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, InventoryFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*InventoryType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeInventoryType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetInventoryType decodes the InventoryType of the specified id. May return an error.
+func GetInventoryType(cache *gokira.Cache, id int) (*InventoryType, error) {
+	data, err := findPack(cache, ConfigArchive, InventoryFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeInventoryType(id, data, cache.Revision())
+}
+
+// InventoryTable is the opcode Table of the InventoryType.
+var InventoryTable = NewTable("inventory", func() interface{} { return new(InventoryType) },
+	Op(2, "Size", U16),
+)
+
+// DecodeInventoryType decodes an InventoryType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeInventoryType(id int, data []byte, revision int) (*InventoryType, error) {
+	value, err := InventoryTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := value.(*InventoryType)
+	inventory.Id = id
+
+	return inventory, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestGetInventoryTypes(t *testing.T) {
+	inventories, err := GetInventoryTypes(loadTestCache(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inventories) != 4 {
+		t.Fatalf("expected room for 4 inventories but got %v", len(inventories))
+	}
+
+	if inventories[0].Size != 28 || inventories[1].Size != 0 || inventories[3].Size != 14 {
+		t.Errorf("unexpected inventory sizes")
+	}
+
+	if inventories[2] != nil {
+		t.Error("expected inventory 2 to not exist")
+	}
+}
+
+func TestGetInventoryType(t *testing.T) {
+	cache := loadTestCache(t)
+
+	inventory, err := GetInventoryType(cache, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inventory.Id != 3 || inventory.Size != 14 {
+		t.Errorf("unexpected inventory %+v", inventory)
+	}
+
+	if _, err := GetInventoryType(cache, 2); err != ErrNotFound {
+		t.Error("expected inventory 2 to not be found")
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// ItemType is the definition of an item, also known as an obj.
+type ItemType struct {
+	Id int
+
+	Name    string
+	Examine string
+
+	// InventoryModel is the id of the model that is shown on the floor and in
+	// inventories. The 2d fields describe how the model is posed in the icon.
+	InventoryModel int
+	Zoom2d         int
+	XAngle2d       int
+	YAngle2d       int
+	ZAngle2d       int
+	XOffset2d      int
+	YOffset2d      int
+
+	Stackable bool
+	Cost      int
+	Members   bool
+	Tradeable bool
+
+	// WearPos1, WearPos2 and WearPos3 are the equipment slots the item
+	// occupies or hides when it is worn, or -1.
+	WearPos1 int
+	WearPos2 int
+	WearPos3 int
+
+	// MaleModel0, MaleModel1 and MaleModel2 are the ids of the models that are
+	// shown when the item is worn by a male character, or -1.
+	MaleModel0 int
+	MaleModel1 int
+	MaleModel2 int
+	MaleOffset int
+
+	FemaleModel0 int
+	FemaleModel1 int
+	FemaleModel2 int
+	FemaleOffset int
+
+	MaleHeadModel0   int
+	MaleHeadModel1   int
+	FemaleHeadModel0 int
+	FemaleHeadModel1 int
+
+	// FloorOptions and BagOptions are the right-click options of the item while
+	// it lies on the floor and while it is in an inventory. Options that are
+	// empty or named "Hidden" are not shown.
+	FloorOptions [5]string
+	BagOptions   [5]string
+
+	RecolorFrom   []int
+	RecolorTo     []int
+	RetextureFrom []int
+	RetextureTo   []int
+
+	// ShiftClickDropIndex is the index of the bag option that is used when the
+	// item is shift clicked, or -2 to use the default.
+	ShiftClickDropIndex int
+
+	// Weight is the weight of the item in grams.
+	Weight int
+
+	Category int
+
+	// NotedId is the id of the noted or unnoted counterpart of the item, or -1.
+	// The NotedTemplate is set for the noted variant.
+	NotedId       int
+	NotedTemplate int
+
+	// CountObj and CountCo are the ids of the items that are shown instead of
+	// this item once a stack of it reaches the associated amount.
+	CountObj [10]int
+	CountCo  [10]int
+
+	ResizeX int
+	ResizeY int
+	ResizeZ int
+
+	Ambient  int
+	Contrast int
+	Team     int
+
+	BoughtId       int
+	BoughtTemplate int
+
+	PlaceholderId       int
+	PlaceholderTemplate int
+
+	Params map[int]interface{}
+}
+
+// GetItemTypes decodes every ItemType in the given Cache, indexed by id. May return an error.
+func GetItemTypes(cache *gokira.Cache) ([]*ItemType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, ItemFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*ItemType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeItemType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetItemType decodes the ItemType of the specified id. May return an error.
+func GetItemType(cache *gokira.Cache, id int) (*ItemType, error) {
+	data, err := findPack(cache, ConfigArchive, ItemFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeItemType(id, data, cache.Revision())
+}
+
+// ItemTable is the opcode Table of the ItemType. The weight and the category of items
+// are only encoded since revision 187.
+var ItemTable = NewTable("item", newItemType,
+	Op(1, "InventoryModel", U16),
+	Op(2, "Name", CString),
+	Op(3, "Examine", CString),
+	Op(4, "Zoom2d", U16),
+	Op(5, "XAngle2d", U16),
+	Op(6, "YAngle2d", U16),
+	Op(7, "XOffset2d", I16),
+	Op(8, "YOffset2d", I16),
+	Op(11, "Stackable", Flag(true)),
+	Op(12, "Cost", I32),
+	Op(13, "WearPos1", U8),
+	Op(14, "WearPos2", U8),
+	Op(16, "Members", Flag(true)),
+	Op(23, "MaleModel0,MaleOffset", Tuple(U16, U8)),
+	Op(24, "MaleModel1", U16),
+	Op(25, "FemaleModel0,FemaleOffset", Tuple(U16, U8)),
+	Op(26, "FemaleModel1", U16),
+	Op(27, "WearPos3", U8),
+	Op(30, "FloorOptions[0]", CString),
+	Op(31, "FloorOptions[1]", CString),
+	Op(32, "FloorOptions[2]", CString),
+	Op(33, "FloorOptions[3]", CString),
+	Op(34, "FloorOptions[4]", CString),
+	Op(35, "BagOptions[0]", CString),
+	Op(36, "BagOptions[1]", CString),
+	Op(37, "BagOptions[2]", CString),
+	Op(38, "BagOptions[3]", CString),
+	Op(39, "BagOptions[4]", CString),
+	Op(40, "RecolorFrom,RecolorTo", Zip(U8, U16, U16)),
+	Op(41, "RetextureFrom,RetextureTo", Zip(U8, U16, U16)),
+	Op(42, "ShiftClickDropIndex", I8),
+	Op(65, "Tradeable", Flag(true)),
+	Op(75, "Weight", I16).Since(187),
+	Op(78, "MaleModel2", U16),
+	Op(79, "FemaleModel2", U16),
+	Op(90, "MaleHeadModel0", U16),
+	Op(91, "FemaleHeadModel0", U16),
+	Op(92, "MaleHeadModel1", U16),
+	Op(93, "FemaleHeadModel1", U16),
+	Op(94, "Category", U16).Since(187),
+	Op(95, "ZAngle2d", U16),
+	Op(97, "NotedId", U16),
+	Op(98, "NotedTemplate", U16),
+	Op(100, "CountObj[0],CountCo[0]", Tuple(U16, U16)),
+	Op(101, "CountObj[1],CountCo[1]", Tuple(U16, U16)),
+	Op(102, "CountObj[2],CountCo[2]", Tuple(U16, U16)),
+	Op(103, "CountObj[3],CountCo[3]", Tuple(U16, U16)),
+	Op(104, "CountObj[4],CountCo[4]", Tuple(U16, U16)),
+	Op(105, "CountObj[5],CountCo[5]", Tuple(U16, U16)),
+	Op(106, "CountObj[6],CountCo[6]", Tuple(U16, U16)),
+	Op(107, "CountObj[7],CountCo[7]", Tuple(U16, U16)),
+	Op(108, "CountObj[8],CountCo[8]", Tuple(U16, U16)),
+	Op(109, "CountObj[9],CountCo[9]", Tuple(U16, U16)),
+	Op(110, "ResizeX", U16),
+	Op(111, "ResizeY", U16),
+	Op(112, "ResizeZ", U16),
+	Op(113, "Ambient", I8),
+	Op(114, "Contrast", Scaled(I8, 5)),
+	Op(115, "Team", U8),
+	Op(139, "BoughtId", U16),
+	Op(140, "BoughtTemplate", U16),
+	Op(148, "PlaceholderId", U16),
+	Op(149, "PlaceholderTemplate", U16),
+	Op(249, "Params", Params),
+)
+
+// DecodeItemType decodes an ItemType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeItemType(id int, data []byte, revision int) (*ItemType, error) {
+	value, err := ItemTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	item := value.(*ItemType)
+	item.Id = id
+
+	return item, nil
+}
+
+// newItemType constructs an ItemType with its default values.
+func newItemType() interface{} {
+	return &ItemType{
+		Name:                "null",
+		Zoom2d:              2000,
+		Cost:                1,
+		WearPos1:            -1,
+		WearPos2:            -1,
+		WearPos3:            -1,
+		MaleModel0:          -1,
+		MaleModel1:          -1,
+		MaleModel2:          -1,
+		FemaleModel0:        -1,
+		FemaleModel1:        -1,
+		FemaleModel2:        -1,
+		MaleHeadModel0:      -1,
+		MaleHeadModel1:      -1,
+		FemaleHeadModel0:    -1,
+		FemaleHeadModel1:    -1,
+		FloorOptions:        [5]string{"", "", "Take", "", ""},
+		BagOptions:          [5]string{"", "", "", "", "Drop"},
+		ShiftClickDropIndex: -2,
+		Category:            -1,
+		NotedId:             -1,
+		NotedTemplate:       -1,
+		ResizeX:             128,
+		ResizeY:             128,
+		ResizeZ:             128,
+		BoughtId:            -1,
+		BoughtTemplate:      -1,
+		PlaceholderId:       -1,
+		PlaceholderTemplate: -1,
+	}
+}
+
+// IsNoted returns whether the item is the noted variant of another item.
+func (item *ItemType) IsNoted() bool {
+	return item.NotedTemplate != -1
+}
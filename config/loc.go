@@ -0,0 +1,206 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// LocType is the definition of a location, also known as a scenery object, such as
+// a wall, a door or a tree.
+type LocType struct {
+	Id int
+
+	Name string
+
+	// Models are the ids of the models of the loc. ModelTypes holds the shape each
+	// model is used for, or is nil if every model is merged into the model of the
+	// centrepiece shape.
+	Models     []int
+	ModelTypes []int
+
+	// SizeX and SizeY are the amount of tiles the loc occupies along each axis.
+	SizeX int
+	SizeY int
+
+	// InteractType tells whether the loc blocks movement: 0 if it does not, 1 if
+	// it only blocks movement when it is solid and 2 if it always does.
+	InteractType     int
+	BlocksProjectile bool
+
+	WallOrDoor      int
+	ContouredGround int
+	MergeNormals    bool
+	Occludes        bool
+	AnimationId     int
+
+	DecorDisplacement int
+	Ambient           int
+	Contrast          int
+
+	// Actions are the right-click options of the loc. Options that are empty or
+	// named "Hidden" are not shown.
+	Actions [5]string
+
+	RecolorFrom   []int
+	RecolorTo     []int
+	RetextureFrom []int
+	RetextureTo   []int
+
+	Category  int
+	IsRotated bool
+	Shadow    bool
+
+	// ModelSizeX, ModelSizeHeight and ModelSizeY scale the model of the loc, where
+	// 128 is its original size.
+	ModelSizeX      int
+	ModelSizeHeight int
+	ModelSizeY      int
+
+	MapSceneId   int
+	BlockingMask int
+
+	OffsetX      int
+	OffsetHeight int
+	OffsetY      int
+
+	ObstructsGround bool
+	IsHollow        bool
+	SupportsItems   int
+
+	// VarbitId and VarpId are the ids of the variables of which the value selects
+	// the id of the loc to transform into from Transforms, or -1.
+	VarbitId   int
+	VarpId     int
+	Transforms []int
+
+	AmbientSoundId             int
+	AmbientSoundDistance       int
+	AmbientSoundChangeTicksMin int
+	AmbientSoundChangeTicksMax int
+	AmbientSoundIds            []int
+
+	MapAreaId               int
+	RandomizeAnimationStart bool
+
+	Params map[int]interface{}
+}
+
+// CentrepieceShape is the shape of a loc that stands on its own in the centre of a tile.
+const CentrepieceShape = 10
+
+// GetLocTypes decodes every LocType in the given Cache, indexed by id. May return an error.
+func GetLocTypes(cache *gokira.Cache) ([]*LocType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, LocFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*LocType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeLocType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetLocType decodes the LocType of the specified id. May return an error.
+func GetLocType(cache *gokira.Cache, id int) (*LocType, error) {
+	data, err := findPack(cache, ConfigArchive, LocFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeLocType(id, data, cache.Revision())
+}
+
+// LocTable is the opcode Table of the LocType. The category of locs is only encoded
+// since revision 187.
+var LocTable = NewTable("loc", newLocType,
+	Op(1, "Models,ModelTypes", Zip(U8, U16, U8)),
+	Op(2, "Name", CString),
+	Op(5, "Models", Array(U8, U16)),
+	Op(14, "SizeX", U8),
+	Op(15, "SizeY", U8),
+	Op(17, "InteractType,BlocksProjectile", Tuple(Flag(0), Flag(false))),
+	Op(18, "BlocksProjectile", Flag(false)),
+	Op(19, "WallOrDoor", U8),
+	Op(21, "ContouredGround", Flag(0)),
+	Op(22, "MergeNormals", Flag(true)),
+	Op(23, "Occludes", Flag(true)),
+	Op(24, "AnimationId", NullableU16),
+	Op(27, "InteractType", Flag(1)),
+	Op(28, "DecorDisplacement", U8),
+	Op(29, "Ambient", I8),
+	Op(30, "Actions[0]", CString),
+	Op(31, "Actions[1]", CString),
+	Op(32, "Actions[2]", CString),
+	Op(33, "Actions[3]", CString),
+	Op(34, "Actions[4]", CString),
+	Op(39, "Contrast", Scaled(I8, 25)),
+	Op(40, "RecolorFrom,RecolorTo", Zip(U8, U16, U16)),
+	Op(41, "RetextureFrom,RetextureTo", Zip(U8, U16, U16)),
+	Op(61, "Category", U16).Since(187),
+	Op(62, "IsRotated", Flag(true)),
+	Op(64, "Shadow", Flag(false)),
+	Op(65, "ModelSizeX", U16),
+	Op(66, "ModelSizeHeight", U16),
+	Op(67, "ModelSizeY", U16),
+	Op(68, "MapSceneId", U16),
+	Op(69, "BlockingMask", U8),
+	Op(70, "OffsetX", I16),
+	Op(71, "OffsetHeight", I16),
+	Op(72, "OffsetY", I16),
+	Op(73, "ObstructsGround", Flag(true)),
+	Op(74, "IsHollow", Flag(true)),
+	Op(75, "SupportsItems", U8),
+	Op(77, "VarbitId,VarpId,Transforms", Transforms(false)),
+	Op(78, "AmbientSoundId,AmbientSoundDistance", Tuple(U16, U8)),
+	Op(79, "AmbientSoundChangeTicksMin,AmbientSoundChangeTicksMax,AmbientSoundDistance,AmbientSoundIds",
+		Tuple(U16, U16, U8, Array(U8, U16))),
+	Op(81, "ContouredGround", Scaled(U8, 256)),
+	Op(82, "MapAreaId", U16),
+	Op(89, "RandomizeAnimationStart", Flag(true)),
+	Op(92, "VarbitId,VarpId,Transforms", Transforms(true)),
+	Op(249, "Params", Params),
+)
+
+// DecodeLocType decodes a LocType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeLocType(id int, data []byte, revision int) (*LocType, error) {
+	value, err := LocTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := value.(*LocType)
+	loc.Id = id
+
+	return loc, nil
+}
+
+// newLocType constructs a LocType with its default values.
+func newLocType() interface{} {
+	return &LocType{
+		Name:              "null",
+		SizeX:             1,
+		SizeY:             1,
+		InteractType:      2,
+		BlocksProjectile:  true,
+		WallOrDoor:        -1,
+		ContouredGround:   -1,
+		AnimationId:       -1,
+		DecorDisplacement: 16,
+		Category:          -1,
+		Shadow:            true,
+		ModelSizeX:        128,
+		ModelSizeHeight:   128,
+		ModelSizeY:        128,
+		MapSceneId:        -1,
+		SupportsItems:     -1,
+		VarbitId:          -1,
+		VarpId:            -1,
+		AmbientSoundId:    -1,
+		MapAreaId:         -1,
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+)
+
+// NpcType is the definition of a non-player character.
+type NpcType struct {
+	Id int
+
+	Name string
+
+	// Size is the amount of tiles the npc occupies along each axis.
+	Size int
+
+	// Models are the ids of the models that are merged into the model of the
+	// npc, and ChatheadModels those of the head that is shown in dialogues.
+	Models         []int
+	ChatheadModels []int
+
+	StandingAnimation    int
+	WalkAnimation        int
+	IdleRotateLeftAnim   int
+	IdleRotateRightAnim  int
+	Rotate180Animation   int
+	RotateLeftAnimation  int
+	RotateRightAnimation int
+
+	Category int
+
+	// Actions are the right-click options of the npc. Options that are empty
+	// or named "Hidden" are not shown.
+	Actions [5]string
+
+	RecolorFrom   []int
+	RecolorTo     []int
+	RetextureFrom []int
+	RetextureTo   []int
+
+	// Stats are the attack, defence, strength, hitpoints, ranged and magic levels
+	// of the npc.
+	Stats [6]int
+
+	IsMinimapVisible bool
+	CombatLevel      int
+
+	// WidthScale and HeightScale scale the model of the npc, where 128 is its
+	// original size.
+	WidthScale  int
+	HeightScale int
+
+	HasRenderPriority bool
+	Ambient           int
+	Contrast          int
+	HeadIcon          int
+	RotationSpeed     int
+
+	// VarbitId and VarpId are the ids of the variables of which the value selects
+	// the id of the npc to transform into from Transforms, or -1.
+	VarbitId   int
+	VarpId     int
+	Transforms []int
+
+	IsInteractable bool
+	RotationFlag   bool
+	IsPet          bool
+
+	Params map[int]interface{}
+}
+
+// GetNpcTypes decodes every NpcType in the given Cache, indexed by id. May return an error.
+func GetNpcTypes(cache *gokira.Cache) ([]*NpcType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, NpcFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*NpcType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeNpcType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetNpcType decodes the NpcType of the specified id. May return an error.
+func GetNpcType(cache *gokira.Cache, id int) (*NpcType, error) {
+	data, err := findPack(cache, ConfigArchive, NpcFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeNpcType(id, data, cache.Revision())
+}
+
+// NpcTable is the opcode Table of the NpcType. The category of npcs is only encoded
+// since revision 187.
+var NpcTable = NewTable("npc", newNpcType,
+	Op(1, "Models", Array(U8, U16)),
+	Op(2, "Name", CString),
+	Op(12, "Size", U8),
+	Op(13, "StandingAnimation", U16),
+	Op(14, "WalkAnimation", U16),
+	Op(15, "IdleRotateLeftAnim", U16),
+	Op(16, "IdleRotateRightAnim", U16),
+	Op(17, "WalkAnimation,Rotate180Animation,RotateLeftAnimation,RotateRightAnimation", Tuple(U16, U16, U16, U16)),
+	Op(18, "Category", U16).Since(187),
+	Op(30, "Actions[0]", CString),
+	Op(31, "Actions[1]", CString),
+	Op(32, "Actions[2]", CString),
+	Op(33, "Actions[3]", CString),
+	Op(34, "Actions[4]", CString),
+	Op(40, "RecolorFrom,RecolorTo", Zip(U8, U16, U16)),
+	Op(41, "RetextureFrom,RetextureTo", Zip(U8, U16, U16)),
+	Op(60, "ChatheadModels", Array(U8, U16)),
+	Op(74, "Stats[0]", U16),
+	Op(75, "Stats[1]", U16),
+	Op(76, "Stats[2]", U16),
+	Op(77, "Stats[3]", U16),
+	Op(78, "Stats[4]", U16),
+	Op(79, "Stats[5]", U16),
+	Op(93, "IsMinimapVisible", Flag(false)),
+	Op(95, "CombatLevel", U16),
+	Op(97, "WidthScale", U16),
+	Op(98, "HeightScale", U16),
+	Op(99, "HasRenderPriority", Flag(true)),
+	Op(100, "Ambient", I8),
+	Op(101, "Contrast", I8),
+	Op(102, "HeadIcon", U16),
+	Op(103, "RotationSpeed", U16),
+	Op(106, "VarbitId,VarpId,Transforms", Transforms(false)),
+	Op(107, "IsInteractable", Flag(false)),
+	Op(109, "RotationFlag", Flag(false)),
+	Op(111, "IsPet", Flag(true)),
+	Op(118, "VarbitId,VarpId,Transforms", Transforms(true)),
+	Op(249, "Params", Params),
+)
+
+// DecodeNpcType decodes an NpcType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeNpcType(id int, data []byte, revision int) (*NpcType, error) {
+	value, err := NpcTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	npc := value.(*NpcType)
+	npc.Id = id
+
+	return npc, nil
+}
+
+// newNpcType constructs an NpcType with its default values.
+func newNpcType() interface{} {
+	return &NpcType{
+		Name:                 "null",
+		Size:                 1,
+		StandingAnimation:    -1,
+		WalkAnimation:        -1,
+		IdleRotateLeftAnim:   -1,
+		IdleRotateRightAnim:  -1,
+		Rotate180Animation:   -1,
+		RotateLeftAnimation:  -1,
+		RotateRightAnimation: -1,
+		Category:             -1,
+		IsMinimapVisible:     true,
+		CombatLevel:          -1,
+		WidthScale:           128,
+		HeightScale:          128,
+		HeadIcon:             -1,
+		RotationSpeed:        32,
+		VarbitId:             -1,
+		VarpId:               -1,
+		IsInteractable:       true,
+		RotationFlag:         true,
+	}
+}
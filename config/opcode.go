@@ -0,0 +1,466 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// LatestRevision selects the latest layout of every opcode of a Table.
+const LatestRevision = 0
+
+// Opcode describes the payload that follows an opcode of a config type and the struct
+// field, or fields, the payload is decoded into. Fields are referred to by their name
+// and an element of an array field by its name followed by an index, such as
+// "Options[2]". An Opcode without a field is decoded but otherwise ignored.
+type Opcode struct {
+	Code   int
+	Fields []string
+	Codec  Codec
+
+	// MinRevision and MaxRevision are the first and the last revision the opcode
+	// is encoded with this layout in, or 0 if the range is open on that side.
+	MinRevision int
+	MaxRevision int
+}
+
+// Op produces an Opcode that is decoded into the given comma-separated fields with
+// the given Codec, in every revision.
+func Op(code int, fields string, codec Codec) Opcode {
+	opcode := Opcode{Code: code, Codec: codec}
+	if fields != "" {
+		opcode.Fields = strings.Split(fields, ",")
+	}
+
+	return opcode
+}
+
+// Since restricts the Opcode to the given revision and every revision after it.
+func (opcode Opcode) Since(revision int) Opcode {
+	opcode.MinRevision = revision
+	return opcode
+}
+
+// Until restricts the Opcode to the given revision and every revision before it.
+func (opcode Opcode) Until(revision int) Opcode {
+	opcode.MaxRevision = revision
+	return opcode
+}
+
+// appliesTo returns whether the opcode has this layout in the given revision. The
+// revision of 0 denotes the latest revision.
+func (opcode Opcode) appliesTo(revision int) bool {
+	if revision == 0 {
+		return opcode.MaxRevision == 0
+	}
+
+	return (opcode.MinRevision == 0 || revision >= opcode.MinRevision) &&
+		(opcode.MaxRevision == 0 || revision <= opcode.MaxRevision)
+}
+
+// Table describes a config type as a table of opcodes which is used to both decode
+// and encode the type. Supporting a new revision of a config type only requires the
+// opcodes that have changed to be restricted to a range of revisions.
+type Table struct {
+	// Name is the name of the config type, which is used in errors.
+	Name string
+
+	// New constructs a pointer to a config type that holds its default values.
+	New func() interface{}
+
+	opcodes []Opcode
+}
+
+// NewTable constructs a new Table of the given opcodes. Panics if two opcodes with the
+// same code apply to the same revision, as their layout would be ambiguous.
+func NewTable(name string, new func() interface{}, opcodes ...Opcode) *Table {
+	for i, opcode := range opcodes {
+		for _, other := range opcodes[i+1:] {
+			if opcode.Code == other.Code && overlaps(opcode, other) {
+				panic(fmt.Sprintf("%v opcode %v is ambiguous", name, opcode.Code))
+			}
+		}
+	}
+
+	sorted := make([]Opcode, len(opcodes))
+	copy(sorted, opcodes)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Code < sorted[j].Code
+	})
+
+	return &Table{Name: name, New: new, opcodes: sorted}
+}
+
+// overlaps returns whether the revision ranges of the given opcodes overlap.
+func overlaps(a, b Opcode) bool {
+	aMax, bMax := a.MaxRevision, b.MaxRevision
+	if aMax == 0 {
+		aMax = int(^uint(0) >> 1)
+	}
+
+	if bMax == 0 {
+		bMax = int(^uint(0) >> 1)
+	}
+
+	return a.MinRevision <= bMax && b.MinRevision <= aMax
+}
+
+// Layout returns the opcodes of the table that apply to the given revision, keyed by code.
+func (table *Table) Layout(revision int) map[int]Opcode {
+	layout := make(map[int]Opcode)
+	for _, opcode := range table.opcodes {
+		if opcode.appliesTo(revision) {
+			layout[opcode.Code] = opcode
+		}
+	}
+
+	return layout
+}
+
+// Decode decodes a config type from the given data, using the layout of the given
+// revision. May return an error.
+func (table *Table) Decode(data []byte, revision int) (interface{}, error) {
+	target := table.New()
+	if err := table.DecodeInto(target, data, revision); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// DecodeInto decodes the given data into the given pointer to a config type, using the
+// layout of the given revision. May return an error.
+func (table *Table) DecodeInto(target interface{}, data []byte, revision int) error {
+	layout := table.Layout(revision)
+	value := reflect.ValueOf(target).Elem()
+
+	itr := buffer.NewReader(data)
+	for {
+		code, err := itr.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		if code == 0 {
+			return nil
+		}
+
+		opcode, ok := layout[int(code)]
+		if !ok {
+			return fmt.Errorf("unknown %v opcode %v", table.Name, code)
+		}
+
+		payload, err := opcode.Codec.Decode(itr)
+		if err != nil {
+			return err
+		}
+
+		if err := opcode.assign(value, payload); err != nil {
+			return fmt.Errorf("%v opcode %v: %v", table.Name, code, err)
+		}
+	}
+}
+
+// Encode encodes the given pointer to a config type using the layout of the given
+// revision. Only the fields that differ from their default values are encoded. When
+// several opcodes are bound to the same field, the first opcode that is able to
+// encode the value of the field is used. May return an error.
+func (table *Table) Encode(source interface{}, revision int) ([]byte, error) {
+	value := reflect.ValueOf(source).Elem()
+	defaults := reflect.ValueOf(table.New()).Elem()
+
+	encoded := make(map[string]bool)
+
+	w := buffer.NewWriter()
+	for _, opcode := range table.opcodes {
+		if !opcode.appliesTo(revision) || len(opcode.Fields) == 0 || encoded[opcode.Fields[0]] {
+			continue
+		}
+
+		payload, err := opcode.extract(value)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultPayload, err := opcode.extract(defaults)
+		if err != nil {
+			return nil, err
+		}
+
+		if isDefault(payload, defaultPayload) {
+			continue
+		}
+
+		if codec, ok := opcode.Codec.(matcher); ok && !codec.Matches(payload) {
+			continue
+		}
+
+		w.WriteInt8(opcode.Code)
+		if err := opcode.Codec.Encode(w, payload); err != nil {
+			return nil, fmt.Errorf("%v opcode %v: %v", table.Name, opcode.Code, err)
+		}
+
+		for _, field := range opcode.Fields {
+			encoded[field] = true
+		}
+	}
+
+	w.WriteInt8(0)
+
+	return w.Bytes(), nil
+}
+
+// isDefault returns whether the given payload equals the given default payload.
+func isDefault(payload, defaultPayload interface{}) bool {
+	values, isTuple := payload.([]interface{})
+	defaultValues, _ := defaultPayload.([]interface{})
+
+	if isTuple && len(values) == len(defaultValues) {
+		for i := range values {
+			if !isDefault(values[i], defaultValues[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if isEmpty(payload) && isEmpty(defaultPayload) {
+		return true
+	}
+
+	return reflect.DeepEqual(payload, defaultPayload)
+}
+
+// assign assigns the given decoded payload to the fields of the opcode.
+func (opcode Opcode) assign(target reflect.Value, payload interface{}) error {
+	if len(opcode.Fields) == 0 {
+		return nil
+	}
+
+	if len(opcode.Fields) == 1 {
+		field, err := lookupField(target, opcode.Fields[0])
+		if err != nil {
+			return err
+		}
+
+		return assign(field, payload)
+	}
+
+	values := payload.([]interface{})
+	for i, name := range opcode.Fields {
+		field, err := lookupField(target, name)
+		if err != nil {
+			return err
+		}
+
+		if err := assign(field, values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extract extracts the payload to encode from the fields of the opcode.
+func (opcode Opcode) extract(source reflect.Value) (interface{}, error) {
+	if len(opcode.Fields) == 1 {
+		field, err := lookupField(source, opcode.Fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return extract(field), nil
+	}
+
+	values := make([]interface{}, len(opcode.Fields))
+	for i, name := range opcode.Fields {
+		field, err := lookupField(source, name)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = extract(field)
+	}
+
+	return values, nil
+}
+
+// lookupField looks up the struct field, or the element of an array field, by name.
+func lookupField(target reflect.Value, name string) (reflect.Value, error) {
+	index := -1
+	if open := strings.IndexByte(name, '['); open != -1 && strings.HasSuffix(name, "]") {
+		value, err := strconv.Atoi(name[open+1 : len(name)-1])
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("malformed field %v", name)
+		}
+
+		index = value
+		name = name[:open]
+	}
+
+	field := target.FieldByName(name)
+	if !field.IsValid() {
+		return reflect.Value{}, fmt.Errorf("unknown field %v", name)
+	}
+
+	if index == -1 {
+		return field, nil
+	}
+
+	if field.Kind() != reflect.Array || index >= field.Len() {
+		return reflect.Value{}, fmt.Errorf("field %v has no element %v", name, index)
+	}
+
+	return field.Index(index), nil
+}
+
+// assign converts the given decoded value to the type of the given field and assigns it.
+func assign(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to an integer field", value)
+		}
+
+		field.SetInt(int64(number))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to an integer field", value)
+		}
+
+		field.SetUint(uint64(number))
+
+	case reflect.Bool:
+		switch flag := value.(type) {
+		case bool:
+			field.SetBool(flag)
+		case int:
+			field.SetBool(flag != 0)
+		default:
+			return fmt.Errorf("cannot assign %T to a boolean field", value)
+		}
+
+	case reflect.String:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to a string field", value)
+		}
+
+		field.SetString(text)
+
+	case reflect.Slice:
+		values := asList(value)
+
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, element := range values {
+			if err := assign(slice.Index(i), element); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+
+	case reflect.Array:
+		values := asList(value)
+		for i := 0; i < len(values) && i < field.Len(); i++ {
+			if err := assign(field.Index(i), values[i]); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		values, ok := value.(map[int]interface{})
+		if !ok {
+			return fmt.Errorf("cannot assign %T to a map field", value)
+		}
+
+		mapping := reflect.MakeMapWithSize(field.Type(), len(values))
+		for key, element := range values {
+			keyValue := reflect.New(field.Type().Key()).Elem()
+			if err := assign(keyValue, key); err != nil {
+				return err
+			}
+
+			elementValue := reflect.New(field.Type().Elem()).Elem()
+			if err := assign(elementValue, element); err != nil {
+				return err
+			}
+
+			mapping.SetMapIndex(keyValue, elementValue)
+		}
+
+		field.Set(mapping)
+
+	case reflect.Interface:
+		field.Set(reflect.ValueOf(value))
+
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+
+	return nil
+}
+
+// extract converts the value of the given field to a value a Codec is able to encode.
+func extract(field reflect.Value) interface{} {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(field.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(field.Uint())
+
+	case reflect.Bool:
+		return field.Bool()
+
+	case reflect.String:
+		return field.String()
+
+	case reflect.Slice, reflect.Array:
+		if field.Kind() == reflect.Slice && field.IsNil() {
+			return nil
+		}
+
+		values := make([]interface{}, field.Len())
+		for i := range values {
+			values[i] = extract(field.Index(i))
+		}
+
+		return values
+
+	case reflect.Map:
+		if field.IsNil() {
+			return nil
+		}
+
+		values := make(map[int]interface{}, field.Len())
+		for _, key := range field.MapKeys() {
+			values[extract(key).(int)] = extract(field.MapIndex(key))
+		}
+
+		return values
+
+	case reflect.Interface:
+		if field.IsNil() {
+			return nil
+		}
+
+		return extract(field.Elem())
+	}
+
+	return field.Interface()
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type testType struct {
+	Size    int
+	Name    string
+	Visible bool
+	Models  []int
+}
+
+var testTable = NewTable("test", func() interface{} { return &testType{Visible: true} },
+	Op(1, "Size", U8).Until(149),
+	Op(1, "Size", U16).Since(150),
+	Op(2, "Name", CString),
+	Op(3, "Visible", Flag(false)),
+	Op(4, "Models", Array(U8, BigSmart)),
+)
+
+func TestTableRevisions(t *testing.T) {
+	old, err := testTable.Decode([]byte{1, 7, 2, 'a', 0, 0}, 149)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if old.(*testType).Size != 7 || old.(*testType).Name != "a" {
+		t.Errorf("unexpected decoded value %+v", old)
+	}
+
+	recent, err := testTable.Decode([]byte{1, 1, 7, 3, 0}, 187)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recent.(*testType).Size != 263 || recent.(*testType).Visible {
+		t.Errorf("unexpected decoded value %+v", recent)
+	}
+
+	latest, err := testTable.Decode([]byte{1, 1, 7, 0}, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.(*testType).Size != 263 {
+		t.Errorf("unexpected decoded value %+v", latest)
+	}
+}
+
+func TestTableAmbiguousOpcodes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected overlapping opcodes to be rejected")
+		}
+	}()
+
+	NewTable("test", func() interface{} { return new(testType) },
+		Op(1, "Size", U8).Until(160),
+		Op(1, "Size", U16).Since(150),
+	)
+}
+
+func TestTableEncode(t *testing.T) {
+	value := &testType{Size: 300, Visible: false, Models: []int{1, 40000}}
+
+	data, err := testTable.Encode(value, 187)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{1, 1, 44, 3, 4, 2, 0, 1, 0x80, 0, 0x9C, 0x40, 0}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %v but got %v", expected, data)
+	}
+
+	decoded, err := testTable.Decode(data, 187)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("expected %+v but got %+v", value, decoded)
+	}
+}
+
+func TestItemTableRoundTrip(t *testing.T) {
+	item := newItemType().(*ItemType)
+	item.Name = "Abyssal whip"
+	item.InventoryModel = 5412
+	item.XOffset2d = -3
+	item.Members = true
+	item.Contrast = 25
+	item.FloorOptions[2] = "Pick-up"
+	item.BagOptions[1] = "Wield"
+	item.RecolorFrom = []int{10}
+	item.RecolorTo = []int{20}
+	item.CountObj[1] = 996
+	item.CountCo[1] = 2
+	item.Params = map[int]interface{}{1: 5, 2: "value"}
+
+	data, err := ItemTable.Encode(item, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeItemType(item.Id, data, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, item) {
+		t.Errorf("expected %+v but got %+v", item, decoded)
+	}
+}
+
+func TestSharedFieldEncoding(t *testing.T) {
+	hitsplat := newHitsplatType().(*HitsplatType)
+	hitsplat.FadeStartCycle = 0
+
+	data, err := HitsplatTable.Encode(hitsplat, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte{11, 0}) {
+		t.Errorf("expected the flag opcode to be used but got %v", data)
+	}
+
+	hitsplat.FadeStartCycle = 20
+	hitsplat.VarpId = 5
+	hitsplat.Transforms = []int{3, 4, -1}
+
+	if data, err = HitsplatTable.Encode(hitsplat, LatestRevision); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{14, 0, 20, 17, 0xFF, 0xFF, 0, 5, 1, 0, 3, 0, 4, 0}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %v but got %v", expected, data)
+	}
+}
+
+func TestLocTableRoundTrip(t *testing.T) {
+	loc := newLocType().(*LocType)
+	loc.Name = "Oak tree"
+	loc.Models = []int{1001, 1002}
+	loc.ModelTypes = []int{10, 11}
+	loc.InteractType = 0
+	loc.BlocksProjectile = false
+	loc.Contrast = 50
+	loc.ContouredGround = 512
+	loc.AmbientSoundIds = []int{3, 4}
+	loc.Actions[0] = "Chop down"
+
+	data, err := LocTable.Encode(loc, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeLocType(loc.Id, data, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, loc) {
+		t.Errorf("expected %+v but got %+v", loc, decoded)
+	}
+
+	loc.ModelTypes = nil
+	if data, err = LocTable.Encode(loc, LatestRevision); err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0] != 2 || !bytes.Contains(data, []byte{5, 2, 0x03, 0xE9, 0x03, 0xEA}) {
+		t.Errorf("expected the models to be encoded without shapes but got %v", data)
+	}
+}
+
+func TestCategoryRevisions(t *testing.T) {
+	tables := []struct {
+		table *Table
+		code  int
+	}{
+		{ItemTable, 94},
+		{NpcTable, 18},
+		{LocTable, 61},
+	}
+
+	for _, test := range tables {
+		data := []byte{byte(test.code), 0, 5, 0}
+
+		if _, err := test.table.Decode(data, 149); err == nil {
+			t.Errorf("expected the %v category to be unknown in revision 149", test.table.Name)
+		}
+
+		value, err := test.table.Decode(data, 187)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		category := reflect.ValueOf(value).Elem().FieldByName("Category").Int()
+		if category != 5 {
+			t.Errorf("expected the %v category to be 5 in revision 187 but got %v", test.table.Name, category)
+		}
+
+		// the category is left out of the encoding of revision 149
+		encoded, err := test.table.Encode(value, 149)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(encoded, []byte{0}) {
+			t.Errorf("expected the %v category to be left out in revision 149 but got %v", test.table.Name, encoded)
+		}
+	}
+}
+
+func TestItemWeightRevisions(t *testing.T) {
+	data := []byte{2, 'a', 0, 75, 0x01, 0x2C, 0}
+
+	if _, err := DecodeItemType(0, data, 149); err == nil {
+		t.Error("expected the weight to be unknown in revision 149")
+	}
+
+	item, err := DecodeItemType(0, data, 187)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Name != "a" || item.Weight != 300 {
+		t.Errorf("unexpected item %+v", item)
+	}
+
+	if item, err = DecodeItemType(0, append(data[:3:3], 0), 149); err != nil || item.Name != "a" {
+		t.Errorf("expected the name to be decoded in revision 149 but got %+v, %v", item, err)
+	}
+}
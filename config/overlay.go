@@ -0,0 +1,111 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/hsl"
+)
+
+// OverlayType is the definition of the floor that is drawn on top of the underlay of a
+// tile, either covering the whole tile or a part of it depending on the tile's shape.
+type OverlayType struct {
+	Id int
+
+	// Color is the 24-bit RGB color of the overlay. The client treats the
+	// color 0xFF00FF as fully transparent.
+	Color int
+
+	// Texture is the id of the texture that is drawn on the overlay, or -1.
+	Texture int
+
+	// HideUnderlay is whether the underlay is left undrawn underneath the overlay.
+	HideUnderlay bool
+
+	// SecondaryColor is the 24-bit RGB color that is shown on the minimap, or -1.
+	SecondaryColor int
+
+	// Hue, Saturation and Lightness are the 8-bit components of the Color.
+	Hue        int
+	Saturation int
+	Lightness  int
+
+	// SecondaryHue, SecondarySaturation and SecondaryLightness are the 8-bit
+	// components of the SecondaryColor.
+	SecondaryHue        int
+	SecondarySaturation int
+	SecondaryLightness  int
+}
+
+// GetOverlayTypes decodes every OverlayType in the given Cache, indexed by id. May return an error.
+func GetOverlayTypes(cache *gokira.Cache) ([]*OverlayType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, OverlayFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*OverlayType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeOverlayType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetOverlayType decodes the OverlayType of the specified id. May return an error.
+func GetOverlayType(cache *gokira.Cache, id int) (*OverlayType, error) {
+	data, err := findPack(cache, ConfigArchive, OverlayFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeOverlayType(id, data, cache.Revision())
+}
+
+// OverlayTable is the opcode Table of the OverlayType.
+var OverlayTable = NewTable("overlay", newOverlayType,
+	Op(1, "Color", U24),
+	Op(2, "Texture", U8),
+	Op(5, "HideUnderlay", Flag(false)),
+	Op(7, "SecondaryColor", U24),
+)
+
+// DecodeOverlayType decodes an OverlayType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeOverlayType(id int, data []byte, revision int) (*OverlayType, error) {
+	value, err := OverlayTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay := value.(*OverlayType)
+	overlay.Id = id
+	overlay.computeColorComponents()
+
+	return overlay, nil
+}
+
+// newOverlayType constructs an OverlayType with its default values.
+func newOverlayType() interface{} {
+	return &OverlayType{
+		Texture:        -1,
+		HideUnderlay:   true,
+		SecondaryColor: -1,
+	}
+}
+
+// computeColorComponents computes the HSL components of the overlay's colors the same
+// way the client does.
+func (overlay *OverlayType) computeColorComponents() {
+	if overlay.SecondaryColor != -1 {
+		overlay.SecondaryHue, overlay.SecondarySaturation, overlay.SecondaryLightness = overlayComponents(overlay.SecondaryColor)
+	}
+
+	overlay.Hue, overlay.Saturation, overlay.Lightness = overlayComponents(overlay.Color)
+}
+
+// overlayComponents converts the given RGB color to the 8-bit HSL components of an overlay.
+func overlayComponents(rgb int) (int, int, int) {
+	hue, saturation, lightness := hsl.FromRGB(rgb)
+	return int(hue * 256.0), clampChannel(int(saturation * 256.0)), clampChannel(int(lightness * 256.0))
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestDecodeOverlayType(t *testing.T) {
+	overlay, err := DecodeOverlayType(3, []byte{1, 0x00, 0x00, 0xFF, 2, 12, 5, 7, 0xFF, 0x00, 0x00, 0}, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if overlay.Texture != 12 || overlay.HideUnderlay || overlay.SecondaryColor != 0xFF0000 {
+		t.Errorf("unexpected overlay %+v", overlay)
+	}
+
+	if overlay.Hue != 170 || overlay.Saturation != 255 || overlay.Lightness != 127 {
+		t.Errorf("unexpected color components %v, %v, %v", overlay.Hue, overlay.Saturation, overlay.Lightness)
+	}
+
+	if overlay.SecondaryHue != 0 || overlay.SecondarySaturation != 255 {
+		t.Errorf("unexpected secondary color components %v, %v", overlay.SecondaryHue, overlay.SecondarySaturation)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+// BaseVarType is the primitive type a ScriptVarType is stored as.
+type BaseVarType int
+
+const (
+	BaseInteger BaseVarType = iota
+	BaseLong
+	BaseString
+)
+
+// ScriptVarType is a type of the values that client scripts, params and db tables
+// operate on. Most types are ids of other config types and are stored as integers.
+type ScriptVarType struct {
+	Id       int
+	Char     rune
+	Name     string
+	BaseType BaseVarType
+}
+
+// ScriptVarTypes contains the known script var types, keyed by id.
+var ScriptVarTypes = map[int]ScriptVarType{}
+
+func init() {
+	for _, varType := range []ScriptVarType{
+		{0, 'i', "int", BaseInteger},
+		{1, '1', "boolean", BaseInteger},
+		{6, 'A', "seq", BaseInteger},
+		{7, 'C', "colour", BaseInteger},
+		{9, 'I', "component", BaseInteger},
+		{10, 'K', "idkit", BaseInteger},
+		{11, 'M', "midi", BaseInteger},
+		{13, 'O', "namedobj", BaseInteger},
+		{14, 'P', "synth", BaseInteger},
+		{16, 'R', "area", BaseInteger},
+		{17, 'S', "stat", BaseInteger},
+		{18, 'T', "npc_stat", BaseInteger},
+		{19, 'V', "writeinv", BaseInteger},
+		{22, 'c', "coord", BaseInteger},
+		{23, 'd', "graphic", BaseInteger},
+		{25, 'f', "fontmetrics", BaseInteger},
+		{26, 'g', "enum", BaseInteger},
+		{28, 'j', "jingle", BaseInteger},
+		{30, 'l', "loc", BaseInteger},
+		{31, 'm', "model", BaseInteger},
+		{32, 'n', "npc", BaseInteger},
+		{33, 'o', "obj", BaseInteger},
+		{36, 's', "string", BaseString},
+		{37, 't', "spotanim", BaseInteger},
+		{39, 'v', "inv", BaseInteger},
+		{40, 'x', "texture", BaseInteger},
+		{42, 'z', "char", BaseInteger},
+		{55, '£', "mapsceneicon", BaseInteger},
+		{59, 'µ', "mapelement", BaseInteger},
+		{62, '×', "hitmark", BaseInteger},
+		{73, 'J', "struct", BaseInteger},
+		{74, 'Ð', "dbrow", BaseInteger},
+	} {
+		ScriptVarTypes[varType.Id] = varType
+	}
+}
+
+// baseTypeOf returns the BaseVarType of the ScriptVarType of the given id. Types that
+// are unknown are assumed to be stored as integers.
+func baseTypeOf(id int) BaseVarType {
+	if varType, ok := ScriptVarTypes[id]; ok {
+		return varType.BaseType
+	}
+
+	return BaseInteger
+}
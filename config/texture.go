@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// TextureType is the definition of a texture that is made up of one or more sprites
+// from the sprite archive.
+type TextureType struct {
+	Id int
+
+	// AverageColor is the 16-bit HSL color the client draws in place of the texture
+	// when textures are disabled or the textured face is too far away.
+	AverageColor int
+
+	// Opaque is whether the texture is drawn without any transparent pixels.
+	Opaque bool
+
+	// SpriteIds are the ids of the sprite folders the texture is composed of.
+	SpriteIds []int
+
+	// CombineTypes and CombineArgs describe how each sprite after the first one is
+	// combined with the previous ones.
+	CombineTypes []int
+	CombineArgs  []int
+
+	// Colors contains an RGB color for each sprite that the sprite is tinted with.
+	Colors []int
+
+	// AnimationDirection is the direction in which the texture scrolls, or 0.
+	AnimationDirection int
+
+	// AnimationSpeed is the amount of pixels the texture scrolls per tick.
+	AnimationSpeed int
+}
+
+// GetTextureTypes decodes every TextureType in the given Cache, indexed by id. May return an error.
+func GetTextureTypes(cache *gokira.Cache) ([]*TextureType, error) {
+	packs, err := cache.GetFolderPacks(TextureArchive, TextureFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*TextureType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeTextureType(pack.Id, pack.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetTextureType decodes the TextureType of the specified id. May return an error.
+func GetTextureType(cache *gokira.Cache, id int) (*TextureType, error) {
+	data, err := findPack(cache, TextureArchive, TextureFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeTextureType(id, data)
+}
+
+// DecodeTextureType decodes a TextureType of the given id from the given data. Unlike
+// most config types, textures are not encoded as a series of opcodes. May return an error.
+func DecodeTextureType(id int, data []byte) (*TextureType, error) {
+	texture := &TextureType{Id: id}
+
+	itr := buffer.NewReader(data)
+
+	averageColor, err := itr.ReadUInt16()
+	if err != nil {
+		return nil, err
+	}
+
+	texture.AverageColor = int(averageColor)
+
+	if texture.Opaque, err = itr.ReadBool(); err != nil {
+		return nil, err
+	}
+
+	spriteCount, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if spriteCount < 1 || spriteCount > 4 {
+		return nil, fmt.Errorf("texture %v consists of %v sprites but expected 1-4", id, spriteCount)
+	}
+
+	texture.SpriteIds = make([]int, spriteCount)
+	for i := range texture.SpriteIds {
+		spriteId, err := itr.ReadUInt16()
+		if err != nil {
+			return nil, err
+		}
+
+		texture.SpriteIds[i] = int(spriteId)
+	}
+
+	if spriteCount > 1 {
+		texture.CombineTypes = make([]int, spriteCount-1)
+		for i := range texture.CombineTypes {
+			value, err := itr.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+
+			texture.CombineTypes[i] = int(value)
+		}
+
+		texture.CombineArgs = make([]int, spriteCount-1)
+		for i := range texture.CombineArgs {
+			value, err := itr.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+
+			texture.CombineArgs[i] = int(value)
+		}
+	}
+
+	texture.Colors = make([]int, spriteCount)
+	for i := range texture.Colors {
+		color, err := itr.ReadInt32()
+		if err != nil {
+			return nil, err
+		}
+
+		texture.Colors[i] = int(color)
+	}
+
+	animationDirection, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	animationSpeed, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	texture.AnimationDirection = int(animationDirection)
+	texture.AnimationSpeed = int(animationSpeed)
+
+	return texture, nil
+}
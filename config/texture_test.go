@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestDecodeTextureType(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, // average color
+		1,            // opaque
+		2,            // sprite count
+		0, 10, 0, 11, // sprite ids
+		3,                      // combine type
+		4,                      // combine arg
+		0, 0, 0, 1, 0, 0, 0, 2, // colors
+		1, 2, // animation direction and speed
+	}
+
+	texture, err := DecodeTextureType(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if texture.AverageColor != 0x1234 || !texture.Opaque || len(texture.SpriteIds) != 2 || texture.SpriteIds[1] != 11 {
+		t.Errorf("unexpected texture %+v", texture)
+	}
+
+	if texture.Colors[1] != 2 || texture.AnimationDirection != 1 || texture.AnimationSpeed != 2 {
+		t.Errorf("unexpected texture %+v", texture)
+	}
+}
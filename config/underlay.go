@@ -0,0 +1,104 @@
+package config
+
+import (
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/hsl"
+)
+
+// UnderlayType is the definition of the floor that lies underneath the overlay of a tile.
+// The color of an underlay is blended with the underlays of the surrounding tiles.
+type UnderlayType struct {
+	Id int
+
+	// Color is the 24-bit RGB color of the underlay.
+	Color int
+
+	// Hue, Saturation and Lightness are the components of the Color the client
+	// sums up over neighbouring tiles to blend underlays. The Hue is already
+	// weighted by the HueMultiplier.
+	Hue           int
+	Saturation    int
+	Lightness     int
+	HueMultiplier int
+}
+
+// GetUnderlayTypes decodes every UnderlayType in the given Cache, indexed by id. May return an error.
+func GetUnderlayTypes(cache *gokira.Cache) ([]*UnderlayType, error) {
+	packs, err := cache.GetFolderPacks(ConfigArchive, UnderlayFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]*UnderlayType, capacity(packs))
+	for _, pack := range packs {
+		if types[pack.Id], err = DecodeUnderlayType(pack.Id, pack.Data, cache.Revision()); err != nil {
+			return nil, err
+		}
+	}
+
+	return types, nil
+}
+
+// GetUnderlayType decodes the UnderlayType of the specified id. May return an error.
+func GetUnderlayType(cache *gokira.Cache, id int) (*UnderlayType, error) {
+	data, err := findPack(cache, ConfigArchive, UnderlayFolder, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeUnderlayType(id, data, cache.Revision())
+}
+
+// UnderlayTable is the opcode Table of the UnderlayType.
+var UnderlayTable = NewTable("underlay", func() interface{} { return new(UnderlayType) },
+	Op(1, "Color", U24),
+)
+
+// DecodeUnderlayType decodes an UnderlayType of the given id from the given data, using the layout
+// of the given revision. May return an error.
+func DecodeUnderlayType(id int, data []byte, revision int) (*UnderlayType, error) {
+	value, err := UnderlayTable.Decode(data, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	underlay := value.(*UnderlayType)
+	underlay.Id = id
+	underlay.computeBlendValues()
+
+	return underlay, nil
+}
+
+// computeBlendValues computes the HSL components of the underlay's color the same way
+// the client does.
+func (underlay *UnderlayType) computeBlendValues() {
+	hue, saturation, lightness := hsl.FromRGB(underlay.Color)
+
+	underlay.Saturation = clampChannel(int(saturation * 256.0))
+	underlay.Lightness = clampChannel(int(lightness * 256.0))
+
+	if lightness > 0.5 {
+		underlay.HueMultiplier = int(saturation * (1.0 - lightness) * 512.0)
+	} else {
+		underlay.HueMultiplier = int(saturation * lightness * 512.0)
+	}
+
+	if underlay.HueMultiplier < 1 {
+		underlay.HueMultiplier = 1
+	}
+
+	underlay.Hue = int(float64(underlay.HueMultiplier) * hue)
+}
+
+// clampChannel clamps the given value to the range of 0-255.
+func clampChannel(value int) int {
+	if value < 0 {
+		return 0
+	}
+
+	if value > 255 {
+		return 255
+	}
+
+	return value
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestDecodeUnderlayType(t *testing.T) {
+	underlay, err := DecodeUnderlayType(7, []byte{1, 0x00, 0xFF, 0x00, 0}, LatestRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if underlay.Id != 7 || underlay.Color != 0x00FF00 {
+		t.Errorf("unexpected underlay %+v", underlay)
+	}
+
+	if underlay.Saturation != 255 || underlay.Lightness != 127 {
+		t.Errorf("unexpected saturation %v and lightness %v", underlay.Saturation, underlay.Lightness)
+	}
+
+	if underlay.HueMultiplier != 255 || underlay.Hue != 85 {
+		t.Errorf("unexpected hue multiplier %v and hue %v", underlay.HueMultiplier, underlay.Hue)
+	}
+}
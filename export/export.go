@@ -0,0 +1,220 @@
+// Package export converts models into file formats that are understood by modelling
+// tools and viewers, such as Wavefront OBJ and glTF.
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/hsl"
+	"github.com/sinoz/gokira/model"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// Options configures how the faces of a model are exported.
+type Options struct {
+	// Textures are the definitions of the textures of the texture archive, indexed
+	// by id. Textured faces are colored with the average color of their texture.
+	Textures []*config.TextureType
+
+	// TextureImages are the images of the textures, by id. Formats that are able to
+	// embed images embed those of the textures that are used, while the others
+	// refer to the file that is named after the texture.
+	TextureImages map[int]image.Image
+}
+
+// NewOptions constructs Options with the texture definitions of the given Cache and
+// the images of the textures. May return an error.
+func NewOptions(cache *gokira.Cache) (*Options, error) {
+	textures, err := config.GetTextureTypes(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[int]image.Image)
+	for _, texture := range textures {
+		if texture == nil {
+			continue
+		}
+
+		if images[texture.Id], err = LoadTextureImage(cache, texture); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Options{Textures: textures, TextureImages: images}, nil
+}
+
+// LoadTextureImage loads the image of the given texture from the sprites of the given
+// Cache, which is the first frame of the first sprite of the texture. The sprites that
+// follow the first are not combined into the image. May return an error.
+func LoadTextureImage(cache *gokira.Cache, texture *config.TextureType) (image.Image, error) {
+	if len(texture.SpriteIds) == 0 {
+		return nil, fmt.Errorf("texture %v has no sprites", texture.Id)
+	}
+
+	s, err := sprite.Load(cache, texture.SpriteIds[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Frames) == 0 {
+		return nil, fmt.Errorf("sprite %v of texture %v has no frames", s.Id, texture.Id)
+	}
+
+	return s.Canvas(0), nil
+}
+
+// WriteTextureImages writes the images of the textures of the faces of the given model
+// into the given directory as PNG files, named the way the exported files refer to
+// them. May return an error.
+func WriteTextureImages(dir string, m *model.Model, options *Options) error {
+	written := make(map[int]bool)
+	for _, id := range m.FaceTextures {
+		if id == -1 || written[id] {
+			continue
+		}
+
+		img, ok := options.TextureImages[id]
+		if !ok {
+			return fmt.Errorf("missing the image of texture %v", id)
+		}
+
+		if err := writePNG(filepath.Join(dir, TextureFileName(id)), img); err != nil {
+			return err
+		}
+
+		written[id] = true
+	}
+
+	return nil
+}
+
+// writePNG writes the given image to the given path as a PNG file. May return an error.
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// TextureFileName returns the name of the image file that the exported files refer to
+// for the texture of the given id.
+func TextureFileName(id int) string {
+	return fmt.Sprintf("texture_%d.png", id)
+}
+
+// material is the appearance of a group of faces.
+type material struct {
+	// texture is the id of the texture, or -1.
+	texture int
+
+	// color is the 24-bit RGB color and alpha the transparency, ranging from 0
+	// (opaque) to 255.
+	color int
+	alpha int
+}
+
+// name returns a name that is unique to the material.
+func (m material) name() string {
+	name := fmt.Sprintf("color_%06x", m.color)
+	if m.texture != -1 {
+		name = fmt.Sprintf("texture_%d", m.texture)
+	}
+
+	if m.alpha != 0 {
+		name += fmt.Sprintf("_alpha_%d", m.alpha)
+	}
+
+	return name
+}
+
+// opacity returns the opacity of the material, ranging from 0.0 to 1.0.
+func (m material) opacity() float64 {
+	return float64(255-m.alpha) / 255.0
+}
+
+// channels returns the red, green and blue channels of the color of the material,
+// each ranging from 0.0 to 1.0.
+func (m material) channels() (red, green, blue float64) {
+	return float64(m.color>>16&0xFF) / 255.0, float64(m.color>>8&0xFF) / 255.0, float64(m.color&0xFF) / 255.0
+}
+
+// group is a material along with the faces that have it.
+type group struct {
+	material material
+	faces    []int
+}
+
+// groupFaces groups the visible faces of the given model by their material, in order
+// of the first face of each material.
+func groupFaces(m *model.Model, options *Options) []*group {
+	var groups []*group
+	indices := make(map[material]int)
+
+	for face := 0; face < m.FaceCount; face++ {
+		renderType := 0
+		if m.FaceRenderTypes != nil {
+			renderType = m.FaceRenderTypes[face] & 3
+		}
+
+		// faces of the second render type are never drawn
+		if renderType == 2 {
+			continue
+		}
+
+		key := material{texture: -1, color: hsl.ToRGB(m.FaceColors[face])}
+		if renderType == 3 {
+			key.color = 0
+		}
+
+		if m.FaceTextures != nil && m.FaceTextures[face] != -1 {
+			key.texture = m.FaceTextures[face]
+			key.color = textureColor(key.texture, options)
+		}
+
+		if m.FaceAlphas != nil {
+			key.alpha = m.FaceAlphas[face]
+		}
+
+		index, ok := indices[key]
+		if !ok {
+			index = len(groups)
+			indices[key] = index
+			groups = append(groups, &group{material: key})
+		}
+
+		groups[index].faces = append(groups[index].faces, face)
+	}
+
+	return groups
+}
+
+// textureColor returns the 24-bit RGB average color of the texture of the given id, or
+// white if the texture is not known.
+func textureColor(id int, options *Options) int {
+	if options != nil && id >= 0 && id < len(options.Textures) && options.Textures[id] != nil {
+		return hsl.ToRGB(options.Textures[id].AverageColor)
+	}
+
+	return 0xFFFFFF
+}
+
+// position returns the position of the vertex at the given index, converted from the
+// space of the client, of which the y and z axes point down and away from the camera,
+// to a space of which the y axis points up.
+func position(m *model.Model, vertex int) [3]float32 {
+	return [3]float32{float32(m.VertexX[vertex]), float32(-m.VertexY[vertex]), float32(-m.VertexZ[vertex])}
+}
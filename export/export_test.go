@@ -0,0 +1,162 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/internal/cachetest"
+	"github.com/sinoz/gokira/model"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// quad constructs a model of two faces, of which the second one is textured and
+// semi-transparent.
+func quad() *model.Model {
+	return &model.Model{
+		VertexCount:  4,
+		VertexX:      []int{0, 128, 128, 0},
+		VertexY:      []int{0, 0, -128, -128},
+		VertexZ:      []int{0, 0, 0, 0},
+		FaceCount:    2,
+		FaceA:        []int{0, 0},
+		FaceB:        []int{1, 2},
+		FaceC:        []int{2, 3},
+		FaceColors:   []int{127, 127},
+		FaceAlphas:   []int{0, 128},
+		FaceTextures: []int{-1, 1},
+	}
+}
+
+func TestWriteOBJ(t *testing.T) {
+	options := &Options{Textures: []*config.TextureType{nil, {Id: 1, AverageColor: 127}}}
+
+	var obj, mtl bytes.Buffer
+	if err := WriteOBJ(&obj, &mtl, quad(), "quad.mtl", options); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"mtllib quad.mtl", "v 128 128 0", "vt 0 1", "usemtl color_fefdfd", "f 1 2 3", "usemtl texture_1_alpha_128", "f 1/1 3/2 4/3"} {
+		if !strings.Contains(obj.String(), line+"\n") {
+			t.Errorf("expected line %q in:\n%v", line, obj.String())
+		}
+	}
+
+	for _, line := range []string{"newmtl texture_1_alpha_128", "d 0.4980", "map_Kd texture_1.png"} {
+		if !strings.Contains(mtl.String(), line+"\n") {
+			t.Errorf("expected line %q in:\n%v", line, mtl.String())
+		}
+	}
+}
+
+func TestWriteGLB(t *testing.T) {
+	options := &Options{TextureImages: map[int]image.Image{1: image.NewRGBA(image.Rect(0, 0, 2, 2))}}
+
+	var glb bytes.Buffer
+	if err := WriteGLB(&glb, quad(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	data := glb.Bytes()
+	if binary.LittleEndian.Uint32(data) != glbMagic || int(binary.LittleEndian.Uint32(data[8:])) != len(data) {
+		t.Fatalf("unexpected header %v", data[:12])
+	}
+
+	length := binary.LittleEndian.Uint32(data[12:])
+
+	var document gltfDocument
+	if err := json.Unmarshal(data[20:20+length], &document); err != nil {
+		t.Fatal(err)
+	}
+
+	primitives := document.Meshes[0].Primitives
+	if len(primitives) != 2 || len(document.Images) != 1 || len(document.Materials) != 2 {
+		t.Fatalf("unexpected document %+v", document)
+	}
+
+	if _, ok := primitives[1].Attributes["TEXCOORD_0"]; !ok {
+		t.Error("expected the textured face to have texture coordinates")
+	}
+
+	if document.Materials[1].AlphaMode != "BLEND" || document.Materials[1].PbrMetallicRoughness.BaseColorTexture == nil {
+		t.Errorf("unexpected textured material %+v", document.Materials[1])
+	}
+
+	if count := document.Accessors[primitives[0].Attributes["POSITION"]].Count; count != 3 {
+		t.Errorf("expected 3 vertices but got %v", count)
+	}
+}
+
+func TestTexturedExport(t *testing.T) {
+	texture := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	texture.Set(1, 1, color.NRGBA{R: 200, G: 100, B: 50, A: 0xFF})
+
+	spriteData, err := sprite.EncodeImages(texture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// texture 1 is made up of sprite 7
+	builder := cachetest.New()
+	builder.Add(config.TextureArchive, config.TextureFolder, map[int][]byte{
+		1: buffer.NewWriter().WriteInt16(127).WriteBool(true).WriteInt8(1).WriteInt16(7).
+			WriteInt32(0).WriteInt8(0).WriteInt8(0).Bytes(),
+	})
+	builder.AddFile(sprite.Archive, 7, spriteData)
+
+	options, err := NewOptions(builder.Build(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, ok := options.TextureImages[1]
+	if !ok || img.Bounds().Dx() != 2 || color.NRGBAModel.Convert(img.At(1, 1)) != texture.At(1, 1) {
+		t.Fatalf("expected the image of texture 1 to be loaded but got %v", img)
+	}
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := WriteTextureImages(dir, quad(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, TextureFileName(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	written, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if color.NRGBAModel.Convert(written.At(1, 1)) != texture.At(1, 1) {
+		t.Errorf("expected the written image to be the texture but got %v", written.At(1, 1))
+	}
+
+	var glb bytes.Buffer
+	if err := WriteGLB(&glb, quad(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(glb.Bytes(), []byte("\x89PNG")) {
+		t.Error("expected the texture to be embedded")
+	}
+}
@@ -0,0 +1,337 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/sinoz/gokira/model"
+)
+
+const (
+	glbMagic     = 0x46546C67
+	glbVersion   = 2
+	jsonChunk    = 0x4E4F534A
+	binaryChunk  = 0x004E4942
+	floatType    = 5126
+	arrayBuffer  = 34962
+	triangleMode = 4
+)
+
+// gltfDocument is the JSON document of a glTF 2.0 asset, of which only the parts
+// that are needed to describe a single static mesh are declared.
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Textures    []gltfTexture    `json:"textures,omitempty"`
+	Images      []gltfImage      `json:"images,omitempty"`
+	Samplers    []gltfSampler    `json:"samplers,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name string `json:"name,omitempty"`
+	Mesh int    `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Material   int            `json:"material"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfMaterial struct {
+	Name                 string                   `json:"name"`
+	PbrMetallicRoughness gltfPbrMetallicRoughness `json:"pbrMetallicRoughness"`
+	AlphaMode            string                   `json:"alphaMode,omitempty"`
+}
+
+type gltfPbrMetallicRoughness struct {
+	BaseColorFactor  [4]float64       `json:"baseColorFactor"`
+	BaseColorTexture *gltfTextureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   float64          `json:"metallicFactor"`
+	RoughnessFactor  float64          `json:"roughnessFactor"`
+}
+
+type gltfTextureInfo struct {
+	Index int `json:"index"`
+}
+
+type gltfTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfImage struct {
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// glbBuilder assembles the JSON document and the binary buffer of a binary glTF file.
+type glbBuilder struct {
+	document gltfDocument
+	buffer   bytes.Buffer
+	textures map[int]int
+}
+
+// WriteGLB writes the given model as a binary glTF 2.0 file. Each material becomes a
+// primitive of its own, of which the faces do not share vertices so that each face
+// keeps its own texture coordinates. The images of textured faces are embedded if
+// the Options hold them. May return an error.
+func WriteGLB(w io.Writer, m *model.Model, options *Options) error {
+	builder := &glbBuilder{textures: make(map[int]int)}
+
+	builder.document = gltfDocument{
+		Asset:   gltfAsset{Version: "2.0", Generator: "gokira"},
+		Scenes:  []gltfScene{{Nodes: []int{0}}},
+		Nodes:   []gltfNode{{Mesh: 0}},
+		Meshes:  []gltfMesh{{}},
+		Buffers: []gltfBuffer{{}},
+	}
+
+	for _, g := range groupFaces(m, options) {
+		primitive, err := builder.addGroup(m, g, options)
+		if err != nil {
+			return err
+		}
+
+		builder.document.Meshes[0].Primitives = append(builder.document.Meshes[0].Primitives, primitive)
+	}
+
+	builder.document.Buffers[0].ByteLength = builder.buffer.Len()
+
+	document, err := json.Marshal(builder.document)
+	if err != nil {
+		return err
+	}
+
+	document = pad(document, ' ')
+	payload := pad(builder.buffer.Bytes(), 0)
+
+	length := 12 + 8 + len(document) + 8 + len(payload)
+	header := []uint32{glbMagic, glbVersion, uint32(length), uint32(len(document)), jsonChunk}
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(document); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, []uint32{uint32(len(payload)), binaryChunk}); err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+	return err
+}
+
+// addGroup adds the faces of the given group as a primitive, along with its material.
+// May return an error.
+func (builder *glbBuilder) addGroup(m *model.Model, g *group, options *Options) (gltfPrimitive, error) {
+	positions := make([]float32, 0, len(g.faces)*9)
+	textureCoords := make([]float32, 0, len(g.faces)*6)
+
+	min := [3]float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+	max := [3]float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+
+	for _, face := range g.faces {
+		for _, vertex := range [3]int{m.FaceA[face], m.FaceB[face], m.FaceC[face]} {
+			p := position(m, vertex)
+			positions = append(positions, p[0], p[1], p[2])
+
+			for axis := range p {
+				min[axis] = float32(math.Min(float64(min[axis]), float64(p[axis])))
+				max[axis] = float32(math.Max(float64(max[axis]), float64(p[axis])))
+			}
+		}
+
+		if u, v, ok := m.FaceUVs(face); ok {
+			for i := 0; i < 3; i++ {
+				textureCoords = append(textureCoords, float32(u[i]), float32(v[i]))
+			}
+		}
+	}
+
+	primitive := gltfPrimitive{
+		Attributes: map[string]int{"POSITION": builder.addAccessor(positions, "VEC3", min[:], max[:])},
+		Material:   len(builder.document.Materials),
+		Mode:       triangleMode,
+	}
+
+	if len(textureCoords) > 0 {
+		primitive.Attributes["TEXCOORD_0"] = builder.addAccessor(textureCoords, "VEC2", nil, nil)
+	}
+
+	red, green, blue := g.material.channels()
+	mat := gltfMaterial{
+		Name: g.material.name(),
+		PbrMetallicRoughness: gltfPbrMetallicRoughness{
+			BaseColorFactor: [4]float64{linear(red), linear(green), linear(blue), g.material.opacity()},
+			RoughnessFactor: 1,
+		},
+	}
+
+	if g.material.alpha != 0 {
+		mat.AlphaMode = "BLEND"
+	}
+
+	if g.material.texture != -1 && len(textureCoords) > 0 && options != nil {
+		if img, ok := options.TextureImages[g.material.texture]; ok {
+			index, err := builder.addTexture(g.material.texture, img)
+			if err != nil {
+				return primitive, err
+			}
+
+			mat.PbrMetallicRoughness.BaseColorFactor = [4]float64{1, 1, 1, g.material.opacity()}
+			mat.PbrMetallicRoughness.BaseColorTexture = &gltfTextureInfo{Index: index}
+		}
+	}
+
+	builder.document.Materials = append(builder.document.Materials, mat)
+	return primitive, nil
+}
+
+// addAccessor adds the given floats to the binary buffer and returns the index of the
+// accessor that describes them.
+func (builder *glbBuilder) addAccessor(values []float32, kind string, min, max []float32) int {
+	components := 3
+	if kind == "VEC2" {
+		components = 2
+	}
+
+	view := builder.addBufferView(float32Bytes(values), arrayBuffer)
+
+	builder.document.Accessors = append(builder.document.Accessors, gltfAccessor{
+		BufferView:    view,
+		ComponentType: floatType,
+		Count:         len(values) / components,
+		Type:          kind,
+		Min:           min,
+		Max:           max,
+	})
+
+	return len(builder.document.Accessors) - 1
+}
+
+// addTexture embeds the given image of the texture of the given id as a PNG, unless it
+// was already embedded, and returns the index of the texture. May return an error.
+func (builder *glbBuilder) addTexture(id int, img image.Image) (int, error) {
+	if index, ok := builder.textures[id]; ok {
+		return index, nil
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return 0, err
+	}
+
+	if len(builder.document.Samplers) == 0 {
+		// nearest filtering, as the client does not filter its textures either
+		builder.document.Samplers = []gltfSampler{{MagFilter: 9728, MinFilter: 9728}}
+	}
+
+	view := builder.addBufferView(encoded.Bytes(), 0)
+	builder.document.Images = append(builder.document.Images, gltfImage{BufferView: view, MimeType: "image/png"})
+	builder.document.Textures = append(builder.document.Textures, gltfTexture{Source: len(builder.document.Images) - 1})
+
+	index := len(builder.document.Textures) - 1
+	builder.textures[id] = index
+
+	return index, nil
+}
+
+// addBufferView appends the given data to the binary buffer, aligned to four bytes, and
+// returns the index of the buffer view that describes it.
+func (builder *glbBuilder) addBufferView(data []byte, target int) int {
+	for builder.buffer.Len()%4 != 0 {
+		builder.buffer.WriteByte(0)
+	}
+
+	builder.document.BufferViews = append(builder.document.BufferViews, gltfBufferView{
+		ByteOffset: builder.buffer.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+
+	builder.buffer.Write(data)
+	return len(builder.document.BufferViews) - 1
+}
+
+// float32Bytes encodes the given floats in little-endian byte order.
+func float32Bytes(values []float32) []byte {
+	data := make([]byte, len(values)*4)
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+
+	return data
+}
+
+// pad pads the given data with the given byte up to a multiple of four bytes.
+func pad(data []byte, padding byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, padding)
+	}
+
+	return data
+}
+
+// linear converts a gamma encoded sRGB channel to a linear channel, as glTF expects
+// its color factors to be linear.
+func linear(channel float64) float64 {
+	if channel <= 0.04045 {
+		return channel / 12.92
+	}
+
+	return math.Pow((channel+0.055)/1.055, 2.4)
+}
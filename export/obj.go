@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/sinoz/gokira/model"
+)
+
+// WriteOBJ writes the given model as a Wavefront OBJ file to obj, and its materials as
+// the MTL file of the given name to mtl. Textured faces refer to the image file that is
+// named after their texture. May return an error.
+func WriteOBJ(obj, mtl io.Writer, m *model.Model, mtlName string, options *Options) error {
+	groups := groupFaces(m, options)
+
+	if err := writeMTL(mtl, groups); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(obj)
+	fmt.Fprintf(w, "mtllib %v\n", mtlName)
+
+	for vertex := 0; vertex < m.VertexCount; vertex++ {
+		p := position(m, vertex)
+		fmt.Fprintf(w, "v %v %v %v\n", p[0], p[1], p[2])
+	}
+
+	// texture coordinates are specific to each face, as faces that share a vertex
+	// may be projected onto different texture triangles
+	textureCoords := make(map[int]int)
+	for face := 0; face < m.FaceCount; face++ {
+		u, v, ok := m.FaceUVs(face)
+		if !ok {
+			continue
+		}
+
+		textureCoords[face] = len(textureCoords)*3 + 1
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "vt %v %v\n", float32(u[i]), float32(1-v[i]))
+		}
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(w, "usemtl %v\n", g.material.name())
+
+		for _, face := range g.faces {
+			a, b, c := m.FaceA[face]+1, m.FaceB[face]+1, m.FaceC[face]+1
+
+			if coord, ok := textureCoords[face]; ok {
+				fmt.Fprintf(w, "f %v/%v %v/%v %v/%v\n", a, coord, b, coord+1, c, coord+2)
+			} else {
+				fmt.Fprintf(w, "f %v %v %v\n", a, b, c)
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+// writeMTL writes the materials of the given groups of faces as an MTL file.
+// May return an error.
+func writeMTL(mtl io.Writer, groups []*group) error {
+	w := bufio.NewWriter(mtl)
+
+	for _, g := range groups {
+		red, green, blue := g.material.channels()
+
+		fmt.Fprintf(w, "newmtl %v\n", g.material.name())
+		fmt.Fprintf(w, "Kd %.4f %.4f %.4f\n", red, green, blue)
+		fmt.Fprintf(w, "d %.4f\n", g.material.opacity())
+
+		if g.material.texture != -1 {
+			fmt.Fprintf(w, "map_Kd %v\n", TextureFileName(g.material.texture))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	return w.Flush()
+}
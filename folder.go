@@ -15,6 +15,10 @@ const (
 	gzipCompression  = 2
 )
 
+// ErrInvalidKeys is returned when a folder cannot be deciphered with the given XTEA keys,
+// which shows when the deciphered folder fails to decompress.
+var ErrInvalidKeys = errors.New("folder could not be deciphered with the given keys")
+
 type Folder struct {
 	CompressionType byte
 	Data            []byte
@@ -35,14 +39,19 @@ func newFolder(data []byte, keySet [4]int) (*Folder, error) {
 	folderPayload := data[5:]
 
 	isCompressed := compressionType != noCompression
+	isEncrypted := keySet[0] != 0 || keySet[1] != 0 || keySet[2] != 0 || keySet[3] != 0
 
-	if keySet[0] != 0 || keySet[1] != 0 || keySet[2] != 0 || keySet[3] != 0 {
+	if isEncrypted {
 		sizeEncryptedBlock := folderSize
 		if isCompressed {
 			// + 4 because of the decompressed length in the compressed block's header
 			sizeEncryptedBlock += 4
 		}
 
+		if uint32(len(folderPayload)) < sizeEncryptedBlock {
+			return nil, errors.New("folder contents are truncated")
+		}
+
 		// deciphering will modify the contents.. let's copy it first
 		encryptedBlock := make([]byte, sizeEncryptedBlock)
 		copy(encryptedBlock, folderPayload[:sizeEncryptedBlock])
@@ -62,11 +71,21 @@ func newFolder(data []byte, keySet [4]int) (*Folder, error) {
 		}
 
 		if decompressedLength >= 20000000 {
+			if isEncrypted {
+				return nil, ErrInvalidKeys
+			}
+
 			return nil, errors.New("decompressed size larger than allowed")
 		}
 
 		decompressedData, decompressErr := decompressFolder(folderPayload[4:folderSize+4], decompressedLength, compressionType)
 		if decompressErr != nil {
+			// a folder that was deciphered with the wrong keys decompresses into garbage,
+			// if at all
+			if isEncrypted {
+				return nil, ErrInvalidKeys
+			}
+
 			return nil, decompressErr
 		}
 
@@ -117,6 +136,16 @@ func (folder *Folder) GetPacks(manifest *FolderManifest) ([]*Pack, error) { // T
 	folderSizeInBytes := len(folder.Data)
 
 	amtPacks := len(manifest.PackReferences)
+	if amtPacks == 1 {
+		// a folder with a single pack consists of nothing but the pack itself
+		pack := &Pack{Data: folder.Data}
+		if reference := manifest.PackReferences[0]; reference != nil {
+			pack.Id = reference.Id
+		}
+
+		return []*Pack{pack}, nil
+	}
+
 	amtChunks := int(folder.Data[folderSizeInBytes-1])
 
 	packs := make([]*Pack, amtPacks)
@@ -152,7 +181,10 @@ func (folder *Folder) GetPacks(manifest *FolderManifest) ([]*Pack, error) { // T
 	}
 
 	for pack := 0; pack < amtPacks; pack++ {
-		packs[pack] = &Pack{}
+		packs[pack] = &Pack{Id: pack}
+		if reference := manifest.PackReferences[pack]; reference != nil {
+			packs[pack].Id = reference.Id
+		}
 	}
 
 	var address int
@@ -0,0 +1,148 @@
+package font
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// LoadById decodes the Font of which the glyphs and the metrics are stored in the folders
+// of the given id, which is how interfaces refer to fonts. May return an error.
+func LoadById(cache *gokira.Cache, id int) (*Font, error) {
+	glyphs, err := sprite.Load(cache, id)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := cache.GetUnencryptedFolder(MetricsArchive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := DecodeMetrics(folder.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	icons, err := sprite.LoadByName(cache, ModIcons)
+	if err != nil && err != gokira.ErrLabelNotFound {
+		return nil, err
+	}
+
+	return &Font{Glyphs: glyphs, Metrics: metrics, Icons: icons}, nil
+}
+
+// MaxAscent returns the largest height of any glyph above the baseline.
+func (font *Font) MaxAscent() int {
+	top := font.Metrics.Ascent
+	for _, frame := range font.Glyphs.Frames {
+		if frame.Image.Bounds().Dy() > 0 && frame.OffsetY < top {
+			top = frame.OffsetY
+		}
+	}
+
+	return font.Metrics.Ascent - top
+}
+
+// MaxDescent returns the largest depth of any glyph below the baseline.
+func (font *Font) MaxDescent() int {
+	bottom := font.Metrics.Ascent
+	for _, frame := range font.Glyphs.Frames {
+		if frame.OffsetY+frame.Image.Bounds().Dy() > bottom {
+			bottom = frame.OffsetY + frame.Image.Bounds().Dy()
+		}
+	}
+
+	return bottom - font.Metrics.Ascent
+}
+
+// Draw draws the given text onto the given image in the given 24-bit RGB color, starting
+// at the given x and with its baseline at the given y. The color changes at <col=rrggbb>
+// tags and reverts at </col> tags, while <img=n> tags draw the mod icons.
+func (font *Font) Draw(dst draw.Image, text string, x, y, rgb int) {
+	top := y - font.Metrics.Ascent
+	current := rgb
+	previous := -1
+	tag := -1
+
+	for i, r := range text {
+		if r == '<' {
+			tag = i
+			continue
+		}
+
+		if r == '>' && tag != -1 {
+			name := text[tag+1 : i]
+			tag = -1
+
+			switch {
+			case name == "lt":
+				r = '<'
+			case name == "gt":
+				r = '>'
+			case strings.HasPrefix(name, "col="):
+				if value, err := strconv.ParseInt(name[len("col="):], 16, 32); err == nil {
+					current = int(value)
+				}
+
+				continue
+			case name == "/col":
+				current = rgb
+				continue
+			default:
+				if width, ok := font.iconWidth(name); ok {
+					icon, _ := strconv.Atoi(name[len("img="):])
+					font.drawIcon(dst, icon, x, y-font.Icons.Height)
+
+					x += width
+					previous = -1
+				}
+
+				continue
+			}
+		}
+
+		if tag != -1 {
+			continue
+		}
+
+		glyph := Glyph(r)
+		if font.Metrics.Kerning != nil && previous != -1 {
+			x += int(font.Metrics.Kerning[previous<<8|glyph])
+		}
+
+		if glyph < len(font.Glyphs.Frames) && r != ' ' && r != nbsp {
+			font.drawGlyph(dst, glyph, x, top, current)
+		}
+
+		x += font.CharWidth(r)
+		previous = glyph
+	}
+}
+
+// drawGlyph draws the pixels of the given glyph that are not transparent in the given
+// color, relative to the given position of the top of the text.
+func (font *Font) drawGlyph(dst draw.Image, glyph, x, y, rgb int) {
+	frame := font.Glyphs.Frames[glyph]
+	bounds := frame.Image.Bounds()
+	c := color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xFF}
+
+	for dy := 0; dy < bounds.Dy(); dy++ {
+		for dx := 0; dx < bounds.Dx(); dx++ {
+			if _, _, _, a := frame.Image.At(bounds.Min.X+dx, bounds.Min.Y+dy).RGBA(); a != 0 {
+				dst.Set(x+frame.OffsetX+dx, y+frame.OffsetY+dy, c)
+			}
+		}
+	}
+}
+
+// drawIcon draws the given mod icon with its top left corner at the given position.
+func (font *Font) drawIcon(dst draw.Image, icon, x, y int) {
+	canvas := font.Icons.Canvas(icon)
+	draw.Draw(dst, canvas.Bounds().Add(image.Pt(x, y)), canvas, image.Point{}, draw.Over)
+}
@@ -0,0 +1,203 @@
+// Package font decodes the bitmap fonts of the client and measures and wraps text the
+// way the client does.
+package font
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// MetricsArchive is the archive that holds the metrics of each font, in folders that are
+// labelled with the name of the sprite group of its glyphs.
+const MetricsArchive = 13
+
+// ModIcons is the name of the sprite group of the icons that text refers to with
+// an <img=n> tag.
+const ModIcons = "mod_icons"
+
+// The names of the fonts of the client.
+const (
+	Plain11 = "p11_full"
+	Plain12 = "p12_full"
+	Bold12  = "b12_full"
+	Quill8  = "q8_full"
+)
+
+// ErrMalformed is returned when the metrics of a font are inconsistent.
+var ErrMalformed = errors.New("malformed font metrics")
+
+// Metrics are the dimensions of the glyphs of a font, indexed by the CP-1252 value of
+// the character they represent.
+type Metrics struct {
+	// Advances are the amounts of pixels the pen moves after drawing each glyph.
+	Advances [256]int
+
+	// Kerning holds the adjustment of the advance between each pair of glyphs, indexed
+	// by the previous glyph shifted to the left by 8 bits and the next glyph. It is nil
+	// for fonts that are not kerned.
+	Kerning []int8
+
+	// Ascent is the height of the glyphs above the baseline.
+	Ascent int
+}
+
+// Font is a bitmap font, of which the glyphs are the frames of a sprite group.
+type Font struct {
+	Name    string
+	Glyphs  *sprite.Sprite
+	Metrics *Metrics
+
+	// Icons are the sprites text refers to with an <img=n> tag, or nil.
+	Icons *sprite.Sprite
+}
+
+// Load decodes the Font of the given name from the given Cache, along with the mod icons
+// that text may embed. May return an error.
+func Load(cache *gokira.Cache, name string) (*Font, error) {
+	glyphs, err := sprite.LoadByName(cache, name)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := LoadMetrics(cache, name)
+	if err != nil {
+		return nil, err
+	}
+
+	icons, err := sprite.LoadByName(cache, ModIcons)
+	if err != nil && err != gokira.ErrLabelNotFound {
+		return nil, err
+	}
+
+	return &Font{Name: name, Glyphs: glyphs, Metrics: metrics, Icons: icons}, nil
+}
+
+// LoadMetrics decodes the Metrics of the font of the given name from the given Cache.
+// May return an error.
+func LoadMetrics(cache *gokira.Cache, name string) (*Metrics, error) {
+	manifest, err := cache.GetFolderManifestByName(MetricsArchive, name)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := cache.GetUnencryptedFolder(MetricsArchive, manifest.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeMetrics(folder.Data)
+}
+
+// DecodeMetrics decodes Metrics from the given data. Fonts that are not kerned consist of
+// the advance of each glyph followed by the ascent. Kerned fonts instead start with a
+// version, followed by the advance, the top and the height of each glyph and the
+// outlines of their left and right sides, from which the kerning is derived.
+// May return an error.
+func DecodeMetrics(data []byte) (*Metrics, error) {
+	metrics := new(Metrics)
+
+	if len(data) == 257 {
+		for i := range metrics.Advances {
+			metrics.Advances[i] = int(data[i])
+		}
+
+		metrics.Ascent = int(data[256])
+		return metrics, nil
+	}
+
+	itr := buffer.NewReader(data)
+	if err := itr.Skip(1); err != nil {
+		return nil, ErrMalformed
+	}
+
+	var tops, heights [256]int
+	for _, values := range []*[256]int{&metrics.Advances, &tops, &heights} {
+		for i := range values {
+			value, err := itr.ReadByte()
+			if err != nil {
+				return nil, ErrMalformed
+			}
+
+			values[i] = int(value)
+		}
+	}
+
+	left, err := readOutlines(itr, heights)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := readOutlines(itr, heights)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Kerning = make([]int8, 65536)
+	for previous := 0; previous < 256; previous++ {
+		if previous == ' ' || previous == nbsp {
+			continue
+		}
+
+		for next := 0; next < 256; next++ {
+			if next != ' ' && next != nbsp {
+				metrics.Kerning[previous<<8|next] = int8(kerning(left, right, tops, heights, metrics.Advances, previous, next))
+			}
+		}
+	}
+
+	metrics.Ascent = tops[' '] + heights[' ']
+	return metrics, nil
+}
+
+// readOutlines reads the outline of a side of each glyph, which is the amount of blank
+// pixels on that side of each row of the glyph, stored as deltas. May return an error.
+func readOutlines(itr *buffer.Reader, heights [256]int) ([256][]int, error) {
+	var outlines [256][]int
+	for glyph, height := range heights {
+		outlines[glyph] = make([]int, height)
+
+		var value int8
+		for row := range outlines[glyph] {
+			delta, err := itr.ReadInt8()
+			if err != nil {
+				return outlines, ErrMalformed
+			}
+
+			value += delta
+			outlines[glyph][row] = int(value)
+		}
+	}
+
+	return outlines, nil
+}
+
+// kerning derives the adjustment of the advance between the given glyphs from the
+// smallest gap between the right side of the previous glyph and the left side of the
+// next one, across the rows they share.
+func kerning(left, right [256][]int, tops, heights, advances [256]int, previous, next int) int {
+	top := tops[previous]
+	if tops[next] > top {
+		top = tops[next]
+	}
+
+	bottom := tops[previous] + heights[previous]
+	if tops[next]+heights[next] < bottom {
+		bottom = tops[next] + heights[next]
+	}
+
+	gap := advances[previous]
+	if advances[next] < gap {
+		gap = advances[next]
+	}
+
+	for row := top; row < bottom; row++ {
+		if sum := right[previous][row-tops[previous]] + left[next][row-tops[next]]; sum < gap {
+			gap = sum
+		}
+	}
+
+	return -gap
+}
@@ -0,0 +1,175 @@
+package font
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira/internal/cachetest"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// testGlyphs encodes a sprite of the given amount of frames of the given size, of which
+// every pixel is white.
+func testGlyphs(t *testing.T, count, width, height int) []byte {
+	frames := make([]image.Image, count)
+	for i := range frames {
+		frame := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, frame.Bounds(), image.White, image.Point{}, draw.Src)
+		frames[i] = frame
+	}
+
+	data, err := sprite.EncodeImages(frames...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func loadTestFont(t *testing.T) *Font {
+	// every glyph is six pixels wide apart from the narrow i, space and dash
+	advances := make([]byte, 256)
+	for i := range advances {
+		advances[i] = 6
+	}
+
+	advances['i'] = 3
+	advances[' '] = 4
+	advances['-'] = 5
+
+	builder := cachetest.New()
+	builder.AddFile(8, 3, testGlyphs(t, 256, 1, 1)).Named("p11_full")
+	builder.AddFile(8, 7, testGlyphs(t, 2, 13, 11)).Named("mod_icons")
+	builder.AddFile(13, 2, append(advances, 10)).Named("p11_full")
+
+	font, err := Load(builder.Build(t), Plain11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return font
+}
+
+func TestLoad(t *testing.T) {
+	font := loadTestFont(t)
+
+	if len(font.Glyphs.Frames) != 256 || font.Icons == nil || font.Icons.Width != 13 {
+		t.Fatalf("unexpected glyphs or icons of font %v", font.Name)
+	}
+
+	if font.Metrics.Ascent != 10 || font.Metrics.Advances['i'] != 3 || font.Metrics.Kerning != nil {
+		t.Errorf("unexpected metrics %+v", font.Metrics)
+	}
+}
+
+func TestDecodeKernedMetrics(t *testing.T) {
+	data := []byte{0}
+
+	var advances, tops, heights [256]byte
+	for i := range advances {
+		advances[i] = 6
+	}
+
+	heights['A'], heights['V'] = 2, 2
+	tops[' '], heights[' '] = 7, 1
+
+	data = append(data, advances[:]...)
+	data = append(data, tops[:]...)
+	data = append(data, heights[:]...)
+
+	// the left outlines of the space, A and V, then their right outlines
+	data = append(data, 0, 1, 0, 0, 2)
+	data = append(data, 0, 3, 0xFD, 4, 0)
+
+	metrics, err := DecodeMetrics(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metrics.Ascent != 8 {
+		t.Errorf("expected an ascent of 8 but got %v", metrics.Ascent)
+	}
+
+	if metrics.Kerning['A'<<8|'V'] != -2 || metrics.Kerning['V'<<8|'A'] != -5 {
+		t.Errorf("unexpected kerning %v %v", metrics.Kerning['A'<<8|'V'], metrics.Kerning['V'<<8|'A'])
+	}
+
+	if metrics.Kerning['A'<<8|' '] != 0 {
+		t.Error("expected spaces to not be kerned")
+	}
+
+	if _, err := DecodeMetrics(data[:300]); err != ErrMalformed {
+		t.Errorf("expected malformed metrics but got %v", err)
+	}
+}
+
+func TestMeasureText(t *testing.T) {
+	font := loadTestFont(t)
+
+	tests := map[string]int{
+		"hello world":          64,
+		"<col=ff0000>hi</col>": 9,
+		"<lt>b<gt>":            18,
+		"<img=1>hi":            22,
+		"<img=5>hi":            9,
+		"a b":                  16,
+		"€":                    6,
+	}
+
+	for text, expected := range tests {
+		if width := font.MeasureText(text); width != expected {
+			t.Errorf("expected %q to be %v wide but got %v", text, expected, width)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	font := loadTestFont(t)
+
+	tests := []struct {
+		text     string
+		widths   []int
+		expected []string
+	}{
+		{"hello world", []int{40}, []string{"hello", "world"}},
+		{"hello world", []int{64}, []string{"hello world"}},
+		{"<col=ff0000>hello world</col>", []int{40}, []string{"<col=ff0000>hello", "world</col>"}},
+		{"well-known", []int{30}, []string{"well-", "known"}},
+		{"a<br>b", nil, []string{"a<br>", "b"}},
+		{"a b c d", []int{6, 18}, []string{"a", "b c", "d"}},
+		{"unbreakable", []int{10}, []string{"unbreakable"}},
+	}
+
+	for _, test := range tests {
+		if lines := font.WrapText(test.text, test.widths...); !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("expected %q to wrap into %q but got %q", test.text, test.expected, lines)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	font := loadTestFont(t)
+
+	if font.MaxAscent() != 10 || font.MaxDescent() != 0 {
+		t.Errorf("unexpected ascent %v and descent %v", font.MaxAscent(), font.MaxDescent())
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 20, 12))
+	font.Draw(img, "a<col=ff0000>i</col> a", 1, 11, 0x00FF00)
+
+	green := color.RGBA{G: 0xFF, A: 0xFF}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+
+	expected := map[image.Point]color.RGBA{
+		{1, 1}: green, {7, 1}: red, {10, 1}: {}, {14, 1}: green, {1, 2}: {},
+	}
+
+	for point, c := range expected {
+		if actual := img.RGBAAt(point.X, point.Y); actual != c {
+			t.Errorf("expected %v at %v but got %v", c, point, actual)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package font
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// nbsp is the non-breaking space, which is as wide as a space but never breaks a line.
+const nbsp = 160
+
+// Glyph returns the index of the glyph of the given character, which is its value in the
+// CP-1252 encoding. Characters that the encoding lacks are drawn as a question mark.
+func Glyph(r rune) int {
+	return int(buffer.EncodeCharacter(r))
+}
+
+// CharWidth returns the advance of the glyph of the given character.
+func (font *Font) CharWidth(r rune) int {
+	if r == nbsp {
+		r = ' '
+	}
+
+	return font.Metrics.Advances[Glyph(r)]
+}
+
+// MeasureText returns the width of the given text in pixels. Tags are not drawn, apart
+// from <lt> and <gt> which are drawn as angle brackets and <img=n> which is as wide as
+// the mod icons. Just like the client, line breaks are not taken into account.
+func (font *Font) MeasureText(text string) int {
+	width := 0
+	previous := -1
+	tag := -1
+
+	for i, r := range text {
+		if r == '<' {
+			tag = i
+			continue
+		}
+
+		if r == '>' && tag != -1 {
+			name := text[tag+1 : i]
+			tag = -1
+
+			switch {
+			case name == "lt":
+				r = '<'
+			case name == "gt":
+				r = '>'
+			default:
+				if icon, ok := font.iconWidth(name); ok {
+					width += icon
+					previous = -1
+				}
+
+				continue
+			}
+		}
+
+		if tag == -1 {
+			width += font.advance(r, previous)
+			previous = Glyph(r)
+		}
+	}
+
+	return width
+}
+
+// WrapText splits the given text into lines that are no wider than the given widths,
+// of which the last applies to every remaining line. Lines break at <br> tags and after
+// spaces and hyphens, and keep the tags they contain, such as <col=ff0000>. Text is only
+// split at <br> tags if no widths are given. A word that is wider than a line is never
+// broken up.
+func (font *Font) WrapText(text string, widths ...int) []string {
+	var lines []string
+	var processed []byte
+
+	start := 0
+	width := 0
+	previous := -1
+	tag := -1
+
+	// breakAt is where the current line may end, breakWidth the width of the line up
+	// to there and breakSkip whether the character before it is left out
+	breakAt, breakWidth, breakSkip := -1, 0, 0
+
+	for i, r := range text {
+		if r == '<' {
+			tag = i
+			continue
+		}
+
+		if r == '>' && tag != -1 {
+			name := text[tag+1 : i]
+			tag = -1
+
+			processed = append(processed, '<')
+			processed = append(processed, name...)
+			processed = append(processed, '>')
+
+			switch {
+			case name == "br":
+				lines = append(lines, string(processed[start:]))
+				start = len(processed)
+				width, previous, breakAt = 0, -1, -1
+			case name == "lt":
+				width += font.advance('<', previous)
+				previous = '<'
+			case name == "gt":
+				width += font.advance('>', previous)
+				previous = '>'
+			default:
+				if icon, ok := font.iconWidth(name); ok {
+					width += icon
+					previous = -1
+				}
+			}
+
+			r = 0
+		}
+
+		if tag != -1 {
+			continue
+		}
+
+		if r != 0 {
+			processed = append(processed, string(r)...)
+			width += font.advance(r, previous)
+			previous = Glyph(r)
+		}
+
+		if r == ' ' {
+			breakAt, breakWidth, breakSkip = len(processed), width, 1
+		}
+
+		if len(widths) > 0 && width > widths[minInt(len(lines), len(widths)-1)] && breakAt >= 0 {
+			lines = append(lines, string(processed[start:breakAt-breakSkip]))
+			start = breakAt
+			width -= breakWidth
+			previous, breakAt = -1, -1
+		}
+
+		if r == '-' {
+			breakAt, breakWidth, breakSkip = len(processed), width, 0
+		}
+	}
+
+	if len(processed) > start {
+		lines = append(lines, string(processed[start:]))
+	}
+
+	return lines
+}
+
+// advance returns the advance of the glyph of the given character, kerned against the
+// glyph of the previous character unless that is -1.
+func (font *Font) advance(r rune, previous int) int {
+	width := font.CharWidth(r)
+	if font.Metrics.Kerning != nil && previous != -1 {
+		width += int(font.Metrics.Kerning[previous<<8|Glyph(r)])
+	}
+
+	return width
+}
+
+// iconWidth returns the width of the icon that the given tag refers to, and whether it
+// refers to an icon that exists.
+func (font *Font) iconWidth(tag string) (int, bool) {
+	if !strings.HasPrefix(tag, "img=") || font.Icons == nil {
+		return 0, false
+	}
+
+	icon, err := strconv.Atoi(tag[len("img="):])
+	if err != nil || icon < 0 || icon >= len(font.Icons.Frames) {
+		return 0, false
+	}
+
+	return font.Icons.Width, true
+}
+
+// minInt returns the smallest of the given values.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
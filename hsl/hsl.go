@@ -0,0 +1,182 @@
+package hsl
+
+import "math"
+
+const (
+	// DefaultBrightness is the brightness the client uses for its color palette by default.
+	DefaultBrightness = 0.8
+
+	paletteSize = 65536
+)
+
+// defaultPalette is the color palette built with the DefaultBrightness.
+var defaultPalette = NewPalette(DefaultBrightness)
+
+// Palette maps each 16-bit HSL value to a 24-bit RGB value.
+type Palette [paletteSize]int
+
+// FromRGB converts the given 24-bit RGB value into its hue, saturation and lightness
+// components, each ranging from 0.0 to 1.0. The channels are divided by 256 rather
+// than 255 to match the client's arithmetic.
+func FromRGB(rgb int) (hue, saturation, lightness float64) {
+	red := float64(rgb>>16&0xFF) / 256.0
+	green := float64(rgb>>8&0xFF) / 256.0
+	blue := float64(rgb&0xFF) / 256.0
+
+	min := math.Min(red, math.Min(green, blue))
+	max := math.Max(red, math.Max(green, blue))
+
+	lightness = (min + max) / 2.0
+
+	if max != min {
+		if lightness < 0.5 {
+			saturation = (max - min) / (max + min)
+		} else {
+			saturation = (max - min) / (2.0 - max - min)
+		}
+
+		if max == red {
+			hue = (green - blue) / (max - min)
+		} else if max == green {
+			hue = 2.0 + (blue-red)/(max-min)
+		} else {
+			hue = 4.0 + (red-green)/(max-min)
+		}
+	}
+
+	hue /= 6.0
+
+	return hue, saturation, lightness
+}
+
+// Pack packs the given 8-bit hue, saturation and lightness components into a 16-bit
+// HSL value, as the client does for the colors of floor tiles. The saturation is
+// reduced for lighter colors.
+func Pack(hue, saturation, lightness int) int {
+	if lightness > 179 {
+		saturation /= 2
+	}
+
+	if lightness > 192 {
+		saturation /= 2
+	}
+
+	if lightness > 217 {
+		saturation /= 2
+	}
+
+	if lightness > 243 {
+		saturation /= 2
+	}
+
+	return (hue/4)<<10 + (saturation/32)<<7 + lightness/2
+}
+
+// Hue returns the 6-bit hue component of the given 16-bit HSL value.
+func Hue(hsl int) int {
+	return hsl >> 10 & 0x3F
+}
+
+// Saturation returns the 3-bit saturation component of the given 16-bit HSL value.
+func Saturation(hsl int) int {
+	return hsl >> 7 & 0x7
+}
+
+// Lightness returns the 7-bit lightness component of the given 16-bit HSL value.
+func Lightness(hsl int) int {
+	return hsl & 0x7F
+}
+
+// ToRGB converts the given 16-bit HSL value into a 24-bit RGB value using the
+// palette of the DefaultBrightness.
+func ToRGB(hsl int) int {
+	return defaultPalette[hsl&0xFFFF]
+}
+
+// NewPalette builds the color palette the client uses to convert 16-bit HSL values
+// into RGB values, for the given brightness. The client offers brightness levels
+// ranging from 0.6 (brightest) to 0.9 (darkest).
+func NewPalette(brightness float64) *Palette {
+	palette := new(Palette)
+
+	index := 0
+	for hueSaturation := 0; hueSaturation < 512; hueSaturation++ {
+		hue := float64(hueSaturation>>3)/64.0 + 0.0078125
+		saturation := float64(hueSaturation&7)/8.0 + 0.0625
+
+		for lightness := 0; lightness < 128; lightness++ {
+			value := float64(lightness) / 128.0
+
+			red := value
+			green := value
+			blue := value
+
+			if saturation != 0.0 {
+				var q float64
+				if value < 0.5 {
+					q = value * (1.0 + saturation)
+				} else {
+					q = value + saturation - value*saturation
+				}
+
+				p := 2.0*value - q
+
+				redHue := hue + 1.0/3.0
+				if redHue > 1.0 {
+					redHue--
+				}
+
+				blueHue := hue - 1.0/3.0
+				if blueHue < 0.0 {
+					blueHue++
+				}
+
+				red = hueToChannel(p, q, redHue)
+				green = hueToChannel(p, q, hue)
+				blue = hueToChannel(p, q, blueHue)
+			}
+
+			rgb := int(red*256.0)<<16 | int(green*256.0)<<8 | int(blue*256.0)
+			rgb = brighten(rgb, brightness)
+			if rgb == 0 {
+				rgb = 1
+			}
+
+			palette[index] = rgb
+			index++
+		}
+	}
+
+	return palette
+}
+
+// ToRGB converts the given 16-bit HSL value into a 24-bit RGB value.
+func (palette *Palette) ToRGB(hsl int) int {
+	return palette[hsl&0xFFFF]
+}
+
+// hueToChannel computes the value of a single color channel for the given hue.
+func hueToChannel(p, q, hue float64) float64 {
+	if 6.0*hue < 1.0 {
+		return p + (q-p)*6.0*hue
+	}
+
+	if 2.0*hue < 1.0 {
+		return q
+	}
+
+	if 3.0*hue < 2.0 {
+		return p + (q-p)*(2.0/3.0-hue)*6.0
+	}
+
+	return p
+}
+
+// brighten applies the gamma of the given brightness to each channel of the RGB value.
+func brighten(rgb int, brightness float64) int {
+	red := math.Pow(float64(rgb>>16)/256.0, brightness)
+	green := math.Pow(float64(rgb>>8&0xFF)/256.0, brightness)
+	blue := math.Pow(float64(rgb&0xFF)/256.0, brightness)
+
+	return int(red*256.0)<<16 | int(green*256.0)<<8 | int(blue*256.0)
+}
@@ -0,0 +1,40 @@
+package hsl
+
+import "testing"
+
+func TestFromRGB(t *testing.T) {
+	hue, saturation, lightness := FromRGB(0x00FF00)
+
+	if hue < 0.333 || hue > 0.334 {
+		t.Errorf("expected a hue of 1/3 but got %v", hue)
+	}
+
+	if saturation != 1.0 {
+		t.Errorf("expected a saturation of 1.0 but got %v", saturation)
+	}
+
+	if lightness != 0.498046875 {
+		t.Errorf("expected a lightness of 0.498046875 but got %v", lightness)
+	}
+}
+
+func TestPack(t *testing.T) {
+	if value := Pack(0, 255, 255); value != 127 {
+		t.Errorf("expected the saturation of a light color to be reduced but got %v", value)
+	}
+
+	value := Pack(255, 255, 100)
+	if Hue(value) != 63 || Saturation(value) != 7 || Lightness(value) != 50 {
+		t.Errorf("unexpected components of packed value %v", value)
+	}
+}
+
+func TestToRGB(t *testing.T) {
+	if rgb := ToRGB(0); rgb != 1 {
+		t.Errorf("expected black to be converted to 1 but got %v", rgb)
+	}
+
+	if rgb := ToRGB(127); rgb != 0xFEFDFD {
+		t.Errorf("expected 0xFEFDFD but got %X", rgb)
+	}
+}
@@ -0,0 +1,306 @@
+// Package cachetest assembles caches in memory, so that tests decode from fixtures
+// that are built in code rather than from binary files.
+package cachetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sort"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/crypto"
+)
+
+const (
+	// pageSize is the size of a page of the main file, of which the header takes up the
+	// first 8 bytes and the payload the remaining 512.
+	pageSize        = 520
+	pagePayloadSize = 512
+
+	// manifestArchive is the archive that holds the manifests of the other archives.
+	manifestArchive = 255
+)
+
+// Builder collects the folders of a cache and builds them into a Cache.
+type Builder struct {
+	archives map[int]map[int]*Folder
+}
+
+// Folder is a folder of the cache that a Builder builds.
+type Folder struct {
+	// Packs are the packs of the folder by their id.
+	Packs map[int][]byte
+
+	// NameHash is the hash of the label of the folder and PackNameHashes are the hashes
+	// of the labels of its packs, by their id. Zero if unlabelled.
+	NameHash       int
+	PackNameHashes map[int]int
+
+	// Compressed is whether the folder is stored gzip compressed.
+	Compressed bool
+
+	// Keys are the XTEA keys that the folder is enciphered with, if not zero.
+	Keys [4]int
+
+	// Container replaces the stored folder with the given bytes as they are, such as
+	// to store a folder that is corrupt.
+	Container []byte
+}
+
+// New constructs an empty Builder.
+func New() *Builder {
+	return &Builder{archives: make(map[int]map[int]*Folder)}
+}
+
+// Add adds a folder of the given packs, by their id, to the given archive and returns
+// it to be labelled, compressed or enciphered.
+func (b *Builder) Add(archiveId, folderId int, packs map[int][]byte) *Folder {
+	folders, ok := b.archives[archiveId]
+	if !ok {
+		folders = make(map[int]*Folder)
+		b.archives[archiveId] = folders
+	}
+
+	folder := &Folder{Packs: packs}
+	folders[folderId] = folder
+
+	return folder
+}
+
+// AddFile adds a folder that consists of a single pack of the given data.
+func (b *Builder) AddFile(archiveId, folderId int, data []byte) *Folder {
+	return b.Add(archiveId, folderId, map[int][]byte{0: data})
+}
+
+// Named labels the folder with the given name.
+func (folder *Folder) Named(name string) *Folder {
+	folder.NameHash = crypto.Djb2(name)
+	return folder
+}
+
+// Bundle builds the resource files of the cache.
+func (b *Builder) Bundle() *gokira.FileBundle {
+	// the first page is never used, as a page of zero ends a chain of pages
+	main := make([]byte, pageSize)
+	entries := make(map[int]map[int][]byte)
+
+	store := func(archiveId, folderId int, data []byte) {
+		page := len(main) / pageSize
+
+		entry := buffer.NewWriter().WriteInt24(len(data)).WriteInt24(page).Bytes()
+		if entries[archiveId] == nil {
+			entries[archiveId] = make(map[int][]byte)
+		}
+
+		entries[archiveId][folderId] = entry
+
+		for position := 0; position == 0 || position < len(data); position += pagePayloadSize {
+			end := position + pagePayloadSize
+			next := page + 1
+			if end >= len(data) {
+				end = len(data)
+				next = 0
+			}
+
+			header := buffer.NewWriter().
+				WriteInt16(folderId).
+				WriteInt16(position / pagePayloadSize).
+				WriteInt24(next).
+				WriteInt8(archiveId)
+
+			payload := make([]byte, pagePayloadSize)
+			copy(payload, data[position:end])
+
+			main = append(append(main, header.Bytes()...), payload...)
+			page++
+		}
+	}
+
+	archiveIds := sortedKeys(b.archives)
+	for _, archiveId := range archiveIds {
+		folders := b.archives[archiveId]
+		for _, folderId := range sortedKeys(folders) {
+			store(archiveId, folderId, folders[folderId].container())
+		}
+
+		store(manifestArchive, archiveId, newContainer(manifest(folders), false, [4]int{}))
+	}
+
+	var indices [][]byte
+	if len(archiveIds) > 0 {
+		indices = make([][]byte, archiveIds[len(archiveIds)-1]+1)
+	}
+
+	for archiveId := range indices {
+		indices[archiveId] = index(entries[archiveId])
+		if indices[archiveId] == nil {
+			// an archive without an index is left out of the cache, which would leave
+			// out every archive after it
+			indices[archiveId] = make([]byte, 6)
+		}
+	}
+
+	return gokira.NewFileBundle(main, indices, index(entries[manifestArchive]))
+}
+
+// Build builds the Cache, failing the given test if the Cache cannot be constructed.
+func (b *Builder) Build(t *testing.T) *gokira.Cache {
+	t.Helper()
+
+	cache, err := gokira.NewCache(b.Bundle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
+
+// container returns the folder as it is stored, which is its packs grouped into a
+// single chunk and wrapped into a container.
+func (folder *Folder) container() []byte {
+	if folder.Container != nil {
+		return folder.Container
+	}
+
+	ids := sortedKeys(folder.Packs)
+	if len(ids) == 1 {
+		return newContainer(folder.Packs[ids[0]], folder.Compressed, folder.Keys)
+	}
+
+	w := buffer.NewWriter()
+	for _, id := range ids {
+		w.WriteBytes(folder.Packs[id])
+	}
+
+	previous := 0
+	for _, id := range ids {
+		w.WriteInt32(len(folder.Packs[id]) - previous)
+		previous = len(folder.Packs[id])
+	}
+
+	return newContainer(w.WriteInt8(1).Bytes(), folder.Compressed, folder.Keys)
+}
+
+// newContainer wraps the given data into a container that is optionally gzip
+// compressed and enciphered with the given XTEA keys.
+func newContainer(data []byte, compressed bool, keys [4]int) []byte {
+	if !compressed {
+		payload := append([]byte(nil), data...)
+		encipher(payload, keys)
+
+		return buffer.NewWriter().WriteInt8(0).WriteInt32(len(data)).WriteBytes(payload).Bytes()
+	}
+
+	var gzipped bytes.Buffer
+	compressor := gzip.NewWriter(&gzipped)
+	compressor.Write(data)
+	compressor.Close()
+
+	payload := buffer.NewWriter().WriteInt32(len(data)).WriteBytes(gzipped.Bytes()).Bytes()
+	encipher(payload, keys)
+
+	return buffer.NewWriter().WriteInt8(2).WriteInt32(gzipped.Len()).WriteBytes(payload).Bytes()
+}
+
+// encipher enciphers the given data with the given XTEA keys, unless the keys are zero.
+func encipher(data []byte, keys [4]int) {
+	if keys != [4]int{} {
+		crypto.EncipherXTEA(data, keys)
+	}
+}
+
+// manifest encodes the manifest of an archive of the given folders in the sixth format.
+func manifest(folders map[int]*Folder) []byte {
+	ids := sortedKeys(folders)
+
+	named := false
+	for _, folder := range folders {
+		named = named || folder.NameHash != 0
+	}
+
+	w := buffer.NewWriter().WriteInt8(6).WriteInt32(1).WriteBool(named).WriteInt16(len(ids))
+
+	previous := 0
+	for _, id := range ids {
+		w.WriteInt16(id - previous)
+		previous = id
+	}
+
+	if named {
+		for _, id := range ids {
+			w.WriteInt32(folders[id].NameHash)
+		}
+	}
+
+	// the checksums and the versions of the folders
+	for range ids {
+		w.WriteInt32(0)
+	}
+
+	for range ids {
+		w.WriteInt32(1)
+	}
+
+	for _, id := range ids {
+		w.WriteInt16(len(folders[id].Packs))
+	}
+
+	for _, id := range ids {
+		previous := 0
+		for _, packId := range sortedKeys(folders[id].Packs) {
+			w.WriteInt16(packId - previous)
+			previous = packId
+		}
+	}
+
+	if named {
+		for _, id := range ids {
+			for _, packId := range sortedKeys(folders[id].Packs) {
+				w.WriteInt32(folders[id].PackNameHashes[packId])
+			}
+		}
+	}
+
+	return w.Bytes()
+}
+
+// index encodes the index of an archive of the given entries, by folder id.
+func index(entries map[int][]byte) []byte {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := sortedKeys(entries)
+
+	data := make([]byte, 6*(ids[len(ids)-1]+1))
+	for id, entry := range entries {
+		copy(data[6*id:], entry)
+	}
+
+	return data
+}
+
+// sortedKeys returns the keys of the given map, which is keyed by int, in ascending
+// order.
+func sortedKeys(m interface{}) []int {
+	var keys []int
+	switch m := m.(type) {
+	case map[int]map[int]*Folder:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[int]*Folder:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[int][]byte:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Ints(keys)
+	return keys
+}
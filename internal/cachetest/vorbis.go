@@ -0,0 +1,220 @@
+package cachetest
+
+import "github.com/sinoz/gokira/buffer"
+
+const (
+	// vorbisShortExponent and vorbisLongExponent are the exponents of the short and the
+	// long block size of the test setup, which are 256 and 512.
+	vorbisShortExponent = 8
+	vorbisLongExponent  = 9
+
+	// vorbisPartitionSize is the amount of coefficients of a partition of the residue.
+	vorbisPartitionSize = 8
+
+	// vorbisCodebookMagic starts every codebook.
+	vorbisCodebookMagic = 0x564342
+)
+
+// bitWriter packs values into bytes the way Vorbis does, least significant bit first.
+type bitWriter struct {
+	data     []byte
+	position int
+}
+
+// write writes the lower given amount of bits of the given value.
+func (w *bitWriter) write(value, bits int) {
+	for i := 0; i < bits; i++ {
+		if w.position>>3 == len(w.data) {
+			w.data = append(w.data, 0)
+		}
+
+		w.data[w.position>>3] |= byte(value>>uint(i)&1) << uint(w.position&7)
+		w.position++
+	}
+}
+
+// writeCodeword writes a Huffman codeword of the given length, most significant bit
+// first.
+func (w *bitWriter) writeCodeword(codeword, length int) {
+	for i := length - 1; i >= 0; i-- {
+		w.write(codeword>>uint(i), 1)
+	}
+}
+
+// noise is a linear congruential generator of the residues of test packets.
+type noise uint32
+
+// next returns the next value below the given bound.
+func (n *noise) next(bound int) int {
+	*n = *n*1103515245 + 12345
+	return int(*n>>16) % bound
+}
+
+// VorbisSetup encodes the Vorbis setup of the test samples. It has block sizes of 256
+// and 512, a classbook of two classes and a codebook of the four values -1, 0, 1 and 2,
+// a floor 1 without partitions of a multiplier of 4, a residue 1 of which the second
+// class draws eight coefficients per partition from the codebook, and a short and a
+// long mode.
+func VorbisSetup() []byte {
+	w := &bitWriter{}
+	w.write(vorbisShortExponent, 4)
+	w.write(vorbisLongExponent, 4)
+
+	w.write(2-1, 8)
+
+	// the classbook, of which both entries are a single bit long
+	w.write(vorbisCodebookMagic, 24)
+	w.write(1, 16)
+	w.write(2, 24)
+	w.write(0, 1)
+	w.write(0, 1)
+	w.write(0, 5)
+	w.write(0, 5)
+	w.write(0, 4)
+
+	// the codebook of ordered lengths of two bits, of which the values start at -1
+	// and increase by 1
+	w.write(vorbisCodebookMagic, 24)
+	w.write(1, 16)
+	w.write(4, 24)
+	w.write(1, 1)
+	w.write(2-1, 5)
+	w.write(4, 3)
+	w.write(1, 4)
+	w.write(vorbisFloat(1, 0, true), 32)
+	w.write(vorbisFloat(1, 0, false), 32)
+	w.write(2-1, 4)
+	w.write(0, 1)
+	for i := 0; i < 4; i++ {
+		w.write(i, 2)
+	}
+
+	// the time domain transforms
+	w.write(0, 6)
+	w.write(0, 16)
+
+	// the floor
+	w.write(0, 6)
+	w.write(1, 16)
+	w.write(0, 5)
+	w.write(4-1, 2)
+	w.write(8, 4)
+
+	// the residue, of which the first class is silent
+	w.write(0, 6)
+	w.write(1, 16)
+	w.write(0, 24)
+	w.write(256, 24)
+	w.write(vorbisPartitionSize-1, 24)
+	w.write(2-1, 6)
+	w.write(0, 8)
+	w.write(0, 3)
+	w.write(0, 1)
+	w.write(1, 3)
+	w.write(0, 1)
+	w.write(1, 8)
+
+	// the mapping
+	w.write(0, 6)
+	w.write(0, 16)
+	w.write(0, 1)
+	w.write(0, 1)
+	w.write(0, 2)
+	w.write(0, 8)
+	w.write(0, 8)
+	w.write(0, 8)
+
+	// the modes
+	w.write(2-1, 6)
+	for _, long := range []int{0, 1} {
+		w.write(long, 1)
+		w.write(0, 16)
+		w.write(0, 16)
+		w.write(0, 8)
+	}
+
+	return w.data
+}
+
+// vorbisFloat packs the given mantissa and exponent into the float format of Vorbis.
+func vorbisFloat(mantissa, exponent int, negative bool) int {
+	value := (exponent+788)<<21 | mantissa
+	if negative {
+		value |= 1 << 31
+	}
+
+	return value
+}
+
+// VorbisPacket encodes an audio packet of the test setup that uses the short or the long
+// block size, and for a long block the given sizes of its neighbours. The floor runs
+// from the first to the second of the given values, of which a nil floor leaves the
+// packet silent. The classes and the coefficients of the residue are drawn from a
+// generator of the given seed.
+func VorbisPacket(long, previousLong, nextLong bool, floor []int, seed int) []byte {
+	w := &bitWriter{}
+	w.write(0, 1)
+
+	size := 1 << vorbisShortExponent
+	if long {
+		size = 1 << vorbisLongExponent
+
+		w.write(1, 1)
+		w.write(boolBit(previousLong), 1)
+		w.write(boolBit(nextLong), 1)
+	} else {
+		w.write(0, 1)
+	}
+
+	if floor == nil {
+		w.write(0, 1)
+		return w.data
+	}
+
+	w.write(1, 1)
+	w.write(floor[0], 6)
+	w.write(floor[1], 6)
+
+	n := noise(seed)
+	for partition := 0; partition < size/2/vorbisPartitionSize; partition++ {
+		class := n.next(2)
+		w.writeCodeword(class, 1)
+
+		if class == 1 {
+			for i := 0; i < vorbisPartitionSize; i++ {
+				w.writeCodeword(n.next(4), 2)
+			}
+		}
+	}
+
+	return w.data
+}
+
+// boolBit returns 1 for true and 0 for false.
+func boolBit(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// VorbisSample encodes a sample file of the given attributes and packets. A negative
+// loop end is the complement of the loop end of a loop that plays back and forth.
+func VorbisSample(sampleRate, sampleCount, loopStart, loopEnd int, packets ...[]byte) []byte {
+	w := buffer.NewWriter().
+		WriteInt32(sampleRate).WriteInt32(sampleCount).
+		WriteInt32(loopStart).WriteInt32(loopEnd).
+		WriteInt32(len(packets))
+
+	for _, packet := range packets {
+		length := len(packet)
+		for ; length >= 255; length -= 255 {
+			w.WriteInt8(255)
+		}
+
+		w.WriteInt8(length).WriteBytes(packet)
+	}
+
+	return w.Bytes()
+}
@@ -5,6 +5,7 @@ import (
 	"hash/crc32"
 
 	"github.com/sinoz/bytecat"
+	"github.com/sinoz/gokira/buffer"
 )
 
 const polynomial = 0xEDB88320
@@ -36,7 +37,8 @@ type FolderManifest struct {
 
 // PackManifest contains metadata about a pack in a folder.
 type PackManifest struct {
-	// TODO
+	Id        int
+	LabelHash uint32
 }
 
 // newReleaseManifest constructs a new GetReleaseManifest that contains information about every archive in the given Cache.
@@ -73,7 +75,7 @@ func newReleaseManifest(cache *Cache) (*ReleaseManifest, error) {
 func newArchiveManifest(id int, data []byte) (*ArchiveManifest, error) {
 	var err error
 
-	itr := bytecat.StringWrap(data).Iterator()
+	itr := buffer.NewReader(data)
 
 	manifest := new(ArchiveManifest)
 	manifest.Id = id
@@ -97,7 +99,7 @@ func newArchiveManifest(id int, data []byte) (*ArchiveManifest, error) {
 		return nil, err
 	}
 
-	folderCount, err := itr.ReadUInt16()
+	folderCount, err := manifest.readCount(itr)
 	if err != nil {
 		return nil, err
 	}
@@ -110,12 +112,12 @@ func newArchiveManifest(id int, data []byte) (*ArchiveManifest, error) {
 	// read the id of each and every referenced folder in the archive's manifest.
 	// this is due to a sudden padding in between some folders where an id is skipped
 	for i := 0; i < len(folderIds); i++ {
-		idDelta, err := itr.ReadUInt16()
+		idDelta, err := manifest.readCount(itr)
 		if err != nil {
 			return nil, err
 		}
 
-		accumulator += int(idDelta)
+		accumulator += idDelta
 		folderIds[i] = accumulator
 
 		if folderIds[i] > lastId {
@@ -168,10 +170,10 @@ func newArchiveManifest(id int, data []byte) (*ArchiveManifest, error) {
 		}
 	}
 
-	// TODO
+	// read the amount of packs in each folder
 	for _, folder := range manifest.FolderReferences {
 		if folder != nil {
-			packCount, err := itr.ReadUInt16()
+			packCount, err := manifest.readCount(itr)
 			if err != nil {
 				return nil, err
 			}
@@ -180,9 +182,50 @@ func newArchiveManifest(id int, data []byte) (*ArchiveManifest, error) {
 		}
 	}
 
+	// read the ids of the packs in each folder, which are delta encoded just like the folder ids
+	for _, folder := range manifest.FolderReferences {
+		if folder != nil {
+			accumulator := 0
+
+			for index := range folder.PackReferences {
+				idDelta, err := manifest.readCount(itr)
+				if err != nil {
+					return nil, err
+				}
+
+				accumulator += idDelta
+				folder.PackReferences[index] = &PackManifest{Id: accumulator}
+			}
+		}
+	}
+
+	// and the label hashes of the packs in each folder, if there are any
+	if manifest.containsLabels() {
+		for _, folder := range manifest.FolderReferences {
+			if folder != nil {
+				for _, pack := range folder.PackReferences {
+					pack.LabelHash, err = itr.ReadUInt32()
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
 	return manifest, nil
 }
 
+// readCount reads an amount or an id delta, which is a big smart as of format 7. May return an error.
+func (manifest *ArchiveManifest) readCount(itr *buffer.Reader) (int, error) {
+	if manifest.Format >= 7 {
+		return itr.ReadBigSmart()
+	}
+
+	value, err := itr.ReadUInt16()
+	return int(value), err
+}
+
 // containsNames returns whether this manifest contains the DJB2 name hashes.
 func (manifest *ArchiveManifest) containsLabels() bool {
 	return manifest.Directive != 0
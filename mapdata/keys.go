@@ -0,0 +1,233 @@
+package mapdata
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/crypto"
+)
+
+// maxLocationType is the highest shape a Location can have.
+const maxLocationType = 22
+
+// The types of compression of a container, which follow from its first byte.
+const (
+	bzip2Compression = 1
+	gzipCompression  = 2
+)
+
+var (
+	// ErrMissingKeys is reported for a region of which the locations are enciphered but
+	// for which no XTEA keys are given.
+	ErrMissingKeys = errors.New("no keys for the locations of the region")
+
+	// ErrNoValidKeys is returned when none of the candidate XTEA keys decipher the
+	// locations of a region.
+	ErrNoValidKeys = errors.New("none of the keys decipher the locations of the region")
+
+	// ErrMalformedKeyFile is returned when a key file is malformed.
+	ErrMalformedKeyFile = errors.New("malformed key file")
+)
+
+// ValidateKeys reports whether the given XTEA keys decipher the locations of the region
+// of the given id, by deciphering, decompressing and decoding them. Returns nil if they
+// do, gokira.ErrInvalidKeys if they do not or gokira.ErrLabelNotFound if the region has
+// no locations. Keys of zero are valid for locations that are not enciphered. Errors
+// that deciphering does not cause, such as those reading the cache, are returned as
+// they are.
+func ValidateKeys(cache *gokira.Cache, regionId int, keys [4]int) error {
+	data, err := loadFolder(cache, LocationsName(regionId), keys)
+	if err != nil {
+		// locations that are enciphered but deciphered with keys of zero fail to
+		// decompress as if they are not enciphered at all
+		if keys == [4]int{} && compressed(cache, regionId) {
+			return gokira.ErrInvalidKeys
+		}
+
+		return err
+	}
+
+	// locations that are not compressed decipher into garbage rather than failing to,
+	// which shows when decoding them as the garbage rarely ends where the locations do
+	itr := buffer.NewReader(data)
+
+	locations, err := readLocations(itr)
+	if err != nil || itr.IsReadable() {
+		return gokira.ErrInvalidKeys
+	}
+
+	for _, location := range locations {
+		if location.Type > maxLocationType {
+			return gokira.ErrInvalidKeys
+		}
+	}
+
+	return nil
+}
+
+// compressed returns whether the locations of the region of the given id are stored
+// compressed. The header of the container of the locations is never enciphered, unlike
+// the compressed data that follows it.
+func compressed(cache *gokira.Cache, regionId int) bool {
+	manifest, err := cache.GetFolderManifestByName(Archive, LocationsName(regionId))
+	if err != nil {
+		return false
+	}
+
+	container, err := cache.GetFolderPages(Archive, manifest.Id)
+	if err != nil || len(container) == 0 {
+		return false
+	}
+
+	return container[0] == bzip2Compression || container[0] == gzipCompression
+}
+
+// FindKeys returns the first of the given candidate XTEA keys that deciphers the
+// locations of the region of the given id, or ErrNoValidKeys if none of them do.
+// May return an error.
+func FindKeys(cache *gokira.Cache, regionId int, candidates [][4]int) ([4]int, error) {
+	for _, keys := range candidates {
+		err := ValidateKeys(cache, regionId, keys)
+		if err == nil {
+			return keys, nil
+		}
+
+		if err != gokira.ErrInvalidKeys {
+			return [4]int{}, err
+		}
+	}
+
+	return [4]int{}, ErrNoValidKeys
+}
+
+// KeyReport is the outcome of validating the XTEA keys of a single region.
+type KeyReport struct {
+	RegionId int
+	Keys     [4]int
+
+	// Err is nil if the keys are valid, gokira.ErrInvalidKeys if they are not,
+	// ErrMissingKeys if the region requires keys that are not given,
+	// gokira.ErrLabelNotFound if the region has no locations or the error that
+	// loading the locations otherwise fails with.
+	Err error
+}
+
+// Valid returns whether the keys of the report decipher the locations of the region.
+func (report *KeyReport) Valid() bool {
+	return report.Err == nil
+}
+
+// ValidateKeySet validates the given XTEA keys, indexed by region id, against the
+// locations of every region of the given Cache as well as against the regions the keys
+// are given for. Regions without keys are validated as if they are not enciphered.
+// The reports are ordered by region id. May return an error if the archive of the
+// regions cannot be read.
+func ValidateKeySet(cache *gokira.Cache, keySet map[int][4]int) ([]*KeyReport, error) {
+	regions, err := Regions(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	regionIds := make(map[int]bool)
+	for _, regionId := range regions {
+		regionIds[regionId] = true
+	}
+
+	for regionId := range keySet {
+		regionIds[regionId] = true
+	}
+
+	reports := make([]*KeyReport, 0, len(regionIds))
+	for regionId := range regionIds {
+		keys, ok := keySet[regionId]
+
+		err := ValidateKeys(cache, regionId, keys)
+		if err == gokira.ErrInvalidKeys && !ok {
+			err = ErrMissingKeys
+		}
+
+		reports = append(reports, &KeyReport{RegionId: regionId, Keys: keys, Err: err})
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].RegionId < reports[j].RegionId
+	})
+
+	return reports, nil
+}
+
+// Regions returns the ids of the regions of which the given Cache holds locations,
+// in ascending order. May return an error.
+func Regions(cache *gokira.Cache) ([]int, error) {
+	manifest, err := cache.GetArchiveManifest(Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[uint32]bool)
+	for _, folder := range manifest.FolderReferences {
+		if folder != nil {
+			labels[folder.LabelHash] = true
+		}
+	}
+
+	var regions []int
+	for regionId := 0; regionId < 1<<16; regionId++ {
+		if labels[uint32(crypto.Djb2(LocationsName(regionId)))] {
+			regions = append(regions, regionId)
+		}
+	}
+
+	return regions, nil
+}
+
+// keyEntry is an entry of a key file. Key files label the region id and the keys
+// differently depending on where they are obtained from.
+type keyEntry struct {
+	Region    *int  `json:"region"`
+	MapSquare *int  `json:"mapsquare"`
+	Key       []int `json:"key"`
+	Keys      []int `json:"keys"`
+}
+
+// ReadKeys reads a JSON key file from the given reader, which lists the XTEA keys of
+// each region as an object of the region id, labelled "mapsquare" or "region", and
+// the keys, labelled "key" or "keys". Returns the keys indexed by region id. May
+// return an error.
+func ReadKeys(r io.Reader) (map[int][4]int, error) {
+	var entries []*keyEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	keySet := make(map[int][4]int, len(entries))
+	for _, entry := range entries {
+		regionId := entry.MapSquare
+		if regionId == nil {
+			regionId = entry.Region
+		}
+
+		keys := entry.Keys
+		if keys == nil {
+			keys = entry.Key
+		}
+
+		if regionId == nil || len(keys) != 4 {
+			return nil, ErrMalformedKeyFile
+		}
+
+		// keys are commonly listed as signed integers, but not always
+		var set [4]int
+		for i, key := range keys {
+			set[i] = int(int32(key))
+		}
+
+		keySet[*regionId] = set
+	}
+
+	return keySet, nil
+}
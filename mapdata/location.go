@@ -0,0 +1,80 @@
+package mapdata
+
+import "github.com/sinoz/gokira/buffer"
+
+// Location is a location, such as a wall or a tree, that is placed on a tile of a region.
+type Location struct {
+	// Id is the id of the LocType of the location.
+	Id int
+
+	// Plane, X and Y are the coordinates of the tile of the location, relative to the
+	// south-western corner of its region.
+	Plane int
+	X     int
+	Y     int
+
+	// Type is the shape of the location, such as a straight wall (0) or a centrepiece
+	// (10), and Orientation the amount of quarter turns it is rotated clockwise by.
+	Type        int
+	Orientation int
+}
+
+// DecodeLocations decodes the locations of a region from the given deciphered data, which
+// groups the locations by the increments of their ids and each location by the increments
+// of its packed coordinates. May return an error.
+func DecodeLocations(data []byte) ([]*Location, error) {
+	return readLocations(buffer.NewReader(data))
+}
+
+// readLocations reads the locations of a region from the given reader, up until the end
+// of the locations. May return an error.
+func readLocations(itr *buffer.Reader) ([]*Location, error) {
+	var locations []*Location
+
+	id := -1
+	for {
+		offset, err := itr.ReadIncrementalSmart()
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		if offset == 0 {
+			return locations, nil
+		}
+
+		id += offset
+
+		position := 0
+		for {
+			offset, err := itr.ReadSmart()
+			if err != nil {
+				return nil, ErrMalformed
+			}
+
+			if offset == 0 {
+				break
+			}
+
+			position += offset - 1
+
+			attributes, err := itr.ReadByte()
+			if err != nil {
+				return nil, ErrMalformed
+			}
+
+			plane := position >> 12
+			if plane >= Planes {
+				return nil, ErrMalformed
+			}
+
+			locations = append(locations, &Location{
+				Id:          id,
+				Plane:       plane,
+				X:           position >> 6 & 0x3F,
+				Y:           position & 0x3F,
+				Type:        int(attributes >> 2),
+				Orientation: int(attributes & 3),
+			})
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package mapdata decodes the regions of the map, which are squares of 64 by 64 tiles on
+// each of the four planes that hold the terrain of the tiles and the locations, such as
+// walls and trees, that are placed on them.
+package mapdata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sinoz/gokira"
+)
+
+// Archive is the archive that holds the terrain and the locations of each region, in
+// folders that are labelled m{x}_{y} and l{x}_{y} respectively.
+const Archive = 5
+
+const (
+	// Size is the length of each side of a region, in tiles.
+	Size = 64
+
+	// Planes is the amount of planes of a region, of which plane zero is the ground.
+	Planes = 4
+)
+
+// ErrMalformed is returned when the data of a region ends prematurely or describes
+// tiles or locations that lie outside of the region.
+var ErrMalformed = errors.New("malformed map data")
+
+// Region is a square of 64 by 64 tiles of the map along with the locations on it.
+type Region struct {
+	Id        int
+	Terrain   *Terrain
+	Locations []*Location
+}
+
+// RegionId packs the given coordinates of a region, in units of regions, into the id
+// of the region.
+func RegionId(x, y int) int {
+	return x<<8 | y
+}
+
+// SplitRegionId unpacks the given id of a region into its coordinates, in units of regions.
+func SplitRegionId(id int) (int, int) {
+	return id >> 8, id & 0xFF
+}
+
+// RegionOf returns the id of the region that contains the tile at the given coordinates.
+func RegionOf(x, y int) int {
+	return RegionId(x/Size, y/Size)
+}
+
+// TerrainName returns the label of the folder that holds the terrain of the region of
+// the given id.
+func TerrainName(regionId int) string {
+	x, y := SplitRegionId(regionId)
+	return fmt.Sprintf("m%d_%d", x, y)
+}
+
+// LocationsName returns the label of the folder that holds the locations of the region
+// of the given id.
+func LocationsName(regionId int) string {
+	x, y := SplitRegionId(regionId)
+	return fmt.Sprintf("l%d_%d", x, y)
+}
+
+// Load decodes the terrain and the locations of the region of the given id from the given
+// Cache, deciphering the locations with the given XTEA keys. May return an error.
+func Load(cache *gokira.Cache, regionId int, keys [4]int) (*Region, error) {
+	terrain, err := LoadTerrain(cache, regionId)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := LoadLocations(cache, regionId, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Region{Id: regionId, Terrain: terrain, Locations: locations}, nil
+}
+
+// LoadTerrain decodes the Terrain of the region of the given id from the given Cache.
+// May return an error.
+func LoadTerrain(cache *gokira.Cache, regionId int) (*Terrain, error) {
+	data, err := loadFolder(cache, TerrainName(regionId), [4]int{})
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeTerrain(regionId, data, cache.Revision())
+}
+
+// LoadLocations decodes the locations of the region of the given id from the given Cache,
+// deciphering them with the given XTEA keys. May return an error.
+func LoadLocations(cache *gokira.Cache, regionId int, keys [4]int) ([]*Location, error) {
+	data, err := loadFolder(cache, LocationsName(regionId), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeLocations(data)
+}
+
+// loadFolder fetches the data of the folder of the given label, deciphering it with the
+// given XTEA keys. May return an error.
+func loadFolder(cache *gokira.Cache, name string, keys [4]int) ([]byte, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, name)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := cache.GetFolder(Archive, manifest.Id, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return folder.Data, nil
+}
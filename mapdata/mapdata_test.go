@@ -0,0 +1,276 @@
+package mapdata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+var testKeys = [4]int{0x12345678, -0x1234568, 0x0BADF00D, 42}
+
+// testLocations encodes the locations of the test regions, which are a pair of
+// locations of id 1276 and a location of id 40000.
+func testLocations() []byte {
+	position := func(plane, x, y int) int {
+		return plane<<12 | x<<6 | y
+	}
+
+	w := buffer.NewWriter()
+
+	w.WriteIncrementalSmart(1277)
+	w.WriteSmart(position(0, 10, 20) + 1)
+	w.WriteInt8(10<<2 | 1)
+	w.WriteSmart(position(1, 63, 63) - position(0, 10, 20) + 1)
+	w.WriteInt8(22 << 2)
+	w.WriteSmart(0)
+
+	w.WriteIncrementalSmart(40000 - 1276)
+	w.WriteSmart(position(3, 5, 6) + 1)
+	w.WriteInt8(0<<2 | 3)
+	w.WriteSmart(0)
+
+	w.WriteSmart(0)
+
+	return w.Bytes()
+}
+
+// loadTestCache builds the fixture cache that holds the terrain of region 50, 50 and
+// the locations of regions 50, 50 to 52, 50. The locations of region 50, 50 are
+// enciphered with the test keys, those of region 51, 50 are compressed and enciphered
+// with other keys and those of region 52, 50 are neither.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	terrain := buffer.NewWriter()
+	for plane := 0; plane < Planes; plane++ {
+		for x := 0; x < Size; x++ {
+			for y := 0; y < Size; y++ {
+				switch {
+				case plane == 0 && x == 0 && y == 0:
+					terrain.WriteInt16(8).WriteInt16(5).WriteInt16(50).WriteInt16(84).WriteInt16(1).WriteInt8(5)
+				case plane == 0 && x == 1 && y == 0:
+					terrain.WriteInt16(1).WriteInt8(1)
+				default:
+					terrain.WriteInt16(0)
+				}
+			}
+		}
+	}
+
+	builder := cachetest.New()
+	builder.AddFile(Archive, 0, terrain.Bytes()).Named("m50_50")
+
+	enciphered := builder.AddFile(Archive, 1, testLocations()).Named("l50_50")
+	enciphered.Keys = testKeys
+
+	compressed := builder.AddFile(Archive, 2, testLocations()).Named("l51_50")
+	compressed.Compressed = true
+	compressed.Keys = [4]int{7, 8, 9, 10}
+
+	builder.AddFile(Archive, 3, testLocations()).Named("l52_50")
+
+	return builder.Build(t)
+}
+
+func TestLoad(t *testing.T) {
+	region, err := Load(loadTestCache(t), RegionId(50, 50), testKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tile := region.Terrain.Tile(0, 0, 0)
+	expected := Tile{Height: -40, OverlayId: 4, OverlayShape: 1, OverlayRotation: 2, UnderlayId: 2, Settings: BlockedFlag}
+	if *tile != expected {
+		t.Errorf("expected tile %+v but got %+v", expected, *tile)
+	}
+
+	heights := map[[3]int]int{
+		{0, 1, 0}:   0,
+		{1, 0, 0}:   -280,
+		{1, 1, 0}:   -240,
+		{0, 0, 1}:   -232,
+		{0, 5, 7}:   -304,
+		{0, 63, 63}: -320,
+		{3, 63, 63}: -1040,
+	}
+
+	for coordinates, height := range heights {
+		if tile := region.Terrain.Tile(coordinates[0], coordinates[1], coordinates[2]); tile.Height != height {
+			t.Errorf("expected a height of %v at %v but got %v", height, coordinates, tile.Height)
+		}
+	}
+
+	locations := []*Location{
+		{Id: 1276, Plane: 0, X: 10, Y: 20, Type: 10, Orientation: 1},
+		{Id: 1276, Plane: 1, X: 63, Y: 63, Type: 22, Orientation: 0},
+		{Id: 40000, Plane: 3, X: 5, Y: 6, Type: 0, Orientation: 3},
+	}
+
+	if !reflect.DeepEqual(region.Locations, locations) {
+		t.Errorf("unexpected locations %+v", region.Locations)
+	}
+}
+
+func TestLoadWithWrongKeys(t *testing.T) {
+	if _, err := LoadLocations(loadTestCache(t), RegionId(50, 50), [4]int{1, 2, 3, 4}); err == nil {
+		t.Errorf("expected an error deciphering with the wrong keys")
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	cache := loadTestCache(t)
+
+	validations := []struct {
+		regionId int
+		keys     [4]int
+		err      error
+	}{
+		{RegionId(50, 50), testKeys, nil},
+		{RegionId(50, 50), [4]int{1, 2, 3, 4}, gokira.ErrInvalidKeys},
+		{RegionId(50, 50), [4]int{}, gokira.ErrInvalidKeys},
+		{RegionId(51, 50), [4]int{7, 8, 9, 10}, nil},
+		{RegionId(51, 50), testKeys, gokira.ErrInvalidKeys},
+		{RegionId(52, 50), [4]int{}, nil},
+		{RegionId(1, 1), testKeys, gokira.ErrLabelNotFound},
+	}
+
+	for _, validation := range validations {
+		if err := ValidateKeys(cache, validation.regionId, validation.keys); err != validation.err {
+			t.Errorf("expected %v for the keys %v of region %v but got %v", validation.err, validation.keys, validation.regionId, err)
+		}
+	}
+
+	if _, err := LoadLocations(cache, RegionId(51, 50), testKeys); err != gokira.ErrInvalidKeys {
+		t.Errorf("expected %v but got %v", gokira.ErrInvalidKeys, err)
+	}
+}
+
+func TestValidateKeysPassesOtherErrors(t *testing.T) {
+	builder := cachetest.New()
+
+	// the container of locations that claim to be longer than they are, and of those
+	// of an unknown type of compression
+	builder.AddFile(Archive, 0, nil).Named("l50_50").Container = []byte{0, 0, 0, 0, 100, 1, 2}
+	builder.AddFile(Archive, 1, nil).Named("l51_50").Container = []byte{5, 0, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 0}
+
+	cache := builder.Build(t)
+
+	if err := ValidateKeys(cache, RegionId(50, 50), testKeys); err == nil || err == gokira.ErrInvalidKeys {
+		t.Errorf("expected the truncated locations to fail to load but got %v", err)
+	}
+
+	if err := ValidateKeys(cache, RegionId(51, 50), [4]int{}); err == nil || err == gokira.ErrInvalidKeys {
+		t.Errorf("expected the unknown compression to fail to load but got %v", err)
+	}
+}
+
+func TestFindKeys(t *testing.T) {
+	cache := loadTestCache(t)
+
+	candidates := [][4]int{{1, 2, 3, 4}, {7, 8, 9, 10}, testKeys}
+	if keys, err := FindKeys(cache, RegionId(50, 50), candidates); err != nil || keys != testKeys {
+		t.Errorf("expected %v but got %v, %v", testKeys, keys, err)
+	}
+
+	if _, err := FindKeys(cache, RegionId(51, 50), candidates[:1]); err != ErrNoValidKeys {
+		t.Errorf("expected %v but got %v", ErrNoValidKeys, err)
+	}
+}
+
+func TestValidateKeySet(t *testing.T) {
+	keySet, err := ReadKeys(strings.NewReader(`[
+		{"mapsquare": 12850, "key": [305419896, -19088744, 195948557, 42]},
+		{"region": 13107, "keys": [1, 2, 3, 4]}
+	]`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keySet[RegionId(50, 50)] != testKeys {
+		t.Errorf("unexpected keys %v", keySet)
+	}
+
+	reports, err := ValidateKeySet(loadTestCache(t), keySet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*KeyReport{
+		{RegionId: RegionId(50, 50), Keys: testKeys},
+		{RegionId: RegionId(51, 50), Err: ErrMissingKeys},
+		{RegionId: RegionId(51, 51), Keys: [4]int{1, 2, 3, 4}, Err: gokira.ErrLabelNotFound},
+		{RegionId: RegionId(52, 50)},
+	}
+
+	if !reflect.DeepEqual(reports, expected) {
+		for _, report := range reports {
+			t.Errorf("unexpected report %+v", report)
+		}
+	}
+
+	if _, err := ReadKeys(strings.NewReader(`[{"mapsquare": 1, "key": [1, 2]}]`)); err != ErrMalformedKeyFile {
+		t.Errorf("expected %v but got %v", ErrMalformedKeyFile, err)
+	}
+}
+
+func TestDecodeTerrainBeforeExtendedRevision(t *testing.T) {
+	// the first tile takes six bytes and each of the others a single byte
+	data := make([]byte, 5+Planes*Size*Size)
+	copy(data, []byte{2 + 4*3 + 1, 7, 49 + BridgeFlag, 81 + 1, 1, 10})
+
+	terrain, err := DecodeTerrain(RegionId(50, 50), data, ExtendedRevision-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Tile{Height: -80, OverlayId: 6, OverlayShape: 3, OverlayRotation: 1, UnderlayId: 0, Settings: BridgeFlag}
+	if tile := terrain.Tile(0, 0, 0); *tile != expected {
+		t.Errorf("expected tile %+v but got %+v", expected, *tile)
+	}
+
+	if _, err := DecodeTerrain(RegionId(50, 50), data[:100], ExtendedRevision-1); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+}
+
+func TestLoadTerrainOfModernCache(t *testing.T) {
+	// both terrains raise the first tile by 10, of which the legacy terrain encodes
+	// every opcode in one byte and the extended terrain in two
+	legacy := make([]byte, 1+Planes*Size*Size)
+	legacy[0], legacy[1] = 1, 10
+
+	extended := make([]byte, 1+2*Planes*Size*Size)
+	extended[1], extended[2] = 1, 10
+
+	// the db table archive marks the cache as one of the latest revision, although
+	// the caches of the revisions before the extended terrain carry it too
+	builder := cachetest.New()
+	builder.AddFile(Archive, 0, legacy).Named("m50_50")
+	builder.AddFile(Archive, 1, extended).Named("m51_50")
+	builder.AddFile(21, 0, []byte{0})
+
+	cache := builder.Build(t)
+	if revision := cache.Revision(); revision != 0 {
+		t.Fatalf("expected the latest revision but got %v", revision)
+	}
+
+	for _, regionId := range []int{RegionId(50, 50), RegionId(51, 50)} {
+		terrain, err := LoadTerrain(cache, regionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if height := terrain.Tile(0, 0, 0).Height; height != -80 {
+			t.Errorf("expected region %v to raise the first tile to -80 but got %v", regionId, height)
+		}
+	}
+}
+
+func TestRegionOf(t *testing.T) {
+	if id := RegionOf(3222, 3218); id != 12850 || TerrainName(id) != "m50_50" || LocationsName(id) != "l50_50" {
+		t.Errorf("unexpected region %v", id)
+	}
+}
@@ -0,0 +1,252 @@
+package mapdata
+
+import (
+	"math"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// ExtendedRevision is the first revision of which the terrain encodes its opcodes and
+// overlays in two bytes rather than one, allowing for more than 256 overlays.
+const ExtendedRevision = 209
+
+// The flags of the settings of a tile.
+const (
+	// BlockedFlag marks a tile that cannot be walked on.
+	BlockedFlag = 1
+
+	// BridgeFlag marks a tile of which the plane above is drawn and walked on as if it
+	// were the plane of the tile itself, such as a bridge.
+	BridgeFlag = 2
+
+	// RoofFlag marks a tile that is under a roof, of which the planes above are hidden
+	// while the player stands on it.
+	RoofFlag = 4
+
+	// GroundFlag marks a tile that is drawn as part of the lowest plane, regardless of
+	// its own plane.
+	GroundFlag = 8
+)
+
+const (
+	// heightScale is the amount of units of height per unit the terrain encodes.
+	heightScale = 8
+
+	// planeHeight is the height of a plane above the one below it when the terrain
+	// does not encode the height of a tile of the plane.
+	planeHeight = 240
+)
+
+// Tile is the terrain of a single tile.
+type Tile struct {
+	// Height is the height of the south-western corner of the tile, which like in the
+	// client decreases as the tile rises.
+	Height int
+
+	// OverlayId is the id of the OverlayType that is drawn on the tile, or -1. The
+	// shape describes which part of the tile the overlay covers and the rotation the
+	// amount of quarter turns the shape is rotated clockwise by.
+	OverlayId       int
+	OverlayShape    int
+	OverlayRotation int
+
+	// UnderlayId is the id of the UnderlayType of the tile, or -1.
+	UnderlayId int
+
+	// Settings are the flags of the tile, see BlockedFlag and its siblings.
+	Settings int
+}
+
+// Terrain is the terrain of each tile of a region, indexed by plane, x and y.
+type Terrain struct {
+	Tiles [Planes][Size][Size]Tile
+}
+
+// Tile returns the terrain of the tile at the given coordinates relative to the
+// south-western corner of the region.
+func (terrain *Terrain) Tile(plane, x, y int) *Tile {
+	return &terrain.Tiles[plane][x][y]
+}
+
+// DecodeTerrain decodes the Terrain of the region of the given id from the given data of
+// the given revision. Tiles of the ground of which the height is left out are assigned
+// the height the client generates for them with noise. As caches of the extended
+// revision cannot be told apart from the caches of the revisions just before it, the
+// data is decoded in the other layout if it does not exactly fit the layout of the
+// revision. May return an error.
+func DecodeTerrain(regionId int, data []byte, revision int) (*Terrain, error) {
+	extended := revision == 0 || revision >= ExtendedRevision
+
+	terrain, remaining, err := decodeTerrain(regionId, data, extended)
+	if err == nil && remaining == 0 {
+		return terrain, nil
+	}
+
+	if other, remaining, otherErr := decodeTerrain(regionId, data, !extended); otherErr == nil && remaining == 0 {
+		return other, nil
+	}
+
+	return terrain, err
+}
+
+// decodeTerrain decodes the Terrain of the region of the given id from the given data,
+// of which the opcodes and overlays are encoded in two bytes if the terrain is extended.
+// Returns the terrain along with the amount of bytes that are left. May return an error.
+func decodeTerrain(regionId int, data []byte, extended bool) (*Terrain, int, error) {
+	itr := buffer.NewReader(data)
+
+	regionX, regionY := SplitRegionId(regionId)
+	baseX, baseY := regionX*Size, regionY*Size
+
+	terrain := &Terrain{}
+	for plane := 0; plane < Planes; plane++ {
+		for x := 0; x < Size; x++ {
+			for y := 0; y < Size; y++ {
+				tile := &terrain.Tiles[plane][x][y]
+				tile.OverlayId = -1
+				tile.UnderlayId = -1
+
+				below := 0
+				if plane > 0 {
+					below = terrain.Tiles[plane-1][x][y].Height
+				}
+
+				height, encoded, err := decodeTile(itr, tile, extended)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				switch {
+				case encoded:
+					tile.Height = below - height*heightScale
+				case plane == 0:
+					tile.Height = -generateHeight(baseX+x+932731, baseY+y+556238) * heightScale
+				default:
+					tile.Height = below - planeHeight
+				}
+			}
+		}
+	}
+
+	return terrain, itr.ReadableBytes(), nil
+}
+
+// decodeTile decodes the attributes of the given tile along with the height of the tile
+// relative to the plane below, if it is encoded. May return an error.
+func decodeTile(itr *buffer.Reader, tile *Tile, extended bool) (int, bool, error) {
+	for {
+		opcode, err := readOpcode(itr, extended)
+		if err != nil {
+			return 0, false, ErrMalformed
+		}
+
+		switch {
+		case opcode == 0:
+			return 0, false, nil
+
+		case opcode == 1:
+			height, err := itr.ReadByte()
+			if err != nil {
+				return 0, false, ErrMalformed
+			}
+
+			// a height of one is encoded in place of zero
+			if height == 1 {
+				height = 0
+			}
+
+			return int(height), true, nil
+
+		case opcode <= 49:
+			overlay, err := readOpcode(itr, extended)
+			if err != nil {
+				return 0, false, ErrMalformed
+			}
+
+			tile.OverlayId = overlay - 1
+			tile.OverlayShape = (opcode - 2) / 4
+			tile.OverlayRotation = (opcode - 2) & 3
+
+		case opcode <= 81:
+			tile.Settings = opcode - 49
+
+		default:
+			tile.UnderlayId = opcode - 81 - 1
+		}
+	}
+}
+
+// readOpcode reads a value of one byte, or of two bytes if the terrain is extended. May
+// return an error.
+func readOpcode(itr *buffer.Reader, extended bool) (int, error) {
+	if extended {
+		value, err := itr.ReadUInt16()
+		return int(value), err
+	}
+
+	value, err := itr.ReadByte()
+	return int(value), err
+}
+
+// cosine holds the cosine of each of the 2048 angles of the client, scaled by 65536.
+var cosine [2048]int
+
+func init() {
+	for angle := range cosine {
+		cosine[angle] = int(65536 * math.Cos(float64(angle)*(2*math.Pi/2048)))
+	}
+}
+
+// generateHeight generates the height of the tile at the given offset coordinates from
+// layers of noise, like the client does for tiles of which the height is left out.
+func generateHeight(x, y int) int {
+	height := interpolateNoise(x+45365, y+91923, 4) - 128 +
+		(interpolateNoise(x+10294, y+37821, 2)-128)>>1 +
+		(interpolateNoise(x, y, 1)-128)>>2
+
+	height = int(0.3*float64(height)) + 35
+	if height < 10 {
+		return 10
+	}
+
+	if height > 60 {
+		return 60
+	}
+
+	return height
+}
+
+// interpolateNoise smoothly interpolates the noise of the grid of the given frequency at
+// the given coordinates.
+func interpolateNoise(x, y, frequency int) int {
+	gridX, fractionX := x/frequency, x&(frequency-1)
+	gridY, fractionY := y/frequency, y&(frequency-1)
+
+	south := interpolate(smoothNoise(gridX, gridY), smoothNoise(gridX+1, gridY), fractionX, frequency)
+	north := interpolate(smoothNoise(gridX, gridY+1), smoothNoise(gridX+1, gridY+1), fractionX, frequency)
+
+	return interpolate(south, north, fractionY, frequency)
+}
+
+// interpolate interpolates between the given values by the cosine of the given fraction.
+func interpolate(a, b, fraction, frequency int) int {
+	weight := (65536 - cosine[fraction*1024/frequency]) >> 1
+	return (weight * b >> 16) + (a * (65536 - weight) >> 16)
+}
+
+// smoothNoise averages the noise at the given coordinates with the noise around it.
+func smoothNoise(x, y int) int {
+	corners := noise(x-1, y-1) + noise(x+1, y-1) + noise(x-1, y+1) + noise(x+1, y+1)
+	sides := noise(x-1, y) + noise(x+1, y) + noise(x, y-1) + noise(x, y+1)
+
+	return noise(x, y)/4 + sides/8 + corners/16
+}
+
+// noise generates a pseudo-random value in the range of 0-255 from the given coordinates,
+// overflowing in 32 bits like the client does.
+func noise(x, y int) int {
+	n := int32(x + y*57)
+	n ^= n << 13
+
+	return int((n*(n*n*15731+789221)+1376312589)&math.MaxInt32) >> 19 & 0xFF
+}
@@ -0,0 +1,197 @@
+package minimap
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/mapdata"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// The shapes of locations that the minimap draws, see mapdata.Location.
+const (
+	straightWallShape = 0
+	wallCornerShape   = 2
+	wallPillarShape   = 3
+	diagonalWallShape = 9
+	diagonalShape     = 11
+	groundDecorShape  = 22
+)
+
+// drawLocations draws the walls and the map scenes of the locations of the given region
+// that are visible on the given plane, with the north-western pixel of the region at the
+// given origin. May return an error.
+func (r *Renderer) drawLocations(img *image.RGBA, region *mapdata.Region, plane int, origin image.Point) error {
+	return r.visitLocations(region, plane, func(location *mapdata.Location, loc *config.LocType) error {
+		at := tileOrigin(origin, location.X, location.Y)
+
+		switch {
+		case location.Type <= wallPillarShape || location.Type == diagonalWallShape:
+			if loc.MapSceneId != -1 {
+				r.drawMapScene(img, loc, 1, 1, at)
+			} else {
+				drawWall(img, location.Type, location.Orientation, wallColor(loc), at)
+			}
+
+		case location.Type == config.CentrepieceShape || location.Type == diagonalShape:
+			// like in the client, the map scene is centred on the size of the loc without
+			// turning it by the orientation of the location
+			r.drawMapScene(img, loc, loc.SizeX, loc.SizeY, at.Sub(image.Pt(0, (loc.SizeY-1)*TileSize)))
+
+		case location.Type == groundDecorShape:
+			r.drawMapScene(img, loc, 1, 1, at)
+		}
+
+		return nil
+	})
+}
+
+// drawMapFunctions draws the icons of the map functions of the locations of the given
+// region that are visible on the given plane, centred on the tile of each location, with
+// the north-western pixel of the region at the given origin. May return an error.
+func (r *Renderer) drawMapFunctions(img *image.RGBA, region *mapdata.Region, plane int, origin image.Point) error {
+	if r.Sprites == nil {
+		return nil
+	}
+
+	return r.visitLocations(region, plane, func(location *mapdata.Location, loc *config.LocType) error {
+		if loc.MapAreaId < 0 || loc.MapAreaId >= len(r.Areas) || r.Areas[loc.MapAreaId] == nil {
+			return nil
+		}
+
+		area := r.Areas[loc.MapAreaId]
+		if area.SpriteId == -1 {
+			return nil
+		}
+
+		icon, err := r.Sprites(area.SpriteId)
+		if err != nil {
+			return err
+		}
+
+		if len(icon.Frames) == 0 {
+			return nil
+		}
+
+		center := tileOrigin(origin, location.X, location.Y).Add(image.Pt(TileSize/2, TileSize/2))
+		drawFrame(img, icon.Frames[0], center.Sub(image.Pt(icon.Width/2, icon.Height/2)))
+
+		return nil
+	})
+}
+
+// visitLocations calls the given function with each location of the given region that is
+// visible on the given plane, along with its loc type, until the function returns an
+// error. Returns config.ErrNotFound if a location refers to an unknown loc type.
+func (r *Renderer) visitLocations(region *mapdata.Region, plane int, visit func(*mapdata.Location, *config.LocType) error) error {
+	for _, location := range region.Locations {
+		if location.Id >= len(r.Locs) || r.Locs[location.Id] == nil {
+			return config.ErrNotFound
+		}
+
+		planes := visiblePlanes(region.Terrain, plane, location.X, location.Y)
+		if location.Plane != planes[0] && location.Plane != planes[1] {
+			continue
+		}
+
+		if err := visit(location, r.Locs[location.Id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drawMapScene draws the map scene of the given loc, centred on an area of the given size
+// in tiles of which the north-western pixel is at the given position.
+func (r *Renderer) drawMapScene(img *image.RGBA, loc *config.LocType, sizeX, sizeY int, at image.Point) {
+	if r.MapScenes == nil || loc.MapSceneId < 0 || loc.MapSceneId >= len(r.MapScenes.Frames) {
+		return
+	}
+
+	frame := r.MapScenes.Frames[loc.MapSceneId]
+	bounds := frame.Image.Bounds()
+
+	dx := (sizeX*TileSize - bounds.Dx()) / 2
+	dy := (sizeY*TileSize - bounds.Dy()) / 2
+
+	drawFrame(img, frame, at.Add(image.Pt(dx, dy)))
+}
+
+// drawFrame draws the given frame over the given image, at its offsets from the given
+// position.
+func drawFrame(img *image.RGBA, frame *sprite.Frame, at image.Point) {
+	min := at.Add(image.Pt(frame.OffsetX, frame.OffsetY))
+	bounds := image.Rectangle{Min: min, Max: min.Add(frame.Image.Bounds().Size())}
+
+	draw.Draw(img, bounds, frame.Image, frame.Image.Bounds().Min, draw.Over)
+}
+
+// drawWall draws a wall of the given shape and orientation in the given color onto the
+// tile of which the north-western pixel is at the given position. Straight walls and the
+// corners of walls are drawn along the sides of the tile, pillars as a single pixel in
+// its corner and diagonal walls as a line across it.
+func drawWall(img *image.RGBA, shape, orientation, color int, at image.Point) {
+	const last = TileSize - 1
+
+	// side draws the side of the tile that the given orientation faces, where the west
+	// side is faced by orientation 0 and the following orientations turn clockwise
+	side := func(orientation int) {
+		for i := 0; i < TileSize; i++ {
+			switch orientation & 3 {
+			case 0:
+				setPixel(img, at.X, at.Y+i, color)
+			case 1:
+				setPixel(img, at.X+i, at.Y, color)
+			case 2:
+				setPixel(img, at.X+last, at.Y+i, color)
+			default:
+				setPixel(img, at.X+i, at.Y+last, color)
+			}
+		}
+	}
+
+	switch shape {
+	case straightWallShape:
+		side(orientation)
+
+	case wallCornerShape:
+		side(orientation)
+		side(orientation + 1)
+
+	case wallPillarShape:
+		corners := [4]image.Point{{0, 0}, {last, 0}, {last, last}, {0, last}}
+		corner := at.Add(corners[orientation&3])
+		setPixel(img, corner.X, corner.Y, color)
+
+	case diagonalWallShape:
+		for i := 0; i < TileSize; i++ {
+			if orientation&1 == 0 {
+				setPixel(img, at.X+i, at.Y+last-i, color)
+			} else {
+				setPixel(img, at.X+i, at.Y+i, color)
+			}
+		}
+	}
+}
+
+// wallColor returns the color the given loc is drawn in as a wall, which is DoorColor if
+// the loc can be interacted with and WallColor otherwise.
+func wallColor(loc *config.LocType) int {
+	interactive := loc.WallOrDoor == 1
+	if loc.WallOrDoor == -1 {
+		for _, action := range loc.Actions {
+			if action != "" && !strings.EqualFold(action, "Hidden") {
+				interactive = true
+			}
+		}
+	}
+
+	if interactive {
+		return DoorColor
+	}
+
+	return WallColor
+}
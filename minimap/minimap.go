@@ -0,0 +1,289 @@
+// Package minimap draws overhead images of the map the way the client draws its minimap,
+// at four pixels per tile: the blended colors of the underlays and the overlays of the
+// terrain, the walls, the map scenes of locations and the icons of map functions.
+package minimap
+
+import (
+	"errors"
+	"image"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/hsl"
+	"github.com/sinoz/gokira/mapdata"
+	"github.com/sinoz/gokira/sprite"
+)
+
+const (
+	// TileSize is the width and the height of a tile, in pixels.
+	TileSize = 4
+
+	// RegionSize is the width and the height of a region, in pixels.
+	RegionSize = mapdata.Size * TileSize
+
+	// WallColor is the 24-bit RGB color of walls, and DoorColor that of the walls that
+	// can be interacted with, such as doors.
+	WallColor = 0xEEEEEE
+	DoorColor = 0xEE0000
+
+	// MapScenes is the name of the sprite group of which the frames are the map scenes
+	// that locations draw onto the minimap, such as trees and rocks.
+	MapScenes = "mapscene"
+)
+
+const (
+	// blendRadius is the distance in tiles over which underlays are blended with the
+	// underlays of the surrounding tiles.
+	blendRadius = 5
+
+	// transparent is the color of overlays that the client leaves undrawn.
+	transparent = 0xFF00FF
+
+	// minimapLightness is the lightness the colors of tiles are scaled to.
+	minimapLightness = 96
+
+	// hiddenFlag marks a tile that is left off the minimap, along with the tiles that
+	// are drawn as part of the plane below.
+	hiddenFlag = 16
+)
+
+// ErrPlane is returned when the requested plane does not exist.
+var ErrPlane = errors.New("plane is out of range")
+
+// Renderer draws the regions of the map with the config types that the terrain and the
+// locations refer to by id.
+type Renderer struct {
+	Underlays []*config.UnderlayType
+	Overlays  []*config.OverlayType
+	Textures  []*config.TextureType
+	Locs      []*config.LocType
+	Areas     []*config.AreaType
+
+	// MapScenes holds a frame for each map scene, or is nil to leave map scenes out.
+	MapScenes *sprite.Sprite
+
+	// Sprites loads the sprite groups of the icons of map functions, or is nil to
+	// leave map functions out.
+	Sprites func(id int) (*sprite.Sprite, error)
+
+	// Palette converts the 16-bit HSL colors of the terrain to RGB.
+	Palette *hsl.Palette
+}
+
+// NewRenderer constructs a Renderer of the config types and sprites of the given Cache,
+// decoding each sprite group once. May return an error.
+func NewRenderer(cache *gokira.Cache) (*Renderer, error) {
+	var r Renderer
+	var err error
+
+	if r.Underlays, err = config.GetUnderlayTypes(cache); err != nil {
+		return nil, err
+	}
+
+	if r.Overlays, err = config.GetOverlayTypes(cache); err != nil {
+		return nil, err
+	}
+
+	if r.Textures, err = config.GetTextureTypes(cache); err != nil {
+		return nil, err
+	}
+
+	if r.Locs, err = config.GetLocTypes(cache); err != nil {
+		return nil, err
+	}
+
+	if r.Areas, err = config.GetAreaTypes(cache); err != nil {
+		return nil, err
+	}
+
+	if r.MapScenes, err = sprite.LoadByName(cache, MapScenes); err != nil {
+		return nil, err
+	}
+
+	sprites := make(map[int]*sprite.Sprite)
+	r.Sprites = func(id int) (*sprite.Sprite, error) {
+		if s, ok := sprites[id]; ok {
+			return s, nil
+		}
+
+		s, err := sprite.Load(cache, id)
+		if err != nil {
+			return nil, err
+		}
+
+		sprites[id] = s
+		return s, nil
+	}
+
+	r.Palette = hsl.NewPalette(hsl.DefaultBrightness)
+	return &r, nil
+}
+
+// Generate decodes every region of which the XTEA keys are given from the given Cache and
+// draws the given plane of them onto a single image, see Renderer.Render. Regions of which
+// the terrain does not exist are skipped. May return an error.
+func Generate(cache *gokira.Cache, keys map[int][4]int, plane int) (*image.RGBA, error) {
+	r, err := NewRenderer(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []*mapdata.Region
+	for regionId, regionKeys := range keys {
+		region, err := mapdata.Load(cache, regionId, regionKeys)
+		if err == gokira.ErrLabelNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		regions = append(regions, region)
+	}
+
+	return r.Render(regions, plane)
+}
+
+// Bounds returns the rectangle of regions, in region coordinates, that the given regions
+// span. The maximum is exclusive.
+func Bounds(regions []*mapdata.Region) image.Rectangle {
+	var bounds image.Rectangle
+	for i, region := range regions {
+		x, y := mapdata.SplitRegionId(region.Id)
+		r := image.Rect(x, y, x+1, y+1)
+
+		if i == 0 {
+			bounds = r
+		} else {
+			bounds = bounds.Union(r)
+		}
+	}
+
+	return bounds
+}
+
+// RenderRegion draws the given plane of a single region onto an image of RegionSize by
+// RegionSize pixels. May return an error.
+func (r *Renderer) RenderRegion(region *mapdata.Region, plane int) (*image.RGBA, error) {
+	return r.Render([]*mapdata.Region{region}, plane)
+}
+
+// Render draws the given plane of the given regions onto a single image that spans the
+// Bounds of the regions, with north pointing up. The top left pixel is the north-western
+// corner of the north-western region and the pixels that are not covered by any region
+// are transparent. Underlays blend across the borders of neighbouring regions and map
+// functions may extend into them. May return an error.
+func (r *Renderer) Render(regions []*mapdata.Region, plane int) (*image.RGBA, error) {
+	if plane < 0 || plane >= mapdata.Planes {
+		return nil, ErrPlane
+	}
+
+	bounds := Bounds(regions)
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*RegionSize, bounds.Dy()*RegionSize))
+
+	world := newWorld(regions)
+	for _, region := range regions {
+		origin := regionOrigin(region.Id, bounds)
+		if err := r.drawTerrain(img, world, region, plane, origin); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, region := range regions {
+		origin := regionOrigin(region.Id, bounds)
+		if err := r.drawLocations(img, region, plane, origin); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, region := range regions {
+		origin := regionOrigin(region.Id, bounds)
+		if err := r.drawMapFunctions(img, region, plane, origin); err != nil {
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
+
+// regionOrigin returns the position of the north-western pixel of the region of the given
+// id within an image that spans the given bounds.
+func regionOrigin(regionId int, bounds image.Rectangle) image.Point {
+	x, y := mapdata.SplitRegionId(regionId)
+	return image.Pt((x-bounds.Min.X)*RegionSize, (bounds.Max.Y-1-y)*RegionSize)
+}
+
+// tileOrigin returns the position of the north-western pixel of the tile at the given
+// coordinates relative to the region of which the north-western pixel is at the given
+// origin.
+func tileOrigin(origin image.Point, x, y int) image.Point {
+	return origin.Add(image.Pt(x*TileSize, (mapdata.Size-1-y)*TileSize))
+}
+
+// scenePlane returns the plane that the client draws the tile at the given coordinates
+// and plane of the given terrain on, which is the plane below if the tile lies below a
+// bridge, or -1 if the tile is part of the ground below a bridge.
+func scenePlane(terrain *mapdata.Terrain, plane, x, y int) int {
+	if terrain.Tiles[1][x][y].Settings&mapdata.BridgeFlag != 0 {
+		return plane - 1
+	}
+
+	return plane
+}
+
+// terrainPlane returns the plane of the terrain that the client draws on the given plane
+// at the given coordinates, the inverse of scenePlane, or -1 if there is none.
+func terrainPlane(terrain *mapdata.Terrain, plane, x, y int) int {
+	if terrain.Tiles[1][x][y].Settings&mapdata.BridgeFlag != 0 {
+		plane++
+	}
+
+	if plane >= mapdata.Planes {
+		return -1
+	}
+
+	return plane
+}
+
+// visiblePlanes returns the planes of the terrain of which the tile at the given
+// coordinates is drawn on the minimap of the given plane, like the client does: the tile
+// of the plane itself unless it is hidden, followed by the tile of the plane above if
+// that tile is drawn as part of the plane below. Planes that do not exist are -1.
+func visiblePlanes(terrain *mapdata.Terrain, plane, x, y int) [2]int {
+	planes := [2]int{-1, -1}
+
+	if level := terrainPlane(terrain, plane, x, y); level >= 0 {
+		if terrain.Tiles[level][x][y].Settings&(mapdata.GroundFlag|hiddenFlag) == 0 {
+			planes[0] = level
+		}
+	}
+
+	if plane+1 < mapdata.Planes {
+		if level := terrainPlane(terrain, plane+1, x, y); level >= 0 {
+			if terrain.Tiles[level][x][y].Settings&mapdata.GroundFlag != 0 {
+				planes[1] = level
+			}
+		}
+	}
+
+	return planes
+}
+
+// minInt returns the smallest of the given values.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the largest of the given values.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
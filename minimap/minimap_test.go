@@ -0,0 +1,253 @@
+package minimap
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/hsl"
+	"github.com/sinoz/gokira/internal/cachetest"
+	"github.com/sinoz/gokira/mapdata"
+	"github.com/sinoz/gokira/sprite"
+)
+
+// testSprite encodes a sprite of a single frame of the given size and color.
+func testSprite(t *testing.T, width, height int, c color.Color) []byte {
+	frame := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+
+	data, err := sprite.EncodeImages(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// testLocation is a location of the test region.
+type testLocation struct {
+	plane, x, y, shape, orientation int
+}
+
+// loadTestCache builds the fixture cache that holds region 50, 50 of which the ground
+// is green, with a blue square overlay and a blue triangle overlay, along with a door,
+// a loc with a map scene and a loc with the icon of a map area.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	terrain := buffer.NewWriter()
+	for plane := 0; plane < mapdata.Planes; plane++ {
+		for x := 0; x < mapdata.Size; x++ {
+			for y := 0; y < mapdata.Size; y++ {
+				switch {
+				case plane == 0 && x == 10 && y == 10:
+					terrain.WriteInt16(2).WriteInt16(1)
+				case plane == 0 && x == 20 && y == 20:
+					terrain.WriteInt16(2 + 1*4).WriteInt16(1)
+				}
+
+				if plane == 0 {
+					terrain.WriteInt16(82)
+				}
+
+				terrain.WriteInt16(0)
+			}
+		}
+	}
+
+	// a loc of each loc type
+	groups := [][]testLocation{
+		{{0, 5, 5, 0, 0}},
+		{{0, 30, 30, 10, 0}},
+		{{0, 40, 40, 10, 0}},
+	}
+
+	locations := buffer.NewWriter()
+	for _, group := range groups {
+		locations.WriteSmart(1)
+
+		previous := 0
+		for _, l := range group {
+			position := l.plane<<12 | l.x<<6 | l.y
+			locations.WriteSmart(position - previous + 1)
+			locations.WriteInt8(l.shape<<2 | l.orientation)
+			previous = position
+		}
+
+		locations.WriteSmart(0)
+	}
+
+	locations.WriteSmart(0)
+
+	builder := cachetest.New()
+	builder.AddFile(2, 1, []byte{1, 0x40, 0xA0, 0x40, 0})
+	builder.AddFile(2, 4, []byte{1, 0x00, 0x00, 0xFF, 0})
+	builder.Add(2, 6, map[int][]byte{
+		0: buffer.NewWriter().WriteInt8(30).WriteCString("Open").WriteInt8(0).Bytes(),
+		1: {68, 0, 0, 0},
+		2: {82, 0, 0, 0},
+	})
+
+	builder.AddFile(2, 35, []byte{1, 0, 1, 0})
+
+	terrainFolder := builder.AddFile(mapdata.Archive, 0, terrain.Bytes()).Named("m50_50")
+	terrainFolder.Compressed = true
+
+	builder.AddFile(mapdata.Archive, 1, locations.Bytes()).Named("l50_50")
+
+	builder.AddFile(8, 0, testSprite(t, 2, 2, color.NRGBA{G: 0xFF, A: 0xFF})).Named("mapscene")
+	builder.AddFile(8, 1, testSprite(t, 3, 3, color.NRGBA{R: 0xFF, G: 0xFF, A: 0xFF}))
+
+	builder.AddFile(9, 0, []byte{0x12, 0x34, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0})
+
+	return builder.Build(t)
+}
+
+func TestGenerate(t *testing.T) {
+	cache := loadTestCache(t)
+
+	img, err := Generate(cache, map[int][4]int{mapdata.RegionId(50, 50): {}, mapdata.RegionId(1, 1): {}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != RegionSize || img.Bounds().Dy() != RegionSize {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+
+	r, err := NewRenderer(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	underlay := r.Underlays[0]
+	ground := r.Palette.ToRGB(adjustLightness(hsl.Pack(underlay.Hue*256/underlay.HueMultiplier, underlay.Saturation, underlay.Lightness)))
+	water := r.overlayColor(r.Overlays[0])
+
+	expected := map[[2]int]int{
+		{0, 0}:     ground,
+		{40, 212}:  water,
+		{43, 215}:  water,
+		{80, 172}:  water,
+		{81, 172}:  ground,
+		{20, 232}:  DoorColor,
+		{20, 235}:  DoorColor,
+		{21, 233}:  ground,
+		{121, 133}: 0x00FF00,
+		{122, 134}: 0x00FF00,
+		{120, 133}: ground,
+		{161, 93}:  0xFFFF00,
+		{163, 95}:  0xFFFF00,
+		{164, 95}:  ground,
+	}
+
+	for point, color := range expected {
+		pixel := img.RGBAAt(point[0], point[1])
+		if actual := int(pixel.R)<<16 | int(pixel.G)<<8 | int(pixel.B); actual != color || pixel.A != 0xFF {
+			t.Errorf("expected pixel %v to be %06X but got %06X", point, color, actual)
+		}
+	}
+
+	if _, err := Generate(cache, nil, mapdata.Planes); err != ErrPlane {
+		t.Errorf("expected %v but got %v", ErrPlane, err)
+	}
+}
+
+func TestRenderStitchesRegions(t *testing.T) {
+	r := &Renderer{Palette: hsl.NewPalette(hsl.DefaultBrightness)}
+	for id, data := range [][]byte{{1, 0xFF, 0x00, 0x00, 0}, {1, 0x00, 0x00, 0xFF, 0}} {
+		underlay, err := config.DecodeUnderlayType(id, data, config.LatestRevision)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.Underlays = append(r.Underlays, underlay)
+	}
+
+	west := &mapdata.Region{Id: mapdata.RegionId(10, 20), Terrain: new(mapdata.Terrain)}
+	east := &mapdata.Region{Id: mapdata.RegionId(11, 20), Terrain: new(mapdata.Terrain)}
+
+	for i, region := range []*mapdata.Region{west, east} {
+		for plane := 0; plane < mapdata.Planes; plane++ {
+			for x := 0; x < mapdata.Size; x++ {
+				for y := 0; y < mapdata.Size; y++ {
+					region.Terrain.Tiles[plane][x][y] = mapdata.Tile{OverlayId: -1, UnderlayId: -1}
+					if plane == 0 {
+						region.Terrain.Tiles[plane][x][y].UnderlayId = i
+					}
+				}
+			}
+		}
+	}
+
+	img, err := r.Render([]*mapdata.Region{east, west}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 2*RegionSize || img.Bounds().Dy() != RegionSize {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+
+	red, blue := img.RGBAAt(0, 0), img.RGBAAt(2*RegionSize-1, 0)
+	if red.R <= red.B || blue.B <= blue.R {
+		t.Errorf("expected the western region to be red and the eastern region blue but got %v and %v", red, blue)
+	}
+
+	// the underlays blend across the border of the regions
+	border := img.RGBAAt(RegionSize-1, 0)
+	if border == red || border == blue {
+		t.Errorf("expected the underlays to blend at the border but got %v", border)
+	}
+
+	// the plane above lacks any underlay
+	above, err := r.Render([]*mapdata.Region{west}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pixel := above.RGBAAt(0, 0); pixel.A != 0 {
+		t.Errorf("expected a transparent pixel but got %v", pixel)
+	}
+}
+
+func TestWriteTiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minimap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	img, err := Generate(loadTestCache(t), map[int][4]int{mapdata.RegionId(50, 50): {}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteTiles(dir, img, 128, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"0/0_0.png", "0/1_0.png", "0/0_1.png", "0/1_1.png", "1/0_0.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "1/1_0.png")); !os.IsNotExist(err) {
+		t.Errorf("expected no tile beyond the halved image but got %v", err)
+	}
+
+	if half := halve(img); half.Bounds().Dx() != RegionSize/2 {
+		t.Errorf("unexpected bounds %v", half.Bounds())
+	}
+
+	if err := WriteTiles(dir, img, 0, 1); err != ErrTileSize {
+		t.Errorf("expected %v but got %v", ErrTileSize, err)
+	}
+}
@@ -0,0 +1,246 @@
+package minimap
+
+import (
+	"image"
+
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/hsl"
+	"github.com/sinoz/gokira/mapdata"
+)
+
+// tileShapes holds for each shape of a tile which of its 16 pixels, from the top left to
+// the bottom right, are covered by the overlay. The shape of an overlay is offset by one,
+// as the first shape is that of a tile without an overlay.
+var tileShapes = [...][16]int{
+	{},
+	{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	{1, 0, 0, 0, 1, 1, 0, 0, 1, 1, 1, 0, 1, 1, 1, 1},
+	{1, 1, 0, 0, 1, 1, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0},
+	{0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 0, 1, 0, 0, 0, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	{1, 1, 1, 0, 1, 1, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 1, 0, 0},
+	{1, 1, 1, 1, 1, 1, 1, 1, 0, 1, 1, 1, 0, 0, 1, 1},
+	{1, 1, 1, 1, 1, 1, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0},
+	{0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 1, 1, 0, 1, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 0, 1, 1, 1},
+	{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 1, 1, 0, 0},
+}
+
+// tileRotations maps each pixel of a tile to the pixel of its shape for each rotation.
+var tileRotations = [4][16]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{12, 8, 4, 0, 13, 9, 5, 1, 14, 10, 6, 2, 15, 11, 7, 3},
+	{15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	{3, 7, 11, 15, 2, 6, 10, 14, 1, 5, 9, 13, 0, 4, 8, 12},
+}
+
+// world looks up the tiles of a set of regions by their absolute coordinates.
+type world map[int]*mapdata.Region
+
+// newWorld constructs a world of the given regions.
+func newWorld(regions []*mapdata.Region) world {
+	w := make(world, len(regions))
+	for _, region := range regions {
+		w[region.Id] = region
+	}
+
+	return w
+}
+
+// tile returns the terrain of the tile at the given absolute coordinates, or nil if its
+// region is not part of the world.
+func (w world) tile(plane, x, y int) *mapdata.Tile {
+	if x < 0 || y < 0 {
+		return nil
+	}
+
+	region, ok := w[mapdata.RegionOf(x, y)]
+	if !ok {
+		return nil
+	}
+
+	return region.Terrain.Tile(plane, x%mapdata.Size, y%mapdata.Size)
+}
+
+// drawTerrain draws the tiles of the given region that are visible on the given plane,
+// with the north-western pixel of the region at the given origin.
+func (r *Renderer) drawTerrain(img *image.RGBA, w world, region *mapdata.Region, plane int, origin image.Point) error {
+	var underlays [mapdata.Planes]*[mapdata.Size][mapdata.Size]int
+
+	for x := 0; x < mapdata.Size; x++ {
+		for y := 0; y < mapdata.Size; y++ {
+			for _, level := range visiblePlanes(region.Terrain, plane, x, y) {
+				if level < 0 {
+					continue
+				}
+
+				if underlays[level] == nil {
+					underlays[level] = r.blendUnderlays(w, region.Id, level)
+				}
+
+				if err := r.drawTile(img, region.Terrain.Tile(level, x, y), underlays[level][x][y], tileOrigin(origin, x, y)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// blendUnderlays returns the RGB color of the underlay of each tile of the given plane of
+// the region of the given id, or 0 for the tiles without an underlay. Like in the client,
+// the color of an underlay is the average of the underlays within the blendRadius of the
+// tile, including those of the neighbouring regions that are part of the world.
+func (r *Renderer) blendUnderlays(w world, regionId, plane int) *[mapdata.Size][mapdata.Size]int {
+	const window = mapdata.Size + 2*blendRadius
+
+	// the sums of the components of the underlays of the tiles within the window to the
+	// south-west of and including each tile, from which the sums of any rectangle follow
+	var hues, multipliers, saturations, lightnesses, counts [window + 1][window + 1]int
+
+	regionX, regionY := mapdata.SplitRegionId(regionId)
+	baseX, baseY := regionX*mapdata.Size-blendRadius, regionY*mapdata.Size-blendRadius
+
+	for x := 0; x < window; x++ {
+		for y := 0; y < window; y++ {
+			var hue, multiplier, saturation, lightness, count int
+			if underlay := r.underlay(w.tile(plane, baseX+x, baseY+y)); underlay != nil {
+				hue, multiplier = underlay.Hue, underlay.HueMultiplier
+				saturation, lightness, count = underlay.Saturation, underlay.Lightness, 1
+			}
+
+			hues[x+1][y+1] = hue + hues[x][y+1] + hues[x+1][y] - hues[x][y]
+			multipliers[x+1][y+1] = multiplier + multipliers[x][y+1] + multipliers[x+1][y] - multipliers[x][y]
+			saturations[x+1][y+1] = saturation + saturations[x][y+1] + saturations[x+1][y] - saturations[x][y]
+			lightnesses[x+1][y+1] = lightness + lightnesses[x][y+1] + lightnesses[x+1][y] - lightnesses[x][y]
+			counts[x+1][y+1] = count + counts[x][y+1] + counts[x+1][y] - counts[x][y]
+		}
+	}
+
+	sum := func(sums *[window + 1][window + 1]int, x, y int) int {
+		maxX, maxY := x+2*blendRadius+1, y+2*blendRadius+1
+		return sums[maxX][maxY] - sums[x][maxY] - sums[maxX][y] + sums[x][y]
+	}
+
+	region := w[regionId]
+
+	var colors [mapdata.Size][mapdata.Size]int
+	for x := 0; x < mapdata.Size; x++ {
+		for y := 0; y < mapdata.Size; y++ {
+			if r.underlay(region.Terrain.Tile(plane, x, y)) == nil {
+				continue
+			}
+
+			count := sum(&counts, x, y)
+			hue := sum(&hues, x, y) * 256 / sum(&multipliers, x, y)
+			color := hsl.Pack(hue, sum(&saturations, x, y)/count, sum(&lightnesses, x, y)/count)
+
+			colors[x][y] = r.Palette.ToRGB(adjustLightness(color))
+		}
+	}
+
+	return &colors
+}
+
+// underlay returns the UnderlayType of the given tile, or nil if the tile does not exist
+// or lacks an underlay.
+func (r *Renderer) underlay(tile *mapdata.Tile) *config.UnderlayType {
+	if tile == nil || tile.UnderlayId < 0 || tile.UnderlayId >= len(r.Underlays) {
+		return nil
+	}
+
+	return r.Underlays[tile.UnderlayId]
+}
+
+// overlayColor returns the RGB color the client draws the given overlay with on the
+// minimap, which is its secondary color if it has one and the average color of its
+// texture if it is textured, or 0 if the overlay is transparent.
+func (r *Renderer) overlayColor(overlay *config.OverlayType) int {
+	var color int
+
+	switch {
+	case overlay.SecondaryColor != -1:
+		color = hsl.Pack(overlay.SecondaryHue, overlay.SecondarySaturation, overlay.SecondaryLightness)
+	case overlay.Texture >= 0 && overlay.Texture < len(r.Textures) && r.Textures[overlay.Texture] != nil:
+		color = r.Textures[overlay.Texture].AverageColor
+	case overlay.Color == transparent:
+		return 0
+	default:
+		color = hsl.Pack(overlay.Hue, overlay.Saturation, overlay.Lightness)
+	}
+
+	return r.Palette.ToRGB(adjustLightness(color))
+}
+
+// drawTile draws the given tile with the given color of its underlay, with its
+// north-western pixel at the given position. Pixels of which the color is 0 are left
+// undrawn, like in the client.
+func (r *Renderer) drawTile(img *image.RGBA, tile *mapdata.Tile, underlay int, at image.Point) error {
+	if tile.OverlayId < 0 {
+		fillTile(img, at, underlay)
+		return nil
+	}
+
+	if tile.OverlayId >= len(r.Overlays) || r.Overlays[tile.OverlayId] == nil {
+		return config.ErrNotFound
+	}
+
+	overlay := r.overlayColor(r.Overlays[tile.OverlayId])
+
+	shape := tile.OverlayShape + 1
+	if shape >= len(tileShapes) {
+		return mapdata.ErrMalformed
+	}
+
+	if shape == 1 {
+		fillTile(img, at, overlay)
+		return nil
+	}
+
+	mask, rotation := &tileShapes[shape], &tileRotations[tile.OverlayRotation&3]
+	for i := 0; i < TileSize*TileSize; i++ {
+		color := underlay
+		if mask[rotation[i]] != 0 {
+			color = overlay
+		}
+
+		setPixel(img, at.X+i%TileSize, at.Y+i/TileSize, color)
+	}
+
+	return nil
+}
+
+// adjustLightness scales the lightness of the given 16-bit HSL color to that of the
+// minimap, keeping it within the range the client allows.
+func adjustLightness(color int) int {
+	lightness := (color & 0x7F) * minimapLightness >> 7
+	lightness = maxInt(2, minInt(lightness, 126))
+
+	return color&0xFF80 | lightness
+}
+
+// fillTile fills the pixels of a tile of which the north-western pixel is at the given
+// position with the given color, unless the color is 0.
+func fillTile(img *image.RGBA, at image.Point, color int) {
+	for y := 0; y < TileSize; y++ {
+		for x := 0; x < TileSize; x++ {
+			setPixel(img, at.X+x, at.Y+y, color)
+		}
+	}
+}
+
+// setPixel sets the pixel at the given position to the given opaque color, unless the
+// color is 0 or the position lies outside the bounds of the image.
+func setPixel(img *image.RGBA, x, y, color int) {
+	if color == 0 || !image.Pt(x, y).In(img.Bounds()) {
+		return
+	}
+
+	i := img.PixOffset(x, y)
+	img.Pix[i] = uint8(color >> 16)
+	img.Pix[i+1] = uint8(color >> 8)
+	img.Pix[i+2] = uint8(color)
+	img.Pix[i+3] = 0xFF
+}
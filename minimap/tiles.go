@@ -0,0 +1,118 @@
+package minimap
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// ErrTileSize is returned when the size of the tiles of a zoom level is not positive.
+var ErrTileSize = errors.New("tile size must be positive")
+
+// WriteTiles splits the given image into square tiles of the given size in pixels and
+// writes each of them as a PNG image named "<level>/<x>_<y>.png" within the given
+// directory, for the given amount of zoom levels. Level 0 holds the image at its full
+// resolution and every following level halves it. Tiles that are entirely transparent
+// are not written. May return an error.
+func WriteTiles(dir string, img *image.RGBA, size, levels int) error {
+	if size <= 0 {
+		return ErrTileSize
+	}
+
+	for level := 0; level < levels; level++ {
+		if level > 0 {
+			img = halve(img)
+		}
+
+		levelDir := filepath.Join(dir, fmt.Sprint(level))
+		if err := os.MkdirAll(levelDir, 0755); err != nil {
+			return err
+		}
+
+		bounds := img.Bounds()
+		for y := 0; y*size < bounds.Dy(); y++ {
+			for x := 0; x*size < bounds.Dx(); x++ {
+				tile := img.SubImage(image.Rect(x*size, y*size, (x+1)*size, (y+1)*size).Add(bounds.Min)).(*image.RGBA)
+				if isTransparent(tile) {
+					continue
+				}
+
+				if err := writePNG(filepath.Join(levelDir, fmt.Sprintf("%d_%d.png", x, y)), tile); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// writePNG writes the given image as a PNG image to a file at the given path. May return
+// an error.
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// halve scales the given image down to half of its size, averaging each square of four
+// pixels into one.
+func halve(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	half := image.NewRGBA(image.Rect(0, 0, (bounds.Dx()+1)/2, (bounds.Dy()+1)/2))
+
+	for y := 0; y < half.Rect.Dy(); y++ {
+		for x := 0; x < half.Rect.Dx(); x++ {
+			var sums [4]int
+			var count int
+
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					p := image.Pt(bounds.Min.X+2*x+dx, bounds.Min.Y+2*y+dy)
+					if !p.In(bounds) {
+						continue
+					}
+
+					i := img.PixOffset(p.X, p.Y)
+					for channel := range sums {
+						sums[channel] += int(img.Pix[i+channel])
+					}
+
+					count++
+				}
+			}
+
+			i := half.PixOffset(x, y)
+			for channel, sum := range sums {
+				half.Pix[i+channel] = uint8(sum / count)
+			}
+		}
+	}
+
+	return half
+}
+
+// isTransparent returns whether each pixel of the given image is fully transparent.
+func isTransparent(img *image.RGBA) bool {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
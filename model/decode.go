@@ -0,0 +1,133 @@
+package model
+
+import "github.com/sinoz/gokira/buffer"
+
+// newCursor constructs a cursor that starts reading the given data at the given position.
+func newCursor(data []byte, position int) *buffer.Cursor {
+	c := buffer.NewCursor(data)
+	c.Seek(position)
+
+	return c
+}
+
+// newModel constructs a Model with room for the given amount of vertices, faces and
+// texture triangles.
+func newModel(vertexCount, faceCount, textureTriangleCount int) *Model {
+	return &Model{
+		VertexCount:          vertexCount,
+		VertexX:              make([]int, vertexCount),
+		VertexY:              make([]int, vertexCount),
+		VertexZ:              make([]int, vertexCount),
+		FaceCount:            faceCount,
+		FaceA:                make([]int, faceCount),
+		FaceB:                make([]int, faceCount),
+		FaceC:                make([]int, faceCount),
+		FaceColors:           make([]int, faceCount),
+		TextureTriangleCount: textureTriangleCount,
+		TextureRenderTypes:   make([]int, textureTriangleCount),
+		TextureP:             make([]int, textureTriangleCount),
+		TextureM:             make([]int, textureTriangleCount),
+		TextureN:             make([]int, textureTriangleCount),
+	}
+}
+
+// readVertices reads the positions of the vertices. Each position is delta encoded
+// against the previous vertex, where the flags of a vertex tell which of the axes
+// have a delta.
+func readVertices(model *Model, flags, x, y, z *buffer.Cursor) {
+	var vertexX, vertexY, vertexZ int
+
+	for i := 0; i < model.VertexCount; i++ {
+		mask := flags.UInt8()
+
+		if mask&1 != 0 {
+			vertexX += x.SignedSmart()
+		}
+
+		if mask&2 != 0 {
+			vertexY += y.SignedSmart()
+		}
+
+		if mask&4 != 0 {
+			vertexZ += z.SignedSmart()
+		}
+
+		model.VertexX[i] = vertexX
+		model.VertexY[i] = vertexY
+		model.VertexZ[i] = vertexZ
+	}
+}
+
+// readFaceIndices reads the indices of the vertices of each face. The type of each face
+// tells which of the indices of the previous face are reused, and every index that is
+// read is delta encoded against the previously read index.
+func readFaceIndices(model *Model, types, indices *buffer.Cursor) {
+	var a, b, c, last int
+
+	for i := 0; i < model.FaceCount; i++ {
+		switch types.UInt8() {
+		case 1:
+			a = indices.SignedSmart() + last
+			b = indices.SignedSmart() + a
+			c = indices.SignedSmart() + b
+			last = c
+
+		case 2:
+			b = c
+			c = indices.SignedSmart() + last
+			last = c
+
+		case 3:
+			a = c
+			c = indices.SignedSmart() + last
+			last = c
+
+		case 4:
+			a, b = b, a
+			c = indices.SignedSmart() + last
+			last = c
+		}
+
+		model.FaceA[i] = a
+		model.FaceB[i] = b
+		model.FaceC[i] = c
+	}
+}
+
+// readSkins reads a label for each of the given amount of vertices or faces.
+func readSkins(c *buffer.Cursor, count int) []int {
+	skins := make([]int, count)
+	for i := range skins {
+		skins[i] = c.UInt8()
+	}
+
+	return skins
+}
+
+// readAnimaya reads the bones each vertex is rigged to, along with their weights.
+func readAnimaya(model *Model, c *buffer.Cursor) {
+	model.AnimayaGroups = make([][]int, model.VertexCount)
+	model.AnimayaScales = make([][]int, model.VertexCount)
+
+	for i := 0; i < model.VertexCount; i++ {
+		count := c.UInt8()
+
+		model.AnimayaGroups[i] = make([]int, count)
+		model.AnimayaScales[i] = make([]int, count)
+
+		for j := 0; j < count; j++ {
+			model.AnimayaGroups[i][j] = c.UInt8()
+			model.AnimayaScales[i][j] = c.UInt8()
+		}
+	}
+}
+
+// filled returns a slice of the given length of which each element is the given value.
+func filled(length, value int) []int {
+	values := make([]int, length)
+	for i := range values {
+		values[i] = value
+	}
+
+	return values
+}
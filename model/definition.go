@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/config"
+)
+
+// ErrNoModel is returned when a definition has no model to load.
+var ErrNoModel = errors.New("definition has no model")
+
+// LoadItem loads the model that represents the given item on the floor and in
+// inventories, with the recolors, retextures and resizing of the item applied.
+// May return an error.
+func LoadItem(cache *gokira.Cache, item *config.ItemType) (*Model, error) {
+	model, err := Load(cache, item.InventoryModel)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ResizeX != 128 || item.ResizeY != 128 || item.ResizeZ != 128 {
+		model.Scale(item.ResizeX, item.ResizeY, item.ResizeZ)
+	}
+
+	model.Recolor(item.RecolorFrom, item.RecolorTo)
+	model.Retexture(item.RetextureFrom, item.RetextureTo)
+
+	return model, nil
+}
+
+// LoadNpc loads and merges the models of the given npc, with the recolors, retextures
+// and scaling of the npc applied. May return an error.
+func LoadNpc(cache *gokira.Cache, npc *config.NpcType) (*Model, error) {
+	model, err := loadMerged(cache, npc.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	if npc.WidthScale != 128 || npc.HeightScale != 128 {
+		model.Scale(npc.WidthScale, npc.HeightScale, npc.WidthScale)
+	}
+
+	model.Recolor(npc.RecolorFrom, npc.RecolorTo)
+	model.Retexture(npc.RetextureFrom, npc.RetextureTo)
+
+	return model, nil
+}
+
+// LoadLoc loads the model of the given shape of the given loc, with the recolors,
+// retextures, scaling and offsets of the loc applied. Locs of which the models are
+// not assigned to shapes only have a model of the centrepiece shape. May return an error.
+func LoadLoc(cache *gokira.Cache, loc *config.LocType, shape int) (*Model, error) {
+	var ids []int
+
+	if loc.ModelTypes == nil {
+		if shape != config.CentrepieceShape {
+			return nil, ErrNoModel
+		}
+
+		ids = loc.Models
+	} else {
+		for i, modelType := range loc.ModelTypes {
+			if modelType == shape && i < len(loc.Models) {
+				ids = []int{loc.Models[i]}
+				break
+			}
+		}
+	}
+
+	model, err := loadMerged(cache, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if loc.IsRotated {
+		model.Mirror()
+	}
+
+	if loc.ModelSizeX != 128 || loc.ModelSizeHeight != 128 || loc.ModelSizeY != 128 {
+		model.Scale(loc.ModelSizeX, loc.ModelSizeHeight, loc.ModelSizeY)
+	}
+
+	if loc.OffsetX != 0 || loc.OffsetHeight != 0 || loc.OffsetY != 0 {
+		model.Translate(loc.OffsetX, loc.OffsetHeight, loc.OffsetY)
+	}
+
+	model.Recolor(loc.RecolorFrom, loc.RecolorTo)
+	model.Retexture(loc.RetextureFrom, loc.RetextureTo)
+
+	return model, nil
+}
+
+// loadMerged loads each of the models of the given ids and merges them into a single
+// model. May return an error.
+func loadMerged(cache *gokira.Cache, ids []int) (*Model, error) {
+	if len(ids) == 0 {
+		return nil, ErrNoModel
+	}
+
+	models := make([]*Model, len(ids))
+	for i, id := range ids {
+		model, err := Load(cache, id)
+		if err != nil {
+			return nil, err
+		}
+
+		models[i] = model
+	}
+
+	if len(models) == 1 {
+		return models[0], nil
+	}
+
+	return Merge(models...), nil
+}
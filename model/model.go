@@ -0,0 +1,149 @@
+// Package model decodes the 3D models that are stored within the model archive of the
+// cache, in each of the encodings the client supports.
+package model
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+)
+
+// Archive is the archive that holds a folder for each model.
+const Archive = 7
+
+// ErrTruncated is returned when the sections of a model exceed its data.
+var ErrTruncated = errors.New("model data is truncated")
+
+// Model is a mesh of triangular faces. Each face is colored with a 16-bit HSL color or
+// mapped to a texture. Optional attributes are nil when the model does not encode them.
+type Model struct {
+	Id int
+
+	VertexCount int
+	VertexX     []int
+	VertexY     []int
+	VertexZ     []int
+
+	// FaceCount is the amount of faces, of which FaceA, FaceB and FaceC hold the
+	// indices of the vertices.
+	FaceCount int
+	FaceA     []int
+	FaceB     []int
+	FaceC     []int
+
+	// FaceColors are the 16-bit HSL colors of each face.
+	FaceColors []int
+
+	// FaceRenderTypes tell how each face is shaded: 0 for gouraud shading, 1 for flat
+	// shading, 2 to hide the face and 3 for a black face.
+	FaceRenderTypes []int
+
+	// Priority is the render priority of every face, unless each face has its own
+	// priority in FacePriorities.
+	Priority       int
+	FacePriorities []int
+
+	// FaceAlphas are the transparencies of each face, ranging from 0 (opaque) to 255.
+	FaceAlphas []int
+
+	// FaceTextures are the ids of the texture of each face, or -1. TextureCoords are
+	// the indices of the texture triangles that map the textures onto each face, or
+	// -1 to map the texture onto the face itself.
+	FaceTextures  []int
+	TextureCoords []int
+
+	// TextureRenderTypes are the projection types of each texture triangle, of
+	// which TextureP, TextureM and TextureN hold the indices of the vertices.
+	TextureTriangleCount int
+	TextureRenderTypes   []int
+	TextureP             []int
+	TextureM             []int
+	TextureN             []int
+
+	// VertexSkins and FaceSkins are the labels animations use to transform
+	// groups of vertices and the transparency of groups of faces.
+	VertexSkins []int
+	FaceSkins   []int
+
+	// AnimayaGroups and AnimayaScales are the bones each vertex is rigged to and
+	// the weights of each bone, as used by skeletal animations.
+	AnimayaGroups [][]int
+	AnimayaScales [][]int
+}
+
+// Load decodes the Model of the specified id from the given Cache. May return an error.
+func Load(cache *gokira.Cache, id int) (*Model, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(id, folder.Data)
+}
+
+// Decode decodes a Model of the given id from the given data, of which the encoding is
+// identified by its last two bytes. May return an error.
+func Decode(id int, data []byte) (*Model, error) {
+	if len(data) < 2 {
+		return nil, ErrTruncated
+	}
+
+	var model *Model
+	var err error
+
+	switch trailer := data[len(data)-2:]; {
+	case trailer[0] == 0xFF && trailer[1] == 0xFD:
+		model, err = decodeNewFormat(data, true)
+	case trailer[0] == 0xFF && trailer[1] == 0xFE:
+		model, err = decodeOldFormat(data, true)
+	case trailer[0] == 0xFF && trailer[1] == 0xFF:
+		model, err = decodeNewFormat(data, false)
+	default:
+		model, err = decodeOldFormat(data, false)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	model.Id = id
+	return model, nil
+}
+
+// VertexGroups returns the indices of the vertices of each vertex skin, indexed by label.
+func (model *Model) VertexGroups() [][]int {
+	return groupByLabel(model.VertexSkins)
+}
+
+// FaceGroups returns the indices of the faces of each face skin, indexed by label.
+func (model *Model) FaceGroups() [][]int {
+	return groupByLabel(model.FaceSkins)
+}
+
+// IsTextured returns whether any face of the model is mapped to a texture.
+func (model *Model) IsTextured() bool {
+	for _, texture := range model.FaceTextures {
+		if texture != -1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// groupByLabel returns the indices of the given labels, grouped by label.
+func groupByLabel(labels []int) [][]int {
+	var count int
+	for _, label := range labels {
+		if label >= count {
+			count = label + 1
+		}
+	}
+
+	groups := make([][]int, count)
+	for index, label := range labels {
+		groups[label] = append(groups[label], index)
+	}
+
+	return groups
+}
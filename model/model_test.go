@@ -0,0 +1,182 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// triangle encodes a model of a single triangle in the new format, of which the
+// vertices are optionally rigged to bones.
+func triangle(rigged bool) []byte {
+	w := buffer.NewWriter()
+
+	w.WriteBytes([]byte{0, 1, 7}) // vertex flags
+	w.WriteBytes([]byte{1})       // face types
+	w.WriteBytes([]byte{3})       // face skins
+	if rigged {
+		w.WriteBytes([]byte{1, 4, 255, 2, 0, 2, 5, 128, 1, 6, 127})
+	}
+
+	w.WriteBytes([]byte{0x40, 0x41, 0x41})       // face indices
+	w.WriteInt16(0x1234)                         // colors
+	w.WriteBytes([]byte{0x4A, 0x36, 0x2C, 0x45}) // x, y and z deltas
+
+	w.WriteInt16(3).WriteInt16(1).WriteInt8(0)
+	w.WriteBytes([]byte{0, 5, 0, 1, 0, 0})
+	if rigged {
+		w.WriteInt8(1)
+	}
+
+	w.WriteInt16(2).WriteInt16(1).WriteInt16(1).WriteInt16(3).WriteInt16(0)
+	if rigged {
+		w.WriteInt16(11).WriteInt8(0xFF).WriteInt8(0xFD)
+	} else {
+		w.WriteInt8(0xFF).WriteInt8(0xFF)
+	}
+
+	return w.Bytes()
+}
+
+func TestDecodeNewFormat(t *testing.T) {
+	model, err := Decode(1, triangle(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(model.VertexX, []int{0, 10, 0}) || !reflect.DeepEqual(model.VertexY, []int{0, 0, -20}) ||
+		!reflect.DeepEqual(model.VertexZ, []int{0, 0, 5}) {
+		t.Errorf("unexpected vertices %v %v %v", model.VertexX, model.VertexY, model.VertexZ)
+	}
+
+	if model.FaceA[0] != 0 || model.FaceB[0] != 1 || model.FaceC[0] != 2 {
+		t.Errorf("unexpected face %v %v %v", model.FaceA, model.FaceB, model.FaceC)
+	}
+
+	if model.FaceColors[0] != 0x1234 || model.Priority != 5 || model.FacePriorities != nil {
+		t.Errorf("unexpected face attributes %+v", model)
+	}
+
+	if !reflect.DeepEqual(model.FaceGroups(), [][]int{nil, nil, nil, {0}}) {
+		t.Errorf("unexpected face groups %v", model.FaceGroups())
+	}
+
+	if model.FaceTextures != nil || model.AnimayaGroups != nil {
+		t.Error("expected absent attributes to be nil")
+	}
+}
+
+func TestDecodeRiggedNewFormat(t *testing.T) {
+	model, err := Decode(1, triangle(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(model.AnimayaGroups, [][]int{{4}, {0, 5}, {6}}) ||
+		!reflect.DeepEqual(model.AnimayaScales, [][]int{{255}, {2, 128}, {127}}) {
+		t.Errorf("unexpected rigging %v %v", model.AnimayaGroups, model.AnimayaScales)
+	}
+
+	if model.VertexX[1] != 10 || model.FaceColors[0] != 0x1234 {
+		t.Errorf("unexpected model %+v", model)
+	}
+}
+
+func TestDecodeOldFormat(t *testing.T) {
+	w := buffer.NewWriter()
+
+	w.WriteBytes([]byte{0, 1, 7})                // vertex flags
+	w.WriteBytes([]byte{1, 3})                   // face types
+	w.WriteBytes([]byte{3, 2 | 1<<2})            // texture info
+	w.WriteBytes([]byte{1, 1, 0})                // vertex skins
+	w.WriteBytes([]byte{0x40, 0x41, 0x41, 0x41}) // face indices
+	w.WriteInt16(0x1234).WriteInt16(42)          // colors
+	w.WriteInt16(0).WriteInt16(1).WriteInt16(2)  // texture triangles
+	w.WriteInt16(2).WriteInt16(1).WriteInt16(0)
+	w.WriteBytes([]byte{0x4A, 0x36, 0x2C, 0x45}) // x, y and z deltas
+
+	w.WriteInt16(3).WriteInt16(2).WriteInt8(2)
+	w.WriteBytes([]byte{1, 0, 0, 0, 1})
+	w.WriteInt16(2).WriteInt16(1).WriteInt16(1).WriteInt16(4)
+
+	model, err := Decode(2, w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if model.FaceA[1] != 2 || model.FaceB[1] != 1 || model.FaceC[1] != 3 {
+		t.Errorf("unexpected second face %v %v %v", model.FaceA[1], model.FaceB[1], model.FaceC[1])
+	}
+
+	if !reflect.DeepEqual(model.FaceRenderTypes, []int{1, 0}) || !reflect.DeepEqual(model.FaceTextures, []int{0x1234, 42}) {
+		t.Errorf("unexpected texture info %v %v", model.FaceRenderTypes, model.FaceTextures)
+	}
+
+	if !reflect.DeepEqual(model.FaceColors, []int{127, 127}) || !reflect.DeepEqual(model.TextureCoords, []int{-1, 1}) {
+		t.Errorf("unexpected texture mapping %v %v", model.FaceColors, model.TextureCoords)
+	}
+
+	if !reflect.DeepEqual(model.VertexGroups(), [][]int{{2}, {0, 1}}) {
+		t.Errorf("unexpected vertex groups %v", model.VertexGroups())
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	data := triangle(false)
+	if _, err := Decode(1, append([]byte{}, data[4:]...)); err == nil {
+		t.Error("expected truncated model to be rejected")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first, err := Decode(1, triangle(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := first.Copy()
+	second.FaceTextures = []int{7}
+	second.TextureCoords = []int{-1}
+	second.Recolor([]int{0x1234}, []int{0x4321})
+
+	merged := Merge(first, second)
+	if merged.VertexCount != 6 || merged.FaceCount != 2 || merged.FaceA[1] != 3 || merged.FaceC[1] != 5 {
+		t.Fatalf("unexpected merged geometry %+v", merged)
+	}
+
+	if !reflect.DeepEqual(merged.FaceColors, []int{0x1234, 0x4321}) || !reflect.DeepEqual(merged.FaceTextures, []int{-1, 7}) {
+		t.Errorf("unexpected merged faces %v %v", merged.FaceColors, merged.FaceTextures)
+	}
+
+	if !reflect.DeepEqual(merged.FacePriorities, []int{5, 5}) || first.FaceColors[0] != 0x1234 {
+		t.Errorf("unexpected priorities %v", merged.FacePriorities)
+	}
+}
+
+func TestFaceUVs(t *testing.T) {
+	model := &Model{
+		VertexCount:   4,
+		VertexX:       []int{0, 64, 0, 32},
+		VertexY:       []int{0, 0, 64, 32},
+		VertexZ:       []int{0, 0, 0, 0},
+		FaceCount:     2,
+		FaceA:         []int{0, 3},
+		FaceB:         []int{1, 1},
+		FaceC:         []int{2, 2},
+		FaceTextures:  []int{-1, 5},
+		TextureCoords: []int{-1, 0},
+	}
+
+	if _, _, ok := model.FaceUVs(0); ok {
+		t.Error("expected an untextured face to have no texture coordinates")
+	}
+
+	model.TextureTriangleCount = 1
+	model.TextureP, model.TextureM, model.TextureN = []int{0}, []int{1}, []int{2}
+
+	u, v, ok := model.FaceUVs(1)
+	if !ok || u != [3]float64{0.5, 1, 0} || v != [3]float64{0.5, 0, 1} {
+		t.Errorf("unexpected texture coordinates %v %v", u, v)
+	}
+}
@@ -0,0 +1,215 @@
+package model
+
+import "github.com/sinoz/gokira/buffer"
+
+// decodeNewFormat decodes a model of the format that ends with a trailer of 0xFF 0xFF,
+// which supports textures of every projection type, or of its successor that ends with
+// 0xFF 0xFD and adds skeletal rigging. May return an error.
+func decodeNewFormat(data []byte, rigged bool) (*Model, error) {
+	headerSize := 23
+	if rigged {
+		headerSize = 26
+	}
+
+	if len(data) < headerSize {
+		return nil, ErrTruncated
+	}
+
+	header := newCursor(data, len(data)-headerSize)
+
+	vertexCount := header.UInt16()
+	faceCount := header.UInt16()
+	textureTriangleCount := header.UInt8()
+
+	hasRenderTypes := header.UInt8() == 1
+	priority := header.UInt8()
+	hasAlphas := header.UInt8() == 1
+	hasFaceSkins := header.UInt8() == 1
+	hasTextures := header.UInt8() == 1
+	hasVertexSkins := header.UInt8() == 1
+
+	hasAnimaya := false
+	if rigged {
+		hasAnimaya = header.UInt8() == 1
+	}
+
+	xLength := header.UInt16()
+	yLength := header.UInt16()
+	zLength := header.UInt16()
+	faceIndexLength := header.UInt16()
+	textureCoordLength := header.UInt16()
+
+	animayaLength := 0
+	if rigged {
+		animayaLength = header.UInt16()
+	}
+
+	if header.Err() != nil {
+		return nil, header.Err()
+	}
+
+	model := newModel(vertexCount, faceCount, textureTriangleCount)
+
+	// the texture triangles lead the data, as their projection types tell the
+	// size of the sections of texture triangles at the end of the data
+	var simpleCount, complexCount, cylindricalCount int
+
+	renderTypes := newCursor(data, 0)
+	for i := 0; i < textureTriangleCount; i++ {
+		renderType := renderTypes.Int8()
+		model.TextureRenderTypes[i] = renderType
+
+		if renderType == 0 {
+			simpleCount++
+		}
+
+		if renderType >= 1 && renderType <= 3 {
+			complexCount++
+		}
+
+		if renderType == 2 {
+			cylindricalCount++
+		}
+	}
+
+	position := textureTriangleCount
+	section := func(length int) int {
+		offset := position
+		position += length
+
+		return offset
+	}
+
+	vertexFlagsOffset := section(vertexCount)
+	renderTypesOffset := section(lengthIf(hasRenderTypes, faceCount))
+	faceTypesOffset := section(faceCount)
+	prioritiesOffset := section(lengthIf(priority == 255, faceCount))
+	faceSkinsOffset := section(lengthIf(hasFaceSkins, faceCount))
+	vertexSkinsOffset := section(lengthIf(hasVertexSkins, vertexCount))
+	animayaOffset := section(lengthIf(hasAnimaya, animayaLength))
+	alphasOffset := section(lengthIf(hasAlphas, faceCount))
+	faceIndicesOffset := section(faceIndexLength)
+	texturesOffset := section(lengthIf(hasTextures, faceCount*2))
+	textureCoordsOffset := section(textureCoordLength)
+	colorsOffset := section(faceCount * 2)
+	xOffset := section(xLength)
+	yOffset := section(yLength)
+	zOffset := section(zLength)
+	simpleOffset := section(simpleCount * 6)
+	complexOffset := section(complexCount * 6)
+	section(complexCount*6 + complexCount*2 + complexCount + complexCount*2 + cylindricalCount*2)
+
+	if position > len(data)-headerSize {
+		return nil, ErrTruncated
+	}
+
+	flags := newCursor(data, vertexFlagsOffset)
+	x, y, z := newCursor(data, xOffset), newCursor(data, yOffset), newCursor(data, zOffset)
+	readVertices(model, flags, x, y, z)
+
+	if hasVertexSkins {
+		model.VertexSkins = readSkins(newCursor(data, vertexSkinsOffset), vertexCount)
+	}
+
+	colors := newCursor(data, colorsOffset)
+	faceRenderTypes := newCursor(data, renderTypesOffset)
+	priorities := newCursor(data, prioritiesOffset)
+	alphas := newCursor(data, alphasOffset)
+	faceSkins := newCursor(data, faceSkinsOffset)
+	textures := newCursor(data, texturesOffset)
+	textureCoords := newCursor(data, textureCoordsOffset)
+
+	model.Priority = priority
+	if hasRenderTypes {
+		model.FaceRenderTypes = make([]int, faceCount)
+	}
+
+	if priority == 255 {
+		model.Priority = 0
+		model.FacePriorities = make([]int, faceCount)
+	}
+
+	if hasAlphas {
+		model.FaceAlphas = make([]int, faceCount)
+	}
+
+	if hasFaceSkins {
+		model.FaceSkins = make([]int, faceCount)
+	}
+
+	if hasTextures {
+		model.FaceTextures = make([]int, faceCount)
+		model.TextureCoords = make([]int, faceCount)
+	}
+
+	for i := 0; i < faceCount; i++ {
+		model.FaceColors[i] = colors.UInt16()
+
+		if hasRenderTypes {
+			model.FaceRenderTypes[i] = faceRenderTypes.Int8()
+		}
+
+		if priority == 255 {
+			model.FacePriorities[i] = priorities.Int8()
+		}
+
+		if hasAlphas {
+			model.FaceAlphas[i] = alphas.UInt8()
+		}
+
+		if hasFaceSkins {
+			model.FaceSkins[i] = faceSkins.UInt8()
+		}
+
+		if hasTextures {
+			model.FaceTextures[i] = textures.UInt16() - 1
+			model.TextureCoords[i] = -1
+
+			if model.FaceTextures[i] != -1 {
+				model.TextureCoords[i] = textureCoords.UInt8() - 1
+			}
+		}
+	}
+
+	faceTypes := newCursor(data, faceTypesOffset)
+	faceIndices := newCursor(data, faceIndicesOffset)
+	readFaceIndices(model, faceTypes, faceIndices)
+
+	// only the vertices of the texture triangles are decoded. The scale, rotation,
+	// direction and speed of complex projections are not used by the client
+	simple := newCursor(data, simpleOffset)
+	complex := newCursor(data, complexOffset)
+
+	for i := 0; i < textureTriangleCount; i++ {
+		source := simple
+		if model.TextureRenderTypes[i] != 0 {
+			source = complex
+		}
+
+		model.TextureP[i] = source.UInt16()
+		model.TextureM[i] = source.UInt16()
+		model.TextureN[i] = source.UInt16()
+	}
+
+	animaya := newCursor(data, animayaOffset)
+	if hasAnimaya {
+		readAnimaya(model, animaya)
+	}
+
+	err := buffer.FirstError(renderTypes, flags, x, y, z, colors, faceRenderTypes, priorities, alphas,
+		faceSkins, textures, textureCoords, faceTypes, faceIndices, simple, complex, animaya)
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// lengthIf returns the given length if the given condition holds, or zero otherwise.
+func lengthIf(condition bool, length int) int {
+	if condition {
+		return length
+	}
+
+	return 0
+}
@@ -0,0 +1,178 @@
+package model
+
+import "github.com/sinoz/gokira/buffer"
+
+// decodeOldFormat decodes a model of the original format, which only supports textures
+// mapped through simple texture triangles, or of its successor that ends with a
+// trailer of 0xFF 0xFE and adds skeletal rigging. May return an error.
+func decodeOldFormat(data []byte, rigged bool) (*Model, error) {
+	headerSize := 18
+	if rigged {
+		headerSize = 23
+	}
+
+	if len(data) < headerSize {
+		return nil, ErrTruncated
+	}
+
+	header := newCursor(data, len(data)-headerSize)
+
+	vertexCount := header.UInt16()
+	faceCount := header.UInt16()
+	textureTriangleCount := header.UInt8()
+
+	hasTextureInfo := header.UInt8() == 1
+	priority := header.UInt8()
+	hasAlphas := header.UInt8() == 1
+	hasFaceSkins := header.UInt8() == 1
+	hasVertexSkins := header.UInt8() == 1
+
+	hasAnimaya := false
+	if rigged {
+		hasAnimaya = header.UInt8() == 1
+	}
+
+	xLength := header.UInt16()
+	yLength := header.UInt16()
+	zLength := header.UInt16()
+	faceIndexLength := header.UInt16()
+
+	animayaLength := 0
+	if rigged {
+		animayaLength = header.UInt16()
+	}
+
+	if header.Err() != nil {
+		return nil, header.Err()
+	}
+
+	model := newModel(vertexCount, faceCount, textureTriangleCount)
+
+	position := 0
+	section := func(length int) int {
+		offset := position
+		position += length
+
+		return offset
+	}
+
+	vertexFlagsOffset := section(vertexCount)
+	faceTypesOffset := section(faceCount)
+	prioritiesOffset := section(lengthIf(priority == 255, faceCount))
+	faceSkinsOffset := section(lengthIf(hasFaceSkins, faceCount))
+	textureInfoOffset := section(lengthIf(hasTextureInfo, faceCount))
+	vertexSkinsOffset := section(lengthIf(hasVertexSkins, vertexCount))
+	animayaOffset := section(lengthIf(hasAnimaya, animayaLength))
+	alphasOffset := section(lengthIf(hasAlphas, faceCount))
+	faceIndicesOffset := section(faceIndexLength)
+	colorsOffset := section(faceCount * 2)
+	textureTrianglesOffset := section(textureTriangleCount * 6)
+	xOffset := section(xLength)
+	yOffset := section(yLength)
+	zOffset := section(zLength)
+
+	if position > len(data)-headerSize {
+		return nil, ErrTruncated
+	}
+
+	flags := newCursor(data, vertexFlagsOffset)
+	x, y, z := newCursor(data, xOffset), newCursor(data, yOffset), newCursor(data, zOffset)
+	readVertices(model, flags, x, y, z)
+
+	if hasVertexSkins {
+		model.VertexSkins = readSkins(newCursor(data, vertexSkinsOffset), vertexCount)
+	}
+
+	colors := newCursor(data, colorsOffset)
+	textureInfo := newCursor(data, textureInfoOffset)
+	priorities := newCursor(data, prioritiesOffset)
+	alphas := newCursor(data, alphasOffset)
+	faceSkins := newCursor(data, faceSkinsOffset)
+
+	model.Priority = priority
+	if hasTextureInfo {
+		model.FaceRenderTypes = make([]int, faceCount)
+		model.FaceTextures = filled(faceCount, -1)
+		model.TextureCoords = filled(faceCount, -1)
+	}
+
+	if priority == 255 {
+		model.Priority = 0
+		model.FacePriorities = make([]int, faceCount)
+	}
+
+	if hasAlphas {
+		model.FaceAlphas = make([]int, faceCount)
+	}
+
+	if hasFaceSkins {
+		model.FaceSkins = make([]int, faceCount)
+	}
+
+	for i := 0; i < faceCount; i++ {
+		model.FaceColors[i] = colors.UInt16()
+
+		// the first bit of the texture info tells whether the face is flat shaded,
+		// the second bit whether its color is actually the id of a texture that is
+		// mapped through the texture triangle of which the index follows
+		if hasTextureInfo {
+			info := textureInfo.UInt8()
+			model.FaceRenderTypes[i] = info & 1
+
+			if info&2 != 0 {
+				model.TextureCoords[i] = info >> 2
+				model.FaceTextures[i] = model.FaceColors[i]
+				model.FaceColors[i] = 127
+			}
+		}
+
+		if priority == 255 {
+			model.FacePriorities[i] = priorities.Int8()
+		}
+
+		if hasAlphas {
+			model.FaceAlphas[i] = alphas.UInt8()
+		}
+
+		if hasFaceSkins {
+			model.FaceSkins[i] = faceSkins.UInt8()
+		}
+	}
+
+	faceTypes := newCursor(data, faceTypesOffset)
+	faceIndices := newCursor(data, faceIndicesOffset)
+	readFaceIndices(model, faceTypes, faceIndices)
+
+	textureTriangles := newCursor(data, textureTrianglesOffset)
+	for i := 0; i < textureTriangleCount; i++ {
+		model.TextureP[i] = textureTriangles.UInt16()
+		model.TextureM[i] = textureTriangles.UInt16()
+		model.TextureN[i] = textureTriangles.UInt16()
+	}
+
+	// a texture that is mapped through a triangle of the vertices of the face itself
+	// does not need a texture triangle at all
+	for i, coord := range model.TextureCoords {
+		if coord == -1 || coord >= textureTriangleCount {
+			continue
+		}
+
+		if model.TextureP[coord] == model.FaceA[i] && model.TextureM[coord] == model.FaceB[i] &&
+			model.TextureN[coord] == model.FaceC[i] {
+			model.TextureCoords[i] = -1
+		}
+	}
+
+	animaya := newCursor(data, animayaOffset)
+	if hasAnimaya {
+		readAnimaya(model, animaya)
+	}
+
+	err := buffer.FirstError(flags, x, y, z, colors, textureInfo, priorities, alphas, faceSkins,
+		faceTypes, faceIndices, textureTriangles, animaya)
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
@@ -0,0 +1,195 @@
+package model
+
+// Copy returns a deep copy of the model, which can be transformed without affecting
+// the original.
+func (model *Model) Copy() *Model {
+	duplicate := *model
+
+	duplicate.VertexX = copyInts(model.VertexX)
+	duplicate.VertexY = copyInts(model.VertexY)
+	duplicate.VertexZ = copyInts(model.VertexZ)
+	duplicate.FaceA = copyInts(model.FaceA)
+	duplicate.FaceB = copyInts(model.FaceB)
+	duplicate.FaceC = copyInts(model.FaceC)
+	duplicate.FaceColors = copyInts(model.FaceColors)
+	duplicate.FaceRenderTypes = copyInts(model.FaceRenderTypes)
+	duplicate.FacePriorities = copyInts(model.FacePriorities)
+	duplicate.FaceAlphas = copyInts(model.FaceAlphas)
+	duplicate.FaceTextures = copyInts(model.FaceTextures)
+	duplicate.TextureCoords = copyInts(model.TextureCoords)
+	duplicate.TextureRenderTypes = copyInts(model.TextureRenderTypes)
+	duplicate.TextureP = copyInts(model.TextureP)
+	duplicate.TextureM = copyInts(model.TextureM)
+	duplicate.TextureN = copyInts(model.TextureN)
+	duplicate.VertexSkins = copyInts(model.VertexSkins)
+	duplicate.FaceSkins = copyInts(model.FaceSkins)
+
+	if model.AnimayaGroups != nil {
+		duplicate.AnimayaGroups = make([][]int, len(model.AnimayaGroups))
+		duplicate.AnimayaScales = make([][]int, len(model.AnimayaScales))
+
+		for i := range model.AnimayaGroups {
+			duplicate.AnimayaGroups[i] = copyInts(model.AnimayaGroups[i])
+			duplicate.AnimayaScales[i] = copyInts(model.AnimayaScales[i])
+		}
+	}
+
+	return &duplicate
+}
+
+// Recolor replaces each face color of from with the color at the same index of to.
+func (model *Model) Recolor(from, to []int) {
+	for i := 0; i < len(from) && i < len(to); i++ {
+		for face, color := range model.FaceColors {
+			if color == from[i] {
+				model.FaceColors[face] = to[i]
+			}
+		}
+	}
+}
+
+// Retexture replaces each face texture of from with the texture at the same index of to.
+func (model *Model) Retexture(from, to []int) {
+	for i := 0; i < len(from) && i < len(to); i++ {
+		for face, texture := range model.FaceTextures {
+			if texture == from[i] {
+				model.FaceTextures[face] = to[i]
+			}
+		}
+	}
+}
+
+// Scale scales the model along each axis, where a factor of 128 keeps its original size.
+func (model *Model) Scale(x, y, z int) {
+	for i := 0; i < model.VertexCount; i++ {
+		model.VertexX[i] = model.VertexX[i] * x / 128
+		model.VertexY[i] = model.VertexY[i] * y / 128
+		model.VertexZ[i] = model.VertexZ[i] * z / 128
+	}
+}
+
+// Translate moves each vertex of the model by the given offsets.
+func (model *Model) Translate(x, y, z int) {
+	for i := 0; i < model.VertexCount; i++ {
+		model.VertexX[i] += x
+		model.VertexY[i] += y
+		model.VertexZ[i] += z
+	}
+}
+
+// Mirror mirrors the model along the z axis, reversing the winding of each face so
+// that it keeps facing outwards.
+func (model *Model) Mirror() {
+	for i := 0; i < model.VertexCount; i++ {
+		model.VertexZ[i] = -model.VertexZ[i]
+	}
+
+	for i := 0; i < model.FaceCount; i++ {
+		model.FaceA[i], model.FaceC[i] = model.FaceC[i], model.FaceA[i]
+	}
+}
+
+// Merge combines the given models into a single model, such as the body parts of a
+// character. Attributes that only some of the models encode are filled with their
+// defaults for the faces of the others.
+func Merge(models ...*Model) *Model {
+	var vertexCount, faceCount, textureTriangleCount int
+
+	for _, part := range models {
+		vertexCount += part.VertexCount
+		faceCount += part.FaceCount
+		textureTriangleCount += part.TextureTriangleCount
+	}
+
+	merged := newModel(0, 0, 0)
+	merged.Id = -1
+
+	for _, part := range models {
+		vertexOffset := merged.VertexCount
+		faceOffset := merged.FaceCount
+		textureOffset := merged.TextureTriangleCount
+
+		merged.VertexX = append(merged.VertexX, part.VertexX...)
+		merged.VertexY = append(merged.VertexY, part.VertexY...)
+		merged.VertexZ = append(merged.VertexZ, part.VertexZ...)
+
+		for i := 0; i < part.FaceCount; i++ {
+			merged.FaceA = append(merged.FaceA, part.FaceA[i]+vertexOffset)
+			merged.FaceB = append(merged.FaceB, part.FaceB[i]+vertexOffset)
+			merged.FaceC = append(merged.FaceC, part.FaceC[i]+vertexOffset)
+		}
+
+		merged.FaceColors = append(merged.FaceColors, part.FaceColors...)
+
+		priorities := part.FacePriorities
+		if priorities == nil {
+			priorities = filled(part.FaceCount, part.Priority)
+		}
+
+		merged.FacePriorities = append(merged.FacePriorities, priorities...)
+
+		merged.FaceRenderTypes = mergeAttribute(merged.FaceRenderTypes, part.FaceRenderTypes, faceOffset, faceCount, part.FaceCount, 0)
+		merged.FaceAlphas = mergeAttribute(merged.FaceAlphas, part.FaceAlphas, faceOffset, faceCount, part.FaceCount, 0)
+		merged.FaceSkins = mergeAttribute(merged.FaceSkins, part.FaceSkins, faceOffset, faceCount, part.FaceCount, 0)
+		merged.FaceTextures = mergeAttribute(merged.FaceTextures, part.FaceTextures, faceOffset, faceCount, part.FaceCount, -1)
+		merged.VertexSkins = mergeAttribute(merged.VertexSkins, part.VertexSkins, vertexOffset, vertexCount, part.VertexCount, 0)
+
+		coords := part.TextureCoords
+		if coords != nil {
+			coords = make([]int, part.FaceCount)
+			for i, coord := range part.TextureCoords {
+				coords[i] = coord
+				if coord != -1 {
+					coords[i] += textureOffset
+				}
+			}
+		}
+
+		merged.TextureCoords = mergeAttribute(merged.TextureCoords, coords, faceOffset, faceCount, part.FaceCount, -1)
+
+		merged.TextureRenderTypes = append(merged.TextureRenderTypes, part.TextureRenderTypes...)
+		for i := 0; i < part.TextureTriangleCount; i++ {
+			merged.TextureP = append(merged.TextureP, part.TextureP[i]+vertexOffset)
+			merged.TextureM = append(merged.TextureM, part.TextureM[i]+vertexOffset)
+			merged.TextureN = append(merged.TextureN, part.TextureN[i]+vertexOffset)
+		}
+
+		merged.VertexCount += part.VertexCount
+		merged.FaceCount += part.FaceCount
+		merged.TextureTriangleCount += part.TextureTriangleCount
+	}
+
+	return merged
+}
+
+// mergeAttribute appends the values of an optional attribute of a model that is being
+// merged to the values of the models merged before it. The attribute is only allocated
+// once any of the models encodes it, after which models that do not encode it are
+// filled with the given default value.
+func mergeAttribute(merged, values []int, offset, total, count, value int) []int {
+	if merged == nil && values == nil {
+		return nil
+	}
+
+	if merged == nil {
+		merged = make([]int, offset, total)
+		for i := range merged {
+			merged[i] = value
+		}
+	}
+
+	if values == nil {
+		return append(merged, filled(count, value)...)
+	}
+
+	return append(merged, values...)
+}
+
+// copyInts returns a copy of the given slice, which may be nil.
+func copyInts(values []int) []int {
+	if values == nil {
+		return nil
+	}
+
+	return append([]int(nil), values...)
+}
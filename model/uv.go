@@ -0,0 +1,64 @@
+package model
+
+// FaceUVs computes the texture coordinates of the vertices of the specified face in the
+// way the client does. A texture is projected onto the plane of the texture triangle of
+// the face, where its first vertex is the origin, the second vertex lies at u=1 and the
+// third vertex at v=1. A face without a texture triangle is its own texture triangle.
+// Returns false if the face is not textured.
+func (model *Model) FaceUVs(face int) (u, v [3]float64, ok bool) {
+	if model.FaceTextures == nil || model.FaceTextures[face] == -1 {
+		return u, v, false
+	}
+
+	p, m, n := model.FaceA[face], model.FaceB[face], model.FaceC[face]
+	if model.TextureCoords != nil {
+		if coord := model.TextureCoords[face]; coord != -1 && coord < model.TextureTriangleCount {
+			p, m, n = model.TextureP[coord], model.TextureM[coord], model.TextureN[coord]
+		}
+	}
+
+	origin := model.vertex(p)
+	pm := model.vertex(m).sub(origin)
+	pn := model.vertex(n).sub(origin)
+
+	normal := pm.cross(pn)
+
+	// the axes along which each coordinate is measured are perpendicular to the
+	// other edge of the texture triangle, so that the edges map onto the unit axes
+	uAxis := pn.cross(normal)
+	vAxis := pm.cross(normal)
+
+	uScale := 1.0 / uAxis.dot(pm)
+	vScale := 1.0 / vAxis.dot(pn)
+
+	for i, vertex := range [3]int{model.FaceA[face], model.FaceB[face], model.FaceC[face]} {
+		offset := model.vertex(vertex).sub(origin)
+
+		u[i] = uAxis.dot(offset) * uScale
+		v[i] = vAxis.dot(offset) * vScale
+	}
+
+	return u, v, true
+}
+
+// vector is a position or direction in the space of a model.
+type vector struct {
+	x, y, z float64
+}
+
+// vertex returns the position of the vertex at the given index.
+func (model *Model) vertex(index int) vector {
+	return vector{float64(model.VertexX[index]), float64(model.VertexY[index]), float64(model.VertexZ[index])}
+}
+
+func (a vector) sub(b vector) vector {
+	return vector{a.x - b.x, a.y - b.y, a.z - b.z}
+}
+
+func (a vector) dot(b vector) float64 {
+	return a.x*b.x + a.y*b.y + a.z*b.z
+}
+
+func (a vector) cross(b vector) vector {
+	return vector{a.y*b.z - a.z*b.y, a.z*b.x - a.x*b.z, a.x*b.y - a.y*b.x}
+}
@@ -0,0 +1,321 @@
+package music
+
+// The opcodes of the events of the encoded tracks. The lower four bits of an opcode hold
+// the kind of the event and the upper four bits toggle the bits of the channel of the
+// event and of those that follow.
+const (
+	noteOnOpcode          = 0
+	noteOffOpcode         = 1
+	controlChangeOpcode   = 2
+	pitchBendOpcode       = 3
+	channelPressureOpcode = 4
+	keyPressureOpcode     = 5
+	programChangeOpcode   = 6
+	endOfTrackOpcode      = 7
+	tempoOpcode           = 23
+)
+
+// The controllers whose values are encoded in sections of their own.
+const (
+	bankSelect       = 0
+	modulation       = 1
+	volume           = 7
+	pan              = 10
+	bankSelectFine   = 32
+	modulationFine   = 33
+	volumeFine       = 39
+	panFine          = 42
+	sustain          = 64
+	portamento       = 65
+	allSoundOff      = 120
+	resetControllers = 121
+	allNotesOff      = 123
+	nrpnFine         = 98
+	nrpn             = 99
+	rpnFine          = 100
+	rpn              = 101
+	maxControllers   = 128
+)
+
+const (
+	// tempoLength is the amount of bytes of a tempo.
+	tempoLength = 3
+
+	// trailerLength is the amount of bytes of the amount of tracks and the division
+	// that end an encoded track.
+	trailerLength = 3
+
+	// maxVarIntBytes is the highest amount of bytes of a delta time.
+	maxVarIntBytes = 4
+)
+
+// The sections of an encoded track that follow the opcodes and the delta times, in the
+// order they occur. Events draw their fields from the sections of their kind, and the
+// values of the controllers are split into sections by controller.
+const (
+	switchSection = iota
+	keyPressureSection
+	channelPressureSection
+	pitchBendHighSection
+	modulationSection
+	volumeSection
+	panSection
+	keySection
+	noteOnVelocitySection
+	controllerSection
+	noteOffVelocitySection
+	modulationFineSection
+	volumeFineSection
+	panFineSection
+	programSection
+	pitchBendLowSection
+	nrpnSection
+	nrpnFineSection
+	rpnSection
+	rpnFineSection
+	tempoSection
+	sectionCount
+)
+
+// controllerSections maps each controller to the section of its values.
+var controllerSections = func() [maxControllers]int {
+	var sections [maxControllers]int
+	for controller := range sections {
+		sections[controller] = controllerSection
+	}
+
+	sections[bankSelect] = programSection
+	sections[bankSelectFine] = programSection
+	sections[modulation] = modulationSection
+	sections[modulationFine] = modulationFineSection
+	sections[volume] = volumeSection
+	sections[volumeFine] = volumeFineSection
+	sections[pan] = panSection
+	sections[panFine] = panFineSection
+	sections[nrpn] = nrpnSection
+	sections[nrpnFine] = nrpnFineSection
+	sections[rpn] = rpnSection
+	sections[rpnFine] = rpnFineSection
+
+	for _, controller := range []int{sustain, portamento, allSoundOff, resetControllers, allNotesOff} {
+		sections[controller] = switchSection
+	}
+
+	return sections
+}()
+
+// decoder reads the sections of an encoded track, each from its own position.
+type decoder struct {
+	data      []byte
+	positions [sectionCount]int
+}
+
+// next returns the next byte of the given section, as a signed value.
+func (d *decoder) next(section int) int {
+	value := int(int8(d.data[d.positions[section]]))
+	d.positions[section]++
+
+	return value
+}
+
+// Decode decodes a Track of the given id from the given data. The encoding lists the
+// opcodes of the events of every track, followed by their delta times and by the
+// sections that hold their fields, and ends with the amount of tracks and the division.
+// Notes, controllers and pitch bends are delta encoded per kind. May return an error.
+func Decode(id int, data []byte) (*Track, error) {
+	if len(data) < trailerLength {
+		return nil, ErrMalformed
+	}
+
+	body := data[:len(data)-trailerLength]
+	trackCount := int(data[len(data)-3])
+	division := int(data[len(data)-2])<<8 | int(data[len(data)-1])
+
+	// the opcodes determine the amount of fields in each of the sections
+	var counts [sectionCount]int
+	var opcodeCount, controllerCount, noteCount int
+
+	for track := 0; track < trackCount; track++ {
+		for {
+			if opcodeCount >= len(body) {
+				return nil, ErrMalformed
+			}
+
+			opcode := int(body[opcodeCount])
+			opcodeCount++
+
+			if opcode == endOfTrackOpcode {
+				break
+			}
+
+			switch {
+			case opcode == tempoOpcode:
+				counts[tempoSection] += tempoLength
+			case opcode&0xF == noteOnOpcode:
+				counts[noteOnVelocitySection]++
+				noteCount++
+			case opcode&0xF == noteOffOpcode:
+				counts[noteOffVelocitySection]++
+				noteCount++
+			case opcode&0xF == controlChangeOpcode:
+				controllerCount++
+			case opcode&0xF == pitchBendOpcode:
+				counts[pitchBendHighSection]++
+				counts[pitchBendLowSection]++
+			case opcode&0xF == channelPressureOpcode:
+				counts[channelPressureSection]++
+			case opcode&0xF == keyPressureOpcode:
+				counts[keyPressureSection]++
+				noteCount++
+			case opcode&0xF == programChangeOpcode:
+				counts[programSection]++
+			default:
+				return nil, ErrMalformed
+			}
+		}
+	}
+
+	counts[keySection] = noteCount
+
+	// the delta times of every event, including the ends of the tracks, follow
+	position := opcodeCount
+	deltaStart := position
+
+	for i := 0; i < opcodeCount; i++ {
+		var err error
+		if _, position, err = readVarInt(body, position); err != nil {
+			return nil, err
+		}
+	}
+
+	controllerStart := position
+	if controllerStart+controllerCount > len(body) {
+		return nil, ErrMalformed
+	}
+
+	var controller int
+	for _, offset := range body[controllerStart : controllerStart+controllerCount] {
+		controller = (controller + int(offset)) & 0x7F
+		counts[controllerSections[controller]]++
+	}
+
+	d := &decoder{data: body}
+
+	position = controllerStart + controllerCount
+	for section := range d.positions {
+		d.positions[section] = position
+		position += counts[section]
+	}
+
+	if position > len(body) {
+		return nil, ErrMalformed
+	}
+
+	return d.decode(id, trackCount, division, deltaStart, controllerStart), nil
+}
+
+// decode decodes the events of the given amount of tracks, of which the opcodes start
+// at the start of the data. The sections must hold the fields of every event.
+func (d *decoder) decode(id, trackCount, division, deltaStart, controllerStart int) *Track {
+	track := &Track{Id: id, Division: division, Tracks: make([][]*Event, trackCount)}
+
+	opcodePosition, deltaPosition, controllerPosition := 0, deltaStart, controllerStart
+
+	var channel, key, onVelocity, offVelocity, pressure, channelPressure, bend, controller int
+	var controllerValues [maxControllers]int
+
+	for i := range track.Tracks {
+		for {
+			var delta int
+			delta, deltaPosition, _ = readVarInt(d.data, deltaPosition)
+
+			opcode := int(d.data[opcodePosition])
+			opcodePosition++
+
+			event := &Event{Delta: delta}
+			track.Tracks[i] = append(track.Tracks[i], event)
+
+			if opcode == endOfTrackOpcode {
+				event.Status = Meta
+				event.Data = []byte{MetaEndOfTrack}
+				break
+			}
+
+			if opcode == tempoOpcode {
+				event.Status = Meta
+				event.Data = []byte{MetaTempo, byte(d.next(tempoSection)), byte(d.next(tempoSection)), byte(d.next(tempoSection))}
+				continue
+			}
+
+			channel ^= opcode >> 4
+
+			switch opcode & 0xF {
+			case noteOnOpcode:
+				key += d.next(keySection)
+				onVelocity += d.next(noteOnVelocitySection)
+				event.Status = NoteOn | channel
+				event.Data = []byte{byte(key & 0x7F), byte(onVelocity & 0x7F)}
+
+			case noteOffOpcode:
+				key += d.next(keySection)
+				offVelocity += d.next(noteOffVelocitySection)
+				event.Status = NoteOff | channel
+				event.Data = []byte{byte(key & 0x7F), byte(offVelocity & 0x7F)}
+
+			case controlChangeOpcode:
+				controller = (controller + int(d.data[controllerPosition])) & 0x7F
+				controllerPosition++
+
+				controllerValues[controller] += d.next(controllerSections[controller])
+				event.Status = ControlChange | channel
+				event.Data = []byte{byte(controller), byte(controllerValues[controller] & 0x7F)}
+
+			case pitchBendOpcode:
+				bend += d.next(pitchBendLowSection)
+				bend += d.next(pitchBendHighSection) << 7
+				event.Status = PitchBend | channel
+				event.Data = []byte{byte(bend & 0x7F), byte(bend >> 7 & 0x7F)}
+
+			case channelPressureOpcode:
+				channelPressure += d.next(channelPressureSection)
+				event.Status = ChannelPressure | channel
+				event.Data = []byte{byte(channelPressure & 0x7F)}
+
+			case keyPressureOpcode:
+				key += d.next(keySection)
+				pressure += d.next(keyPressureSection)
+				event.Status = KeyPressure | channel
+				event.Data = []byte{byte(key & 0x7F), byte(pressure & 0x7F)}
+
+			case programChangeOpcode:
+				event.Status = ProgramChange | channel
+				event.Data = []byte{byte(d.next(programSection) & 0x7F)}
+			}
+		}
+	}
+
+	return track
+}
+
+// readVarInt reads a value of the given data at the given position that is encoded in
+// groups of seven bits, most significant group first, where the most significant bit
+// of each byte indicates whether another group follows. Returns the value and the
+// position that follows it. May return an error.
+func readVarInt(data []byte, position int) (int, int, error) {
+	var value int
+	for i := 0; i < maxVarIntBytes; i++ {
+		if position >= len(data) {
+			return 0, 0, ErrMalformed
+		}
+
+		group := data[position]
+		position++
+
+		value = value<<7 | int(group&0x7F)
+		if group < 0x80 {
+			return value, position, nil
+		}
+	}
+
+	return 0, 0, ErrMalformed
+}
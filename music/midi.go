@@ -0,0 +1,84 @@
+package music
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// WriteMIDI writes the track as a standard MIDI file to the given writer. Like the
+// client, a track of multiple MIDI tracks is written in format 1 and a track of a
+// single MIDI track in format 0. Events share their status with the event before them
+// where possible. May return an error.
+func (track *Track) WriteMIDI(w io.Writer) error {
+	format := 0
+	if len(track.Tracks) > 1 {
+		format = 1
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("MThd")
+	binary.Write(&buf, binary.BigEndian, []uint32{6})
+	binary.Write(&buf, binary.BigEndian, []uint16{uint16(format), uint16(len(track.Tracks)), uint16(track.Division)})
+
+	for _, events := range track.Tracks {
+		contents := encodeEvents(events)
+
+		buf.WriteString("MTrk")
+		binary.Write(&buf, binary.BigEndian, uint32(len(contents)))
+		buf.Write(contents)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// encodeEvents encodes the given events of a MIDI track, each preceded by its delta
+// time. Meta events are written along with the length of their contents and cancel
+// the status that the events before them share.
+func encodeEvents(events []*Event) []byte {
+	var buf bytes.Buffer
+
+	status := -1
+	for _, event := range events {
+		writeVarInt(&buf, event.Delta)
+
+		if event.Status == Meta {
+			status = -1
+
+			buf.WriteByte(Meta)
+			if len(event.Data) > 0 {
+				buf.WriteByte(event.Data[0])
+				writeVarInt(&buf, len(event.Data)-1)
+				buf.Write(event.Data[1:])
+			}
+
+			continue
+		}
+
+		if event.Status != status {
+			status = event.Status
+			buf.WriteByte(byte(status))
+		}
+
+		buf.Write(event.Data)
+	}
+
+	return buf.Bytes()
+}
+
+// writeVarInt writes the given value in the encoding of readVarInt.
+func writeVarInt(buf *bytes.Buffer, value int) {
+	var groups [maxVarIntBytes + 1]byte
+
+	i := len(groups) - 1
+	groups[i] = byte(value & 0x7F)
+
+	for value >>= 7; value > 0 && i > 0; value >>= 7 {
+		i--
+		groups[i] = byte(value&0x7F) | 0x80
+	}
+
+	buf.Write(groups[i:])
+}
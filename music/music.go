@@ -0,0 +1,144 @@
+// Package music decodes the music tracks and the jingles of the cache, which are MIDI
+// files in a compact encoding that groups the fields of the events by kind, and
+// reconstructs standard MIDI files out of them.
+package music
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+)
+
+const (
+	// Archive is the archive that holds a folder for each music track, labelled with
+	// the name of the track.
+	Archive = 6
+
+	// JingleArchive is the archive that holds a folder for each jingle, the short
+	// tracks that are played over the music, such as when a level is gained.
+	JingleArchive = 11
+)
+
+// The types of the events, which are the upper four bits of their status.
+const (
+	NoteOff         = 0x80
+	NoteOn          = 0x90
+	KeyPressure     = 0xA0
+	ControlChange   = 0xB0
+	ProgramChange   = 0xC0
+	ChannelPressure = 0xD0
+	PitchBend       = 0xE0
+
+	// Meta is the status of meta events, of which tracks only hold those of the
+	// MetaTempo and the MetaEndOfTrack types.
+	Meta = 0xFF
+)
+
+// The types of the meta events.
+const (
+	// MetaTempo sets the duration of a quarter note in microseconds, as three bytes.
+	MetaTempo = 0x51
+
+	// MetaEndOfTrack ends a track.
+	MetaEndOfTrack = 0x2F
+)
+
+// DefaultTempo is the duration of a quarter note in microseconds until a track sets
+// its tempo.
+const DefaultTempo = 500000
+
+// ErrMalformed is returned when a music track is malformed.
+var ErrMalformed = errors.New("music track is malformed")
+
+// Track is a music track or a jingle, which consists of MIDI tracks that are played
+// alongside each other.
+type Track struct {
+	Id int
+
+	// Division is the amount of ticks per quarter note.
+	Division int
+
+	// Tracks are the events of each of the MIDI tracks, in the order they occur.
+	Tracks [][]*Event
+}
+
+// Event is an event of a MIDI track.
+type Event struct {
+	// Delta is the amount of ticks since the previous event of the track.
+	Delta int
+
+	// Status holds the type of the event in its upper four bits and the channel of the
+	// event in its lower four bits, or is Meta for a meta event.
+	Status int
+
+	// Data are the data bytes of the event. Those of a meta event start with the type
+	// of the meta event, followed by its contents.
+	Data []byte
+}
+
+// Type returns the type of the event, see NoteOn and its siblings.
+func (event *Event) Type() int {
+	if event.Status == Meta {
+		return Meta
+	}
+
+	return event.Status & 0xF0
+}
+
+// Channel returns the channel of the event, which is meaningless for meta events.
+func (event *Event) Channel() int {
+	return event.Status & 0xF
+}
+
+// Load decodes the music Track of the specified id from the given Cache. May return an
+// error.
+func Load(cache *gokira.Cache, id int) (*Track, error) {
+	return load(cache, Archive, id)
+}
+
+// LoadJingle decodes the jingle of the specified id from the given Cache. May return an
+// error.
+func LoadJingle(cache *gokira.Cache, id int) (*Track, error) {
+	return load(cache, JingleArchive, id)
+}
+
+// LoadByName decodes the music Track that is labelled with the given name from the given
+// Cache, such as "harmony". Returns gokira.ErrLabelNotFound if there is no such track.
+// May return an error.
+func LoadByName(cache *gokira.Cache, name string) (*Track, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return load(cache, Archive, manifest.Id)
+}
+
+// load decodes the Track of the given folder of the given archive. May return an error.
+func load(cache *gokira.Cache, archive, id int) (*Track, error) {
+	folder, err := cache.GetUnencryptedFolder(archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(id, folder.Data)
+}
+
+// Tempo returns the tempo the track starts at, in microseconds per quarter note.
+func (track *Track) Tempo() int {
+	for _, events := range track.Tracks {
+		var ticks int
+		for _, event := range events {
+			ticks += event.Delta
+			if ticks > 0 {
+				break
+			}
+
+			if event.Status == Meta && len(event.Data) == 4 && event.Data[0] == MetaTempo {
+				return int(event.Data[1])<<16 | int(event.Data[2])<<8 | int(event.Data[3])
+			}
+		}
+	}
+
+	return DefaultTempo
+}
@@ -0,0 +1,230 @@
+package music
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// harmony are the tracks of the test music track, of which the first sets the tempo and
+// the second plays notes on two channels.
+var harmony = [][]*Event{
+	{
+		{Delta: 0, Status: Meta, Data: []byte{MetaTempo, 0x09, 0x27, 0xC0}},
+		{Delta: 0, Status: Meta, Data: []byte{MetaEndOfTrack}},
+	},
+	{
+		{Delta: 0, Status: ProgramChange, Data: []byte{41}},
+		{Delta: 0, Status: ControlChange, Data: []byte{7, 100}},
+		{Delta: 0, Status: ControlChange, Data: []byte{10, 64}},
+		{Delta: 0, Status: NoteOn, Data: []byte{60, 100}},
+		{Delta: 0, Status: NoteOn, Data: []byte{64, 90}},
+		{Delta: 480, Status: NoteOff, Data: []byte{60, 0}},
+		{Delta: 10, Status: PitchBend, Data: []byte{0x64, 0x40}},
+		{Delta: 0, Status: NoteOn | 2, Data: []byte{72, 127}},
+		{Delta: 0, Status: ControlChange | 2, Data: []byte{64, 127}},
+		{Delta: 0, Status: KeyPressure | 2, Data: []byte{72, 50}},
+		{Delta: 0, Status: ChannelPressure | 2, Data: []byte{30}},
+		{Delta: 1000, Status: NoteOff | 2, Data: []byte{72, 0}},
+		{Delta: 0, Status: Meta, Data: []byte{MetaEndOfTrack}},
+	},
+}
+
+// encodeTrack encodes the given tracks the way the cache does, which Decode reverses.
+// Bank selects share their section with the program changes, so the tracks may only
+// hold either of them.
+func encodeTrack(tracks [][]*Event, division int) []byte {
+	var opcodes, deltas, controllers []byte
+	var sections [sectionCount][]byte
+
+	var channel, key, onVelocity, offVelocity, pressure, channelPressure, bend, controller int
+	var controllerValues [maxControllers]int
+
+	// write writes the difference of the given value to the previous value of its
+	// section, and remembers the value
+	write := func(section, value int, previous *int) {
+		sections[section] = append(sections[section], byte(value-*previous))
+		*previous = value
+	}
+
+	for _, events := range tracks {
+		for _, event := range events {
+			for shift := uint(21); shift > 0; shift -= 7 {
+				if group := event.Delta >> shift; group > 0 {
+					deltas = append(deltas, byte(group&0x7F|0x80))
+				}
+			}
+
+			deltas = append(deltas, byte(event.Delta&0x7F))
+
+			if event.Status == Meta {
+				if event.Data[0] == MetaTempo {
+					opcodes = append(opcodes, tempoOpcode)
+					sections[tempoSection] = append(sections[tempoSection], event.Data[1:]...)
+				} else {
+					opcodes = append(opcodes, endOfTrackOpcode)
+				}
+
+				continue
+			}
+
+			var opcode int
+			switch event.Type() {
+			case NoteOn:
+				opcode = noteOnOpcode
+				write(keySection, int(event.Data[0]), &key)
+				write(noteOnVelocitySection, int(event.Data[1]), &onVelocity)
+			case NoteOff:
+				opcode = noteOffOpcode
+				write(keySection, int(event.Data[0]), &key)
+				write(noteOffVelocitySection, int(event.Data[1]), &offVelocity)
+			case KeyPressure:
+				opcode = keyPressureOpcode
+				write(keySection, int(event.Data[0]), &key)
+				write(keyPressureSection, int(event.Data[1]), &pressure)
+			case ControlChange:
+				opcode = controlChangeOpcode
+				controllers = append(controllers, byte((int(event.Data[0])-controller)&0x7F))
+				controller = int(event.Data[0])
+				write(controllerSections[controller], int(event.Data[1]), &controllerValues[controller])
+			case PitchBend:
+				opcode = pitchBendOpcode
+				value := int(event.Data[0]) | int(event.Data[1])<<7
+				difference := value - bend
+				sections[pitchBendHighSection] = append(sections[pitchBendHighSection], byte(difference>>7))
+				sections[pitchBendLowSection] = append(sections[pitchBendLowSection], byte(difference&0x7F))
+				bend = value
+			case ChannelPressure:
+				opcode = channelPressureOpcode
+				write(channelPressureSection, int(event.Data[0]), &channelPressure)
+			case ProgramChange:
+				opcode = programChangeOpcode
+				sections[programSection] = append(sections[programSection], event.Data[0])
+			}
+
+			opcodes = append(opcodes, byte(opcode|(event.Channel()^channel)<<4))
+			channel = event.Channel()
+		}
+	}
+
+	data := append(append(opcodes, deltas...), controllers...)
+	for _, section := range sections {
+		data = append(data, section...)
+	}
+
+	return append(data, byte(len(tracks)), byte(division>>8), byte(division))
+}
+
+// loadTestCache builds the fixture cache that holds the music track "harmony" and a
+// compressed jingle that plays a single note.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	jingle := [][]*Event{{
+		{Delta: 0, Status: ProgramChange | 1, Data: []byte{5}},
+		{Delta: 100, Status: NoteOn | 1, Data: []byte{50, 60}},
+		{Delta: 100, Status: NoteOff | 1, Data: []byte{50, 60}},
+		{Delta: 0, Status: Meta, Data: []byte{MetaEndOfTrack}},
+	}}
+
+	builder := cachetest.New()
+	builder.AddFile(Archive, 3, encodeTrack(harmony, 480)).Named("harmony")
+	builder.AddFile(JingleArchive, 4, encodeTrack(jingle, 96)).Compressed = true
+
+	return builder.Build(t)
+}
+
+func TestLoad(t *testing.T) {
+	track, err := LoadByName(loadTestCache(t), "harmony")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.Id != 3 || track.Division != 480 || len(track.Tracks) != 2 || track.Tempo() != 600000 {
+		t.Fatalf("unexpected track %+v", track)
+	}
+
+	if !reflect.DeepEqual(track.Tracks, harmony) {
+		for _, event := range track.Tracks[1] {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+
+	if event := track.Tracks[1][7]; event.Type() != NoteOn || event.Channel() != 2 {
+		t.Errorf("unexpected type %v and channel %v", event.Type(), event.Channel())
+	}
+
+	if _, err := LoadByName(loadTestCache(t), "unknown"); err != gokira.ErrLabelNotFound {
+		t.Errorf("expected %v but got %v", gokira.ErrLabelNotFound, err)
+	}
+}
+
+func TestLoadJingle(t *testing.T) {
+	jingle, err := LoadJingle(loadTestCache(t), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jingle.Division != 96 || len(jingle.Tracks) != 1 || len(jingle.Tracks[0]) != 4 || jingle.Tempo() != DefaultTempo {
+		t.Fatalf("unexpected jingle %+v", jingle)
+	}
+
+	if event := jingle.Tracks[0][2]; event.Status != NoteOff|1 || event.Delta != 100 || !bytes.Equal(event.Data, []byte{50, 60}) {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestWriteMIDI(t *testing.T) {
+	track, err := Load(loadTestCache(t), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := track.WriteMIDI(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tempo := []byte{0, 0xFF, 0x51, 3, 0x09, 0x27, 0xC0, 0, 0xFF, 0x2F, 0}
+	notes := []byte{
+		0, 0xC0, 41,
+		0, 0xB0, 7, 100,
+		0, 10, 64,
+		0, 0x90, 60, 100,
+		0, 64, 90,
+		0x83, 0x60, 0x80, 60, 0,
+		10, 0xE0, 0x64, 0x40,
+		0, 0x92, 72, 127,
+		0, 0xB2, 64, 127,
+		0, 0xA2, 72, 50,
+		0, 0xD2, 30,
+		0x87, 0x68, 0x82, 72, 0,
+		0, 0xFF, 0x2F, 0,
+	}
+
+	expected := []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 2, 0x01, 0xE0}
+	expected = append(expected, 'M', 'T', 'r', 'k', 0, 0, 0, byte(len(tempo)))
+	expected = append(expected, tempo...)
+	expected = append(expected, 'M', 'T', 'r', 'k', 0, 0, 0, byte(len(notes)))
+	expected = append(expected, notes...)
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.Bytes())
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	malformed := [][]byte{
+		{1, 0},
+		{0x27, 0, 1, 0, 96},
+		{0, 0, 1, 0, 96},
+		{0, 7, 0, 0x80, 1, 0, 96},
+	}
+
+	for _, data := range malformed {
+		if _, err := Decode(0, data); err != ErrMalformed {
+			t.Errorf("expected %v for %v but got %v", ErrMalformed, data, err)
+		}
+	}
+}
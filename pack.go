@@ -2,5 +2,6 @@ package gokira
 
 // Pack is a pack of assets stored in a Folder.
 type Pack struct {
+	Id   int
 	Data []byte
 }
@@ -0,0 +1,81 @@
+package pathfinding
+
+import "github.com/sinoz/gokira/collision"
+
+// HasLineOfSight returns whether a projectile can travel from the given source to the
+// given destination without being blocked by walls or objects that block projectiles. The
+// line is traced tile by tile along its longest axis, checking each tile it enters for a
+// flag that blocks entering it from the side the line comes from.
+func HasLineOfSight(grid Grid, src, dst Tile) bool {
+	if src.X == dst.X && src.Y == dst.Y {
+		return true
+	}
+
+	dx, dy := dst.X-src.X, dst.Y-src.Y
+
+	// the flags that block entering a tile along each axis, which are the walls on the
+	// side the line enters the tile from
+	xFlags := collision.ProjectileWallWest | collision.ProjectileObject
+	if dx < 0 {
+		xFlags = collision.ProjectileWallEast | collision.ProjectileObject
+	}
+
+	yFlags := collision.ProjectileWallSouth | collision.ProjectileObject
+	if dy < 0 {
+		yFlags = collision.ProjectileWallNorth | collision.ProjectileObject
+	}
+
+	flags := func(x, y int) int {
+		return grid.Flags(src.Plane, x, y)
+	}
+
+	if absInt(dx) > absInt(dy) {
+		return trace(src.X, src.Y, dst.X, dx, dy, xFlags, yFlags, flags)
+	}
+
+	return trace(src.Y, src.X, dst.Y, dy, dx, yFlags, xFlags, func(y, x int) int {
+		return flags(x, y)
+	})
+}
+
+// trace steps along the major axis from the given start to the given end, moving along
+// the minor axis by the slope of the line in 16-bit fixed point, and returns whether
+// none of the tiles entered has a flag that blocks entering it.
+func trace(major, minor, end, majorDelta, minorDelta, majorFlags, minorFlags int, flags func(major, minor int) int) bool {
+	step := 1
+	if majorDelta < 0 {
+		step = -1
+	}
+
+	scaled := minor<<16 + 0x8000
+	slope := (minorDelta << 16) / absInt(majorDelta)
+	if minorDelta < 0 {
+		// keep lines that run exactly along a tile boundary on the tile they started on
+		scaled--
+	}
+
+	for major != end {
+		major += step
+
+		current := scaled >> 16
+		if flags(major, current)&majorFlags != 0 {
+			return false
+		}
+
+		scaled += slope
+		if next := scaled >> 16; next != current && flags(major, next)&minorFlags != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// absInt returns the absolute value of the given value.
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,380 @@
+// Package pathfinding finds the paths of movement across the collision flags of the map
+// like the client does, and checks whether projectiles can travel between tiles.
+package pathfinding
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// GraphSize is the length of each side of the square of tiles around the source that
+	// paths are searched within.
+	GraphSize = 128
+
+	// Radius is the distance from the destination within which the closest reachable
+	// tile is searched for if the destination itself cannot be reached.
+	Radius = 10
+
+	// queueSize is the capacity of the ring buffer of the tiles that are yet to be visited.
+	queueSize = 4096
+
+	// maxDistance is the distance beyond which tiles are not considered to be the closest
+	// reachable tile.
+	maxDistance = 100
+)
+
+// The directions that the search stores for each tile it visits, which point back toward
+// the tile it was visited from.
+const (
+	source = 99
+	south  = 1
+	west   = 2
+	north  = 4
+	east   = 8
+)
+
+// The masks of the collision flags that block movement onto a tile from the tile next to
+// it, named after the direction of the movement. Beside the walls on the side of the
+// movement, each of them includes the flags of objects, floors and unloaded tiles.
+const (
+	blockWest      = 0x1240108
+	blockEast      = 0x1240180
+	blockSouth     = 0x1240102
+	blockNorth     = 0x1240120
+	blockSouthWest = 0x124010e
+	blockSouthEast = 0x1240183
+	blockNorthWest = 0x1240138
+	blockNorthEast = 0x12401e0
+
+	// the masks of the tiles along the edges of entities that are larger than a tile
+	blockWestEdge  = 0x124013e
+	blockEastEdge  = 0x12401e3
+	blockSouthEdge = 0x124018f
+	blockNorthEdge = 0x12401f8
+)
+
+// ErrNoPath is returned when no path leads to the destination, nor to any tile near it.
+var ErrNoPath = errors.New("no path to the destination")
+
+// Grid is a source of the collision flags of tiles, such as a collision.Map.
+type Grid interface {
+	// Flags returns the collision flags of the tile at the given absolute coordinates.
+	Flags(plane, x, y int) int
+}
+
+// Tile is the absolute position of a tile.
+type Tile struct {
+	Plane int
+	X     int
+	Y     int
+}
+
+// Pathfinder finds paths across the collision flags of a Grid.
+type Pathfinder struct {
+	Grid Grid
+
+	// Closest is whether a path leads to the closest reachable tile near the destination
+	// if the destination cannot be reached, like the client does for the clicks of
+	// players.
+	Closest bool
+
+	directions [GraphSize][GraphSize]int
+	distances  [GraphSize][GraphSize]int
+	queueX     [queueSize]int
+	queueY     [queueSize]int
+}
+
+// NewPathfinder constructs a Pathfinder that finds paths across the given Grid and that
+// falls back to the closest reachable tile.
+func NewPathfinder(grid Grid) *Pathfinder {
+	return &Pathfinder{Grid: grid, Closest: true}
+}
+
+// FindPath finds a path from the given source on the given Grid to the given destination
+// for an entity of the given size, using the given Strategy to determine when the entity
+// has arrived, and falling back to the closest reachable tile. May return an error.
+func FindPath(grid Grid, src, dst Tile, size int, strategy Strategy) ([]Tile, error) {
+	return NewPathfinder(grid).FindPath(src, dst, size, strategy)
+}
+
+// FindPath finds a path from the given source to the given destination for an entity of
+// the given size, using the given Strategy to determine when the entity has arrived. The
+// path consists of the checkpoints of the route, which are the tiles at which it changes
+// direction followed by the tile it ends at, like the route the client sends to the
+// server. The path is empty if the entity has already arrived. May return an error.
+func (p *Pathfinder) FindPath(src, dst Tile, size int, strategy Strategy) ([]Tile, error) {
+	for x := range p.directions {
+		for y := range p.directions[x] {
+			p.directions[x][y] = 0
+			p.distances[x][y] = math.MaxInt32
+		}
+	}
+
+	var endX, endY int
+	var found bool
+	if size == 1 {
+		endX, endY, found = p.search1(src, dst, strategy)
+	} else {
+		endX, endY, found = p.searchN(src, dst, size, strategy)
+	}
+
+	baseX, baseY := src.X-GraphSize/2, src.Y-GraphSize/2
+	if !found {
+		if !p.Closest {
+			return nil, ErrNoPath
+		}
+
+		var ok bool
+		if endX, endY, ok = p.closest(baseX, baseY, dst, strategy); !ok {
+			return nil, ErrNoPath
+		}
+	}
+
+	if endX == src.X && endY == src.Y {
+		return []Tile{}, nil
+	}
+
+	// walk back from the end to the source, keeping the tiles at which the direction
+	// changes, of which the order is then reversed
+	var checkpoints []Tile
+	checkpoints = append(checkpoints, Tile{Plane: src.Plane, X: endX, Y: endY})
+
+	direction := p.directions[endX-baseX][endY-baseY]
+	previous := direction
+	for endX != src.X || endY != src.Y {
+		if previous != direction {
+			previous = direction
+			checkpoints = append(checkpoints, Tile{Plane: src.Plane, X: endX, Y: endY})
+		}
+
+		if direction&west != 0 {
+			endX++
+		} else if direction&east != 0 {
+			endX--
+		}
+
+		if direction&south != 0 {
+			endY++
+		} else if direction&north != 0 {
+			endY--
+		}
+
+		direction = p.directions[endX-baseX][endY-baseY]
+	}
+
+	for i, j := 0, len(checkpoints)-1; i < j; i, j = i+1, j-1 {
+		checkpoints[i], checkpoints[j] = checkpoints[j], checkpoints[i]
+	}
+
+	return checkpoints, nil
+}
+
+// closest returns the tile among those that the search visited within the Radius of the
+// destination that is closest to the destination, preferring the tiles that are fewer
+// steps away from the source, and whether there is such a tile.
+func (p *Pathfinder) closest(baseX, baseY int, dst Tile, strategy Strategy) (int, int, bool) {
+	sizeX, sizeY := strategy.Size()
+
+	lowestCost, lowestDistance := math.MaxInt32, math.MaxInt32
+	var endX, endY int
+
+	for x := dst.X - Radius; x <= dst.X+Radius; x++ {
+		for y := dst.Y - Radius; y <= dst.Y+Radius; y++ {
+			localX, localY := x-baseX, y-baseY
+			if localX < 0 || localY < 0 || localX >= GraphSize || localY >= GraphSize || p.distances[localX][localY] >= maxDistance {
+				continue
+			}
+
+			dx := 0
+			if x < dst.X {
+				dx = dst.X - x
+			} else if x > dst.X+sizeX-1 {
+				dx = x - (dst.X + sizeX - 1)
+			}
+
+			dy := 0
+			if y < dst.Y {
+				dy = dst.Y - y
+			} else if y > dst.Y+sizeY-1 {
+				dy = y - (dst.Y + sizeY - 1)
+			}
+
+			cost := dx*dx + dy*dy
+			if cost < lowestCost || cost == lowestCost && p.distances[localX][localY] < lowestDistance {
+				lowestCost, lowestDistance = cost, p.distances[localX][localY]
+				endX, endY = x, y
+			}
+		}
+	}
+
+	return endX, endY, lowestCost != math.MaxInt32
+}
+
+// visit queues the tile at the given absolute coordinates, storing the direction back to
+// the tile it is visited from and its distance from the source.
+func (p *Pathfinder) visit(write *int, baseX, baseY, x, y, direction, distance int) {
+	p.queueX[*write], p.queueY[*write] = x, y
+	*write = (*write + 1) & (queueSize - 1)
+
+	p.directions[x-baseX][y-baseY] = direction
+	p.distances[x-baseX][y-baseY] = distance
+}
+
+// search1 searches breadth-first for a path for an entity of a single tile, returning the
+// tile at which the entity arrives at the destination, or the last tile visited, and
+// whether it arrives.
+func (p *Pathfinder) search1(src, dst Tile, strategy Strategy) (int, int, bool) {
+	baseX, baseY := src.X-GraphSize/2, src.Y-GraphSize/2
+	plane := src.Plane
+
+	read, write := 0, 0
+	p.visit(&write, baseX, baseY, src.X, src.Y, source, 0)
+
+	x, y := src.X, src.Y
+	for read != write {
+		x, y = p.queueX[read], p.queueY[read]
+		read = (read + 1) & (queueSize - 1)
+
+		if strategy.Reached(p.Grid, Tile{Plane: plane, X: x, Y: y}, 1, dst) {
+			return x, y, true
+		}
+
+		localX, localY := x-baseX, y-baseY
+		distance := p.distances[localX][localY] + 1
+		flags := func(dx, dy int) int { return p.Grid.Flags(plane, x+dx, y+dy) }
+
+		if localX > 0 && p.directions[localX-1][localY] == 0 && flags(-1, 0)&blockWest == 0 {
+			p.visit(&write, baseX, baseY, x-1, y, west, distance)
+		}
+
+		if localX < GraphSize-1 && p.directions[localX+1][localY] == 0 && flags(1, 0)&blockEast == 0 {
+			p.visit(&write, baseX, baseY, x+1, y, east, distance)
+		}
+
+		if localY > 0 && p.directions[localX][localY-1] == 0 && flags(0, -1)&blockSouth == 0 {
+			p.visit(&write, baseX, baseY, x, y-1, south, distance)
+		}
+
+		if localY < GraphSize-1 && p.directions[localX][localY+1] == 0 && flags(0, 1)&blockNorth == 0 {
+			p.visit(&write, baseX, baseY, x, y+1, north, distance)
+		}
+
+		if localX > 0 && localY > 0 && p.directions[localX-1][localY-1] == 0 &&
+			flags(-1, -1)&blockSouthWest == 0 && flags(-1, 0)&blockWest == 0 && flags(0, -1)&blockSouth == 0 {
+			p.visit(&write, baseX, baseY, x-1, y-1, south|west, distance)
+		}
+
+		if localX < GraphSize-1 && localY > 0 && p.directions[localX+1][localY-1] == 0 &&
+			flags(1, -1)&blockSouthEast == 0 && flags(1, 0)&blockEast == 0 && flags(0, -1)&blockSouth == 0 {
+			p.visit(&write, baseX, baseY, x+1, y-1, south|east, distance)
+		}
+
+		if localX > 0 && localY < GraphSize-1 && p.directions[localX-1][localY+1] == 0 &&
+			flags(-1, 1)&blockNorthWest == 0 && flags(-1, 0)&blockWest == 0 && flags(0, 1)&blockNorth == 0 {
+			p.visit(&write, baseX, baseY, x-1, y+1, north|west, distance)
+		}
+
+		if localX < GraphSize-1 && localY < GraphSize-1 && p.directions[localX+1][localY+1] == 0 &&
+			flags(1, 1)&blockNorthEast == 0 && flags(1, 0)&blockEast == 0 && flags(0, 1)&blockNorth == 0 {
+			p.visit(&write, baseX, baseY, x+1, y+1, north|east, distance)
+		}
+	}
+
+	return x, y, false
+}
+
+// searchN searches breadth-first for a path for an entity of the given size, of which
+// the coordinates are those of its south-western tile, returning the tile at which the
+// entity arrives at the destination, or the last tile visited, and whether it arrives.
+func (p *Pathfinder) searchN(src, dst Tile, size int, strategy Strategy) (int, int, bool) {
+	baseX, baseY := src.X-GraphSize/2, src.Y-GraphSize/2
+	plane := src.Plane
+
+	read, write := 0, 0
+	p.visit(&write, baseX, baseY, src.X, src.Y, source, 0)
+
+	x, y := src.X, src.Y
+	for read != write {
+		x, y = p.queueX[read], p.queueY[read]
+		read = (read + 1) & (queueSize - 1)
+
+		if strategy.Reached(p.Grid, Tile{Plane: plane, X: x, Y: y}, size, dst) {
+			return x, y, true
+		}
+
+		localX, localY := x-baseX, y-baseY
+		distance := p.distances[localX][localY] + 1
+		flags := func(dx, dy int) int { return p.Grid.Flags(plane, x+dx, y+dy) }
+
+		// edge returns whether none of the tiles along an edge that is entered is blocked
+		edge := func(from, to int, tile func(i int) (int, int), mask int) bool {
+			for i := from; i < to; i++ {
+				if dx, dy := tile(i); flags(dx, dy)&mask != 0 {
+					return false
+				}
+			}
+
+			return true
+		}
+
+		westEdge := func(i int) (int, int) { return -1, i }
+		eastEdge := func(i int) (int, int) { return size, i }
+		southEdge := func(i int) (int, int) { return i, -1 }
+		northEdge := func(i int) (int, int) { return i, size }
+
+		if localX > 0 && p.directions[localX-1][localY] == 0 &&
+			flags(-1, 0)&blockSouthWest == 0 && flags(-1, size-1)&blockNorthWest == 0 &&
+			edge(1, size-1, westEdge, blockWestEdge) {
+			p.visit(&write, baseX, baseY, x-1, y, west, distance)
+		}
+
+		if localX < GraphSize-size && p.directions[localX+1][localY] == 0 &&
+			flags(size, 0)&blockSouthEast == 0 && flags(size, size-1)&blockNorthEast == 0 &&
+			edge(1, size-1, eastEdge, blockEastEdge) {
+			p.visit(&write, baseX, baseY, x+1, y, east, distance)
+		}
+
+		if localY > 0 && p.directions[localX][localY-1] == 0 &&
+			flags(0, -1)&blockSouthWest == 0 && flags(size-1, -1)&blockSouthEast == 0 &&
+			edge(1, size-1, southEdge, blockSouthEdge) {
+			p.visit(&write, baseX, baseY, x, y-1, south, distance)
+		}
+
+		if localY < GraphSize-size && p.directions[localX][localY+1] == 0 &&
+			flags(0, size)&blockNorthWest == 0 && flags(size-1, size)&blockNorthEast == 0 &&
+			edge(1, size-1, northEdge, blockNorthEdge) {
+			p.visit(&write, baseX, baseY, x, y+1, north, distance)
+		}
+
+		if localX > 0 && localY > 0 && p.directions[localX-1][localY-1] == 0 &&
+			flags(-1, -1)&blockSouthWest == 0 &&
+			edge(1, size, func(i int) (int, int) { return -1, i - 1 }, blockWestEdge) &&
+			edge(1, size, func(i int) (int, int) { return i - 1, -1 }, blockSouthEdge) {
+			p.visit(&write, baseX, baseY, x-1, y-1, south|west, distance)
+		}
+
+		if localX < GraphSize-size && localY > 0 && p.directions[localX+1][localY-1] == 0 &&
+			flags(size, -1)&blockSouthEast == 0 &&
+			edge(1, size, func(i int) (int, int) { return size, i - 1 }, blockEastEdge) &&
+			edge(1, size, func(i int) (int, int) { return i, -1 }, blockSouthEdge) {
+			p.visit(&write, baseX, baseY, x+1, y-1, south|east, distance)
+		}
+
+		if localX > 0 && localY < GraphSize-size && p.directions[localX-1][localY+1] == 0 &&
+			flags(-1, size)&blockNorthWest == 0 &&
+			edge(1, size, func(i int) (int, int) { return -1, i }, blockWestEdge) &&
+			edge(1, size, func(i int) (int, int) { return i - 1, size }, blockNorthEdge) {
+			p.visit(&write, baseX, baseY, x-1, y+1, north|west, distance)
+		}
+
+		if localX < GraphSize-size && localY < GraphSize-size && p.directions[localX+1][localY+1] == 0 &&
+			flags(size, size)&blockNorthEast == 0 &&
+			edge(1, size, func(i int) (int, int) { return i, size }, blockNorthEdge) &&
+			edge(1, size, func(i int) (int, int) { return size, i }, blockEastEdge) {
+			p.visit(&write, baseX, baseY, x+1, y+1, north|east, distance)
+		}
+	}
+
+	return x, y, false
+}
@@ -0,0 +1,186 @@
+package pathfinding
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira/collision"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/mapdata"
+)
+
+var (
+	wall  = &config.LocType{SizeX: 1, SizeY: 1, InteractType: 2, BlocksProjectile: true}
+	fence = &config.LocType{SizeX: 1, SizeY: 1, InteractType: 2}
+)
+
+// newTestMap constructs a collision map of which the regions around the tile of 3200, 3200
+// are loaded and free of collisions.
+func newTestMap() *collision.Map {
+	m := collision.NewMap()
+	for x := 49; x <= 50; x++ {
+		for y := 49; y <= 50; y++ {
+			m.AddRegion(&mapdata.Region{Id: mapdata.RegionId(x, y), Terrain: &mapdata.Terrain{}}, nil)
+		}
+	}
+
+	return m
+}
+
+func tiles(coordinates ...int) []Tile {
+	path := []Tile{}
+	for i := 0; i < len(coordinates); i += 2 {
+		path = append(path, Tile{X: coordinates[i], Y: coordinates[i+1]})
+	}
+
+	return path
+}
+
+func TestFindPath(t *testing.T) {
+	walled := newTestMap()
+	for y := 3195; y <= 3205; y++ {
+		walled.AddLocation(0, 3210, y, 0, 0, wall)
+	}
+
+	tests := []struct {
+		name     string
+		grid     Grid
+		dst      Tile
+		size     int
+		strategy Strategy
+		expected []Tile
+	}{
+		{"arrived", newTestMap(), Tile{X: 3200, Y: 3200}, 1, TileStrategy{}, tiles()},
+		{"straight", newTestMap(), Tile{X: 3205, Y: 3200}, 1, TileStrategy{}, tiles(3205, 3200)},
+		{"diagonal", newTestMap(), Tile{X: 3203, Y: 3205}, 1, TileStrategy{}, tiles(3200, 3202, 3203, 3205)},
+		{"around a wall", walled, Tile{X: 3215, Y: 3200}, 1, TileStrategy{},
+			tiles(3203, 3200, 3209, 3194, 3210, 3194, 3210, 3195, 3215, 3200)},
+		{"to a door", newTestMap(), Tile{X: 3205, Y: 3200}, 1, WallStrategy{Shape: 0, Orientation: 0}, tiles(3204, 3200)},
+		{"to the south of a door", newTestMap(), Tile{X: 3205, Y: 3210}, 1, WallStrategy{Shape: 0, Orientation: 3}, tiles(3200, 3204, 3205, 3209)},
+	}
+
+	for _, test := range tests {
+		path, err := FindPath(test.grid, Tile{X: 3200, Y: 3200}, test.dst, test.size, test.strategy)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(path, test.expected) {
+			t.Errorf("%v: expected %v but got %v", test.name, test.expected, path)
+		}
+	}
+}
+
+func TestFindPathToClosest(t *testing.T) {
+	m := newTestMap()
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			m.AddFlags(0, 3205+dx, 3205+dy, collision.Object)
+		}
+	}
+
+	path, err := FindPath(m, Tile{X: 3200, Y: 3200}, Tile{X: 3205, Y: 3205}, 1, TileStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if end := path[len(path)-1]; end != (Tile{X: 3203, Y: 3205}) {
+		t.Errorf("expected to end at the closest tile but ended at %v", end)
+	}
+
+	pathfinder := NewPathfinder(m)
+	pathfinder.Closest = false
+
+	if _, err := pathfinder.FindPath(Tile{X: 3200, Y: 3200}, Tile{X: 3205, Y: 3205}, 1, TileStrategy{}); err != ErrNoPath {
+		t.Errorf("expected %v but got %v", ErrNoPath, err)
+	}
+}
+
+func TestFindPathForLargeEntities(t *testing.T) {
+	m := newTestMap()
+	for y := 3190; y <= 3210; y++ {
+		if y != 3200 {
+			m.AddLocation(0, 3205, y, 10, 0, fence)
+		}
+	}
+
+	path, err := FindPath(m, Tile{X: 3200, Y: 3200}, Tile{X: 3210, Y: 3200}, 1, TileStrategy{})
+	if err != nil || !reflect.DeepEqual(path, tiles(3210, 3200)) {
+		t.Errorf("expected a single tile to walk through the gap but got %v, %v", path, err)
+	}
+
+	path, err = FindPath(m, Tile{X: 3200, Y: 3200}, Tile{X: 3210, Y: 3200}, 2, TileStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(path) < 2 || path[len(path)-1] != (Tile{X: 3210, Y: 3200}) {
+		t.Errorf("expected two tiles to walk around the fence but got %v", path)
+	}
+
+	for _, checkpoint := range path {
+		if checkpoint.X == 3205 && checkpoint.Y >= 3190 && checkpoint.Y <= 3210 {
+			t.Errorf("expected two tiles not to fit through the gap but got %v", path)
+		}
+	}
+}
+
+func TestRectangleStrategy(t *testing.T) {
+	m := newTestMap()
+
+	table := &config.LocType{SizeX: 2, SizeY: 1, InteractType: 2, BlockingMask: AccessNorth}
+	m.AddLocation(0, 3205, 3205, 10, 1, table)
+
+	strategy := LocStrategy(table, 10, 1)
+	if expected := (RectangleStrategy{SizeX: 1, SizeY: 2, AccessFlags: AccessEast}); strategy != expected {
+		t.Fatalf("expected %+v but got %+v", expected, strategy)
+	}
+
+	rectangle := strategy.(RectangleStrategy)
+	rectangle.AccessFlags |= AccessWest | AccessSouth
+
+	path, err := FindPath(m, Tile{X: 3200, Y: 3200}, Tile{X: 3205, Y: 3205}, 1, rectangle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if end := path[len(path)-1]; end != (Tile{X: 3205, Y: 3207}) {
+		t.Errorf("expected to approach from the north but ended at %v", end)
+	}
+
+	if !rectangle.Reached(m, Tile{X: 3205, Y: 3206}, 1, Tile{X: 3205, Y: 3205}) {
+		t.Errorf("expected to have arrived on the rectangle")
+	}
+
+	if rectangle.Reached(m, Tile{X: 3206, Y: 3207}, 1, Tile{X: 3205, Y: 3205}) {
+		t.Errorf("expected not to arrive diagonally")
+	}
+}
+
+func TestHasLineOfSight(t *testing.T) {
+	m := newTestMap()
+	m.AddLocation(0, 3205, 3200, 10, 0, wall)
+	m.AddLocation(0, 3200, 3207, 0, 1, wall)
+	m.AddLocation(0, 3195, 3200, 10, 0, fence)
+	m.AddFlags(0, 3200, 3203, collision.Floor)
+
+	tests := []struct {
+		dst      Tile
+		expected bool
+	}{
+		{Tile{X: 3210, Y: 3200}, false},
+		{Tile{X: 3190, Y: 3200}, true},
+		{Tile{X: 3200, Y: 3206}, true},
+		{Tile{X: 3200, Y: 3210}, false},
+		{Tile{X: 3220, Y: 3201}, false},
+		{Tile{X: 3210, Y: 3202}, true},
+		{Tile{X: 3210, Y: 3210}, true},
+	}
+
+	for _, test := range tests {
+		if actual := HasLineOfSight(m, Tile{X: 3200, Y: 3200}, test.dst); actual != test.expected {
+			t.Errorf("expected a line of sight to %v to be %v", test.dst, test.expected)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package pathfinding
+
+import (
+	"github.com/sinoz/gokira/collision"
+	"github.com/sinoz/gokira/config"
+)
+
+// Strategy determines when an entity has arrived at its destination.
+type Strategy interface {
+	// Size returns the size of the destination along each axis, which the search for the
+	// closest reachable tile measures the distance to.
+	Size() (int, int)
+
+	// Reached returns whether an entity of the given size at the given tile has arrived
+	// at the given destination.
+	Reached(grid Grid, tile Tile, size int, dst Tile) bool
+}
+
+// The sides of a rectangle that the access flags of a RectangleStrategy block the approach
+// from.
+const (
+	AccessNorth = 1
+	AccessEast  = 2
+	AccessSouth = 4
+	AccessWest  = 8
+)
+
+// The masks of the collision flags that block reaching a wall from the tile next to it,
+// named after the side of the wall that the tile lies on.
+const (
+	reachNorth = 0x1280120
+	reachSouth = 0x1280102
+	reachWest  = 0x1280108
+	reachEast  = 0x1280180
+)
+
+// The shapes of locations that strategies distinguish, see mapdata.Location.
+const (
+	straightWallShape    = 0
+	wallCornerShape      = 2
+	diagonalOutsideShape = 6
+	diagonalInsideShape  = 7
+	diagonalBothShape    = 8
+	diagonalWallShape    = 9
+	centrepieceShape     = 10
+)
+
+// TileStrategy arrives at the destination tile itself, which is how the client walks to
+// the tiles players click on.
+type TileStrategy struct{}
+
+// Size returns a size of a single tile.
+func (TileStrategy) Size() (int, int) {
+	return 1, 1
+}
+
+// Reached returns whether the tile is the destination.
+func (TileStrategy) Reached(grid Grid, tile Tile, size int, dst Tile) bool {
+	return tile.X == dst.X && tile.Y == dst.Y
+}
+
+// RectangleStrategy arrives next to a rectangle of the given size of which the destination
+// is the south-western tile, such as a location or another entity, unless a wall stands in
+// between or the access flags block the approach from that side.
+type RectangleStrategy struct {
+	SizeX       int
+	SizeY       int
+	AccessFlags int
+}
+
+// Size returns the size of the rectangle.
+func (strategy RectangleStrategy) Size() (int, int) {
+	return strategy.SizeX, strategy.SizeY
+}
+
+// Reached returns whether an entity of the given size at the given tile overlaps the
+// rectangle or is next to one of its sides without a wall in between.
+func (strategy RectangleStrategy) Reached(grid Grid, tile Tile, size int, dst Tile) bool {
+	srcMaxX, srcMaxY := tile.X+size-1, tile.Y+size-1
+	dstMaxX, dstMaxY := dst.X+strategy.SizeX-1, dst.Y+strategy.SizeY-1
+
+	overlapsX := tile.X <= dstMaxX && srcMaxX >= dst.X
+	overlapsY := tile.Y <= dstMaxY && srcMaxY >= dst.Y
+	if overlapsX && overlapsY {
+		return true
+	}
+
+	// open returns whether any of the tiles along the given side of the entity that
+	// face the rectangle lacks the given wall
+	open := func(x, y, dx, dy, from, to, wall int) bool {
+		for i := from; i <= to; i++ {
+			if grid.Flags(tile.Plane, x+dx*i, y+dy*i)&wall == 0 {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	minY, maxY := maxInt(tile.Y, dst.Y), minInt(srcMaxY, dstMaxY)
+	minX, maxX := maxInt(tile.X, dst.X), minInt(srcMaxX, dstMaxX)
+
+	switch {
+	case srcMaxX+1 == dst.X && overlapsY && strategy.AccessFlags&AccessWest == 0:
+		return open(srcMaxX, 0, 0, 1, minY, maxY, collision.WallEast)
+	case tile.X == dstMaxX+1 && overlapsY && strategy.AccessFlags&AccessEast == 0:
+		return open(tile.X, 0, 0, 1, minY, maxY, collision.WallWest)
+	case srcMaxY+1 == dst.Y && overlapsX && strategy.AccessFlags&AccessSouth == 0:
+		return open(0, srcMaxY, 1, 0, minX, maxX, collision.WallNorth)
+	case tile.Y == dstMaxY+1 && overlapsX && strategy.AccessFlags&AccessNorth == 0:
+		return open(0, tile.Y, 1, 0, minX, maxX, collision.WallSouth)
+	}
+
+	return false
+}
+
+// WallStrategy arrives at a wall of the given shape and orientation, or at a wall
+// decoration, standing on the tile of the wall or on a side of it that the wall does not
+// block.
+type WallStrategy struct {
+	Shape       int
+	Orientation int
+}
+
+// Size returns a size of a single tile.
+func (WallStrategy) Size() (int, int) {
+	return 1, 1
+}
+
+// Reached returns whether an entity at the given tile can reach the wall. Entities that
+// are larger than a tile reach walls like they reach a rectangle of a single tile.
+func (strategy WallStrategy) Reached(grid Grid, tile Tile, size int, dst Tile) bool {
+	if size > 1 {
+		return RectangleStrategy{SizeX: 1, SizeY: 1}.Reached(grid, tile, size, dst)
+	}
+
+	if tile.X == dst.X && tile.Y == dst.Y {
+		return true
+	}
+
+	flags := grid.Flags(tile.Plane, tile.X, tile.Y)
+	dx, dy := tile.X-dst.X, tile.Y-dst.Y
+
+	// free returns whether the tile is at the given offset from the wall, and whether the
+	// tile lacks the given flags if any are given
+	free := func(x, y, mask int) bool {
+		return dx == x && dy == y && flags&mask == 0
+	}
+
+	orientation := strategy.Orientation & 3
+
+	switch strategy.Shape {
+	case straightWallShape:
+		switch orientation {
+		case 0:
+			return free(-1, 0, 0) || free(0, 1, reachNorth) || free(0, -1, reachSouth)
+		case 1:
+			return free(0, 1, 0) || free(-1, 0, reachWest) || free(1, 0, reachEast)
+		case 2:
+			return free(1, 0, 0) || free(0, 1, reachNorth) || free(0, -1, reachSouth)
+		default:
+			return free(0, -1, 0) || free(-1, 0, reachWest) || free(1, 0, reachEast)
+		}
+
+	case wallCornerShape:
+		switch orientation {
+		case 0:
+			return free(-1, 0, 0) || free(0, 1, 0) || free(1, 0, reachEast) || free(0, -1, reachSouth)
+		case 1:
+			return free(-1, 0, reachWest) || free(0, 1, 0) || free(1, 0, 0) || free(0, -1, reachSouth)
+		case 2:
+			return free(-1, 0, reachWest) || free(0, 1, reachNorth) || free(1, 0, 0) || free(0, -1, 0)
+		default:
+			return free(-1, 0, 0) || free(0, 1, reachNorth) || free(1, 0, reachEast) || free(0, -1, 0)
+		}
+
+	case diagonalOutsideShape, diagonalInsideShape:
+		if strategy.Shape == diagonalInsideShape {
+			orientation = (orientation + 2) & 3
+		}
+
+		switch orientation {
+		case 0:
+			return free(1, 0, collision.WallWest) || free(0, -1, collision.WallNorth)
+		case 1:
+			return free(-1, 0, collision.WallEast) || free(0, -1, collision.WallNorth)
+		case 2:
+			return free(-1, 0, collision.WallEast) || free(0, 1, collision.WallSouth)
+		default:
+			return free(1, 0, collision.WallWest) || free(0, 1, collision.WallSouth)
+		}
+
+	case diagonalBothShape, diagonalWallShape:
+		return free(0, 1, collision.WallSouth) || free(0, -1, collision.WallNorth) ||
+			free(-1, 0, collision.WallEast) || free(1, 0, collision.WallWest)
+	}
+
+	return false
+}
+
+// LocStrategy returns the Strategy that the client uses to reach a location of the given
+// loc type, shape and orientation: walls and wall decorations are reached through a
+// WallStrategy and every other shape through a RectangleStrategy of the size of the
+// location, turned by its orientation. Like in the client, the corners of walls and the
+// straight wall decorations are only reached by standing on their tile.
+func LocStrategy(loc *config.LocType, shape, orientation int) Strategy {
+	if shape < centrepieceShape {
+		return WallStrategy{Shape: shape, Orientation: orientation}
+	}
+
+	sizeX, sizeY := loc.SizeX, loc.SizeY
+	if orientation == 1 || orientation == 3 {
+		sizeX, sizeY = sizeY, sizeX
+	}
+
+	// the access flags are turned clockwise along with the location
+	access := loc.BlockingMask
+	if orientation != 0 {
+		access = (access<<uint(orientation))&0xF | access>>uint(4-orientation)
+	}
+
+	return RectangleStrategy{SizeX: sizeX, SizeY: sizeY, AccessFlags: access}
+}
+
+// minInt returns the smallest of the given values.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the largest of the given values.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,378 @@
+package render
+
+import (
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/font"
+	"github.com/sinoz/gokira/sprite"
+	"github.com/sinoz/gokira/widget"
+)
+
+const (
+	// PlaceholderColor is the 24-bit RGB color of the boxes that are drawn in place of
+	// the models of model components.
+	PlaceholderColor = 0x808080
+
+	// justify is the mode of alignment that spreads lines of text evenly over the
+	// height of their component.
+	justify = 3
+)
+
+// InterfaceRenderer draws interfaces the way the client lays them out, without running
+// any of their scripts. Models are drawn as placeholders, as are their animations.
+type InterfaceRenderer struct {
+	// Sprites and Fonts load the sprite groups and the fonts that components refer
+	// to by id.
+	Sprites func(id int) (*sprite.Sprite, error)
+	Fonts   func(id int) (*font.Font, error)
+
+	// ShowHidden draws the components that are hidden until a script reveals them.
+	ShowHidden bool
+
+	// Scroll holds the scroll positions of layers, keyed by the id of the component.
+	// Layers that are not listed are scrolled to their top left corner.
+	Scroll map[int]image.Point
+}
+
+// NewInterfaceRenderer constructs an InterfaceRenderer that loads sprites and fonts from
+// the given Cache, decoding each of them once.
+func NewInterfaceRenderer(cache *gokira.Cache) *InterfaceRenderer {
+	sprites := make(map[int]*sprite.Sprite)
+	fonts := make(map[int]*font.Font)
+
+	return &InterfaceRenderer{
+		Sprites: func(id int) (*sprite.Sprite, error) {
+			if s, ok := sprites[id]; ok {
+				return s, nil
+			}
+
+			s, err := sprite.Load(cache, id)
+			if err != nil {
+				return nil, err
+			}
+
+			sprites[id] = s
+			return s, nil
+		},
+
+		Fonts: func(id int) (*font.Font, error) {
+			if f, ok := fonts[id]; ok {
+				return f, nil
+			}
+
+			f, err := font.LoadById(cache, id)
+			if err != nil {
+				return nil, err
+			}
+
+			fonts[id] = f
+			return f, nil
+		},
+	}
+}
+
+// RenderInterface draws the given interface onto a viewport of the given size with the
+// sprites and fonts of the given Cache and writes it as a PNG image. May return an error.
+func RenderInterface(w io.Writer, cache *gokira.Cache, iface *widget.Interface, width, height int) error {
+	return NewInterfaceRenderer(cache).WritePNG(w, iface, width, height)
+}
+
+// Render draws the given interface onto a viewport of the given size, of which the pixels
+// that no component covers are transparent. May return an error.
+func (r *InterfaceRenderer) Render(iface *widget.Interface, width, height int) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := r.drawComponents(img, iface.Roots, img.Bounds()); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// WritePNG draws the given interface onto a viewport of the given size and writes it as
+// a PNG image. May return an error.
+func (r *InterfaceRenderer) WritePNG(w io.Writer, iface *widget.Interface, width, height int) error {
+	img, err := r.Render(iface, width, height)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// drawComponents lays out the given siblings within the given bounds of their parent
+// and draws them, parents before their children, clipped to the bounds of the given
+// image.
+func (r *InterfaceRenderer) drawComponents(img *image.RGBA, components []*widget.Component, parent image.Rectangle) error {
+	for _, component := range components {
+		if component.Hidden && !r.ShowHidden {
+			continue
+		}
+
+		bounds := component.Layout(parent.Dx(), parent.Dy()).Add(parent.Min)
+		if err := r.drawComponent(img, component, bounds); err != nil {
+			return err
+		}
+
+		if len(component.Children) == 0 {
+			continue
+		}
+
+		// the children of a layer are clipped to the layer and moved by its scroll
+		// position, while the size they are laid out against remains that of the layer
+		clip := img
+		if component.Type == widget.LayerType {
+			clip = img.SubImage(bounds).(*image.RGBA)
+		}
+
+		scroll := r.Scroll[component.Id]
+		if err := r.drawComponents(clip, component.Children, bounds.Sub(scroll)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drawComponent draws the given component within the given bounds.
+func (r *InterfaceRenderer) drawComponent(img *image.RGBA, component *widget.Component, bounds image.Rectangle) error {
+	alpha := 256 - component.Transparency&0xFF
+
+	switch component.Type {
+	case widget.RectangleType:
+		if component.Filled {
+			fillRectangle(img, bounds, component.Color, alpha)
+		} else {
+			outlineRectangle(img, bounds, component.Color, alpha)
+		}
+
+	case widget.LineType:
+		if component.LineDirection {
+			drawLine(img, bounds.Min.X, bounds.Max.Y, bounds.Max.X, bounds.Min.Y, component.Color, alpha)
+		} else {
+			drawLine(img, bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y, component.Color, alpha)
+		}
+
+	case widget.SpriteType:
+		return r.drawSprite(img, component, bounds, alpha)
+
+	case widget.TextType:
+		return r.drawText(img, component, bounds)
+
+	case widget.ModelType:
+		if component.ModelId != -1 || component.ModelType > 1 {
+			drawPlaceholder(img, bounds)
+		}
+	}
+
+	return nil
+}
+
+// drawSprite draws the sprite of the given component, which is tiled across its bounds
+// if the component tiles it and stretched to its bounds if it is an if3 component.
+func (r *InterfaceRenderer) drawSprite(img *image.RGBA, component *widget.Component, bounds image.Rectangle, alpha int) error {
+	if component.SpriteId == -1 {
+		return nil
+	}
+
+	s, err := r.Sprites(component.SpriteId)
+	if err != nil {
+		return err
+	}
+
+	canvas := s.Canvas(0)
+	width, height := canvas.Bounds().Dx(), canvas.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	tile := component.SpriteTiling
+	stretch := component.If3 && !tile
+	if !tile && !stretch {
+		bounds = image.Rectangle{Min: bounds.Min, Max: bounds.Min.Add(image.Pt(width, height))}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sx, sy := x-bounds.Min.X, y-bounds.Min.Y
+			if tile {
+				sx, sy = sx%width, sy%height
+			} else if stretch {
+				sx, sy = sx*width/bounds.Dx(), sy*height/bounds.Dy()
+			}
+
+			if component.FlipHorizontal {
+				sx = width - 1 - sx
+			}
+
+			if component.FlipVertical {
+				sy = height - 1 - sy
+			}
+
+			pixel := canvas.NRGBAAt(sx, sy)
+			if pixel.A != 0 {
+				blend(img, x, y, int(pixel.R)<<16|int(pixel.G)<<8|int(pixel.B), alpha*int(pixel.A)/0xFF)
+			}
+		}
+	}
+
+	return nil
+}
+
+// drawText draws the text of the given component within its bounds with the font of
+// the component, wrapping it to the width of the component and aligning the lines like
+// the client does.
+func (r *InterfaceRenderer) drawText(img *image.RGBA, component *widget.Component, bounds image.Rectangle) error {
+	if component.FontId == -1 || component.Text == "" {
+		return nil
+	}
+
+	f, err := r.Fonts(component.FontId)
+	if err != nil {
+		return err
+	}
+
+	maxAscent, maxDescent := f.MaxAscent(), f.MaxDescent()
+	lineHeight := component.LineHeight
+	if lineHeight == 0 {
+		lineHeight = f.Metrics.Ascent
+	}
+
+	// text is only wrapped if the component is tall enough for more than one line
+	var lines []string
+	if bounds.Dy() < lineHeight+maxAscent+maxDescent && bounds.Dy() < 2*lineHeight {
+		lines = f.WrapText(component.Text)
+	} else {
+		lines = f.WrapText(component.Text, bounds.Dx())
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	alignment := component.TextAlignmentY
+	if alignment == justify && len(lines) == 1 {
+		alignment = widget.AlignCenter
+	}
+
+	spacing := lineHeight * (len(lines) - 1)
+
+	var y int
+	switch alignment {
+	case widget.AlignStart:
+		y = bounds.Min.Y + maxAscent
+	case widget.AlignCenter:
+		y = bounds.Min.Y + (bounds.Dy()-maxAscent-maxDescent-spacing)/2 + maxAscent
+	case widget.AlignEnd:
+		y = bounds.Max.Y - maxDescent - spacing
+	default:
+		gap := (bounds.Dy() - maxAscent - maxDescent - spacing) / (len(lines) + 1)
+		if gap < 0 {
+			gap = 0
+		}
+
+		y = bounds.Min.Y + maxAscent + gap
+		lineHeight += gap
+	}
+
+	for _, line := range lines {
+		x := bounds.Min.X
+		switch component.TextAlignmentX {
+		case widget.AlignCenter:
+			x += (bounds.Dx() - f.MeasureText(line)) / 2
+		case widget.AlignEnd:
+			x = bounds.Max.X - f.MeasureText(line)
+		}
+
+		if component.TextShadowed {
+			f.Draw(img, line, x+1, y+1, 0)
+		}
+
+		f.Draw(img, line, x, y, component.Color)
+		y += lineHeight
+	}
+
+	return nil
+}
+
+// drawPlaceholder marks the given bounds of a model with a translucent box that is
+// crossed by its diagonals.
+func drawPlaceholder(img *image.RGBA, bounds image.Rectangle) {
+	fillRectangle(img, bounds, PlaceholderColor, 64)
+	outlineRectangle(img, bounds, PlaceholderColor, 256)
+
+	drawLine(img, bounds.Min.X, bounds.Min.Y, bounds.Max.X-1, bounds.Max.Y-1, PlaceholderColor, 256)
+	drawLine(img, bounds.Min.X, bounds.Max.Y-1, bounds.Max.X-1, bounds.Min.Y, PlaceholderColor, 256)
+}
+
+// fillRectangle blends the pixels within the given bounds with the given color.
+func fillRectangle(img *image.RGBA, bounds image.Rectangle, rgb, alpha int) {
+	bounds = bounds.Intersect(img.Bounds())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			blend(img, x, y, rgb, alpha)
+		}
+	}
+}
+
+// outlineRectangle blends the pixels along the inner edge of the given bounds with the
+// given color.
+func outlineRectangle(img *image.RGBA, bounds image.Rectangle, rgb, alpha int) {
+	if bounds.Empty() {
+		return
+	}
+
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		blend(img, x, bounds.Min.Y, rgb, alpha)
+		if bounds.Dy() > 1 {
+			blend(img, x, bounds.Max.Y-1, rgb, alpha)
+		}
+	}
+
+	for y := bounds.Min.Y + 1; y < bounds.Max.Y-1; y++ {
+		blend(img, bounds.Min.X, y, rgb, alpha)
+		if bounds.Dx() > 1 {
+			blend(img, bounds.Max.X-1, y, rgb, alpha)
+		}
+	}
+}
+
+// drawLine blends the pixels of a line of a single pixel wide between the given points
+// with the given color.
+func drawLine(img *image.RGBA, x0, y0, x1, y1, rgb, alpha int) {
+	dx, dy := x1-x0, y1-y0
+	steps := maxInt(absInt(dx), absInt(dy))
+	if steps == 0 {
+		blend(img, x0, y0, rgb, alpha)
+		return
+	}
+
+	for i := 0; i <= steps; i++ {
+		blend(img, x0+(dx*i+steps/2)/steps, y0+(dy*i+steps/2)/steps, rgb, alpha)
+	}
+}
+
+// blend blends the pixel at the given position with the given color by the given alpha,
+// where 256 is opaque, unless the position lies outside the bounds of the image.
+func blend(img *image.RGBA, x, y, rgb, alpha int) {
+	if !image.Pt(x, y).In(img.Bounds()) {
+		return
+	}
+
+	i := img.PixOffset(x, y)
+	source := [4]int{rgb >> 16 & 0xFF, rgb >> 8 & 0xFF, rgb & 0xFF, 0xFF}
+	for channel, value := range source {
+		img.Pix[i+channel] = uint8((value*alpha + int(img.Pix[i+channel])*(256-alpha)) >> 8)
+	}
+}
+
+// absInt returns the absolute value of the given value.
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
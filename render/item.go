@@ -0,0 +1,103 @@
+package render
+
+import (
+	"image"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/model"
+)
+
+const (
+	// IconWidth and IconHeight are the size of the icon of an item.
+	IconWidth  = 36
+	IconHeight = 32
+
+	// DefaultShadowColor is the color of the shadow of the icons of items in inventories.
+	DefaultShadowColor = 0x302020
+)
+
+// IconOptions configures how the icon of an item is drawn.
+type IconOptions struct {
+	// Border is 1 to outline the icon in black, as the client does for items that are
+	// selected, or 2 to additionally outline it in white and enlarge it slightly.
+	Border int
+
+	// ShadowColor is the 24-bit RGB color of the shadow that is cast to the lower
+	// right of the icon, or 0 to cast no shadow.
+	ShadowColor int
+
+	// Textures are the definitions of the textures of the texture archive, indexed
+	// by id. Textured faces are drawn with the average color of their texture.
+	Textures []*config.TextureType
+}
+
+// DefaultIconOptions are the options of the icons of items in inventories.
+var DefaultIconOptions = IconOptions{ShadowColor: DefaultShadowColor}
+
+// RenderItemIcon loads the model of the given item and draws its icon. May return an error.
+func RenderItemIcon(cache *gokira.Cache, item *config.ItemType, options IconOptions) (*image.RGBA, error) {
+	m, err := model.LoadItem(cache, item)
+	if err != nil {
+		return nil, err
+	}
+
+	return ItemIcon(m, item, options), nil
+}
+
+// ItemIcon draws the icon of the given item with the given model of the item, which is
+// posed by the 2d zoom, angles and offsets of the item and lit by its ambient and
+// contrast, like the client draws the icons of items in inventories.
+func ItemIcon(m *model.Model, item *config.ItemType, options IconOptions) *image.RGBA {
+	zoom := item.Zoom2d
+	if options.Border == 2 {
+		zoom = int(float64(zoom) * 1.04)
+	}
+
+	pitch := item.XAngle2d & (angleCount - 1)
+
+	// the model is raised by half of its height so that it is centred, and the
+	// camera orbits around it at the distance of the zoom
+	var height int
+	for _, y := range m.VertexY {
+		height = maxInt(height, -y)
+	}
+
+	camera := &Camera{
+		Width:   IconWidth,
+		Height:  IconHeight,
+		CenterX: IconWidth / 2,
+		CenterY: IconHeight / 2,
+		Pitch:   pitch,
+		Yaw:     item.YAngle2d & (angleCount - 1),
+		Roll:    item.ZAngle2d & (angleCount - 1),
+		X:       item.XOffset2d,
+		Y:       height/2 + zoom*sine[pitch]>>16 + item.YOffset2d,
+		Z:       zoom*cosine[pitch]>>16 + item.YOffset2d,
+		Lighting: Lighting{
+			Ambient:  DefaultLighting.Ambient + item.Ambient,
+			Contrast: DefaultLighting.Contrast + item.Contrast,
+			X:        DefaultLighting.X,
+			Y:        DefaultLighting.Y,
+			Z:        DefaultLighting.Z,
+		},
+		Textures: options.Textures,
+	}
+
+	r := newRaster(IconWidth, IconHeight)
+	r.drawModel(m, camera)
+
+	if options.Border >= 1 {
+		r.outline(1)
+	}
+
+	if options.Border >= 2 {
+		r.outline(0xFFFFFF)
+	}
+
+	if options.ShadowColor != 0 {
+		r.shadow(options.ShadowColor)
+	}
+
+	return r.image()
+}
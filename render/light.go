@@ -0,0 +1,165 @@
+package render
+
+import (
+	"math"
+
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/model"
+)
+
+// Lighting describes how a model is lit, in the units of the client.
+type Lighting struct {
+	Ambient  int
+	Contrast int
+
+	// X, Y and Z point from the light towards the model.
+	X int
+	Y int
+	Z int
+}
+
+// DefaultLighting is the lighting the client applies to the models of items and npcs.
+var DefaultLighting = Lighting{Ambient: 64, Contrast: 768, X: -50, Y: -10, Z: -50}
+
+const (
+	// hiddenFace marks a face that is not drawn.
+	hiddenFace = -2
+
+	// flatFace marks a face of which each vertex has the color of the first one.
+	flatFace = -1
+)
+
+// normal is the sum of the normals of the faces that share a vertex.
+type normal struct {
+	x, y, z   int
+	magnitude int
+}
+
+// shade computes the 16-bit HSL color of each vertex of each face of the given model
+// in the way the client does. The third color of a face is flatFace if the face is
+// flat shaded, or hiddenFace if it is not drawn. Textured faces are shaded with the
+// average color of their texture, if known.
+func shade(m *model.Model, lighting Lighting, textures []*config.TextureType) (colorA, colorB, colorC []int) {
+	vertexNormals := make([]normal, m.VertexCount)
+	faceNormals := make([]normal, m.FaceCount)
+
+	for face := 0; face < m.FaceCount; face++ {
+		a, b, c := m.FaceA[face], m.FaceB[face], m.FaceC[face]
+
+		dx1, dy1, dz1 := m.VertexX[b]-m.VertexX[a], m.VertexY[b]-m.VertexY[a], m.VertexZ[b]-m.VertexZ[a]
+		dx2, dy2, dz2 := m.VertexX[c]-m.VertexX[a], m.VertexY[c]-m.VertexY[a], m.VertexZ[c]-m.VertexZ[a]
+
+		nx := dy1*dz2 - dy2*dz1
+		ny := dz1*dx2 - dz2*dx1
+		nz := dx1*dy2 - dx2*dy1
+
+		for nx > 8192 || ny > 8192 || nz > 8192 || nx < -8192 || ny < -8192 || nz < -8192 {
+			nx >>= 1
+			ny >>= 1
+			nz >>= 1
+		}
+
+		length := int(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
+		if length <= 0 {
+			length = 1
+		}
+
+		nx = nx * 256 / length
+		ny = ny * 256 / length
+		nz = nz * 256 / length
+
+		if renderType(m, face) == 1 {
+			faceNormals[face] = normal{x: nx, y: ny, z: nz}
+			continue
+		}
+
+		for _, vertex := range [3]int{a, b, c} {
+			vertexNormals[vertex].x += nx
+			vertexNormals[vertex].y += ny
+			vertexNormals[vertex].z += nz
+			vertexNormals[vertex].magnitude++
+		}
+	}
+
+	magnitude := int(math.Sqrt(float64(lighting.X*lighting.X + lighting.Y*lighting.Y + lighting.Z*lighting.Z)))
+	contrast := magnitude * lighting.Contrast >> 8
+
+	colorA = make([]int, m.FaceCount)
+	colorB = make([]int, m.FaceCount)
+	colorC = make([]int, m.FaceCount)
+
+	for face := 0; face < m.FaceCount; face++ {
+		color := m.FaceColors[face]
+		if m.FaceTextures != nil && m.FaceTextures[face] != -1 {
+			color = textureColor(m.FaceTextures[face], textures)
+		}
+
+		switch renderType(m, face) {
+		case 0:
+			for i, vertex := range [3]int{m.FaceA[face], m.FaceB[face], m.FaceC[face]} {
+				n := vertexNormals[vertex]
+
+				divisor := contrast * n.magnitude
+				if divisor == 0 {
+					divisor = 1
+				}
+
+				lightness := lighting.Ambient + (lighting.X*n.x+lighting.Y*n.y+lighting.Z*n.z)/divisor
+				[3][]int{colorA, colorB, colorC}[i][face] = applyLightness(color, lightness)
+			}
+
+		case 1:
+			n := faceNormals[face]
+
+			divisor := contrast/2 + contrast
+			if divisor == 0 {
+				divisor = 1
+			}
+
+			lightness := lighting.Ambient + (lighting.X*n.x+lighting.Y*n.y+lighting.Z*n.z)/divisor
+			colorA[face] = applyLightness(color, lightness)
+			colorC[face] = flatFace
+
+		case 3:
+			colorA[face] = 128
+			colorC[face] = flatFace
+
+		default:
+			colorC[face] = hiddenFace
+		}
+	}
+
+	return colorA, colorB, colorC
+}
+
+// applyLightness scales the lightness of the given 16-bit HSL color by the given
+// lightness, of which 128 leaves the color unchanged.
+func applyLightness(hsl, lightness int) int {
+	lightness = lightness * (hsl & 127) >> 7
+	if lightness < 2 {
+		lightness = 2
+	} else if lightness > 126 {
+		lightness = 126
+	}
+
+	return hsl&0xFF80 + lightness
+}
+
+// renderType returns the render type of the specified face of the given model.
+func renderType(m *model.Model, face int) int {
+	if m.FaceRenderTypes == nil {
+		return 0
+	}
+
+	return m.FaceRenderTypes[face] & 3
+}
+
+// textureColor returns the average 16-bit HSL color of the texture of the given id,
+// or a light grey if the texture is not known.
+func textureColor(id int, textures []*config.TextureType) int {
+	if id >= 0 && id < len(textures) && textures[id] != nil {
+		return textures[id].AverageColor
+	}
+
+	return 127
+}
@@ -0,0 +1,121 @@
+package render
+
+import (
+	"math"
+
+	"github.com/sinoz/gokira/hsl"
+)
+
+// raster is a buffer of 24-bit RGB pixels, of which the value of zero marks a pixel
+// that has not been drawn, just like the sprites of the client.
+type raster struct {
+	width  int
+	height int
+	pixels []int
+}
+
+// newRaster constructs a raster of the given size of which no pixel has been drawn.
+func newRaster(width, height int) *raster {
+	return &raster{width: width, height: height, pixels: make([]int, width*height)}
+}
+
+// point is a projected vertex along with its 16-bit HSL color.
+type point struct {
+	x, y  float64
+	color int
+}
+
+// drawTriangle fills the triangle between the given points, interpolating the HSL
+// colors of the points across the triangle like the gouraud shading of the client.
+// The triangle is blended with the pixels behind it by the given alpha, where zero
+// is opaque.
+func (r *raster) drawTriangle(a, b, c point, alpha int, palette *hsl.Palette) {
+	minX := int(math.Max(0, math.Floor(math.Min(a.x, math.Min(b.x, c.x)))))
+	maxX := int(math.Min(float64(r.width-1), math.Ceil(math.Max(a.x, math.Max(b.x, c.x)))))
+	minY := int(math.Max(0, math.Floor(math.Min(a.y, math.Min(b.y, c.y)))))
+	maxY := int(math.Min(float64(r.height-1), math.Ceil(math.Max(a.y, math.Max(b.y, c.y)))))
+
+	area := edge(a, b, c.x, c.y)
+	if area == 0 {
+		return
+	}
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+
+			// the weights of the barycentric coordinates of the pixel, of which the
+			// signs all match the sign of the area if the pixel is inside
+			wa := edge(b, c, px, py) / area
+			wb := edge(c, a, px, py) / area
+			wc := edge(a, b, px, py) / area
+
+			if wa < 0 || wb < 0 || wc < 0 {
+				continue
+			}
+
+			color := int(wa*float64(a.color) + wb*float64(b.color) + wc*float64(c.color) + 0.5)
+			r.blend(x+y*r.width, palette.ToRGB(color&0xFFFF), alpha)
+		}
+	}
+}
+
+// blend blends the given color with the pixel at the given index by the given alpha,
+// where zero is opaque.
+func (r *raster) blend(index, rgb, alpha int) {
+	if alpha == 0 {
+		r.pixels[index] = rgb
+		return
+	}
+
+	opacity := 256 - alpha
+	current := r.pixels[index]
+
+	red := ((rgb>>16&0xFF)*opacity + (current>>16&0xFF)*alpha) >> 8
+	green := ((rgb>>8&0xFF)*opacity + (current>>8&0xFF)*alpha) >> 8
+	blue := ((rgb&0xFF)*opacity + (current&0xFF)*alpha) >> 8
+
+	r.pixels[index] = red<<16 | green<<8 | blue
+}
+
+// edge returns twice the signed area of the triangle between the edge from a to b and
+// the given position.
+func edge(a, b point, x, y float64) float64 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// outline draws the given color onto each pixel that has not been drawn, but borders
+// a pixel that has, like the outline of a selected item.
+func (r *raster) outline(color int) {
+	outlined := make([]int, len(r.pixels))
+	copy(outlined, r.pixels)
+
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			index := x + y*r.width
+			if r.pixels[index] != 0 {
+				continue
+			}
+
+			if (x > 0 && r.pixels[index-1] != 0) || (y > 0 && r.pixels[index-r.width] != 0) ||
+				(x < r.width-1 && r.pixels[index+1] != 0) || (y < r.height-1 && r.pixels[index+r.width] != 0) {
+				outlined[index] = color
+			}
+		}
+	}
+
+	r.pixels = outlined
+}
+
+// shadow draws the given color onto each pixel that has not been drawn, but of which
+// the pixel to its upper left has, casting a shadow to the lower right.
+func (r *raster) shadow(color int) {
+	for y := r.height - 1; y > 0; y-- {
+		for x := r.width - 1; x > 0; x-- {
+			index := x + y*r.width
+			if r.pixels[index] == 0 && r.pixels[index-1-r.width] != 0 {
+				r.pixels[index] = color
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package render draws models into images with a software rasterizer that poses, lights
+// and shades models the way the client does, such as for the icons of items.
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/hsl"
+	"github.com/sinoz/gokira/model"
+)
+
+const (
+	// focalLength is the distance from the eye to the screen, in the units of models.
+	focalLength = 512
+
+	// nearPlane is the depth below which faces are not drawn.
+	nearPlane = 50
+)
+
+// Camera poses a model in front of the viewer. The model is rotated around its pivot by
+// its roll, then by its yaw, and moved by the offsets, after which the whole scene is
+// rotated by the pitch. Angles range from 0 to 2047.
+type Camera struct {
+	Width  int
+	Height int
+
+	// CenterX and CenterY are the position on the image that the axis of view
+	// passes through.
+	CenterX int
+	CenterY int
+
+	Pitch int
+	Yaw   int
+	Roll  int
+
+	// PivotX, PivotY and PivotZ are the position within the model it is rotated
+	// around.
+	PivotX int
+	PivotY int
+	PivotZ int
+
+	// X, Y and Z move the model after it has been rotated, where Z moves it away
+	// from the viewer.
+	X int
+	Y int
+	Z int
+
+	Lighting Lighting
+
+	// Textures are the definitions of the textures of the texture archive, indexed
+	// by id. Textured faces are drawn with the average color of their texture.
+	Textures []*config.TextureType
+
+	// Palette converts the colors of faces to RGB. The default palette is used
+	// if it is nil.
+	Palette *hsl.Palette
+}
+
+// NewCamera constructs a Camera of the given size that looks at the center of the given
+// model at the given pitch and yaw, from a distance at which the whole model is in view.
+func NewCamera(width, height int, m *model.Model, pitch, yaw int) *Camera {
+	minX, minY, minZ := math.MaxInt32, math.MaxInt32, math.MaxInt32
+	maxX, maxY, maxZ := math.MinInt32, math.MinInt32, math.MinInt32
+
+	for i := 0; i < m.VertexCount; i++ {
+		minX, maxX = minInt(minX, m.VertexX[i]), maxInt(maxX, m.VertexX[i])
+		minY, maxY = minInt(minY, m.VertexY[i]), maxInt(maxY, m.VertexY[i])
+		minZ, maxZ = minInt(minZ, m.VertexZ[i]), maxInt(maxZ, m.VertexZ[i])
+	}
+
+	camera := &Camera{
+		Width:    width,
+		Height:   height,
+		CenterX:  width / 2,
+		CenterY:  height / 2,
+		Pitch:    pitch & (angleCount - 1),
+		Yaw:      yaw & (angleCount - 1),
+		Lighting: DefaultLighting,
+	}
+
+	if m.VertexCount == 0 {
+		return camera
+	}
+
+	camera.PivotX = (minX + maxX) / 2
+	camera.PivotY = (minY + maxY) / 2
+	camera.PivotZ = (minZ + maxZ) / 2
+
+	var radius float64
+	for i := 0; i < m.VertexCount; i++ {
+		dx := float64(m.VertexX[i] - camera.PivotX)
+		dy := float64(m.VertexY[i] - camera.PivotY)
+		dz := float64(m.VertexZ[i] - camera.PivotZ)
+
+		radius = math.Max(radius, math.Sqrt(dx*dx+dy*dy+dz*dz))
+	}
+
+	// the sphere around the model fits in view once its radius projects onto half
+	// of the shortest side of the image, measured from its nearest point
+	distance := int(radius*focalLength/float64(minInt(width, height)/2)+radius) + 1
+
+	camera.Y = distance * sine[camera.Pitch] >> 16
+	camera.Z = distance * cosine[camera.Pitch] >> 16
+
+	return camera
+}
+
+// Render draws the given model as seen by the given Camera. Pixels that no face covers
+// are transparent.
+func Render(m *model.Model, camera *Camera) *image.RGBA {
+	r := newRaster(camera.Width, camera.Height)
+	r.drawModel(m, camera)
+
+	return r.image()
+}
+
+// RenderModel draws the given model as seen by the given Camera and writes it as a PNG
+// image. May return an error.
+func RenderModel(w io.Writer, m *model.Model, camera *Camera) error {
+	return png.Encode(w, Render(m, camera))
+}
+
+// drawModel draws the faces of the given model as seen by the given Camera, from the
+// back to the front.
+func (r *raster) drawModel(m *model.Model, camera *Camera) {
+	palette := camera.Palette
+	if palette == nil {
+		palette = defaultPalette
+	}
+
+	colorA, colorB, colorC := shade(m, camera.Lighting, camera.Textures)
+	screenX, screenY, depth := project(m, camera)
+
+	type drawable struct {
+		face     int
+		priority int
+		depth    int
+	}
+
+	var faces []drawable
+	for face := 0; face < m.FaceCount; face++ {
+		if colorC[face] == hiddenFace {
+			continue
+		}
+
+		a, b, c := m.FaceA[face], m.FaceB[face], m.FaceC[face]
+		if depth[a] < nearPlane || depth[b] < nearPlane || depth[c] < nearPlane {
+			continue
+		}
+
+		// faces of which the vertices appear in clockwise order face away
+		if (screenX[a]-screenX[b])*(screenY[c]-screenY[b])-(screenY[a]-screenY[b])*(screenX[c]-screenX[b]) <= 0 {
+			continue
+		}
+
+		priority := m.Priority
+		if m.FacePriorities != nil {
+			priority = m.FacePriorities[face]
+		}
+
+		faces = append(faces, drawable{face: face, priority: priority, depth: depth[a] + depth[b] + depth[c]})
+	}
+
+	sort.SliceStable(faces, func(i, j int) bool {
+		if faces[i].priority != faces[j].priority {
+			return faces[i].priority < faces[j].priority
+		}
+
+		return faces[i].depth > faces[j].depth
+	})
+
+	for _, f := range faces {
+		a, b, c := m.FaceA[f.face], m.FaceB[f.face], m.FaceC[f.face]
+
+		pa := point{float64(screenX[a]), float64(screenY[a]), colorA[f.face]}
+		pb := point{float64(screenX[b]), float64(screenY[b]), colorB[f.face]}
+		pc := point{float64(screenX[c]), float64(screenY[c]), colorC[f.face]}
+
+		if colorC[f.face] == flatFace {
+			pb.color, pc.color = pa.color, pa.color
+		}
+
+		alpha := 0
+		if m.FaceAlphas != nil {
+			alpha = m.FaceAlphas[f.face]
+		}
+
+		r.drawTriangle(pa, pb, pc, alpha, palette)
+	}
+}
+
+// project transforms each vertex of the given model into the space of the given Camera
+// and projects it onto the screen in the way the client does.
+func project(m *model.Model, camera *Camera) (screenX, screenY, depth []int) {
+	screenX = make([]int, m.VertexCount)
+	screenY = make([]int, m.VertexCount)
+	depth = make([]int, m.VertexCount)
+
+	rollSine, rollCosine := sine[camera.Roll&(angleCount-1)], cosine[camera.Roll&(angleCount-1)]
+	yawSine, yawCosine := sine[camera.Yaw&(angleCount-1)], cosine[camera.Yaw&(angleCount-1)]
+	pitchSine, pitchCosine := sine[camera.Pitch&(angleCount-1)], cosine[camera.Pitch&(angleCount-1)]
+
+	for i := 0; i < m.VertexCount; i++ {
+		x := m.VertexX[i] - camera.PivotX
+		y := m.VertexY[i] - camera.PivotY
+		z := m.VertexZ[i] - camera.PivotZ
+
+		if camera.Roll != 0 {
+			x, y = (y*rollSine+x*rollCosine)>>16, (y*rollCosine-x*rollSine)>>16
+		}
+
+		if camera.Yaw != 0 {
+			x, z = (z*yawSine+x*yawCosine)>>16, (z*yawCosine-x*yawSine)>>16
+		}
+
+		x += camera.X
+		y += camera.Y
+		z += camera.Z
+
+		y, z = (y*pitchCosine-z*pitchSine)>>16, (y*pitchSine+z*pitchCosine)>>16
+
+		depth[i] = z
+		if z >= nearPlane {
+			screenX[i] = camera.CenterX + (x<<9)/z
+			screenY[i] = camera.CenterY + (y<<9)/z
+		}
+	}
+
+	return screenX, screenY, depth
+}
+
+// image converts the raster into an image, of which the pixels that have not been
+// drawn are transparent.
+func (r *raster) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+
+	for i, rgb := range r.pixels {
+		if rgb == 0 {
+			continue
+		}
+
+		img.SetRGBA(i%r.width, i/r.width, color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xFF})
+	}
+
+	return img
+}
+
+// defaultPalette is the palette of the default brightness of the client.
+var defaultPalette = hsl.NewPalette(hsl.DefaultBrightness)
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
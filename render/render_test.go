@@ -0,0 +1,179 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/font"
+	"github.com/sinoz/gokira/model"
+	"github.com/sinoz/gokira/sprite"
+	"github.com/sinoz/gokira/widget"
+)
+
+// square constructs a flat model of a square of the given color that faces the viewer.
+func square(halfSize, hsl int) *model.Model {
+	return &model.Model{
+		VertexCount: 4,
+		VertexX:     []int{-halfSize, halfSize, halfSize, -halfSize},
+		VertexY:     []int{-halfSize, -halfSize, halfSize, halfSize},
+		VertexZ:     []int{0, 0, 0, 0},
+		FaceCount:   2,
+		FaceA:       []int{0, 0},
+		FaceB:       []int{2, 3},
+		FaceC:       []int{1, 2},
+		FaceColors:  []int{hsl, hsl},
+	}
+}
+
+func TestItemIcon(t *testing.T) {
+	item := &config.ItemType{Zoom2d: 2000}
+
+	icon := ItemIcon(square(32, 0x1234), item, DefaultIconOptions)
+	if icon.Bounds().Dx() != IconWidth || icon.Bounds().Dy() != IconHeight {
+		t.Fatalf("unexpected icon size %v", icon.Bounds())
+	}
+
+	// the square is raised by half of its height, so it lies below the centre
+	if icon.RGBAAt(18, 20).A != 0xFF || icon.RGBAAt(18, 10).A != 0 {
+		t.Errorf("expected only the square to be drawn")
+	}
+
+	shadow := color.RGBA{R: 0x30, G: 0x20, B: 0x20, A: 0xFF}
+	if icon.RGBAAt(26, 28) != shadow || icon.RGBAAt(9, 11).A != 0 {
+		t.Errorf("expected a shadow to the lower right but got %v", icon.RGBAAt(26, 28))
+	}
+
+	outlined := ItemIcon(square(32, 0x1234), item, IconOptions{Border: 1})
+	if outlined.RGBAAt(9, 20) != (color.RGBA{B: 1, A: 0xFF}) {
+		t.Errorf("expected an outline but got %v", outlined.RGBAAt(9, 20))
+	}
+}
+
+func TestRenderModel(t *testing.T) {
+	m := square(64, 0x1234)
+
+	back := Render(m, NewCamera(64, 64, m, 0, 1024))
+	if back.RGBAAt(32, 32).A != 0 {
+		t.Error("expected the back of the square to be culled")
+	}
+
+	var encoded bytes.Buffer
+	if err := RenderModel(&encoded, m, NewCamera(64, 64, m, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, alpha := img.At(32, 32).RGBA(); alpha == 0 {
+		t.Error("expected the square to be drawn in the centre")
+	}
+
+	if _, _, _, alpha := img.At(0, 0).RGBA(); alpha != 0 {
+		t.Error("expected the square to fit in view")
+	}
+}
+
+// testFont constructs a font of which every glyph is a white block of 2 by 3 pixels
+// with an advance of 3 pixels.
+func testFont() *font.Font {
+	block := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	for i := 0; i < len(block.Pix); i++ {
+		block.Pix[i] = 0xFF
+	}
+
+	glyphs := &sprite.Sprite{Width: 2, Height: 3}
+	metrics := &font.Metrics{Ascent: 3}
+	for i := 0; i < 256; i++ {
+		glyphs.Frames = append(glyphs.Frames, &sprite.Frame{Image: block})
+		metrics.Advances[i] = 3
+	}
+
+	return &font.Font{Glyphs: glyphs, Metrics: metrics}
+}
+
+func TestRenderInterface(t *testing.T) {
+	checker := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	checker.Set(0, 0, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xFF})
+	checker.Set(1, 1, color.NRGBA{R: 0x40, G: 0x50, B: 0x60, A: 0xFF})
+
+	component := func(index, parent, kind, x, y, width, height int) *widget.Component {
+		parentId := -1
+		if parent != -1 {
+			parentId = widget.ComponentId(1, parent)
+		}
+
+		return &widget.Component{
+			Id: widget.ComponentId(1, index), If3: true, Type: kind, ParentId: parentId,
+			X: x, Y: y, Width: width, Height: height, SpriteId: -1, ModelId: -1, FontId: -1,
+		}
+	}
+
+	layer := component(0, -1, widget.LayerType, 5, 5, 20, 10)
+	red := component(1, 0, widget.RectangleType, 0, 0, 30, 30)
+	red.Color, red.Filled = 0xFF0000, true
+	hidden := component(2, 0, widget.RectangleType, 0, 0, 30, 30)
+	hidden.Color, hidden.Filled, hidden.Hidden = 0x00FF00, true, true
+	blue := component(3, 0, widget.RectangleType, 0, 4, 2, 2)
+	blue.Color, blue.Filled = 0x0000FF, true
+	stretched := component(4, -1, widget.SpriteType, 30, 0, 4, 4)
+	stretched.SpriteId = 7
+	text := component(5, -1, widget.TextType, 0, 20, 40, 10)
+	text.FontId, text.Text, text.Color = 0, "ab", 0x00FF00
+	placeholder := component(6, -1, widget.ModelType, 36, 10, 4, 4)
+	placeholder.ModelId = 5
+
+	iface := widget.NewInterface(1, map[int]*widget.Component{
+		0: layer, 1: red, 2: hidden, 3: blue, 4: stretched, 5: text, 6: placeholder,
+	})
+
+	renderer := &InterfaceRenderer{
+		Sprites: func(id int) (*sprite.Sprite, error) { return sprite.FromImages(id, checker), nil },
+		Fonts:   func(id int) (*font.Font, error) { return testFont(), nil },
+		Scroll:  map[int]image.Point{layer.Id: {0, 2}},
+	}
+
+	img, err := renderer.Render(iface, 40, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opaque := func(rgb int) color.RGBA {
+		return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xFF}
+	}
+
+	expected := map[image.Point]color.RGBA{
+		{24, 14}: opaque(0xFF0000),
+		{25, 14}: {},
+		{5, 7}:   opaque(0x0000FF),
+		{5, 9}:   opaque(0xFF0000),
+		{31, 1}:  opaque(0x102030),
+		{33, 3}:  opaque(0x405060),
+		{33, 0}:  {},
+		{0, 20}:  opaque(0x00FF00),
+		{2, 20}:  {},
+		{3, 22}:  opaque(0x00FF00),
+		{36, 10}: opaque(PlaceholderColor),
+	}
+
+	for point, c := range expected {
+		if actual := img.RGBAAt(point.X, point.Y); actual != c {
+			t.Errorf("expected %v at %v but got %v", c, point, actual)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.WritePNG(&buf, iface, 40, 30); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := png.Decode(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,18 @@
+package render
+
+import "math"
+
+// angleCount is the amount of angles in a full circle, in the units of the client.
+const angleCount = 2048
+
+// sine and cosine hold the sine and cosine of each angle, scaled by 65536.
+var sine, cosine [angleCount]int
+
+func init() {
+	for angle := 0; angle < angleCount; angle++ {
+		radians := float64(angle) * (2 * math.Pi / angleCount)
+
+		sine[angle] = int(65536 * math.Sin(radians))
+		cosine[angle] = int(65536 * math.Cos(radians))
+	}
+}
@@ -0,0 +1,147 @@
+package gokira
+
+// RevisionSignature is a trait of the structure of a cache that is only found in
+// caches of a particular revision onwards.
+type RevisionSignature struct {
+	// Revision is the earliest revision of which caches are known to carry the trait.
+	Revision int
+
+	// Description briefly describes the trait.
+	Description string
+
+	// Matches returns whether the given Cache carries the trait.
+	Matches func(cache *Cache) bool
+}
+
+// RevisionSignatures lists the signatures DetectRevision tests a cache against, in
+// descending order of revision.
+var RevisionSignatures = []RevisionSignature{
+	{
+		Revision:    200,
+		Description: "db table index archive (21)",
+		Matches: func(cache *Cache) bool {
+			return cache.manifestCount() > 21
+		},
+	},
+	{
+		Revision:    200,
+		Description: "db table config folder (2/39)",
+		Matches: func(cache *Cache) bool {
+			return cache.hasFolder(2, 39)
+		},
+	},
+	{
+		Revision:    180,
+		Description: "reference tables of format 7",
+		Matches: func(cache *Cache) bool {
+			for archiveId := 0; archiveId < cache.manifestCount(); archiveId++ {
+				manifest, err := cache.GetArchiveManifest(archiveId)
+				if err == nil && manifest.Format >= 7 {
+					return true
+				}
+			}
+
+			return false
+		},
+	},
+	{
+		Revision:    149,
+		Description: "world map ground archive (20)",
+		Matches: func(cache *Cache) bool {
+			return cache.manifestCount() > 20
+		},
+	},
+}
+
+// RevisionRange is the range of revisions a cache may be of, as far as the traits of
+// its structure tell. The signatures only mark a few revisions, so a range rarely
+// narrows down to a single revision and must not be mistaken for the exact revision.
+type RevisionRange struct {
+	// Earliest is the revision of the latest signature the cache matches, or 0 if it
+	// matches none.
+	Earliest int
+
+	// Latest is the revision before the earliest signature after Earliest that the
+	// cache lacks, or 0 if it lacks none, in which case the range is open.
+	Latest int
+}
+
+// Open returns whether the range extends to the latest revision.
+func (r RevisionRange) Open() bool {
+	return r.Latest == 0
+}
+
+// DetectRevision determines the RevisionRange of the given Cache from the
+// RevisionSignatures it matches.
+func DetectRevision(cache *Cache) RevisionRange {
+	var r RevisionRange
+	for _, signature := range RevisionSignatures {
+		if signature.Revision > r.Earliest && signature.Matches(cache) {
+			r.Earliest = signature.Revision
+		}
+	}
+
+	for _, signature := range RevisionSignatures {
+		if signature.Revision > r.Earliest && !signature.Matches(cache) {
+			if r.Latest == 0 || signature.Revision-1 < r.Latest {
+				r.Latest = signature.Revision - 1
+			}
+		}
+	}
+
+	return r
+}
+
+// Revision returns the revision of which decoders select the layouts of the data of
+// the Cache. Unless the revision is set through SetRevision, this is the latest
+// revision of the detected RevisionRange, or zero, which decoders treat as the latest
+// revision, if the range is open. It is not the exact revision of the cache, which has
+// to be set when it matters. Safe for concurrent use.
+func (cache *Cache) Revision() int {
+	cache.revisionLock.Lock()
+	revision := cache.revision
+	cache.revisionLock.Unlock()
+
+	if revision != 0 {
+		return revision
+	}
+
+	return cache.DetectedRevision().Latest
+}
+
+// DetectedRevision returns the RevisionRange of the Cache, which is detected on first
+// use. Safe for concurrent use.
+func (cache *Cache) DetectedRevision() RevisionRange {
+	cache.detection.Do(func() {
+		cache.detectedRevision = DetectRevision(cache)
+	})
+
+	return cache.detectedRevision
+}
+
+// SetRevision overrides the revision of the Cache. Setting a revision of zero restores
+// the detected revision. Safe for concurrent use.
+func (cache *Cache) SetRevision(revision int) {
+	cache.revisionLock.Lock()
+	cache.revision = revision
+	cache.revisionLock.Unlock()
+}
+
+// manifestCount returns the amount of archives that the release manifest index lists.
+func (cache *Cache) manifestCount() int {
+	return len(cache.bundle.manifestResource) / indexSize
+}
+
+// hasFolder returns whether the manifest of the specified archive lists the specified folder.
+func (cache *Cache) hasFolder(archiveId, folderId int) bool {
+	if archiveId >= cache.manifestCount() {
+		return false
+	}
+
+	manifest, err := cache.GetArchiveManifest(archiveId)
+	if err != nil {
+		return false
+	}
+
+	return folderId < len(manifest.FolderReferences) && manifest.FolderReferences[folderId] != nil
+}
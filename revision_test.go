@@ -0,0 +1,51 @@
+package gokira_test
+
+import (
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+func TestCacheRevision(t *testing.T) {
+	// the db table archive is the latest signature, which leaves the range open
+	builder := cachetest.New()
+	builder.AddFile(21, 0, []byte{0})
+
+	cache := builder.Build(t)
+	if r := cache.DetectedRevision(); r != (gokira.RevisionRange{Earliest: 200}) || !r.Open() {
+		t.Errorf("expected an open range from revision 200 but got %+v", r)
+	}
+
+	if revision := cache.Revision(); revision != 0 {
+		t.Errorf("expected the latest revision but got %v", revision)
+	}
+
+	cache.SetRevision(187)
+	if revision := cache.Revision(); revision != 187 {
+		t.Errorf("expected the overridden revision but got %v", revision)
+	}
+
+	cache.SetRevision(0)
+	if revision := cache.Revision(); revision != 0 {
+		t.Errorf("expected the detected revision to be restored but got %v", revision)
+	}
+
+	// the world map ground archive without reference tables of format 7 or the db
+	// table archive bounds the range on both sides
+	builder = cachetest.New()
+	builder.AddFile(20, 0, []byte{0})
+
+	cache = builder.Build(t)
+	if r := cache.DetectedRevision(); r != (gokira.RevisionRange{Earliest: 149, Latest: 179}) {
+		t.Errorf("expected revisions 149 to 179 but got %+v", r)
+	}
+
+	if revision := cache.Revision(); revision != 179 {
+		t.Errorf("expected the latest revision of the range but got %v", revision)
+	}
+
+	if r := cachetest.New().Build(t).DetectedRevision(); r != (gokira.RevisionRange{Latest: 148}) {
+		t.Errorf("expected the revisions before 149 but got %+v", r)
+	}
+}
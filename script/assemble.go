@@ -0,0 +1,275 @@
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// reference is an operand that refers to a label, which is resolved once every label
+// is known.
+type reference struct {
+	line  int
+	label string
+
+	// index is the index of the instruction that refers to the label, and cases the
+	// switch table and value of the case that refers to it, if any.
+	index int
+	table int
+	value int
+}
+
+// assembler is the state of the assembly of a single script.
+type assembler struct {
+	script   *Script
+	revision int
+
+	lines      []int
+	labels     map[string]int
+	references []reference
+
+	// declared holds the local and argument counts that the directives declare.
+	declared map[string]int
+}
+
+// Assemble parses a listing in the syntax of Disassemble into a Script of the given
+// revision. Lines that start with a semicolon are comments. The local counts default
+// to the highest local that is loaded or stored if they are not declared. The script
+// is validated for its stack effects, see Validate. May return an error.
+func Assemble(r io.Reader, revision int) (*Script, error) {
+	a := &assembler{
+		script:   new(Script),
+		revision: revision,
+		labels:   make(map[string]int),
+		declared: make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := a.parse(line, strings.TrimSpace(scanner.Text())); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := a.resolve(); err != nil {
+		return nil, err
+	}
+
+	a.count()
+
+	if err := Validate(a.script, revision); err != nil {
+		if stackErr, ok := err.(*StackError); ok && stackErr.Index < len(a.lines) {
+			return nil, fmt.Errorf("line %d: %s", a.lines[stackErr.Index], stackErr.Message)
+		}
+
+		return nil, err
+	}
+
+	return a.script, nil
+}
+
+// parse parses a single line of a listing. May return an error.
+func (a *assembler) parse(line int, text string) error {
+	switch {
+	case text == "" || strings.HasPrefix(text, ";"):
+		return nil
+	case strings.HasPrefix(text, "."):
+		return a.directive(text)
+	case strings.HasSuffix(text, ":") && !strings.ContainsAny(text, " \t"):
+		name := strings.TrimSuffix(text, ":")
+		if _, ok := a.labels[name]; ok {
+			return fmt.Errorf("label %s is defined twice", name)
+		}
+
+		a.labels[name] = len(a.script.Instructions)
+		return nil
+	case text[0] == '-' || unicode.IsDigit(rune(text[0])):
+		return a.switchCase(line, text)
+	default:
+		return a.instruction(line, text)
+	}
+}
+
+// directive parses a directive, which declares a property of the script. May return
+// an error.
+func (a *assembler) directive(text string) error {
+	name, value := split(text)
+
+	switch name {
+	case ".id":
+		id, err := strconv.Atoi(value)
+		a.script.Id = id
+		return err
+	case ".name":
+		a.script.Name = value
+		return nil
+	case ".int_arg_count", ".string_arg_count", ".long_arg_count", ".int_var_count", ".string_var_count", ".long_var_count":
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 || count > 0xFFFF {
+			return fmt.Errorf("invalid count %q", value)
+		}
+
+		a.declared[name] = count
+		return nil
+	default:
+		return fmt.Errorf("unknown directive %s", name)
+	}
+}
+
+// switchCase parses a case of the switch instruction that precedes it. May return
+// an error.
+func (a *assembler) switchCase(line int, text string) error {
+	separator := strings.Index(text, ":")
+	if separator == -1 {
+		return fmt.Errorf("expected a case but got %q", text)
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(text[:separator]))
+	if err != nil {
+		return fmt.Errorf("invalid case %q", text[:separator])
+	}
+
+	count := len(a.script.Instructions)
+	if count == 0 || a.script.Instructions[count-1].Opcode != Switch {
+		return fmt.Errorf("case %d does not follow a switch", value)
+	}
+
+	table := a.script.Instructions[count-1].IntOperand
+	if _, ok := a.script.Switches[table][value]; ok {
+		return fmt.Errorf("case %d is defined twice", value)
+	}
+
+	a.script.Switches[table][value] = 0
+	a.references = append(a.references, reference{
+		line:  line,
+		label: strings.TrimSpace(text[separator+1:]),
+		index: count - 1,
+		table: table,
+		value: value,
+	})
+
+	return nil
+}
+
+// instruction parses an instruction and its operand. May return an error.
+func (a *assembler) instruction(line int, text string) error {
+	mnemonic, operand := split(text)
+
+	opcode, ok := LookupName(mnemonic, a.revision)
+	if !ok {
+		return fmt.Errorf("unknown instruction %s", mnemonic)
+	}
+
+	instruction := Instruction{Opcode: opcode.Code}
+	index := len(a.script.Instructions)
+
+	var err error
+	switch kind := OperandOf(opcode.Code, a.revision); {
+	case opcode.Code == Switch:
+		if operand != "" {
+			return fmt.Errorf("switch takes its cases on the lines that follow it")
+		}
+
+		instruction.IntOperand = len(a.script.Switches)
+		a.script.Switches = append(a.script.Switches, make(map[int]int))
+	case IsBranch(opcode.Code):
+		if operand == "" {
+			return fmt.Errorf("%s requires a label", mnemonic)
+		}
+
+		a.references = append(a.references, reference{line: line, label: operand, index: index, table: -1})
+	case kind == StringOperand:
+		instruction.StringOperand, err = strconv.Unquote(operand)
+	case kind == LongOperand:
+		instruction.LongOperand, err = strconv.ParseInt(operand, 10, 64)
+	case kind == ByteOperand && operand == "":
+	case kind == ByteOperand:
+		instruction.IntOperand, err = strconv.Atoi(operand)
+		if err == nil && (instruction.IntOperand < 0 || instruction.IntOperand > 0xFF) {
+			return fmt.Errorf("operand %d of %s does not fit in a byte", instruction.IntOperand, mnemonic)
+		}
+	default:
+		var value int64
+		value, err = strconv.ParseInt(operand, 10, 32)
+		instruction.IntOperand = int(value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid operand %q of %s", operand, mnemonic)
+	}
+
+	a.script.Instructions = append(a.script.Instructions, instruction)
+	a.lines = append(a.lines, line)
+
+	return nil
+}
+
+// resolve replaces the labels that operands refer to with the amount of instructions
+// to jump over. May return an error.
+func (a *assembler) resolve() error {
+	for _, reference := range a.references {
+		target, ok := a.labels[reference.label]
+		if !ok {
+			return fmt.Errorf("line %d: undefined label %s", reference.line, reference.label)
+		}
+
+		offset := target - reference.index - 1
+		if reference.table == -1 {
+			a.script.Instructions[reference.index].IntOperand = offset
+		} else {
+			a.script.Switches[reference.table][reference.value] = offset
+		}
+	}
+
+	return nil
+}
+
+// count sets the local and argument counts of the script to those that are declared,
+// raising the local counts to fit the arguments and the locals that are accessed.
+func (a *assembler) count() {
+	script := a.script
+	script.IntArgCount = a.declared[".int_arg_count"]
+	script.StringArgCount = a.declared[".string_arg_count"]
+	script.LongArgCount = a.declared[".long_arg_count"]
+
+	script.LocalIntCount = max(a.declared[".int_var_count"], script.IntArgCount)
+	script.LocalStringCount = max(a.declared[".string_var_count"], script.StringArgCount)
+	script.LocalLongCount = max(a.declared[".long_var_count"], script.LongArgCount)
+
+	for _, instruction := range script.Instructions {
+		switch {
+		case instruction.Opcode == ILoad || instruction.Opcode == IStore:
+			script.LocalIntCount = max(script.LocalIntCount, instruction.IntOperand+1)
+		case instruction.Opcode == SLoad || instruction.Opcode == SStore:
+			script.LocalStringCount = max(script.LocalStringCount, instruction.IntOperand+1)
+		case (instruction.Opcode == LLoad || instruction.Opcode == LStore) && hasLongs(a.revision):
+			script.LocalLongCount = max(script.LocalLongCount, instruction.IntOperand+1)
+		}
+	}
+}
+
+// split splits the given text into its first word and the rest of it.
+func split(text string) (string, string) {
+	separator := strings.IndexAny(text, " \t")
+	if separator == -1 {
+		return text, ""
+	}
+
+	return text[:separator], strings.TrimSpace(text[separator+1:])
+}
+
+// max returns the largest of the given values.
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
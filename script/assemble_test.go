@@ -0,0 +1,109 @@
+package script
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	script, err := Decode(12, testScript(), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Encode(script, testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, testScript()) {
+		t.Errorf("expected the encoding to equal the original data")
+	}
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	script, err := Decode(12, testScript(), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var listing bytes.Buffer
+	if err := Disassemble(&listing, script, testRevision); err != nil {
+		t.Fatal(err)
+	}
+
+	assembled, err := Assemble(&listing, testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Encode(assembled, testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, testScript()) {
+		t.Errorf("expected the assembled script to equal the original data")
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	listing := `
+; greets the player a few times
+.id 3
+.name [proc,greet]
+   iconst 0
+   istore 1
+LOOP:
+   iload 1
+   iconst 3
+   if_icmpge END
+   sconst "Hello, <col=ff0000>world</col>!"
+   mes
+   iload 1
+   iconst 1
+   add
+   istore 1
+   jump LOOP
+END:
+   invoke 5
+   return
+`
+
+	script, err := Assemble(strings.NewReader(listing), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if script.Id != 3 || script.Name != "[proc,greet]" || script.LocalIntCount != 2 || len(script.Instructions) != 14 {
+		t.Fatalf("unexpected script %+v", script)
+	}
+
+	if script.Instructions[4].IntOperand != 7 || script.Instructions[11].IntOperand != -10 {
+		t.Errorf("unexpected jump offsets %v %v", script.Instructions[4].IntOperand, script.Instructions[11].IntOperand)
+	}
+
+	if script.Instructions[5].StringOperand != "Hello, <col=ff0000>world</col>!" {
+		t.Errorf("unexpected string %q", script.Instructions[5].StringOperand)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := map[string]string{
+		"iconst 1\nadd\nreturn":                                 "line 2: add pops an int off an empty stack",
+		"iconst 1\niconst 1\nif_icmpeq L\niconst 5\nL:\nreturn": "line 6: paths join with different stacks: 0 ints, 0 strings, 0 longs versus 1 ints, 0 strings, 0 longs",
+		"jump NOWHERE":                                          "line 1: undefined label NOWHERE",
+		"iconst 1\npop_int":                                     "line 2: execution continues past the last instruction",
+		"iconst x":                                              `line 1: invalid operand "x" of iconst`,
+		"frobnicate":                                            "line 1: unknown instruction frobnicate",
+		"1: L":                                                  "line 1: case 1 does not follow a switch",
+		"cc_settext 256":                                        "line 1: operand 256 of cc_settext does not fit in a byte",
+	}
+
+	for listing, expected := range tests {
+		if _, err := Assemble(strings.NewReader(listing), testRevision); err == nil || err.Error() != expected {
+			t.Errorf("expected %q but got %v", expected, err)
+		}
+	}
+}
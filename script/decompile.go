@@ -0,0 +1,708 @@
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Decompiler reconstructs RuneScript-like source from client scripts, recovering the
+// if, while and switch statements from the jumps and the expressions from the stack
+// operations. Jumps that do not match any of those statements are kept as gotos.
+type Decompiler struct {
+	Revision int
+
+	// Scripts resolves the scripts that are invoked, to determine the values they
+	// take and return. Invocations of scripts that are not resolved take every value
+	// that is on the stack and are assumed to return nothing. May be nil.
+	Scripts func(id int) (*Script, error)
+
+	// Names are the names of known scripts by id, such as "chat_send", which are
+	// used instead of the names that are derived from their id.
+	Names map[int]string
+}
+
+// value is an expression on the symbolic stack.
+type value struct {
+	text string
+
+	// literal is set if the value is a constant, of which number holds the value
+	// if it is an integer.
+	literal bool
+	number  int
+}
+
+// decompilation is the state of the decompilation of a single script.
+type decompilation struct {
+	decompiler *Decompiler
+	script     *Script
+	lines      []string
+
+	ints    []value
+	strings []value
+	longs   []value
+
+	// index is the index of the instruction that is executed and start the index of
+	// the instruction that pushed the oldest value that is still on the stack.
+	index int
+	start int
+
+	// labels are the instructions that gotos jump to, which are known after a first
+	// pass, and jumped those that are jumped to in the current pass.
+	labels map[int]bool
+	jumped map[int]bool
+
+	results int
+}
+
+// Decompile writes the RuneScript-like source of the given Script to the given writer.
+// May return an error.
+func (decompiler *Decompiler) Decompile(w io.Writer, script *Script) error {
+	// the first pass determines which instructions are jumped to by gotos, which the
+	// second pass labels
+	first := decompiler.decompile(script, nil)
+	second := decompiler.decompile(script, first.jumped)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, decompiler.signature(script))
+
+	for _, line := range second.lines {
+		fmt.Fprintln(out, line)
+	}
+
+	return out.Flush()
+}
+
+// decompile decompiles the given script, labelling the given instructions.
+func (decompiler *Decompiler) decompile(script *Script, labels map[int]bool) *decompilation {
+	c := &decompilation{
+		decompiler: decompiler,
+		script:     script,
+		labels:     labels,
+		jumped:     make(map[int]bool),
+	}
+
+	c.block(0, len(script.Instructions), 0)
+	if c.labels[len(script.Instructions)] {
+		c.line(0, label(len(script.Instructions))+":")
+	}
+
+	return c
+}
+
+// signature returns the header of the source of the given script, which declares its
+// arguments and the values it returns.
+func (decompiler *Decompiler) signature(script *Script) string {
+	var arguments []string
+	for i := 0; i < script.IntArgCount; i++ {
+		arguments = append(arguments, fmt.Sprintf("int $int%d", i))
+	}
+
+	for i := 0; i < script.StringArgCount; i++ {
+		arguments = append(arguments, fmt.Sprintf("string $string%d", i))
+	}
+
+	for i := 0; i < script.LongArgCount; i++ {
+		arguments = append(arguments, fmt.Sprintf("long $long%d", i))
+	}
+
+	signature := fmt.Sprintf("[proc,%s](%s)", decompiler.name(script.Id, script), strings.Join(arguments, ", "))
+	if strings.HasPrefix(script.Name, "[") {
+		signature = fmt.Sprintf("%s(%s)", script.Name, strings.Join(arguments, ", "))
+	}
+
+	if returned, ok := script.returned(decompiler.Revision); ok && returned != (stack{known: true}) {
+		var types []string
+		for _, count := range []struct {
+			name  string
+			count int
+		}{{"int", returned.ints}, {"string", returned.strings}, {"long", returned.longs}} {
+			for i := 0; i < count.count; i++ {
+				types = append(types, count.name)
+			}
+		}
+
+		signature += "(" + strings.Join(types, ", ") + ")"
+	}
+
+	return signature
+}
+
+// name returns the name of the script of the given id, which may be nil.
+func (decompiler *Decompiler) name(id int, script *Script) string {
+	if name, ok := decompiler.Names[id]; ok {
+		return name
+	}
+
+	if script != nil && script.Name != "" && !strings.HasPrefix(script.Name, "[") {
+		return script.Name
+	}
+
+	if script != nil && strings.HasPrefix(script.Name, "[") && strings.Contains(script.Name, ",") {
+		return strings.TrimSuffix(script.Name[strings.Index(script.Name, ",")+1:], "]")
+	}
+
+	return fmt.Sprintf("script%d", id)
+}
+
+// line emits a line of source at the given depth.
+func (c *decompilation) line(depth int, format string, arguments ...interface{}) {
+	c.lines = append(c.lines, strings.Repeat("    ", depth)+fmt.Sprintf(format, arguments...))
+}
+
+// block emits the statements of the instructions in the given range. The values that
+// the block pushes but leaves on the stack are emitted as statements of their own.
+func (c *decompilation) block(start, end, depth int) {
+	heights := [3]int{len(c.ints), len(c.strings), len(c.longs)}
+
+	for i := start; i < end; {
+		if c.labels[i] {
+			c.line(depth, "%s:", label(i))
+		}
+
+		i = c.statement(i, end, depth)
+	}
+
+	for i, kind := range "isl" {
+		stack := c.stack(kind)
+		if len(*stack) <= heights[i] {
+			continue
+		}
+
+		for _, v := range (*stack)[heights[i]:] {
+			c.line(depth, "%s;", v.text)
+		}
+
+		*stack = (*stack)[:heights[i]]
+	}
+}
+
+// statement emits the statement that starts at the given index, if the instruction
+// completes one, and returns the index of the instruction after it.
+func (c *decompilation) statement(index, end, depth int) int {
+	instruction := c.script.Instructions[index]
+	c.index = index
+
+	switch {
+	case instruction.Opcode == Jump:
+		c.gotoLine(depth, index+1+instruction.IntOperand)
+		return index + 1
+	case IsBranch(instruction.Opcode):
+		return c.conditional(index, end, depth)
+	case instruction.Opcode == Switch:
+		return c.switchStatement(index, end, depth)
+	case instruction.Opcode == Return:
+		values := c.drain()
+		if len(values) == 0 {
+			c.line(depth, "return;")
+		} else {
+			c.line(depth, "return(%s);", strings.Join(values, ", "))
+		}
+
+		return index + 1
+	default:
+		c.execute(instruction, depth)
+		return index + 1
+	}
+}
+
+// gotoLine emits a goto to the instruction at the given index.
+func (c *decompilation) gotoLine(depth, target int) {
+	c.jumped[target] = true
+	c.line(depth, "goto %s;", label(target))
+}
+
+// conditions are the operators of the comparisons of the conditional jumps, along with
+// the operators of their negations.
+var conditions = map[int][2]string{
+	IfICmpNE: {"!", "="},
+	IfICmpEQ: {"=", "!"},
+	IfICmpLT: {"<", ">="},
+	IfICmpGT: {">", "<="},
+	IfICmpLE: {"<=", ">"},
+	IfICmpGE: {">=", "<"},
+}
+
+// conditional emits the if or while statement that starts with the conditional jump
+// at the given index, or a conditional goto if it does not match either of them.
+func (c *decompilation) conditional(index, end, depth int) int {
+	start := c.start
+	right, left := c.pop('i'), c.pop('i')
+	operators := conditions[c.script.Instructions[index].Opcode]
+	target, _ := c.script.JumpTarget(index)
+
+	// a jump over a jump when the condition holds, such as how the client scripts
+	// are compiled, or otherwise a jump past the body when it does not hold
+	condition, body, exit := fmt.Sprintf("%s %s %s", left, operators[0], right), index+2, -1
+	if target == index+2 && index+1 < end && c.script.Instructions[index+1].Opcode == Jump {
+		exit, _ = c.script.JumpTarget(index + 1)
+	} else if target > index+1 {
+		condition, body, exit = fmt.Sprintf("%s %s %s", left, operators[1], right), index+1, target
+	}
+
+	if exit <= body || exit > end {
+		c.jumped[target] = true
+		c.line(depth, "if (%s %s %s) goto %s;", left, operators[0], right, label(target))
+		return index + 1
+	}
+
+	// a jump back to the condition at the end of the body makes it a loop, while a
+	// jump forward past another body makes it an if with an else
+	last, lastIsJump := exit-1, c.script.Instructions[exit-1].Opcode == Jump
+	next := exit
+	if lastIsJump {
+		next, _ = c.script.JumpTarget(last)
+	}
+
+	switch {
+	case lastIsJump && next == start && start <= index:
+		c.line(depth, "while (%s) {", condition)
+		c.block(body, last, depth+1)
+		c.line(depth, "}")
+		return exit
+	case lastIsJump && next > exit && next <= end:
+		c.line(depth, "if (%s) {", condition)
+		c.block(body, last, depth+1)
+		c.line(depth, "} else {")
+		c.block(exit, next, depth+1)
+		c.line(depth, "}")
+		return next
+	default:
+		c.line(depth, "if (%s) {", condition)
+		c.block(body, exit, depth+1)
+		c.line(depth, "}")
+		return exit
+	}
+}
+
+// switchStatement emits the switch statement of the switch instruction at the given
+// index, or a list of gotos if its cases are not laid out the way they are compiled.
+func (c *decompilation) switchStatement(index, end, depth int) int {
+	subject := c.pop('i')
+	targets := c.script.SwitchTargets(index)
+
+	cases := make(map[int][]int)
+	for value, target := range targets {
+		cases[target] = append(cases[target], value)
+	}
+
+	// the jump after the switch leads to the default case, or past the switch if
+	// there is none
+	otherwise := -1
+	if index+1 < end && c.script.Instructions[index+1].Opcode == Jump {
+		otherwise, _ = c.script.JumpTarget(index + 1)
+	}
+
+	starts := make([]int, 0, len(cases)+1)
+	for target := range cases {
+		starts = append(starts, target)
+	}
+
+	if _, ok := cases[otherwise]; !ok && otherwise != -1 {
+		starts = append(starts, otherwise)
+	}
+
+	sort.Ints(starts)
+
+	// the bodies end with a jump past the switch, other than the last of them
+	exit := otherwise
+	for i := 1; i < len(starts); i++ {
+		if last := starts[i] - 1; c.script.Instructions[last].Opcode == Jump {
+			if target, _ := c.script.JumpTarget(last); target > exit {
+				exit = target
+			}
+		}
+	}
+
+	if otherwise == -1 || len(starts) == 0 || starts[0] < index+2 || exit < starts[len(starts)-1] || exit > end {
+		c.line(depth, "switch (%s) {", subject)
+		for _, target := range sortedKeys(cases) {
+			c.jumped[target] = true
+			c.line(depth+1, "case %s: goto %s;", joinInts(cases[target]), label(target))
+		}
+
+		c.line(depth, "}")
+		return index + 1
+	}
+
+	if exit == otherwise {
+		starts = starts[:len(starts)-1]
+	}
+
+	c.line(depth, "switch (%s) {", subject)
+	for i, start := range starts {
+		stop := exit
+		if i+1 < len(starts) {
+			stop = starts[i+1]
+		} else if exit != otherwise && otherwise > start {
+			stop = otherwise
+		}
+
+		if values, ok := cases[start]; ok {
+			c.line(depth+1, "case %s:", joinInts(values))
+		} else {
+			c.line(depth+1, "default:")
+		}
+
+		if last := stop - 1; last >= start && c.script.Instructions[last].Opcode == Jump {
+			if target, _ := c.script.JumpTarget(last); target == exit {
+				stop = last
+			}
+		}
+
+		c.block(start, stop, depth+2)
+	}
+
+	c.line(depth, "}")
+	return exit
+}
+
+// arithmetic are the operators of the arithmetic instructions.
+var arithmetic = map[int]string{
+	4000: "+",
+	4001: "-",
+	4002: "*",
+	4003: "/",
+	4011: "%",
+	4014: "&",
+	4015: "|",
+}
+
+// execute applies the given instruction to the symbolic stack, emitting a statement
+// if it does not push a value.
+func (c *decompilation) execute(instruction Instruction, depth int) {
+	operand := instruction.IntOperand
+	revision := c.decompiler.Revision
+
+	switch instruction.Opcode {
+	case IConst:
+		c.push('i', value{text: strconv.Itoa(operand), literal: true, number: operand})
+	case SConst:
+		c.push('s', value{text: strconv.Quote(instruction.StringOperand), literal: true})
+	case LConst:
+		c.push('l', value{text: strconv.FormatInt(instruction.LongOperand, 10) + "L", literal: true})
+	case ILoad:
+		c.push('i', value{text: fmt.Sprintf("$int%d", operand)})
+	case SLoad:
+		c.push('s', value{text: fmt.Sprintf("$string%d", operand)})
+	case LLoad:
+		c.push('l', value{text: fmt.Sprintf("$long%d", operand)})
+	case IStore:
+		c.line(depth, "$int%d = %s;", operand, c.pop('i'))
+	case SStore:
+		c.line(depth, "$string%d = %s;", operand, c.pop('s'))
+	case LStore:
+		c.line(depth, "$long%d = %s;", operand, c.pop('l'))
+	case GetVarp:
+		c.push('i', value{text: fmt.Sprintf("%%varp%d", operand)})
+	case SetVarp:
+		c.line(depth, "%%varp%d = %s;", operand, c.pop('i'))
+	case GetVarbit:
+		c.push('i', value{text: fmt.Sprintf("%%varbit%d", operand)})
+	case SetVarbit:
+		c.line(depth, "%%varbit%d = %s;", operand, c.pop('i'))
+	case GetVarcInt:
+		c.push('i', value{text: fmt.Sprintf("%%varcint%d", operand)})
+	case SetVarcInt:
+		c.line(depth, "%%varcint%d = %s;", operand, c.pop('i'))
+	case GetVarcString, GetVarcStringOld:
+		c.push('s', value{text: fmt.Sprintf("%%varcstring%d", operand)})
+	case SetVarcString, SetVarcStringOld:
+		c.line(depth, "%%varcstring%d = %s;", operand, c.pop('s'))
+	case PopInt:
+		c.line(depth, "%s;", c.pop('i'))
+	case PopString:
+		c.line(depth, "%s;", c.pop('s'))
+	case PopLong:
+		c.line(depth, "%s;", c.pop('l'))
+	case DefineArray:
+		c.line(depth, "def_%s $array%d(%s);", typeOf(operand&0xFFFF), operand>>16, c.pop('i'))
+	case GetArrayInt:
+		c.push('i', value{text: fmt.Sprintf("$array%d(%s)", operand, c.pop('i'))})
+	case SetArrayInt:
+		stored := c.pop('i')
+		c.line(depth, "$array%d(%s) = %s;", operand, c.pop('i'), stored)
+	case JoinString:
+		c.push('s', value{text: join(c.popAll('s', operand))})
+	case Invoke:
+		c.invoke(operand, depth)
+	case 3408:
+		c.enum(depth)
+	default:
+		if operator, ok := arithmetic[instruction.Opcode]; ok {
+			right, left := c.pop('i'), c.pop('i')
+			c.push('i', value{text: fmt.Sprintf("calc(%s %s %s)", unwrap(left), operator, unwrap(right))})
+			return
+		}
+
+		opcode, known := Lookup(instruction.Opcode, revision)
+		name := Mnemonic(instruction.Opcode, revision)
+		if strings.HasPrefix(name, "cc_") && operand == 1 {
+			name = "." + name
+		}
+
+		switch {
+		case known && strings.Contains(name, "_seton"):
+			c.handler(name, strings.HasPrefix(name, "if_"), depth)
+		case known && opcode.HasEffect:
+			c.call(name, opcode.Pops, opcode.Pushes, depth)
+		default:
+			c.line(depth, "%s; // unknown stack effect", name)
+		}
+	}
+}
+
+// call pops the values of the given types off the stack as the arguments of the named
+// command or script, and pushes its result or emits it as a statement.
+func (c *decompilation) call(name, pops, pushes string, depth int) {
+	arguments := make([]string, len(pops))
+	for i := len(pops) - 1; i >= 0; i-- {
+		arguments[i] = c.pop(rune(pops[i]))
+	}
+
+	call := fmt.Sprintf("%s(%s)", name, strings.Join(arguments, ", "))
+
+	switch len(pushes) {
+	case 0:
+		c.line(depth, "%s;", call)
+	case 1:
+		c.push(rune(pushes[0]), value{text: call})
+	default:
+		results := make([]string, len(pushes))
+		for i := range results {
+			results[i] = fmt.Sprintf("$result%d", c.results)
+			c.results++
+		}
+
+		c.line(depth, "%s = %s;", strings.Join(results, ", "), call)
+		for i, result := range results {
+			c.push(rune(pushes[i]), value{text: result})
+		}
+	}
+}
+
+// invoke emits the invocation of the script of the given id.
+func (c *decompilation) invoke(id, depth int) {
+	var callee *Script
+	if c.decompiler.Scripts != nil {
+		callee, _ = c.decompiler.Scripts(id)
+	}
+
+	name := "~" + c.decompiler.name(id, callee)
+	if callee == nil {
+		c.line(depth, "%s(%s);", name, strings.Join(c.drain(), ", "))
+		return
+	}
+
+	pops := strings.Repeat("i", callee.IntArgCount) + strings.Repeat("s", callee.StringArgCount) + strings.Repeat("l", callee.LongArgCount)
+
+	returned, _ := callee.returned(c.decompiler.Revision)
+	pushes := strings.Repeat("i", returned.ints) + strings.Repeat("s", returned.strings) + strings.Repeat("l", returned.longs)
+
+	c.call(name, pops, pushes, depth)
+}
+
+// enum emits a lookup of an enum, which pushes a string if the type of its values is
+// a constant string type.
+func (c *decompilation) enum(depth int) {
+	key, enum, output, input := c.pop('i'), c.pop('i'), c.popValue('i'), c.pop('i')
+
+	result := 'i'
+	if output.literal && output.number == 's' {
+		result = 's'
+	}
+
+	c.push(result, value{text: fmt.Sprintf("enum(%s, %s, %s, %s)", input, output.text, enum, key)})
+}
+
+// handler emits the installation of a script as an event handler of a component. The
+// handler is described by a string of the types of the arguments of the script, which
+// is followed by the 'Y' type if it also takes a list of ids to be notified of.
+func (c *decompilation) handler(name string, popsComponent bool, depth int) {
+	var component string
+	if popsComponent {
+		component = c.pop('i')
+	}
+
+	signature := c.popValue('s')
+	types, err := strconv.Unquote(signature.text)
+	if !signature.literal || err != nil {
+		c.line(depth, "%s(%s); // unknown handler", name, signature.text)
+		return
+	}
+
+	var triggers []string
+	if strings.HasSuffix(types, "Y") {
+		types = types[:len(types)-1]
+		count := c.popValue('i')
+		if count.literal {
+			triggers = c.popAll('i', count.number)
+		}
+	}
+
+	arguments := make([]string, len(types))
+	for i := len(types) - 1; i >= 0; i-- {
+		if types[i] == 's' {
+			arguments[i] = c.pop('s')
+		} else {
+			arguments[i] = c.pop('i')
+		}
+	}
+
+	var handler string
+	if id := c.popValue('i'); id.literal && id.number == -1 {
+		handler = "null"
+	} else {
+		var callee *Script
+		if id.literal && c.decompiler.Scripts != nil {
+			callee, _ = c.decompiler.Scripts(id.number)
+		}
+
+		script := fmt.Sprintf("script(%s)", id.text)
+		if id.literal {
+			script = c.decompiler.name(id.number, callee)
+		}
+
+		handler = fmt.Sprintf("\"%s(%s)", script, strings.Join(arguments, ", "))
+		if triggers != nil {
+			handler += "{" + strings.Join(triggers, ", ") + "}"
+		}
+
+		handler += "\""
+	}
+
+	if popsComponent {
+		c.line(depth, "%s(%s, %s);", name, handler, component)
+	} else {
+		c.line(depth, "%s(%s);", name, handler)
+	}
+}
+
+// stack returns the symbolic stack of values of the given type.
+func (c *decompilation) stack(kind rune) *[]value {
+	switch kind {
+	case 's':
+		return &c.strings
+	case 'l':
+		return &c.longs
+	default:
+		return &c.ints
+	}
+}
+
+// push pushes the given value onto the stack of the given type.
+func (c *decompilation) push(kind rune, v value) {
+	if len(c.ints)+len(c.strings)+len(c.longs) == 0 {
+		c.start = c.index
+	}
+
+	stack := c.stack(kind)
+	*stack = append(*stack, v)
+}
+
+// popValue pops a value off the stack of the given type. Popping off an empty stack,
+// which happens after instructions of which the effect is unknown, yields a
+// placeholder.
+func (c *decompilation) popValue(kind rune) value {
+	stack := c.stack(kind)
+	if len(*stack) == 0 {
+		return value{text: "$unknown"}
+	}
+
+	v := (*stack)[len(*stack)-1]
+	*stack = (*stack)[:len(*stack)-1]
+
+	return v
+}
+
+// pop pops the expression of a value off the stack of the given type.
+func (c *decompilation) pop(kind rune) string {
+	return c.popValue(kind).text
+}
+
+// popAll pops the given amount of values off the stack of the given type, in the order
+// they were pushed.
+func (c *decompilation) popAll(kind rune, count int) []string {
+	values := make([]string, count)
+	for i := count - 1; i >= 0; i-- {
+		values[i] = c.pop(kind)
+	}
+
+	return values
+}
+
+// drain pops every value off the stacks, in the order of their types.
+func (c *decompilation) drain() []string {
+	var values []string
+	for _, kind := range "isl" {
+		stack := c.stack(kind)
+		for _, v := range *stack {
+			values = append(values, v.text)
+		}
+
+		*stack = nil
+	}
+
+	return values
+}
+
+// join returns a string literal that interpolates the given strings.
+func join(parts []string) string {
+	var builder strings.Builder
+	for _, part := range parts {
+		if literal, err := strconv.Unquote(part); err == nil && strings.HasPrefix(part, "\"") {
+			builder.WriteString(literal)
+		} else {
+			builder.WriteString("<" + part + ">")
+		}
+	}
+
+	return strconv.Quote(builder.String())
+}
+
+// unwrap strips the calc of a nested arithmetic expression.
+func unwrap(expression string) string {
+	if strings.HasPrefix(expression, "calc(") {
+		return "(" + strings.TrimPrefix(expression, "calc(")
+	}
+
+	return expression
+}
+
+// typeOf returns the name of the script type of the given character.
+func typeOf(char int) string {
+	switch char {
+	case 's':
+		return "string"
+	case 'i':
+		return "int"
+	default:
+		return string(rune(char))
+	}
+}
+
+// sortedKeys returns the keys of the given map in ascending order.
+func sortedKeys(m map[int][]int) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Ints(keys)
+	return keys
+}
+
+// joinInts joins the given values in ascending order.
+func joinInts(values []int) string {
+	sort.Ints(values)
+
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = strconv.Itoa(value)
+	}
+
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,148 @@
+package script
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// decompile assembles the given listing and decompiles it.
+func decompile(t *testing.T, decompiler *Decompiler, listing string) string {
+	script, err := Assemble(strings.NewReader(listing), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := decompiler.Decompile(&out, script); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.String()
+}
+
+func TestDecompile(t *testing.T) {
+	decompiler := &Decompiler{
+		Revision: testRevision,
+		Names:    map[int]string{7: "add_two"},
+		Scripts: func(id int) (*Script, error) {
+			return &Script{IntArgCount: 2, Instructions: []Instruction{{Opcode: ILoad}, {Opcode: Return}}}, nil
+		},
+	}
+
+	listing := `
+.int_arg_count 1
+   iconst 0
+   istore 1
+LOOP:
+   iload 1
+   iconst 3
+   if_icmpge END
+   sconst "Hello "
+   iload 1
+   tostring
+   join_string 2
+   mes
+   iload 1
+   iconst 1
+   add
+   istore 1
+   jump LOOP
+END:
+   iload 0
+   iconst 5
+   if_icmpeq THEN
+   jump ELSE
+THEN:
+   iconst 1
+   iconst 2
+   invoke 7
+   jump DONE
+ELSE:
+   sconst "no"
+   mes
+DONE:
+   iload 0
+   switch
+      1: ONE
+      2: ONE
+      3: THREE
+   jump DEFAULT
+ONE:
+   sconst "one"
+   mes
+   jump AFTER
+THREE:
+   iconst 5
+   istore 1
+   jump AFTER
+DEFAULT:
+   sconst "default"
+   mes
+AFTER:
+   iconst 7
+   iconst 10
+   iload 1
+   sconst "ii"
+   cc_setonclick 1
+   iload 1
+   return
+`
+
+	expected := strings.Join([]string{
+		"[proc,script0](int $int0)",
+		"$int1 = 0;",
+		"while ($int1 < 3) {",
+		"    mes(\"Hello <tostring($int1)>\");",
+		"    $int1 = calc($int1 + 1);",
+		"}",
+		"if ($int0 = 5) {",
+		"    ~add_two(1, 2);",
+		"} else {",
+		"    mes(\"no\");",
+		"}",
+		"switch ($int0) {",
+		"    case 1, 2:",
+		"        mes(\"one\");",
+		"    case 3:",
+		"        $int1 = 5;",
+		"    default:",
+		"        mes(\"default\");",
+		"}",
+		".cc_setonclick(\"add_two(10, $int1)\");",
+		"return($int1);",
+		"",
+	}, "\n")
+
+	if source := decompile(t, decompiler, listing); source != expected {
+		t.Errorf("unexpected source:\n%s", source)
+	}
+}
+
+func TestDecompileGoto(t *testing.T) {
+	listing := `
+.name [clientscript,spin]
+   op_9000
+LOOP:
+   sconst "again"
+   mes
+   get_varp 5
+   iconst 1
+   if_icmpeq LOOP
+   return
+`
+
+	expected := strings.Join([]string{
+		"[clientscript,spin]()",
+		"op_9000; // unknown stack effect",
+		"LABEL1:",
+		"mes(\"again\");",
+		"if (%varp5 = 1) goto LABEL1;",
+		"return;",
+		"",
+	}, "\n")
+
+	if source := decompile(t, &Decompiler{Revision: testRevision}, listing); source != expected {
+		t.Errorf("unexpected source:\n%s", source)
+	}
+}
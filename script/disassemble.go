@@ -0,0 +1,111 @@
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Disassemble writes a listing of the given Script to the given writer, of which the
+// opcodes are named after their mnemonic in the given revision. The targets of jumps
+// and switch cases are labelled after the index of the instruction they jump to.
+// May return an error.
+func Disassemble(w io.Writer, script *Script, revision int) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "%-20s%d\n", ".id", script.Id)
+	if script.Name != "" {
+		fmt.Fprintf(out, "%-20s%s\n", ".name", script.Name)
+	}
+
+	fmt.Fprintf(out, "%-20s%d\n", ".int_arg_count", script.IntArgCount)
+	fmt.Fprintf(out, "%-20s%d\n", ".string_arg_count", script.StringArgCount)
+	if hasLongs(revision) {
+		fmt.Fprintf(out, "%-20s%d\n", ".long_arg_count", script.LongArgCount)
+	}
+
+	fmt.Fprintf(out, "%-20s%d\n", ".int_var_count", script.LocalIntCount)
+	fmt.Fprintf(out, "%-20s%d\n", ".string_var_count", script.LocalStringCount)
+	if hasLongs(revision) {
+		fmt.Fprintf(out, "%-20s%d\n", ".long_var_count", script.LocalLongCount)
+	}
+
+	labels := script.labels()
+	for i, instruction := range script.Instructions {
+		if labels[i] {
+			fmt.Fprintf(out, "%s:\n", label(i))
+		}
+
+		fmt.Fprintf(out, "   %s\n", strings.TrimRight(fmt.Sprintf("%-22s %s", Mnemonic(instruction.Opcode, revision), script.operand(i, revision)), " "))
+
+		if targets := script.SwitchTargets(i); targets != nil {
+			values := make([]int, 0, len(targets))
+			for value := range targets {
+				values = append(values, value)
+			}
+
+			sort.Ints(values)
+			for _, value := range values {
+				fmt.Fprintf(out, "      %d: %s\n", value, label(targets[value]))
+			}
+		}
+	}
+
+	if labels[len(script.Instructions)] {
+		fmt.Fprintf(out, "%s:\n", label(len(script.Instructions)))
+	}
+
+	return out.Flush()
+}
+
+// labels returns the indices of the instructions that are jumped to.
+func (script *Script) labels() map[int]bool {
+	labels := make(map[int]bool)
+	for i := range script.Instructions {
+		if target, ok := script.JumpTarget(i); ok {
+			labels[target] = true
+		}
+
+		for _, target := range script.SwitchTargets(i) {
+			labels[target] = true
+		}
+	}
+
+	return labels
+}
+
+// operand formats the operand of the instruction at the given index. Jumps refer to the
+// label of their target, while the operands of switches and of bytes that are zero are
+// left out.
+func (script *Script) operand(index, revision int) string {
+	instruction := script.Instructions[index]
+
+	if target, ok := script.JumpTarget(index); ok {
+		return label(target)
+	}
+
+	switch OperandOf(instruction.Opcode, revision) {
+	case StringOperand:
+		return strconv.Quote(instruction.StringOperand)
+	case LongOperand:
+		return strconv.FormatInt(instruction.LongOperand, 10)
+	case ByteOperand:
+		if instruction.IntOperand == 0 {
+			return ""
+		}
+	}
+
+	if instruction.Opcode == Switch {
+		return ""
+	}
+
+	return strconv.Itoa(instruction.IntOperand)
+}
+
+// label returns the name of the label of the instruction at the given index.
+func label(index int) string {
+	return fmt.Sprintf("LABEL%d", index)
+}
@@ -0,0 +1,69 @@
+package script
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Encode encodes the given Script into the data of a script of the given revision,
+// which is the reverse of Decode. May return an error.
+func Encode(script *Script, revision int) ([]byte, error) {
+	if len(script.Switches) > 0xFF {
+		return nil, errors.New("a script has at most 255 switch tables")
+	}
+
+	w := buffer.NewWriter()
+	w.WriteCString(script.Name)
+
+	for _, instruction := range script.Instructions {
+		w.WriteInt16(instruction.Opcode)
+
+		switch OperandOf(instruction.Opcode, revision) {
+		case StringOperand:
+			w.WriteCString(instruction.StringOperand)
+		case LongOperand:
+			w.WriteInt64(instruction.LongOperand)
+		case IntOperand:
+			w.WriteInt32(instruction.IntOperand)
+		default:
+			w.WriteInt8(instruction.IntOperand)
+		}
+	}
+
+	w.WriteInt32(len(script.Instructions))
+	w.WriteInt16(script.LocalIntCount)
+	w.WriteInt16(script.LocalStringCount)
+	if hasLongs(revision) {
+		w.WriteInt16(script.LocalLongCount)
+	}
+
+	w.WriteInt16(script.IntArgCount)
+	w.WriteInt16(script.StringArgCount)
+	if hasLongs(revision) {
+		w.WriteInt16(script.LongArgCount)
+	}
+
+	start := w.Length()
+
+	w.WriteInt8(len(script.Switches))
+	for _, cases := range script.Switches {
+		values := make([]int, 0, len(cases))
+		for value := range cases {
+			values = append(values, value)
+		}
+
+		sort.Ints(values)
+
+		w.WriteInt16(len(values))
+		for _, value := range values {
+			w.WriteInt32(value)
+			w.WriteInt32(cases[value])
+		}
+	}
+
+	w.WriteInt16(w.Length() - start)
+
+	return w.Bytes(), nil
+}
@@ -0,0 +1,454 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The opcodes of the core instructions, which control the flow of a script and
+// operate on its stack and variables.
+const (
+	IConst           = 0
+	GetVarp          = 1
+	SetVarp          = 2
+	SConst           = 3
+	Jump             = 6
+	IfICmpNE         = 7
+	IfICmpEQ         = 8
+	IfICmpLT         = 9
+	IfICmpGT         = 10
+	Return           = 21
+	GetVarbit        = 25
+	SetVarbit        = 27
+	IfICmpLE         = 31
+	IfICmpGE         = 32
+	ILoad            = 33
+	IStore           = 34
+	SLoad            = 35
+	SStore           = 36
+	JoinString       = 37
+	PopInt           = 38
+	PopString        = 39
+	Invoke           = 40
+	GetVarcInt       = 42
+	SetVarcInt       = 43
+	DefineArray      = 44
+	GetArrayInt      = 45
+	SetArrayInt      = 46
+	GetVarcStringOld = 47
+	SetVarcStringOld = 48
+	GetVarcString    = 49
+	SetVarcString    = 50
+	LConst           = 54
+	LLoad            = 55
+	LStore           = 56
+	PopLong          = 57
+	Switch           = 60
+)
+
+// Opcode names an opcode in the revisions it exists in.
+type Opcode struct {
+	Code int
+	Name string
+
+	// MinRevision and MaxRevision are the first and the last revision the opcode
+	// has this name in, or 0 if the range is open on that side.
+	MinRevision int
+	MaxRevision int
+
+	// Pops and Pushes are the types of the values the instruction takes off the stack
+	// and puts onto it, as a character for each value in the order they are pushed:
+	// 'i' for integers, 's' for strings and 'l' for longs. They are only known if
+	// HasEffect is set, as the effect of some instructions depends on their operand or
+	// on the script they invoke.
+	Pops      string
+	Pushes    string
+	HasEffect bool
+}
+
+// Op produces an Opcode of the given name, in every revision.
+func Op(code int, name string) Opcode {
+	return Opcode{Code: code, Name: name}
+}
+
+// Since restricts the Opcode to the given revision and every revision after it.
+func (opcode Opcode) Since(revision int) Opcode {
+	opcode.MinRevision = revision
+	return opcode
+}
+
+// Until restricts the Opcode to the given revision and every revision before it.
+func (opcode Opcode) Until(revision int) Opcode {
+	opcode.MaxRevision = revision
+	return opcode
+}
+
+// Effect sets the types of the values the instruction takes off and puts onto the stack.
+func (opcode Opcode) Effect(pops, pushes string) Opcode {
+	opcode.Pops = pops
+	opcode.Pushes = pushes
+	opcode.HasEffect = true
+	return opcode
+}
+
+// appliesTo returns whether the opcode has this name in the given revision. The
+// revision of 0 denotes the latest revision.
+func (opcode Opcode) appliesTo(revision int) bool {
+	if revision == 0 {
+		return opcode.MaxRevision == 0
+	}
+
+	return revision >= opcode.MinRevision && (opcode.MaxRevision == 0 || revision <= opcode.MaxRevision)
+}
+
+// Opcodes are the names of the known opcodes. Those of the instructions that operate on
+// components are listed in Components instead.
+var Opcodes = []Opcode{
+	Op(IConst, "iconst").Effect("", "i"),
+	Op(GetVarp, "get_varp").Effect("", "i"),
+	Op(SetVarp, "set_varp").Effect("i", ""),
+	Op(SConst, "sconst").Effect("", "s"),
+	Op(Jump, "jump").Effect("", ""),
+	Op(IfICmpNE, "if_icmpne").Effect("ii", ""),
+	Op(IfICmpEQ, "if_icmpeq").Effect("ii", ""),
+	Op(IfICmpLT, "if_icmplt").Effect("ii", ""),
+	Op(IfICmpGT, "if_icmpgt").Effect("ii", ""),
+	Op(Return, "return"),
+	Op(GetVarbit, "get_varbit").Effect("", "i"),
+	Op(SetVarbit, "set_varbit").Effect("i", ""),
+	Op(IfICmpLE, "if_icmple").Effect("ii", ""),
+	Op(IfICmpGE, "if_icmpge").Effect("ii", ""),
+	Op(ILoad, "iload").Effect("", "i"),
+	Op(IStore, "istore").Effect("i", ""),
+	Op(SLoad, "sload").Effect("", "s"),
+	Op(SStore, "sstore").Effect("s", ""),
+	Op(JoinString, "join_string"),
+	Op(PopInt, "pop_int").Effect("i", ""),
+	Op(PopString, "pop_string").Effect("s", ""),
+	Op(Invoke, "invoke"),
+	Op(GetVarcInt, "get_varc_int").Effect("", "i"),
+	Op(SetVarcInt, "set_varc_int").Effect("i", ""),
+	Op(DefineArray, "define_array").Effect("i", ""),
+	Op(GetArrayInt, "get_array_int").Effect("i", "i"),
+	Op(SetArrayInt, "set_array_int").Effect("ii", ""),
+	Op(GetVarcStringOld, "get_varc_string_old").Effect("", "s"),
+	Op(SetVarcStringOld, "set_varc_string_old").Effect("s", ""),
+	Op(GetVarcString, "get_varc_string").Effect("", "s"),
+	Op(SetVarcString, "set_varc_string").Effect("s", ""),
+	Op(LConst, "lconst").Since(LongRevision).Effect("", "l"),
+	Op(LLoad, "lload").Since(LongRevision).Effect("", "l"),
+	Op(LStore, "lstore").Since(LongRevision).Effect("l", ""),
+	Op(PopLong, "pop_long").Since(LongRevision).Effect("l", ""),
+	Op(Switch, "switch").Effect("i", ""),
+
+	Op(100, "cc_create").Effect("iii", ""),
+	Op(101, "cc_delete").Effect("", ""),
+	Op(102, "cc_deleteall").Effect("i", ""),
+	Op(200, "cc_find").Effect("ii", "i"),
+	Op(201, "if_find").Effect("i", "i"),
+
+	Op(3100, "mes").Effect("s", ""),
+	Op(3101, "anim").Effect("ii", ""),
+	Op(3103, "if_close").Effect("", ""),
+	Op(3104, "resume_countdialog").Effect("s", ""),
+	Op(3105, "resume_namedialog").Effect("s", ""),
+	Op(3106, "resume_stringdialog").Effect("s", ""),
+	Op(3107, "opplayer").Effect("is", ""),
+	Op(3108, "if_dragpickup").Effect("iii", ""),
+	Op(3109, "cc_dragpickup").Effect("ii", ""),
+	Op(3110, "mousecam").Effect("i", ""),
+	Op(3111, "getremoveroofs").Effect("", "i"),
+	Op(3112, "setremoveroofs").Effect("i", ""),
+	Op(3113, "openurl").Effect("si", ""),
+	Op(3115, "resume_objdialog").Effect("i", ""),
+	Op(3116, "bug_report"),
+	Op(3117, "setshiftclickdrop").Effect("i", ""),
+	Op(3118, "setshowmouseovertext").Effect("i", ""),
+	Op(3119, "renderself").Effect("i", ""),
+	Op(3124, "setshowmousecross").Effect("i", ""),
+	Op(3125, "setshowloadingmessages").Effect("i", ""),
+	Op(3126, "settaptodrop").Effect("i", ""),
+	Op(3127, "gettaptodrop").Effect("", "i"),
+	Op(3200, "sound_synth").Effect("iii", ""),
+	Op(3201, "sound_song").Effect("i", ""),
+	Op(3202, "sound_jingle").Effect("ii", ""),
+	Op(3300, "clientclock").Effect("", "i"),
+	Op(3301, "inv_getobj").Effect("ii", "i"),
+	Op(3302, "inv_getnum").Effect("ii", "i"),
+	Op(3303, "inv_total").Effect("ii", "i"),
+	Op(3304, "inv_size").Effect("i", "i"),
+	Op(3305, "stat").Effect("i", "i"),
+	Op(3306, "stat_base").Effect("i", "i"),
+	Op(3307, "stat_xp").Effect("i", "i"),
+	Op(3308, "coord").Effect("", "i"),
+	Op(3309, "coordx").Effect("i", "i"),
+	Op(3310, "coordz").Effect("i", "i"),
+	Op(3311, "coordy").Effect("i", "i"),
+	Op(3312, "map_members").Effect("", "i"),
+	Op(3313, "invother_getobj").Effect("ii", "i"),
+	Op(3314, "invother_getnum").Effect("ii", "i"),
+	Op(3315, "invother_total").Effect("ii", "i"),
+	Op(3316, "staffmodlevel").Effect("", "i"),
+	Op(3317, "reboottimer").Effect("", "i"),
+	Op(3318, "map_world").Effect("", "i"),
+	Op(3321, "runenergy_visible").Effect("", "i"),
+	Op(3322, "runweight_visible").Effect("", "i"),
+	Op(3323, "playermod").Effect("", "i"),
+	Op(3324, "worldflags").Effect("", "i"),
+	Op(3325, "movecoord").Effect("iiii", "i"),
+	Op(3400, "enum_string").Effect("ii", "s"),
+	Op(3408, "enum"),
+	Op(3411, "enum_getoutputcount").Effect("i", "i"),
+	Op(3600, "friend_count").Effect("", "i"),
+	Op(3601, "friend_getname").Effect("i", "ss"),
+	Op(3602, "friend_getworld").Effect("i", "i"),
+	Op(3603, "friend_getrank").Effect("i", "i"),
+	Op(3604, "friend_setrank").Effect("si", ""),
+	Op(3605, "friend_add").Effect("s", ""),
+	Op(3606, "friend_del").Effect("s", ""),
+	Op(3607, "ignore_add").Effect("s", ""),
+	Op(3608, "ignore_del").Effect("s", ""),
+	Op(3609, "friend_test").Effect("s", "i"),
+	Op(4000, "add").Effect("ii", "i"),
+	Op(4001, "sub").Effect("ii", "i"),
+	Op(4002, "multiply").Effect("ii", "i"),
+	Op(4003, "div").Effect("ii", "i"),
+	Op(4004, "random").Effect("i", "i"),
+	Op(4005, "randominc").Effect("i", "i"),
+	Op(4006, "interpolate").Effect("iiiii", "i"),
+	Op(4007, "addpercent").Effect("ii", "i"),
+	Op(4008, "setbit").Effect("ii", "i"),
+	Op(4009, "clearbit").Effect("ii", "i"),
+	Op(4010, "testbit").Effect("ii", "i"),
+	Op(4011, "mod").Effect("ii", "i"),
+	Op(4012, "pow").Effect("ii", "i"),
+	Op(4013, "invpow").Effect("ii", "i"),
+	Op(4014, "and").Effect("ii", "i"),
+	Op(4015, "or").Effect("ii", "i"),
+	Op(4018, "scale").Effect("iii", "i"),
+	Op(4100, "append_num").Effect("si", "s"),
+	Op(4101, "append").Effect("ss", "s"),
+	Op(4102, "append_signnum").Effect("si", "s"),
+	Op(4103, "lowercase").Effect("s", "s"),
+	Op(4104, "fromdate").Effect("i", "s"),
+	Op(4105, "text_gender").Effect("ss", "s"),
+	Op(4106, "tostring").Effect("i", "s"),
+	Op(4107, "compare").Effect("ss", "i"),
+	Op(4108, "paraheight").Effect("sii", "i"),
+	Op(4109, "parawidth").Effect("sii", "i"),
+	Op(4110, "text_switch").Effect("ssi", "s"),
+	Op(4111, "escape").Effect("s", "s"),
+	Op(4112, "append_char").Effect("si", "s"),
+	Op(4113, "char_isprintable").Effect("i", "i"),
+	Op(4114, "char_isalphanumeric").Effect("i", "i"),
+	Op(4115, "char_isalpha").Effect("i", "i"),
+	Op(4116, "char_isnumeric").Effect("i", "i"),
+	Op(4117, "string_length").Effect("s", "i"),
+	Op(4118, "substring").Effect("sii", "s"),
+	Op(4119, "removetags").Effect("s", "s"),
+	Op(4120, "string_indexof_char").Effect("si", "i"),
+	Op(4121, "string_indexof_string").Effect("ss", "i"),
+	Op(4200, "oc_name").Effect("i", "s"),
+	Op(4201, "oc_op").Effect("ii", "s"),
+	Op(4202, "oc_iop").Effect("ii", "s"),
+	Op(4203, "oc_cost").Effect("i", "i"),
+	Op(4204, "oc_stackable").Effect("i", "i"),
+	Op(4205, "oc_cert").Effect("i", "i"),
+	Op(4206, "oc_uncert").Effect("i", "i"),
+	Op(4207, "oc_members").Effect("i", "i"),
+	Op(4208, "oc_placeholder").Effect("i", "i"),
+	Op(4209, "oc_unplaceholder").Effect("i", "i"),
+	Op(4210, "oc_find").Effect("si", "i"),
+	Op(4211, "oc_findnext").Effect("", "i"),
+	Op(4212, "oc_findreset").Effect("", ""),
+	Op(5306, "getwindowmode").Effect("", "i"),
+	Op(5307, "setwindowmode").Effect("i", ""),
+	Op(5308, "getdefaultwindowmode").Effect("", "i"),
+	Op(5309, "setdefaultwindowmode").Effect("i", ""),
+	Op(5504, "cam_forceangle").Effect("ii", ""),
+	Op(5505, "cam_getangle_xa").Effect("", "i"),
+	Op(5506, "cam_getangle_ya").Effect("", "i"),
+	Op(5530, "cam_setfollowheight").Effect("i", ""),
+	Op(5531, "cam_getfollowheight").Effect("", "i"),
+	Op(5630, "logout").Effect("", ""),
+}
+
+// Components are the names of the instructions that operate on a component, without
+// their prefix. Each of them exists as an instruction that operates on a component of
+// which the id is popped off the stack, prefixed with "if_", and as an instruction
+// that operates on the component that is selected by its operand, prefixed with "cc_"
+// and of which the opcode is 1000 less.
+var Components = []Opcode{
+	Op(1000, "setposition").Effect("iiii", ""),
+	Op(1001, "setsize").Effect("iiii", ""),
+	Op(1003, "sethide").Effect("i", ""),
+	Op(1005, "setnoclickthrough").Effect("i", ""),
+	Op(1006, "setnoscrollthrough").Effect("i", ""),
+	Op(1100, "setscrollpos").Effect("ii", ""),
+	Op(1101, "setcolour").Effect("i", ""),
+	Op(1102, "setfill").Effect("i", ""),
+	Op(1103, "settrans").Effect("i", ""),
+	Op(1104, "setlinewid").Effect("i", ""),
+	Op(1105, "setgraphic").Effect("i", ""),
+	Op(1106, "set2dangle").Effect("i", ""),
+	Op(1107, "settiling").Effect("i", ""),
+	Op(1108, "setmodel").Effect("i", ""),
+	Op(1109, "setmodelangle").Effect("iiiiii", ""),
+	Op(1110, "setmodelanim").Effect("i", ""),
+	Op(1111, "setmodelorthog").Effect("i", ""),
+	Op(1112, "settext").Effect("s", ""),
+	Op(1113, "settextfont").Effect("i", ""),
+	Op(1114, "settextalign").Effect("iii", ""),
+	Op(1115, "settextshadow").Effect("i", ""),
+	Op(1116, "setoutline").Effect("i", ""),
+	Op(1117, "setgraphicshadow").Effect("i", ""),
+	Op(1118, "setvflip").Effect("i", ""),
+	Op(1119, "sethflip").Effect("i", ""),
+	Op(1120, "setscrollsize").Effect("ii", ""),
+	Op(1121, "resume_pausebutton").Effect("", ""),
+	Op(1122, "setfillcolour").Effect("i", ""),
+	Op(1200, "setobject").Effect("ii", ""),
+	Op(1201, "setnpchead").Effect("i", ""),
+	Op(1202, "setplayerhead_self").Effect("", ""),
+	Op(1205, "setobject_nonum").Effect("ii", ""),
+	Op(1212, "setobject_alwaysnum").Effect("ii", ""),
+	Op(1300, "setop").Effect("is", ""),
+	Op(1301, "setdraggable").Effect("ii", ""),
+	Op(1302, "setdraggablebehavior").Effect("i", ""),
+	Op(1303, "setdragdeadzone").Effect("i", ""),
+	Op(1304, "setdragdeadtime").Effect("i", ""),
+	Op(1305, "setopbase").Effect("s", ""),
+	Op(1306, "settargetverb").Effect("s", ""),
+	Op(1307, "clearops").Effect("", ""),
+	Op(1400, "setonclick"),
+	Op(1401, "setonhold"),
+	Op(1402, "setonrelease"),
+	Op(1403, "setonmouseover"),
+	Op(1404, "setonmouseleave"),
+	Op(1405, "setondragstart"),
+	Op(1406, "setondragcomplete"),
+	Op(1407, "setonvartransmit"),
+	Op(1408, "setontimer"),
+	Op(1409, "setonop"),
+	Op(1410, "setondrag"),
+	Op(1411, "setonclickrepeat"),
+	Op(1412, "setonmouserepeat"),
+	Op(1414, "setoninvtransmit"),
+	Op(1415, "setonstattransmit"),
+	Op(1416, "setontargetenter"),
+	Op(1417, "setonscrollwheel"),
+	Op(1418, "setonchattransmit"),
+	Op(1419, "setonkey"),
+	Op(1500, "getx").Effect("", "i"),
+	Op(1501, "gety").Effect("", "i"),
+	Op(1502, "getwidth").Effect("", "i"),
+	Op(1503, "getheight").Effect("", "i"),
+	Op(1504, "gethide").Effect("", "i"),
+	Op(1505, "getlayer").Effect("", "i"),
+	Op(1600, "getscrollx").Effect("", "i"),
+	Op(1601, "getscrolly").Effect("", "i"),
+	Op(1602, "gettext").Effect("", "s"),
+	Op(1603, "getscrollwidth").Effect("", "i"),
+	Op(1604, "getscrollheight").Effect("", "i"),
+	Op(1605, "getmodelzoom").Effect("", "i"),
+	Op(1606, "getmodelangle_x").Effect("", "i"),
+	Op(1607, "getmodelangle_z").Effect("", "i"),
+	Op(1608, "getmodelangle_y").Effect("", "i"),
+	Op(1609, "gettrans").Effect("", "i"),
+	Op(1611, "getcolour").Effect("", "i"),
+	Op(1612, "getfillcolour").Effect("", "i"),
+	Op(1700, "getinvobject").Effect("", "i"),
+	Op(1701, "getinvcount").Effect("", "i"),
+	Op(1702, "getid").Effect("", "i"),
+	Op(1800, "gettargetmask").Effect("", "i"),
+	Op(1801, "getop").Effect("i", "s"),
+	Op(1802, "getopbase").Effect("", "s"),
+}
+
+// Lookup returns the Opcode of the given code in the given revision, and whether it is
+// known at all. The Opcode of an instruction that operates on a component is named
+// after its prefix and pops the id of the component, which is pushed last, if it is
+// prefixed with "if_".
+func Lookup(code, revision int) (Opcode, bool) {
+	for _, opcode := range Opcodes {
+		if opcode.Code == code && opcode.appliesTo(revision) {
+			return opcode, true
+		}
+	}
+
+	for _, opcode := range Components {
+		if !opcode.appliesTo(revision) {
+			continue
+		}
+
+		if opcode.Code == code {
+			opcode.Name = "cc_" + opcode.Name
+			return opcode, true
+		}
+
+		if opcode.Code+1000 == code {
+			opcode.Code = code
+			opcode.Name = "if_" + opcode.Name
+			opcode.Pops += "i"
+			return opcode, true
+		}
+	}
+
+	return Opcode{}, false
+}
+
+// LookupName returns the Opcode of the given mnemonic in the given revision, and whether
+// it is known at all. Mnemonics of the form "op_3921" name an opcode by its code.
+func LookupName(name string, revision int) (Opcode, bool) {
+	if code, err := strconv.Atoi(strings.TrimPrefix(name, "op_")); strings.HasPrefix(name, "op_") && err == nil {
+		if opcode, ok := Lookup(code, revision); ok {
+			return opcode, true
+		}
+
+		return Opcode{Code: code, Name: name}, true
+	}
+
+	for _, opcode := range Opcodes {
+		if opcode.Name == name && opcode.appliesTo(revision) {
+			return opcode, true
+		}
+	}
+
+	for _, prefix := range []string{"cc_", "if_"} {
+		for _, opcode := range Components {
+			if prefix+opcode.Name != name || !opcode.appliesTo(revision) {
+				continue
+			}
+
+			if prefix == "if_" {
+				return Lookup(opcode.Code+1000, revision)
+			}
+
+			return Lookup(opcode.Code, revision)
+		}
+	}
+
+	return Opcode{}, false
+}
+
+// Mnemonic returns the name of the given opcode in the given revision. Opcodes without a
+// known name are named after their code, such as "op_3921".
+func Mnemonic(code, revision int) string {
+	if opcode, ok := Lookup(code, revision); ok {
+		return opcode.Name
+	}
+
+	return fmt.Sprintf("op_%d", code)
+}
+
+// IsBranch returns whether the given opcode jumps to another instruction, by the amount
+// of instructions in its operand.
+func IsBranch(code int) bool {
+	switch code {
+	case Jump, IfICmpNE, IfICmpEQ, IfICmpLT, IfICmpGT, IfICmpLE, IfICmpGE:
+		return true
+	default:
+		return false
+	}
+}
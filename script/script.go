@@ -0,0 +1,312 @@
+// Package script decodes, disassembles, assembles and decompiles the client scripts
+// (CS2) that drive most of the logic of the interfaces of the client.
+package script
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Archive is the archive that holds a folder for each client script.
+const Archive = 12
+
+// LongRevision is the first revision of which scripts declare long locals and arguments
+// and push long constants.
+const LongRevision = 220
+
+// ErrMalformed is returned when the data of a script is inconsistent.
+var ErrMalformed = errors.New("malformed script data")
+
+// Script is a client script, which is a list of instructions for the stack machine of
+// the client.
+type Script struct {
+	Id   int
+	Name string
+
+	// The amounts of arguments of each type, which are the first locals of the script.
+	IntArgCount    int
+	StringArgCount int
+	LongArgCount   int
+
+	// The amounts of locals of each type, including the arguments.
+	LocalIntCount    int
+	LocalStringCount int
+	LocalLongCount   int
+
+	Instructions []Instruction
+
+	// Switches are the jump tables of the switch instructions, which map each case to
+	// the amount of instructions to jump over, relative to the next instruction.
+	Switches []map[int]int
+}
+
+// Instruction is a single instruction of a Script. Which of the operands is used depends
+// on the opcode, see OperandOf.
+type Instruction struct {
+	Opcode        int
+	IntOperand    int
+	StringOperand string
+	LongOperand   int64
+}
+
+// Operand is the type of the operand of an instruction.
+type Operand int
+
+const (
+	// IntOperand is a 32-bit integer, which most of the core instructions take.
+	IntOperand Operand = iota
+
+	// ByteOperand is a single byte, which the other instructions take. For those that
+	// operate on a component, it selects the component that was created most recently
+	// over the one that triggered the script.
+	ByteOperand
+
+	// StringOperand is a string, which is pushed by the sconst instruction.
+	StringOperand
+
+	// LongOperand is a 64-bit integer, which is pushed by the lconst instruction.
+	LongOperand
+)
+
+// OperandOf returns the type of the operand of the given opcode in the given revision.
+func OperandOf(opcode, revision int) Operand {
+	switch {
+	case opcode == SConst:
+		return StringOperand
+	case opcode == LConst && hasLongs(revision):
+		return LongOperand
+	case opcode < 100 && opcode != Return && opcode != PopInt && opcode != PopString:
+		return IntOperand
+	default:
+		return ByteOperand
+	}
+}
+
+// hasLongs returns whether scripts of the given revision have long sections.
+func hasLongs(revision int) bool {
+	return revision == 0 || revision >= LongRevision
+}
+
+// trailerSize returns the size of the counts at the end of a script of the given revision,
+// which excludes the switch tables.
+func trailerSize(revision int) int {
+	if hasLongs(revision) {
+		return 16
+	}
+
+	return 12
+}
+
+// Load decodes the Script of the specified id from the given Cache, in the layout of
+// the revision of the Cache or, if the script does not fit that layout, in the layout
+// with or without long sections. May return an error.
+func Load(cache *gokira.Cache, id int) (*Script, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// caches of the revision of longs cannot be told apart from the caches of the
+	// revisions just before it, so a script that does not decode in the layout of the
+	// revision is decoded in the other layout, which Decode validates all the same
+	revision := cache.Revision()
+
+	script, err := Decode(id, folder.Data, revision)
+	if err == nil {
+		return script, nil
+	}
+
+	other := LongRevision - 1
+	if !hasLongs(revision) {
+		other = LongRevision
+	}
+
+	if script, otherErr := Decode(id, folder.Data, other); otherErr == nil {
+		return script, nil
+	}
+
+	return nil, err
+}
+
+// LoadByName decodes the Script that is labelled with the given name, such as
+// "[clientscript,chat_send]". May return an error.
+func LoadByName(cache *gokira.Cache, name string) (*Script, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(cache, manifest.Id)
+}
+
+// Decode decodes a Script of the given id from the given data, using the layout of the
+// given revision. The instructions lead the data and are followed by the instruction
+// count, the local and argument counts and the switch tables, while the data ends with
+// the size of the switch tables. May return an error.
+func Decode(id int, data []byte, revision int) (*Script, error) {
+	itr := buffer.NewReader(data)
+
+	if err := itr.Seek(len(data) - 2); err != nil {
+		return nil, ErrMalformed
+	}
+
+	switchSize, err := itr.ReadUInt16()
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(data) - 2 - int(switchSize) - trailerSize(revision)
+	if end < 0 {
+		return nil, ErrMalformed
+	}
+
+	if err := itr.Seek(end); err != nil {
+		return nil, err
+	}
+
+	script := &Script{Id: id}
+
+	count, err := itr.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := []*int{&script.LocalIntCount, &script.LocalStringCount}
+	if hasLongs(revision) {
+		counts = append(counts, &script.LocalLongCount)
+	}
+
+	counts = append(counts, &script.IntArgCount, &script.StringArgCount)
+	if hasLongs(revision) {
+		counts = append(counts, &script.LongArgCount)
+	}
+
+	for _, count := range counts {
+		value, err := itr.ReadUInt16()
+		if err != nil {
+			return nil, err
+		}
+
+		*count = int(value)
+	}
+
+	if script.Switches, err = readSwitches(itr); err != nil {
+		return nil, err
+	}
+
+	if err := itr.Seek(0); err != nil {
+		return nil, err
+	}
+
+	if script.Name, err = itr.ReadCString(); err != nil {
+		return nil, err
+	}
+
+	if count < 0 || int(count) > end {
+		return nil, ErrMalformed
+	}
+
+	script.Instructions = make([]Instruction, 0, count)
+	for itr.Position() < end {
+		instruction, err := readInstruction(itr, revision)
+		if err != nil {
+			return nil, err
+		}
+
+		script.Instructions = append(script.Instructions, instruction)
+	}
+
+	if len(script.Instructions) != int(count) || itr.Position() != end {
+		return nil, ErrMalformed
+	}
+
+	return script, nil
+}
+
+// readSwitches reads the jump tables of the switch instructions. May return an error.
+func readSwitches(itr *buffer.Reader) ([]map[int]int, error) {
+	count, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switches := make([]map[int]int, count)
+	for i := range switches {
+		cases, err := itr.ReadUInt16()
+		if err != nil {
+			return nil, err
+		}
+
+		switches[i] = make(map[int]int, cases)
+		for ; cases > 0; cases-- {
+			value, err := itr.ReadInt32()
+			if err != nil {
+				return nil, err
+			}
+
+			offset, err := itr.ReadInt32()
+			if err != nil {
+				return nil, err
+			}
+
+			switches[i][int(value)] = int(offset)
+		}
+	}
+
+	return switches, nil
+}
+
+// readInstruction reads an opcode and its operand. May return an error.
+func readInstruction(itr *buffer.Reader, revision int) (Instruction, error) {
+	opcode, err := itr.ReadUInt16()
+	if err != nil {
+		return Instruction{}, err
+	}
+
+	instruction := Instruction{Opcode: int(opcode)}
+
+	switch OperandOf(instruction.Opcode, revision) {
+	case StringOperand:
+		instruction.StringOperand, err = itr.ReadCString()
+	case LongOperand:
+		instruction.LongOperand, err = itr.ReadInt64()
+	case IntOperand:
+		var value int32
+		value, err = itr.ReadInt32()
+		instruction.IntOperand = int(value)
+	default:
+		var value byte
+		value, err = itr.ReadByte()
+		instruction.IntOperand = int(value)
+	}
+
+	return instruction, err
+}
+
+// JumpTarget returns the index of the instruction that the instruction at the given
+// index jumps to, and whether it is a jump at all.
+func (script *Script) JumpTarget(index int) (int, bool) {
+	if !IsBranch(script.Instructions[index].Opcode) {
+		return 0, false
+	}
+
+	return index + 1 + script.Instructions[index].IntOperand, true
+}
+
+// SwitchTargets returns the index of the instruction each case of the switch instruction
+// at the given index jumps to, or nil if it is not a switch instruction.
+func (script *Script) SwitchTargets(index int) map[int]int {
+	instruction := script.Instructions[index]
+	if instruction.Opcode != Switch || instruction.IntOperand < 0 || instruction.IntOperand >= len(script.Switches) {
+		return nil
+	}
+
+	targets := make(map[int]int, len(script.Switches[instruction.IntOperand]))
+	for value, offset := range script.Switches[instruction.IntOperand] {
+		targets[value] = index + 1 + offset
+	}
+
+	return targets
+}
@@ -0,0 +1,187 @@
+package script
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// testRevision is a revision of which scripts lack long sections.
+const testRevision = 200
+
+// testScript returns the data of a script that switches over its argument.
+func testScript() []byte {
+	w := buffer.NewWriter()
+	w.WriteCString("")
+
+	w.WriteInt16(ILoad).WriteInt32(0)
+	w.WriteInt16(Switch).WriteInt32(0)
+	w.WriteInt16(SConst).WriteCString("other")
+	w.WriteInt16(Jump).WriteInt32(1)
+	w.WriteInt16(SConst).WriteCString("one")
+	w.WriteInt16(1112).WriteInt8(1)
+	w.WriteInt16(Return).WriteInt8(0)
+
+	// the instruction count and the local and argument counts
+	w.WriteInt32(7)
+	w.WriteInt16(1).WriteInt16(0).WriteInt16(1).WriteInt16(0)
+
+	// a single switch with a single case
+	w.WriteInt8(1)
+	w.WriteInt16(1).WriteInt32(1).WriteInt32(2)
+	w.WriteInt16(11)
+
+	return w.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	script, err := Decode(12, testScript(), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if script.Id != 12 || script.IntArgCount != 1 || script.LocalIntCount != 1 || len(script.Instructions) != 7 {
+		t.Fatalf("unexpected script %+v", script)
+	}
+
+	if script.Instructions[4].StringOperand != "one" || script.Instructions[5].IntOperand != 1 {
+		t.Errorf("unexpected operands %+v", script.Instructions)
+	}
+
+	if target, ok := script.JumpTarget(3); !ok || target != 5 {
+		t.Errorf("expected a jump to 5 but got %v", target)
+	}
+
+	if targets := script.SwitchTargets(1); targets[1] != 4 {
+		t.Errorf("expected case 1 to jump to 4 but got %v", targets)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	data := testScript()
+	data[len(data)-22] = 8
+
+	if _, err := Decode(0, data, testRevision); err != ErrMalformed {
+		t.Errorf("expected a wrong instruction count to be rejected but got %v", err)
+	}
+
+	if _, err := Decode(0, data, LongRevision); err == nil {
+		t.Error("expected the layout of longs to be rejected")
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	script, err := Decode(12, testScript(), testRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, script, testRevision); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		".id                 12",
+		".int_arg_count      1",
+		".string_arg_count   0",
+		".int_var_count      1",
+		".string_var_count   0",
+		"   iload                  0",
+		"   switch",
+		"      1: LABEL4",
+		"   sconst                 \"other\"",
+		"   jump                   LABEL5",
+		"LABEL4:",
+		"   sconst                 \"one\"",
+		"LABEL5:",
+		"   cc_settext             1",
+		"   return",
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Errorf("unexpected listing:\n%s", out.String())
+	}
+}
+
+func TestMnemonic(t *testing.T) {
+	tests := map[int]string{
+		IConst: "iconst",
+		1112:   "cc_settext",
+		2112:   "if_settext",
+		4000:   "add",
+		9999:   "op_9999",
+	}
+
+	for code, expected := range tests {
+		if name := Mnemonic(code, 0); name != expected {
+			t.Errorf("expected %v to be named %v but got %v", code, expected, name)
+		}
+	}
+
+	if Mnemonic(LConst, testRevision) != "op_54" || Mnemonic(LConst, 0) != "lconst" {
+		t.Error("expected lconst to only exist since the revision of longs")
+	}
+}
+
+// testLongScript returns the data of a script that stores a long constant.
+func testLongScript() []byte {
+	w := buffer.NewWriter()
+	w.WriteCString("[proc,longs]")
+	w.WriteInt16(LConst).WriteInt64(1 << 40)
+	w.WriteInt16(LStore).WriteInt32(0)
+	w.WriteInt16(Return).WriteInt8(0)
+
+	w.WriteInt32(3)
+	w.WriteInt16(0).WriteInt16(0).WriteInt16(1).WriteInt16(0).WriteInt16(0).WriteInt16(0)
+	w.WriteInt8(0)
+	w.WriteInt16(1)
+
+	return w.Bytes()
+}
+
+func TestDecodeLongs(t *testing.T) {
+	script, err := Decode(0, testLongScript(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if script.Name != "[proc,longs]" || script.LocalLongCount != 1 || script.Instructions[0].LongOperand != 1<<40 {
+		t.Errorf("unexpected script %+v", script)
+	}
+}
+
+func TestLoadOfModernCache(t *testing.T) {
+	// the db table archive marks the cache as one of the latest revision, although
+	// the caches of the revisions before longs carry it too
+	builder := cachetest.New()
+	builder.AddFile(Archive, 0, testScript())
+	builder.AddFile(Archive, 1, testLongScript())
+	builder.AddFile(21, 0, []byte{0})
+
+	cache := builder.Build(t)
+	if revision := cache.Revision(); revision != 0 {
+		t.Fatalf("expected the latest revision but got %v", revision)
+	}
+
+	script, err := Load(cache, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(script.Instructions) != 7 || script.LocalIntCount != 1 || len(script.Switches) != 1 {
+		t.Errorf("unexpected script %+v", script)
+	}
+
+	if script, err = Load(cache, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if script.LocalLongCount != 1 || script.Instructions[0].LongOperand != 1<<40 {
+		t.Errorf("unexpected script %+v", script)
+	}
+}
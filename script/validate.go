@@ -0,0 +1,198 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StackError is returned when the instructions of a script do not balance the stack.
+type StackError struct {
+	// Index is the index of the offending instruction.
+	Index   int
+	Message string
+}
+
+func (err *StackError) Error() string {
+	return fmt.Sprintf("instruction %d: %s", err.Index, err.Message)
+}
+
+// stack is the amount of values of each type on the stack before an instruction. The
+// heights are unknown once an instruction of which the effect is unknown is executed.
+type stack struct {
+	ints    int
+	strings int
+	longs   int
+	known   bool
+}
+
+// Validate verifies that every jump of the given Script lands on an instruction and that
+// no path through the script pops a value off an empty stack, ends without returning or
+// joins another path with a stack of a different height, using the stack effects of the
+// opcodes in the given revision. Instructions of which the effect is unknown, such as
+// invocations of other scripts, end the verification of the stack along their path.
+// May return an error.
+func Validate(script *Script, revision int) error {
+	_, err := script.analyze(revision)
+	return err
+}
+
+// analyze determines the stack before each instruction of the script that is reached,
+// see Validate. May return an error.
+func (script *Script) analyze(revision int) ([]*stack, error) {
+	stacks := make([]*stack, len(script.Instructions))
+	if len(stacks) == 0 {
+		return stacks, nil
+	}
+
+	stacks[0] = &stack{known: true}
+	pending := []int{0}
+
+	for len(pending) > 0 {
+		index := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		after, err := script.execute(index, *stacks[index], revision)
+		if err != nil {
+			return nil, err
+		}
+
+		successors, err := script.successors(index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, successor := range successors {
+			if successor == len(stacks) {
+				return nil, &StackError{Index: index, Message: "execution continues past the last instruction"}
+			}
+
+			current := stacks[successor]
+			switch {
+			case current == nil || !current.known && after.known:
+				stacks[successor] = &after
+				pending = append(pending, successor)
+			case current.known && after.known && *current != after:
+				return nil, &StackError{Index: successor, Message: fmt.Sprintf(
+					"paths join with different stacks: %s versus %s", current.describe(), after.describe())}
+			}
+		}
+	}
+
+	return stacks, nil
+}
+
+// returned returns the stack that the script returns, and whether it is known.
+func (script *Script) returned(revision int) (stack, bool) {
+	stacks, err := script.analyze(revision)
+	if err != nil {
+		return stack{}, false
+	}
+
+	for i, instruction := range script.Instructions {
+		if instruction.Opcode == Return && stacks[i] != nil && stacks[i].known {
+			return *stacks[i], true
+		}
+	}
+
+	return stack{}, false
+}
+
+// execute applies the stack effect of the instruction at the given index to the given
+// stack. May return an error.
+func (script *Script) execute(index int, before stack, revision int) (stack, error) {
+	instruction := script.Instructions[index]
+	opcode, _ := Lookup(instruction.Opcode, revision)
+
+	if instruction.Opcode == JoinString {
+		opcode = opcode.Effect(strings.Repeat("s", instruction.IntOperand), "s")
+	}
+
+	if !opcode.HasEffect || !before.known {
+		return stack{}, nil
+	}
+
+	after := before
+	for _, value := range opcode.Pops {
+		height := after.height(value)
+		if *height == 0 {
+			return after, &StackError{Index: index, Message: fmt.Sprintf("%s pops %s off an empty stack", opcode.Name, typeName(value))}
+		}
+
+		*height--
+	}
+
+	for _, value := range opcode.Pushes {
+		*after.height(value)++
+	}
+
+	return after, nil
+}
+
+// successors returns the indices of the instructions that may be executed after the
+// instruction at the given index. May return an error.
+func (script *Script) successors(index int) ([]int, error) {
+	instruction := script.Instructions[index]
+	next := []int{index + 1}
+
+	switch {
+	case instruction.Opcode == Return:
+		return nil, nil
+	case instruction.Opcode == Switch:
+		if instruction.IntOperand < 0 || instruction.IntOperand >= len(script.Switches) {
+			return nil, &StackError{Index: index, Message: "switch refers to a missing switch table"}
+		}
+
+		targets := script.SwitchTargets(index)
+		for _, target := range targets {
+			next = append(next, target)
+		}
+
+		// the order of the cases is irrelevant but kept stable for the sake of errors
+		sort.Ints(next[1:])
+	case IsBranch(instruction.Opcode):
+		target, _ := script.JumpTarget(index)
+		if instruction.Opcode == Jump {
+			next = next[:0]
+		}
+
+		next = append(next, target)
+	}
+
+	for _, target := range next {
+		if target < 0 || target > len(script.Instructions) {
+			return nil, &StackError{Index: index, Message: fmt.Sprintf("jump to instruction %d is out of bounds", target)}
+		}
+	}
+
+	return next, nil
+}
+
+// height returns the height of the stack of values of the given type.
+func (s *stack) height(value rune) *int {
+	switch value {
+	case 's':
+		return &s.strings
+	case 'l':
+		return &s.longs
+	default:
+		return &s.ints
+	}
+}
+
+// describe describes the heights of the stacks.
+func (s *stack) describe() string {
+	return fmt.Sprintf("%d ints, %d strings, %d longs", s.ints, s.strings, s.longs)
+}
+
+// typeName returns the name of the given type of value.
+func typeName(value rune) string {
+	switch value {
+	case 's':
+		return "a string"
+	case 'l':
+		return "a long"
+	default:
+		return "an int"
+	}
+}
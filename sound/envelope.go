@@ -0,0 +1,126 @@
+package sound
+
+import "github.com/sinoz/gokira/buffer"
+
+// The waveforms of the oscillators of an Instrument and of its modulators.
+const (
+	// Silence produces no sound.
+	Silence = 0
+
+	// Square alternates between the positive and the negative amplitude.
+	Square = 1
+
+	// Sine follows a sine wave.
+	Sine = 2
+
+	// Sawtooth ramps from the negative to the positive amplitude.
+	Sawtooth = 3
+
+	// Noise produces white noise.
+	Noise = 4
+)
+
+// Envelope shapes a parameter of an Instrument over the duration of the instrument.
+// Each of its segments ramps linearly from the phase of the previous segment to its own
+// phase, up to the point in time of its duration. Durations are in units of 1/65536th
+// of the duration of the instrument and phases range from 0 to 65535.
+type Envelope struct {
+	// Form is the waveform of the oscillators or of the modulator that the envelope
+	// drives, see Square and its siblings.
+	Form int
+
+	// Start and End are the range the phases of the envelope map onto, such as the
+	// lowest and the highest pitch of the oscillators.
+	Start int
+	End   int
+
+	Durations []int
+	Phases    []int
+}
+
+// newEnvelope constructs an Envelope of a single segment that ramps up over the
+// duration of the instrument, which is what envelopes default to when their segments
+// are not encoded.
+func newEnvelope() *Envelope {
+	return &Envelope{Durations: []int{0, 65535}, Phases: []int{0, 65535}}
+}
+
+// readEnvelope reads an Envelope, which starts with its form and its range followed
+// by its segments.
+func readEnvelope(c *buffer.Cursor) *Envelope {
+	envelope := &Envelope{Form: c.UInt8(), Start: c.Int32(), End: c.Int32()}
+	readSegments(c, envelope)
+
+	return envelope
+}
+
+// readSegments reads the segments of the given Envelope, which are preceded by their
+// amount.
+func readSegments(c *buffer.Cursor, envelope *Envelope) {
+	segments := c.UInt8()
+
+	envelope.Durations = make([]int, segments)
+	envelope.Phases = make([]int, segments)
+
+	for i := 0; i < segments; i++ {
+		envelope.Durations[i] = c.UInt16()
+		envelope.Phases[i] = c.UInt16()
+	}
+}
+
+// envelopeStepper steps through the segments of an Envelope over a given amount of
+// samples. Amplitudes are in a fixed point format of 15 fractional bits.
+type envelopeStepper struct {
+	envelope *Envelope
+
+	segment   int
+	ticks     int32
+	position  int32
+	step      int32
+	amplitude int32
+}
+
+// newEnvelopeStepper constructs an envelopeStepper that steps through the given
+// Envelope from its start.
+func newEnvelopeStepper(envelope *Envelope) *envelopeStepper {
+	return &envelopeStepper{envelope: envelope}
+}
+
+// next returns the phase of the envelope at the next of the given amount of samples.
+func (s *envelopeStepper) next(samples int) int32 {
+	envelope := s.envelope
+
+	if s.position >= s.ticks && len(envelope.Phases) > 0 {
+		s.amplitude = int32(envelope.Phases[s.segment]) << 15
+
+		s.segment++
+		if s.segment >= len(envelope.Phases) {
+			s.segment = len(envelope.Phases) - 1
+		}
+
+		s.ticks = toInt32(float64(envelope.Durations[s.segment]) / 65536.0 * float64(samples))
+		if s.ticks > s.position {
+			s.step = ((int32(envelope.Phases[s.segment]) << 15) - s.amplitude) / (s.ticks - s.position)
+		}
+	}
+
+	s.amplitude += s.step
+	s.position++
+
+	return (s.amplitude - s.step) >> 15
+}
+
+// toInt32 converts the given value to an int32 the way the client does, truncating
+// towards zero and saturating at the bounds of the type.
+func toInt32(value float64) int32 {
+	switch {
+	case value != value:
+		return 0
+	case value >= 2147483647:
+		return 2147483647
+	case value <= -2147483648:
+		return -2147483648
+	default:
+		return int32(value)
+	}
+}
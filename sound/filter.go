@@ -0,0 +1,169 @@
+package sound
+
+import (
+	"math"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// maxPairs is the highest amount of pairs of a Filter in either direction.
+const maxPairs = 4
+
+// The directions of a Filter.
+const (
+	// Feedforward filters the input of the filter, through its zeros.
+	Feedforward = 0
+
+	// Feedback filters the output of the filter, through its poles.
+	Feedback = 1
+)
+
+// Filter is an infinite impulse response filter that an Instrument passes its samples
+// through. The filter consists of pairs of complex conjugate zeros and poles, of which
+// the phases and the magnitudes are interpolated between two sets over the duration of
+// the instrument, following the filter envelope of the instrument. Every field is
+// indexed by direction first, see Feedforward and Feedback.
+type Filter struct {
+	// Pairs is the amount of pairs of zeros and of poles.
+	Pairs [2]int
+
+	// Unity is the gain of the filter at the start and at the end, in units of
+	// 1/327.68th of a decibel.
+	Unity [2]int
+
+	// Phases are the phases of each pair at the start and at the end, as a pitch in
+	// units of 1/8192th of an octave above 32.7 Hz.
+	Phases [2][2][maxPairs]int
+
+	// Magnitudes are the magnitudes of each pair at the start and at the end, in units
+	// of 1/655.36th of a decibel.
+	Magnitudes [2][2][maxPairs]int
+}
+
+// readFilter reads a Filter, along with the segments of the given filter envelope if the
+// filter changes over time. May return an error.
+func readFilter(c *buffer.Cursor, envelope *Envelope) (*Filter, error) {
+	filter := new(Filter)
+
+	pairs := c.UInt8()
+	if pairs == 0 {
+		return filter, nil
+	}
+
+	filter.Pairs = [2]int{pairs >> 4, pairs & 0xF}
+	if filter.Pairs[Feedforward] > maxPairs || filter.Pairs[Feedback] > maxPairs {
+		return nil, ErrMalformed
+	}
+
+	filter.Unity = [2]int{c.UInt16(), c.UInt16()}
+	changes := c.UInt8()
+
+	for direction := 0; direction < 2; direction++ {
+		for pair := 0; pair < filter.Pairs[direction]; pair++ {
+			filter.Phases[direction][0][pair] = c.UInt16()
+			filter.Magnitudes[direction][0][pair] = c.UInt16()
+		}
+	}
+
+	for direction := 0; direction < 2; direction++ {
+		for pair := 0; pair < filter.Pairs[direction]; pair++ {
+			if changes&(1<<uint(direction*4)<<uint(pair)) != 0 {
+				filter.Phases[direction][1][pair] = c.UInt16()
+				filter.Magnitudes[direction][1][pair] = c.UInt16()
+			} else {
+				filter.Phases[direction][1][pair] = filter.Phases[direction][0][pair]
+				filter.Magnitudes[direction][1][pair] = filter.Magnitudes[direction][0][pair]
+			}
+		}
+	}
+
+	if changes != 0 || filter.Unity[1] != filter.Unity[0] {
+		readSegments(c, envelope)
+	}
+
+	return filter, nil
+}
+
+// magnitude returns the radius of the given pair at the given point in time, which
+// ranges from 0 at the start to 1 at the end.
+func (filter *Filter) magnitude(direction, pair int, t float32) float32 {
+	magnitudes := filter.Magnitudes[direction]
+
+	decibels := float32(magnitudes[0][pair]) + t*float32(magnitudes[1][pair]-magnitudes[0][pair])
+	decibels *= 0.0015258789
+
+	return 1 - float32(math.Pow(10, float64(-decibels/20)))
+}
+
+// phase returns the angle of the given pair at the given point in time, which ranges
+// from 0 at the start to 1 at the end.
+func (filter *Filter) phase(direction, pair int, t float32) float32 {
+	phases := filter.Phases[direction]
+
+	octaves := float32(phases[0][pair]) + t*float32(phases[1][pair]-phases[0][pair])
+	octaves *= 1.2207031e-4
+
+	frequency := 32.703197 * float32(math.Pow(2, float64(octaves)))
+	return frequency * 3.1415927 / 11025
+}
+
+// filterState holds the coefficients of a Filter at a point in time, in a fixed point
+// format of 16 fractional bits.
+type filterState struct {
+	coefficients [2][2 * maxPairs]int32
+	gain         float32
+	forward      int32
+}
+
+// update computes the coefficients of the given direction of the given Filter at the
+// given point in time, which ranges from 0 at the start to 1 at the end, by expanding
+// the product of the second order sections of its pairs into a single polynomial.
+// Returns the amount of coefficients.
+func (s *filterState) update(filter *Filter, direction int, t float32) int {
+	if direction == Feedforward {
+		unity := float32(filter.Unity[0]) + float32(filter.Unity[1]-filter.Unity[0])*t
+		unity *= 0.0030517578
+
+		s.gain = float32(math.Pow(0.1, float64(unity/20)))
+		s.forward = toInt32(float64(s.gain * 65536))
+	}
+
+	pairs := filter.Pairs[direction]
+	if pairs == 0 {
+		return 0
+	}
+
+	var polynomial [2 * maxPairs]float32
+
+	magnitude := filter.magnitude(direction, 0, t)
+	polynomial[0] = -2 * magnitude * float32(math.Cos(float64(filter.phase(direction, 0, t))))
+	polynomial[1] = magnitude * magnitude
+
+	for pair := 1; pair < pairs; pair++ {
+		magnitude := filter.magnitude(direction, pair, t)
+		linear := -2 * magnitude * float32(math.Cos(float64(filter.phase(direction, pair, t))))
+		quadratic := magnitude * magnitude
+
+		polynomial[pair*2+1] = polynomial[pair*2-1] * quadratic
+		polynomial[pair*2] = polynomial[pair*2-1]*linear + polynomial[pair*2-2]*quadratic
+
+		for i := pair*2 - 1; i >= 2; i-- {
+			polynomial[i] += polynomial[i-1]*linear + polynomial[i-2]*quadratic
+		}
+
+		polynomial[1] += polynomial[0]*linear + quadratic
+		polynomial[0] += linear
+	}
+
+	if direction == Feedforward {
+		for i := 0; i < pairs*2; i++ {
+			polynomial[i] *= s.gain
+		}
+	}
+
+	for i := 0; i < pairs*2; i++ {
+		s.coefficients[direction][i] = toInt32(float64(polynomial[i] * 65536))
+	}
+
+	return pairs * 2
+}
@@ -0,0 +1,425 @@
+package sound
+
+import (
+	"math"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// maxOscillators is the highest amount of oscillators of an Instrument.
+const maxOscillators = 10
+
+// waveLength is the length of the period of a waveform, in units of phase.
+const waveLength = 32768
+
+var (
+	// noise is a table of random signs, seeded the way the client seeds it.
+	noise = newNoise()
+
+	// sine is a table of a period of a sine wave with an amplitude of 16384.
+	sine = newSine()
+)
+
+// Instrument is a voice of a SoundEffect, which sums the waveforms of its oscillators
+// and shapes them through its envelopes, its modulators, its gate, its delay and its
+// filter.
+type Instrument struct {
+	// Pitch drives the pitch of the oscillators and holds their waveform, while Volume
+	// drives their volume.
+	Pitch  *Envelope
+	Volume *Envelope
+
+	// PitchModifier and PitchModifierAmplitude drive the rate and the depth of the
+	// vibrato of the oscillators, or are nil if there is no vibrato.
+	PitchModifier          *Envelope
+	PitchModifierAmplitude *Envelope
+
+	// VolumeMultiplier and VolumeMultiplierAmplitude drive the rate and the depth of
+	// the tremolo of the oscillators, or are nil if there is no tremolo.
+	VolumeMultiplier          *Envelope
+	VolumeMultiplierAmplitude *Envelope
+
+	// Release and Attack drive how long the gate of the instrument stays closed and
+	// open respectively, or are nil if the instrument is not gated.
+	Release *Envelope
+	Attack  *Envelope
+
+	Oscillators []*Oscillator
+
+	// DelayTime is the time in milliseconds after which the samples of the instrument
+	// echo, and DelayDecay the percentage of the volume that they echo at.
+	DelayTime  int
+	DelayDecay int
+
+	// Duration is how long the instrument plays, and Offset how long after the start of
+	// the sound effect it starts playing, in milliseconds.
+	Duration int
+	Offset   int
+
+	Filter *Filter
+
+	// FilterEnvelope drives the change of the Filter from its start to its end.
+	FilterEnvelope *Envelope
+}
+
+// Oscillator produces the waveform of an Instrument at a pitch and a volume relative
+// to those of the instrument.
+type Oscillator struct {
+	// Volume is the percentage of the volume of the instrument.
+	Volume int
+
+	// Pitch is the offset to the pitch of the instrument, in tenths of a semitone.
+	Pitch int
+
+	// Delay is the time in milliseconds after which the oscillator starts.
+	Delay int
+}
+
+// readInstrument reads an Instrument. Its optional pairs of envelopes are each preceded
+// by the form of their first envelope, which is zero if they are left out. May return
+// an error.
+func readInstrument(c *buffer.Cursor) (*Instrument, error) {
+	instrument := &Instrument{Pitch: readEnvelope(c), Volume: readEnvelope(c)}
+
+	if c.PeekByte() != 0 {
+		instrument.PitchModifier = readEnvelope(c)
+		instrument.PitchModifierAmplitude = readEnvelope(c)
+	} else {
+		c.UInt8()
+	}
+
+	if c.PeekByte() != 0 {
+		instrument.VolumeMultiplier = readEnvelope(c)
+		instrument.VolumeMultiplierAmplitude = readEnvelope(c)
+	} else {
+		c.UInt8()
+	}
+
+	if c.PeekByte() != 0 {
+		instrument.Release = readEnvelope(c)
+		instrument.Attack = readEnvelope(c)
+	} else {
+		c.UInt8()
+	}
+
+	for i := 0; i < maxOscillators; i++ {
+		volume := c.Smart()
+		if volume == 0 {
+			break
+		}
+
+		instrument.Oscillators = append(instrument.Oscillators, &Oscillator{
+			Volume: volume,
+			Pitch:  c.SignedSmart(),
+			Delay:  c.Smart(),
+		})
+	}
+
+	instrument.DelayTime = c.Smart()
+	instrument.DelayDecay = c.Smart()
+	instrument.Duration = c.UInt16()
+	instrument.Offset = c.UInt16()
+
+	instrument.FilterEnvelope = newEnvelope()
+
+	filter, err := readFilter(c, instrument.FilterEnvelope)
+	if err != nil {
+		return nil, err
+	}
+
+	instrument.Filter = filter
+	return instrument, nil
+}
+
+// oscillatorState holds the progress of an Oscillator through its waveform.
+type oscillatorState struct {
+	phase     int32
+	delay     int32
+	volume    int32
+	pitch     int32
+	basePitch int32
+}
+
+// Synthesize synthesizes the samples of the instrument at the SampleRate, over the
+// Duration of the instrument. Samples range from -32768 to 32767.
+func (instrument *Instrument) Synthesize() []int {
+	count := instrument.Duration * SampleRate / 1000
+
+	samples := make([]int32, count)
+	if instrument.Duration >= 10 {
+		instrument.synthesize(samples)
+	}
+
+	output := make([]int, count)
+	for i, sample := range samples {
+		output[i] = int(sample)
+	}
+
+	return output
+}
+
+// synthesize synthesizes the samples of the instrument into the given slice, following
+// the integer arithmetic of the client.
+func (instrument *Instrument) synthesize(samples []int32) {
+	count := len(samples)
+	samplesPerMillisecond := float64(count) / float64(instrument.Duration)
+
+	pitch := newEnvelopeStepper(instrument.Pitch)
+	volume := newEnvelopeStepper(instrument.Volume)
+
+	var pitchModifier, pitchModifierAmplitude *envelopeStepper
+	var vibratoPhase, vibratoStep, vibratoBaseStep int32
+	if instrument.PitchModifier != nil {
+		pitchModifier = newEnvelopeStepper(instrument.PitchModifier)
+		pitchModifierAmplitude = newEnvelopeStepper(instrument.PitchModifierAmplitude)
+
+		vibratoStep = toInt32(float64(int32(instrument.PitchModifier.End)-int32(instrument.PitchModifier.Start)) * 32.768 / samplesPerMillisecond)
+		vibratoBaseStep = toInt32(float64(instrument.PitchModifier.Start) * 32.768 / samplesPerMillisecond)
+	}
+
+	var volumeMultiplier, volumeMultiplierAmplitude *envelopeStepper
+	var tremoloPhase, tremoloStep, tremoloBaseStep int32
+	if instrument.VolumeMultiplier != nil {
+		volumeMultiplier = newEnvelopeStepper(instrument.VolumeMultiplier)
+		volumeMultiplierAmplitude = newEnvelopeStepper(instrument.VolumeMultiplierAmplitude)
+
+		tremoloStep = toInt32(float64(int32(instrument.VolumeMultiplier.End)-int32(instrument.VolumeMultiplier.Start)) * 32.768 / samplesPerMillisecond)
+		tremoloBaseStep = toInt32(float64(instrument.VolumeMultiplier.Start) * 32.768 / samplesPerMillisecond)
+	}
+
+	oscillators := make([]oscillatorState, len(instrument.Oscillators))
+	for i, oscillator := range instrument.Oscillators {
+		oscillators[i] = oscillatorState{
+			delay:     toInt32(float64(oscillator.Delay) * samplesPerMillisecond),
+			volume:    int32(oscillator.Volume<<14) / 100,
+			pitch:     toInt32(float64(int32(instrument.Pitch.End)-int32(instrument.Pitch.Start)) * 32.768 * math.Pow(1.0057929410678534, float64(oscillator.Pitch)) / samplesPerMillisecond),
+			basePitch: toInt32(float64(instrument.Pitch.Start) * 32.768 / samplesPerMillisecond),
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		frequency := pitch.next(count)
+		amplitude := volume.next(count)
+
+		if pitchModifier != nil {
+			rate := pitchModifier.next(count)
+			depth := pitchModifierAmplitude.next(count)
+
+			frequency += evaluateWave(vibratoPhase, depth, instrument.PitchModifier.Form) >> 1
+			vibratoPhase += vibratoBaseStep + (rate * vibratoStep >> 16)
+		}
+
+		if volumeMultiplier != nil {
+			rate := volumeMultiplier.next(count)
+			depth := volumeMultiplierAmplitude.next(count)
+
+			amplitude = amplitude * ((evaluateWave(tremoloPhase, depth, instrument.VolumeMultiplier.Form) >> 1) + 32768) >> 15
+			tremoloPhase += tremoloBaseStep + (rate * tremoloStep >> 16)
+		}
+
+		for j, oscillator := range instrument.Oscillators {
+			if oscillator.Volume == 0 {
+				continue
+			}
+
+			state := &oscillators[j]
+
+			position := int(state.delay) + i
+			if position < count {
+				samples[position] += evaluateWave(state.phase, amplitude*state.volume>>15, instrument.Pitch.Form)
+				state.phase += (frequency * state.pitch >> 16) + state.basePitch
+			}
+		}
+	}
+
+	if instrument.Release != nil {
+		instrument.gate(samples)
+	}
+
+	if instrument.DelayTime > 0 && instrument.DelayDecay > 0 {
+		delay := int(toInt32(float64(instrument.DelayTime) * samplesPerMillisecond))
+		for i := delay; i < count; i++ {
+			samples[i] += samples[i-delay] * int32(instrument.DelayDecay) / 100
+		}
+	}
+
+	if instrument.Filter.Pairs[Feedforward] > 0 || instrument.Filter.Pairs[Feedback] > 0 {
+		instrument.filter(samples)
+	}
+
+	for i, sample := range samples {
+		if sample < -32768 {
+			samples[i] = -32768
+		} else if sample > 32767 {
+			samples[i] = 32767
+		}
+	}
+}
+
+// gate silences the given samples whenever the gate of the instrument is closed. The
+// gate toggles after as many samples as the Release envelope dictates while it is
+// closed, and as the Attack envelope dictates while it is open.
+func (instrument *Instrument) gate(samples []int32) {
+	count := len(samples)
+
+	release := newEnvelopeStepper(instrument.Release)
+	attack := newEnvelopeStepper(instrument.Attack)
+
+	start, end := int32(instrument.Release.Start), int32(instrument.Release.End)
+
+	var elapsed int32
+	closed := true
+
+	for i := 0; i < count; i++ {
+		releasePhase := release.next(count)
+		attackPhase := attack.next(count)
+
+		var threshold int32
+		if closed {
+			threshold = (releasePhase * (end - start) >> 8) + start
+		} else {
+			threshold = (attackPhase * (end - start) >> 8) + start
+		}
+
+		elapsed += 256
+		if elapsed >= threshold {
+			elapsed = 0
+			closed = !closed
+		}
+
+		if closed {
+			samples[i] = 0
+		}
+	}
+}
+
+// filter passes the given samples through the Filter of the instrument, in place. The
+// coefficients of the filter are updated every 128 samples, following the filter
+// envelope of the instrument.
+func (instrument *Instrument) filter(samples []int32) {
+	count := len(samples)
+	envelope := newEnvelopeStepper(instrument.FilterEnvelope)
+
+	var state filterState
+
+	t := float32(envelope.next(count+1)) / 65536
+	feedforward := state.update(instrument.Filter, Feedforward, t)
+	feedback := state.update(instrument.Filter, Feedback, t)
+
+	if count < feedforward+feedback {
+		return
+	}
+
+	// the zeros look ahead by as many samples as there are coefficients, while the
+	// poles look back at the samples that are filtered already
+	sample := func(i, poles int) int32 {
+		output := int32(int64(samples[i+feedforward]) * int64(state.forward) >> 16)
+		for j := 0; j < feedforward; j++ {
+			output += int32(int64(samples[i+feedforward-1-j]) * int64(state.coefficients[Feedforward][j]) >> 16)
+		}
+
+		for j := 0; j < poles; j++ {
+			output -= int32(int64(samples[i-1-j]) * int64(state.coefficients[Feedback][j]) >> 16)
+		}
+
+		return output
+	}
+
+	i := 0
+
+	end := feedback
+	if end > count-feedforward {
+		end = count - feedforward
+	}
+
+	for ; i < end; i++ {
+		samples[i] = sample(i, i)
+		t = float32(envelope.next(count+1)) / 65536
+	}
+
+	end = 128
+	for {
+		if end > count-feedforward {
+			end = count - feedforward
+		}
+
+		for ; i < end; i++ {
+			samples[i] = sample(i, feedback)
+			t = float32(envelope.next(count+1)) / 65536
+		}
+
+		if i >= count-feedforward {
+			break
+		}
+
+		feedforward = state.update(instrument.Filter, Feedforward, t)
+		feedback = state.update(instrument.Filter, Feedback, t)
+		end += 128
+	}
+
+	// the last samples lack the samples to look ahead at
+	for ; i < count; i++ {
+		var output int32
+		for j := i + feedforward - count; j < feedforward; j++ {
+			output += int32(int64(samples[i+feedforward-1-j]) * int64(state.coefficients[Feedforward][j]) >> 16)
+		}
+
+		for j := 0; j < feedback; j++ {
+			output -= int32(int64(samples[i-1-j]) * int64(state.coefficients[Feedback][j]) >> 16)
+		}
+
+		samples[i] = output
+		envelope.next(count + 1)
+	}
+}
+
+// evaluateWave returns the value of the given waveform at the given phase and the given
+// amplitude.
+func evaluateWave(phase, amplitude int32, form int) int32 {
+	switch form {
+	case Square:
+		if phase&(waveLength-1) < waveLength/2 {
+			return amplitude
+		}
+
+		return -amplitude
+
+	case Sine:
+		return sine[phase&(waveLength-1)] * amplitude >> 14
+
+	case Sawtooth:
+		return (amplitude * (phase & (waveLength - 1)) >> 14) - amplitude
+
+	case Noise:
+		return amplitude * noise[phase/2607&(waveLength-1)]
+
+	default:
+		return 0
+	}
+}
+
+// newNoise constructs the table of noise, drawing random numbers from the linear
+// congruential generator of java.util.Random seeded with zero like the client does.
+func newNoise() []int32 {
+	const multiplier, mask = 0x5DEECE66D, 1<<48 - 1
+
+	table := make([]int32, waveLength)
+
+	seed := int64(0x5DEECE66D)
+	for i := range table {
+		seed = (seed*multiplier + 0xB) & mask
+		table[i] = int32(seed>>16)&2 - 1
+	}
+
+	return table
+}
+
+// newSine constructs the table of a sine wave.
+func newSine() []int32 {
+	table := make([]int32, waveLength)
+	for i := range table {
+		table[i] = int32(math.Sin(float64(i)/5215.1903) * 16384)
+	}
+
+	return table
+}
@@ -0,0 +1,123 @@
+// Package sound decodes the sound effects of the cache, which describe instruments that
+// the client synthesizes at runtime rather than recorded samples, and renders them into
+// pulse-code modulated audio that can be written as WAV files.
+package sound
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Archive is the archive that holds a folder for each sound effect.
+const Archive = 4
+
+// SampleRate is the amount of samples per second that sound effects are rendered at.
+const SampleRate = 22050
+
+// maxInstruments is the amount of instruments of a SoundEffect.
+const maxInstruments = 10
+
+// ErrMalformed is returned when a sound effect is malformed.
+var ErrMalformed = errors.New("sound effect is malformed")
+
+// SoundEffect is a sound effect, which mixes the samples of up to ten instruments.
+type SoundEffect struct {
+	Id int
+
+	// Instruments are the instruments of the sound effect, of which those that are
+	// left out are nil.
+	Instruments [maxInstruments]*Instrument
+
+	// LoopStart and LoopEnd are the range of the sound effect in milliseconds that
+	// repeats when the sound effect is looped.
+	LoopStart int
+	LoopEnd   int
+}
+
+// Load decodes the SoundEffect of the specified id from the given Cache. May return an
+// error.
+func Load(cache *gokira.Cache, id int) (*SoundEffect, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(id, folder.Data)
+}
+
+// Decode decodes a SoundEffect of the given id from the given data, which lists each of
+// the instruments or a zero in place of one that is left out, followed by the loop.
+// May return an error.
+func Decode(id int, data []byte) (*SoundEffect, error) {
+	c := buffer.NewCursor(data)
+
+	effect := &SoundEffect{Id: id}
+	for i := range effect.Instruments {
+		if c.PeekByte() == 0 {
+			c.UInt8()
+			continue
+		}
+
+		instrument, err := readInstrument(c)
+		if err != nil {
+			return nil, err
+		}
+
+		effect.Instruments[i] = instrument
+	}
+
+	effect.LoopStart = c.UInt16()
+	effect.LoopEnd = c.UInt16()
+
+	if c.Err() != nil {
+		return nil, ErrMalformed
+	}
+
+	return effect, nil
+}
+
+// Duration returns the duration of the sound effect in milliseconds, which lasts until
+// the last of its instruments stops playing.
+func (effect *SoundEffect) Duration() int {
+	var duration int
+	for _, instrument := range effect.Instruments {
+		if instrument != nil && instrument.Offset+instrument.Duration > duration {
+			duration = instrument.Offset + instrument.Duration
+		}
+	}
+
+	return duration
+}
+
+// Render synthesizes each of the instruments of the sound effect and mixes them into
+// signed 8-bit samples at the SampleRate, the way the client does.
+func (effect *SoundEffect) Render() *Sound {
+	samples := make([]int8, effect.Duration()*SampleRate/1000)
+
+	for _, instrument := range effect.Instruments {
+		if instrument == nil {
+			continue
+		}
+
+		offset := instrument.Offset * SampleRate / 1000
+		for i, sample := range instrument.Synthesize() {
+			mixed := sample>>8 + int(samples[i+offset])
+			if mixed < -128 {
+				mixed = -128
+			} else if mixed > 127 {
+				mixed = 127
+			}
+
+			samples[i+offset] = int8(mixed)
+		}
+	}
+
+	return &Sound{
+		SampleRate: SampleRate,
+		Samples:    samples,
+		LoopStart:  effect.LoopStart * SampleRate / 1000,
+		LoopEnd:    effect.LoopEnd * SampleRate / 1000,
+	}
+}
@@ -0,0 +1,188 @@
+package sound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// testEnvelope encodes an envelope of the given form and range of the given segments,
+// which are pairs of durations and peaks.
+func testEnvelope(form, start, end int, segments ...[2]int) []byte {
+	w := buffer.NewWriter().WriteInt8(form).WriteInt32(start).WriteInt32(end).WriteInt8(len(segments))
+	for _, segment := range segments {
+		w.WriteInt16(segment[0]).WriteInt16(segment[1])
+	}
+
+	return w.Bytes()
+}
+
+// loadTestEffect builds the fixture cache that holds a sound effect of a square wave
+// instrument and a filtered sine wave instrument, and loads the sound effect.
+func loadTestEffect(t *testing.T) *SoundEffect {
+	square := buffer.NewWriter().
+		WriteBytes(testEnvelope(1, 1000, 1000, [2]int{0, 0})).
+		WriteBytes(testEnvelope(0, 0, 0, [2]int{0, 65535})).
+		WriteInt8(0).WriteInt8(0).WriteInt8(0).
+		WriteInt8(100).WriteInt8(64).WriteInt8(0).WriteInt8(0).
+		WriteInt8(0).WriteInt8(0).WriteInt16(100).WriteInt16(0).
+		WriteInt8(0)
+
+	sine := buffer.NewWriter().
+		WriteBytes(testEnvelope(2, 400, 800, [2]int{0, 0}, [2]int{65535, 65535})).
+		WriteBytes(testEnvelope(0, 0, 0, [2]int{0, 65535})).
+		WriteBytes(testEnvelope(2, 0, 100, [2]int{0, 65535})).
+		WriteBytes(testEnvelope(0, 0, 0, [2]int{0, 20000})).
+		WriteInt8(0).WriteInt8(0).
+		WriteInt8(50).WriteInt8(64 + 12).WriteInt8(5).WriteInt8(0).
+		WriteInt8(20).WriteInt8(50).WriteInt16(100).WriteInt16(50).
+		WriteInt8(0x11).WriteInt16(0).WriteInt16(0).WriteInt8(0).
+		WriteInt16(20000).WriteInt16(30000).WriteInt16(24000).WriteInt16(20000)
+
+	data := buffer.NewWriter().
+		WriteBytes(square.Bytes()).
+		WriteBytes(sine.Bytes()).
+		WriteBytes(make([]byte, 8)).
+		WriteInt16(10).WriteInt16(90)
+
+	builder := cachetest.New()
+	builder.AddFile(Archive, 1, data.Bytes())
+
+	cache := builder.Build(t)
+
+	effect, err := Load(cache, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return effect
+}
+
+func TestLoad(t *testing.T) {
+	effect := loadTestEffect(t)
+
+	square, sine := effect.Instruments[0], effect.Instruments[1]
+	if square == nil || sine == nil || effect.Instruments[2] != nil {
+		t.Fatalf("unexpected instruments %+v", effect.Instruments)
+	}
+
+	if square.Pitch.Form != Square || square.Pitch.Start != 1000 || square.PitchModifier != nil || square.Release != nil {
+		t.Errorf("unexpected instrument %+v", square)
+	}
+
+	if len(square.Oscillators) != 1 || *square.Oscillators[0] != (Oscillator{Volume: 100}) {
+		t.Errorf("unexpected oscillators %+v", square.Oscillators)
+	}
+
+	if sine.PitchModifier == nil || sine.PitchModifierAmplitude.Phases[0] != 20000 || sine.VolumeMultiplier != nil {
+		t.Errorf("unexpected instrument %+v", sine)
+	}
+
+	if *sine.Oscillators[0] != (Oscillator{Volume: 50, Pitch: 12, Delay: 5}) {
+		t.Errorf("unexpected oscillator %+v", sine.Oscillators[0])
+	}
+
+	if sine.DelayTime != 20 || sine.DelayDecay != 50 || sine.Duration != 100 || sine.Offset != 50 {
+		t.Errorf("unexpected instrument %+v", sine)
+	}
+
+	if sine.Filter.Pairs != [2]int{1, 1} || sine.Filter.Phases[Feedback][1][0] != 24000 || sine.Filter.Magnitudes[Feedforward][0][0] != 30000 {
+		t.Errorf("unexpected filter %+v", sine.Filter)
+	}
+
+	if effect.LoopStart != 10 || effect.LoopEnd != 90 || effect.Duration() != 150 {
+		t.Errorf("unexpected sound effect %+v", effect)
+	}
+}
+
+func TestRender(t *testing.T) {
+	effect := loadTestEffect(t)
+
+	sound := effect.Render()
+	if len(sound.Samples) != 150*SampleRate/1000 || sound.LoopStart != 220 || sound.LoopEnd != 1984 {
+		t.Fatalf("unexpected sound of %v samples looping from %v to %v", len(sound.Samples), sound.LoopStart, sound.LoopEnd)
+	}
+
+	// the square wave has a period of about 22 samples
+	for i, sample := range map[int]int8{0: 127, 11: 127, 12: -128, 22: -128, 23: 127} {
+		if sound.Samples[i] != sample {
+			t.Errorf("expected sample %v to be %v but got %v", i, sample, sound.Samples[i])
+		}
+	}
+
+	silent := true
+	for _, sample := range effect.Instruments[1].Synthesize() {
+		if sample < -32768 || sample > 32767 {
+			t.Fatalf("sample %v out of range", sample)
+		}
+
+		if sample != 0 {
+			silent = false
+		}
+	}
+
+	if silent {
+		t.Error("expected the filtered sine wave to be audible")
+	}
+
+	if samples := (&Instrument{Duration: 5}).Synthesize(); len(samples) != 110 || samples[0] != 0 {
+		t.Errorf("expected silence for an instrument shorter than 10 milliseconds")
+	}
+}
+
+func TestWaveforms(t *testing.T) {
+	if noise[0] != -1 || noise[1] != -1 || noise[2] != 1 {
+		t.Errorf("unexpected noise %v", noise[:3])
+	}
+
+	waves := []struct {
+		phase     int32
+		form      int
+		amplitude int32
+	}{
+		{0, Square, 100},
+		{waveLength / 2, Square, -100},
+		{waveLength / 4, Sine, 99},
+		{waveLength / 2, Sawtooth, 0},
+		{0, Silence, 0},
+	}
+
+	for _, wave := range waves {
+		if amplitude := evaluateWave(wave.phase, 100, wave.form); amplitude != wave.amplitude {
+			t.Errorf("expected %v of form %v at phase %v but got %v", wave.amplitude, wave.form, wave.phase, amplitude)
+		}
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	sound := &Sound{SampleRate: SampleRate, Samples: []int8{-128, 0, 127}, LoopStart: 1, LoopEnd: 3}
+
+	var buf bytes.Buffer
+	if err := sound.WriteWAV(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" || int(binary.LittleEndian.Uint32(data[4:])) != len(data)-8 {
+		t.Fatalf("unexpected header %v", data[:16])
+	}
+
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != SampleRate {
+		t.Errorf("expected a sample rate of %v but got %v", SampleRate, rate)
+	}
+
+	if !bytes.Equal(data[36:47], []byte{'d', 'a', 't', 'a', 3, 0, 0, 0, 0, 128, 255}) {
+		t.Errorf("unexpected samples %v", data[36:48])
+	}
+
+	if string(data[48:52]) != "smpl" || binary.LittleEndian.Uint32(data[100:]) != 1 || binary.LittleEndian.Uint32(data[104:]) != 2 {
+		t.Errorf("unexpected loop %v", data[48:])
+	}
+
+	if _, err := Decode(0, []byte{1, 0, 0}); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+}
@@ -0,0 +1,76 @@
+package sound
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// Sound is mono pulse-code modulated audio of signed 8-bit samples.
+type Sound struct {
+	SampleRate int
+	Samples    []int8
+
+	// LoopStart and LoopEnd are the range of samples that repeats when the sound is
+	// looped, of which the end is excluded. The sound does not loop if the range is
+	// empty.
+	LoopStart int
+	LoopEnd   int
+}
+
+// loops returns whether the sound has a range of samples to loop.
+func (sound *Sound) loops() bool {
+	return sound.LoopStart < sound.LoopEnd && sound.LoopEnd <= len(sound.Samples)
+}
+
+// WriteWAV writes the sound as a WAV file to the given writer. The loop of the sound, if
+// any, is written as a sampler chunk that audio tools understand. May return an error.
+func (sound *Sound) WriteWAV(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+
+	size := 4 + 8 + 16 + 8 + len(sound.Samples) + len(sound.Samples)%2
+	if sound.loops() {
+		size += 8 + 60
+	}
+
+	writeChunkHeader(buffered, "RIFF", size)
+	buffered.WriteString("WAVE")
+
+	writeChunkHeader(buffered, "fmt ", 16)
+	writeFields(buffered, []uint16{1, 1})
+	writeFields(buffered, []uint32{uint32(sound.SampleRate), uint32(sound.SampleRate)})
+	writeFields(buffered, []uint16{1, 8})
+
+	// samples of eight bits are unsigned in WAV files
+	writeChunkHeader(buffered, "data", len(sound.Samples))
+	for _, sample := range sound.Samples {
+		buffered.WriteByte(byte(int(sample) + 128))
+	}
+
+	if len(sound.Samples)%2 != 0 {
+		buffered.WriteByte(0)
+	}
+
+	if sound.loops() {
+		writeChunkHeader(buffered, "smpl", 60)
+		writeFields(buffered, []uint32{
+			0, 0, uint32(1000000000 / sound.SampleRate), 60, 0, 0, 0, 1, 0,
+			0, 0, uint32(sound.LoopStart), uint32(sound.LoopEnd - 1), 0, 0,
+		})
+	}
+
+	return buffered.Flush()
+}
+
+// writeChunkHeader writes the header of a chunk of a RIFF file of the given type and
+// size. Errors are left to the flush of the buffered writer.
+func writeChunkHeader(w *bufio.Writer, chunkType string, size int) {
+	w.WriteString(chunkType)
+	writeFields(w, uint32(size))
+}
+
+// writeFields writes the given fields in little endian byte order. Errors are left to
+// the flush of the buffered writer.
+func writeFields(w *bufio.Writer, fields interface{}) {
+	binary.Write(w, binary.LittleEndian, fields)
+}
@@ -0,0 +1,504 @@
+package soundbank
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Archive is the archive that holds a folder for each patch, of which the id is the
+// program of a channel added to its bank.
+const Archive = 15
+
+// KeyCount is the amount of keys of a Patch.
+const KeyCount = 128
+
+const (
+	// unity is the value of the envelopes and the volumes that leaves the volume as is.
+	unity = 64
+
+	// loopFlag marks the pitch offset of a key of which the sample loops.
+	loopFlag = 0x8000
+)
+
+// ErrMalformed is returned when a patch is malformed.
+var ErrMalformed = errors.New("patch is malformed")
+
+// Patch is an instrument that the music is played through, which maps each of the keys
+// of a channel onto a sample and on how it is articulated.
+type Patch struct {
+	Id int
+
+	// Volume is the volume of the patch, of which 128 is full.
+	Volume int
+
+	// Keys are the keys of the patch, of which those without a sample are nil.
+	Keys [KeyCount]*Key
+}
+
+// Key is a key of a Patch.
+type Key struct {
+	// Sample is the id of the sample of the key, which is a sound effect or, if Vorbis
+	// is set, a Vorbis sample.
+	Sample int
+	Vorbis bool
+
+	// Loop indicates that the loop of the sample repeats while the key is held.
+	Loop bool
+
+	// PitchOffset is the pitch the sample is recorded at, in 256ths of a semitone,
+	// such that a key plays its sample at its original rate at a pitch of its key
+	// shifted left by eight.
+	PitchOffset int
+
+	// Volume is the volume of the key, of which 128 is full.
+	Volume int
+
+	// Pan is the pan of the key, from 0 on the left to 128 on the right.
+	Pan int
+
+	// ExclusiveClass is the class of the keys that silence each other on a channel, or
+	// -1 if the key has none.
+	ExclusiveClass int
+
+	Articulation *Articulation
+}
+
+// Articulation describes how the volume and the pitch of the keys that share it evolve
+// while they play. The envelopes and the vibrato are timed in ticks of a hundredth of a
+// second.
+type Articulation struct {
+	// VolumeEnvelope is the envelope of the volume while the key plays, or nil if the
+	// volume is constant. Its positions are in pairs of ticks and its values are
+	// relative to 64. The key ends once the envelope ends at zero.
+	VolumeEnvelope []Point
+
+	// ReleaseEnvelope is the envelope of the volume once the key is released, which
+	// starts at 64 and ends at zero, or nil if the key ends at once.
+	ReleaseEnvelope []Point
+
+	// VolumeEnvelopeScale and ReleaseEnvelopeScale speed up the envelopes of the keys
+	// above the middle C and slow them down below it, where 64 doubles their speed
+	// every octave.
+	VolumeEnvelopeScale  int
+	ReleaseEnvelopeScale int
+
+	// Decay halves the volume over time, and DecayScale scales it the same way as the
+	// envelopes.
+	Decay      int
+	DecayScale int
+
+	// VibratoDepth is the depth of the vibrato in 64ths of a semitone, VibratoRate the
+	// advance of its phase of 512 steps per tick and VibratoDelay the amount of pairs
+	// of ticks it takes to reach its depth.
+	VibratoDepth int
+	VibratoRate  int
+	VibratoDelay int
+}
+
+// Point is a point of an envelope.
+type Point struct {
+	Position int
+	Value    int
+}
+
+// runner walks the keys of a patch through runs of keys that share a value. The last
+// run extends to the final key.
+type runner struct {
+	lengths   []int
+	index     int
+	remaining int
+}
+
+// next advances to the next key and returns whether it starts a run, along with the
+// index of the run.
+func (r *runner) next() (bool, int) {
+	starts, run := r.remaining == 0, r.index
+	if starts {
+		r.remaining = -1
+		if r.index < len(r.lengths) {
+			r.remaining = r.lengths[r.index]
+			r.index++
+		}
+	}
+
+	r.remaining--
+	return starts, run
+}
+
+// Load decodes the Patch of the specified id from the given Cache. May return an error.
+func Load(cache *gokira.Cache, id int) (*Patch, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(id, folder.Data)
+}
+
+// Decode decodes a Patch of the given id from the given data. Most fields are run-length
+// encoded across the keys, and the tables of the fields are grouped by type rather than
+// by key or articulation. May return an error.
+func Decode(id int, data []byte) (*Patch, error) {
+	c := buffer.NewCursor(data)
+
+	// the exclusive classes and the pans of the runs follow their lengths, but are read
+	// alongside the other fields of the keys
+	exclusiveRuns := readRuns(c)
+	exclusives := newCursor(data, c.Position())
+	c.Skip(len(exclusiveRuns) + 1)
+
+	panRuns := readRuns(c)
+	pans := newCursor(data, c.Position())
+	c.Skip(len(panRuns) + 1)
+
+	articulationRuns := readRuns(c)
+	articulationIndices, articulationCount := readArticulationIndices(c, len(articulationRuns))
+
+	articulations := make([]*Articulation, articulationCount)
+	for i := range articulations {
+		articulation := &Articulation{}
+		if count := c.UInt8(); count > 0 {
+			articulation.VolumeEnvelope = make([]Point, count)
+		}
+
+		if count := c.UInt8(); count > 0 {
+			articulation.ReleaseEnvelope = make([]Point, count+1)
+			articulation.ReleaseEnvelope[0].Value = unity
+		}
+
+		articulations[i] = articulation
+	}
+
+	var volumeEnvelope, panEnvelope []Point
+	if count := c.UInt8(); count > 0 {
+		volumeEnvelope = make([]Point, count)
+	}
+
+	if count := c.UInt8(); count > 0 {
+		panEnvelope = make([]Point, count)
+	}
+
+	sampleRuns := readRuns(c)
+
+	var pitchOffsets [KeyCount]int
+	for pass := uint(0); pass < 2; pass++ {
+		var offset int
+		for key := range pitchOffsets {
+			offset += c.UInt8()
+			pitchOffsets[key] += offset << (8 * pass)
+		}
+	}
+
+	patch := &Patch{Id: id}
+
+	var samples [KeyCount]int
+
+	var reference int
+	samplesRunner := &runner{lengths: sampleRuns}
+	for key := range samples {
+		if starts, _ := samplesRunner.next(); starts {
+			reference = readVarInt(c)
+		}
+
+		samples[key] = reference
+	}
+
+	exclusiveRunner := &runner{lengths: exclusiveRuns}
+	panRunner := &runner{lengths: panRuns}
+	articulationRunner := &runner{lengths: articulationRuns}
+
+	var exclusiveClass, pan, articulation int
+	for key, reference := range samples {
+		if reference == 0 {
+			continue
+		}
+
+		if starts, _ := exclusiveRunner.next(); starts {
+			exclusiveClass = exclusives.Int8() - 1
+		}
+
+		if starts, _ := panRunner.next(); starts {
+			pan = (pans.Int8() + 16) << 2
+		}
+
+		if starts, run := articulationRunner.next(); starts {
+			articulation = articulationIndices[run]
+		}
+
+		offset := (pitchOffsets[key] + ((reference-1)&2)<<14) & 0xFFFF
+		patch.Keys[key] = &Key{
+			Sample:         (reference - 1) >> 2,
+			Vorbis:         (reference-1)&1 != 0,
+			Loop:           offset&loopFlag != 0,
+			PitchOffset:    offset &^ loopFlag,
+			Pan:            pan,
+			ExclusiveClass: exclusiveClass,
+			Articulation:   articulations[articulation],
+		}
+	}
+
+	var volume int
+	volumesRunner := &runner{lengths: sampleRuns}
+	for key, reference := range samples {
+		if starts, _ := volumesRunner.next(); starts && reference > 0 {
+			volume = c.UInt8() + 1
+		}
+
+		if patch.Keys[key] != nil {
+			patch.Keys[key].Volume = volume
+		}
+	}
+
+	patch.Volume = c.UInt8() + 1
+
+	readArticulations(c, articulations, volumeEnvelope, panEnvelope)
+
+	if volumeEnvelope != nil {
+		patch.scaleVolumes(volumeEnvelope)
+	}
+
+	if panEnvelope != nil {
+		patch.shiftPans(panEnvelope)
+	}
+
+	if buffer.FirstError(c, exclusives, pans) != nil {
+		return nil, ErrMalformed
+	}
+
+	return patch, nil
+}
+
+// newCursor constructs a cursor that starts reading the given data at the given position.
+func newCursor(data []byte, position int) *buffer.Cursor {
+	c := buffer.NewCursor(data)
+	c.Seek(position)
+
+	return c
+}
+
+// readRuns reads a zero-terminated list of the lengths of runs of keys that share a
+// value.
+func readRuns(c *buffer.Cursor) []int {
+	var lengths []int
+	for c.Err() == nil {
+		length := c.UInt8()
+		if length == 0 {
+			break
+		}
+
+		lengths = append(lengths, length)
+	}
+
+	return lengths
+}
+
+// readVarInt reads a value that is encoded in groups of seven bits, most significant
+// group first, where the most significant bit of each byte indicates whether another
+// group follows.
+func readVarInt(c *buffer.Cursor) int {
+	var value int
+	for i := 0; i < 5; i++ {
+		group := c.UInt8()
+
+		value = value<<7 | group&0x7F
+		if group < 0x80 {
+			break
+		}
+	}
+
+	return value
+}
+
+// readArticulationIndices reads the index of the articulation of each of the given
+// amount of runs of keys, of which the first two use the first two articulations.
+// Each following index is either zero to introduce a new articulation, or one above
+// an earlier articulation, not counting the articulation of the previous run. Returns
+// the indices of every run, including the final run, and the amount of articulations.
+func readArticulationIndices(c *buffer.Cursor, runCount int) ([]int, int) {
+	indices := make([]int, runCount+1)
+	if runCount == 0 {
+		return indices, 1
+	}
+
+	indices[1] = 1
+
+	previous, count := 1, 2
+	for run := 2; run <= runCount; run++ {
+		index := c.UInt8()
+		if index == 0 {
+			index = count
+			count++
+		} else if index <= previous {
+			index--
+		}
+
+		indices[run] = index
+		previous = index
+	}
+
+	return indices, count
+}
+
+// readArticulations reads the envelopes and the remaining fields of the given
+// articulations and the values of the envelopes of the patch.
+func readArticulations(c *buffer.Cursor, articulations []*Articulation, volumeEnvelope, panEnvelope []Point) {
+	for _, articulation := range articulations {
+		for i := range articulation.VolumeEnvelope {
+			articulation.VolumeEnvelope[i].Value = c.Int8()
+		}
+
+		// the release envelope always ends at zero
+		release := articulation.ReleaseEnvelope
+		for i := 1; i < len(release)-1; i++ {
+			release[i].Value = c.Int8()
+		}
+	}
+
+	for i := range volumeEnvelope {
+		volumeEnvelope[i].Value = c.Int8()
+	}
+
+	for i := range panEnvelope {
+		panEnvelope[i].Value = c.Int8()
+	}
+
+	for _, articulation := range articulations {
+		readPositions(c, articulation.ReleaseEnvelope[min(1, len(articulation.ReleaseEnvelope)):], 0)
+	}
+
+	for _, articulation := range articulations {
+		readPositions(c, articulation.VolumeEnvelope[min(1, len(articulation.VolumeEnvelope)):], 0)
+	}
+
+	if volumeEnvelope != nil {
+		start := c.UInt8()
+		volumeEnvelope[0].Position = start
+		readPositions(c, volumeEnvelope[1:], start)
+	}
+
+	if panEnvelope != nil {
+		start := c.UInt8()
+		panEnvelope[0].Position = start
+		readPositions(c, panEnvelope[1:], start)
+	}
+
+	for _, articulation := range articulations {
+		articulation.Decay = c.UInt8()
+	}
+
+	for _, articulation := range articulations {
+		if articulation.VolumeEnvelope != nil {
+			articulation.VolumeEnvelopeScale = c.UInt8()
+		}
+
+		if articulation.ReleaseEnvelope != nil {
+			articulation.ReleaseEnvelopeScale = c.UInt8()
+		}
+
+		if articulation.Decay > 0 {
+			articulation.DecayScale = c.UInt8()
+		}
+	}
+
+	for _, articulation := range articulations {
+		articulation.VibratoDepth = c.UInt8()
+	}
+
+	for _, articulation := range articulations {
+		if articulation.VibratoDepth > 0 {
+			articulation.VibratoRate = c.UInt8()
+		}
+	}
+
+	for _, articulation := range articulations {
+		if articulation.VibratoRate > 0 {
+			articulation.VibratoDelay = c.UInt8()
+		}
+	}
+}
+
+// readPositions reads the positions of the given points, each of which lies at least
+// one past the previous position.
+func readPositions(c *buffer.Cursor, points []Point, position int) {
+	for i := range points {
+		position += c.UInt8() + 1
+		points[i].Position = position
+	}
+}
+
+// scaleVolumes scales the volumes of the keys by the given envelope over the keys, of
+// which the values are relative to 64.
+func (patch *Patch) scaleVolumes(envelope []Point) {
+	patch.applyEnvelope(envelope, func(key *Key, value int) {
+		key.Volume = (value*key.Volume + 32) >> 6
+	})
+}
+
+// shiftPans shifts the pans of the keys by twice the given envelope over the keys.
+func (patch *Patch) shiftPans(envelope []Point) {
+	doubled := make([]Point, len(envelope))
+	for i, point := range envelope {
+		doubled[i] = Point{Position: point.Position, Value: point.Value * 2}
+	}
+
+	patch.applyEnvelope(doubled, func(key *Key, value int) {
+		key.Pan = clamp(key.Pan+value, 0, 128)
+	})
+}
+
+// applyEnvelope applies the values of the given envelope over the keys to each of the
+// keys, interpolating them between the points of the envelope and extending the
+// outermost values beyond them.
+func (patch *Patch) applyEnvelope(envelope []Point, apply func(key *Key, value int)) {
+	for key := 0; key < KeyCount; key++ {
+		value := envelope[len(envelope)-1].Value
+		if key < envelope[0].Position {
+			value = envelope[0].Value
+		}
+
+		for i := 1; i < len(envelope); i++ {
+			start, end := envelope[i-1], envelope[i]
+			if key >= start.Position && key < end.Position {
+				span := end.Position - start.Position
+				value = floorDiv(start.Value*span+span/2+(key-start.Position)*(end.Value-start.Value), span)
+				break
+			}
+		}
+
+		if patch.Keys[key] != nil {
+			apply(patch.Keys[key], value)
+		}
+	}
+}
+
+// floorDiv divides the given values, rounding towards negative infinity.
+func floorDiv(a, b int) int {
+	quotient := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		quotient--
+	}
+
+	return quotient
+}
+
+func clamp(value, minimum, maximum int) int {
+	if value < minimum {
+		return minimum
+	}
+
+	if value > maximum {
+		return maximum
+	}
+
+	return value
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,523 @@
+package soundbank
+
+import (
+	"math"
+	"sort"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/music"
+	"github.com/sinoz/gokira/sound"
+	"github.com/sinoz/gokira/vorbis"
+)
+
+// SampleRate is the amount of samples per second that music is rendered at.
+const SampleRate = 22050
+
+const (
+	// ticksPerSecond is the amount of times per second that the envelopes, the vibrato
+	// and the volumes of the voices are updated.
+	ticksPerSecond = 100
+
+	// envelopeStep is the advance of the envelopes per tick for keys at the middle C,
+	// where 256 is a position of an envelope.
+	envelopeStep = 128
+
+	// maxTail is the highest amount of samples that are rendered after the last event
+	// for the voices to fade out.
+	maxTail = 10 * SampleRate
+)
+
+// The controllers that channels respond to.
+const (
+	bankSelect       = 0
+	modulation       = 1
+	dataEntry        = 6
+	volume           = 7
+	pan              = 10
+	expression       = 11
+	bankSelectFine   = 32
+	fineOffset       = 32
+	sustain          = 64
+	rpnFine          = 100
+	rpn              = 101
+	allSoundOff      = 120
+	resetControllers = 121
+	allNotesOff      = 123
+)
+
+const (
+	// drumChannel is the channel of the percussion, of which the bank starts out as
+	// drumBank rather than as zero.
+	drumChannel = 9
+	drumBank    = 128
+
+	// centre is the centre of the pitch bend, the pan and the other controllers of
+	// fourteen bits.
+	centre = 8192
+
+	defaultVolume     = 100 << 7
+	defaultExpression = 16383
+
+	// defaultBendRange is the range of the pitch bend of two semitones, and
+	// bendRangeRPN is the registered parameter that changes it.
+	defaultBendRange = 256
+	bendRangeRPN     = 0
+)
+
+// Renderer renders music tracks through the patches and the samples of a cache. The
+// patches and the samples are loaded as the tracks need them and kept for the tracks
+// that follow.
+type Renderer struct {
+	cache *gokira.Cache
+	setup *vorbis.Setup
+
+	patches map[int]*Patch
+	samples map[sampleKey]*sample
+}
+
+// sampleKey identifies a sample.
+type sampleKey struct {
+	id     int
+	vorbis bool
+}
+
+// sample is a decoded sample, either a rendered sound effect or a Vorbis sample.
+type sample struct {
+	rate int
+	data []int8
+
+	loopStart int
+	loopEnd   int
+	pingPong  bool
+}
+
+// NewRenderer constructs a Renderer that renders music through the patches and the
+// samples of the given Cache.
+func NewRenderer(cache *gokira.Cache) *Renderer {
+	return &Renderer{
+		cache:   cache,
+		patches: make(map[int]*Patch),
+		samples: make(map[sampleKey]*sample),
+	}
+}
+
+// patch returns the Patch of the given id, or nil if the cache has no such patch. May
+// return an error.
+func (r *Renderer) patch(id int) (*Patch, error) {
+	if patch, ok := r.patches[id]; ok {
+		return patch, nil
+	}
+
+	manifest, err := r.cache.GetArchiveManifest(Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var patch *Patch
+	if id < len(manifest.FolderReferences) && manifest.FolderReferences[id] != nil {
+		if patch, err = Load(r.cache, id); err != nil {
+			return nil, err
+		}
+	}
+
+	r.patches[id] = patch
+	return patch, nil
+}
+
+// sample returns the sample of the given Key. May return an error.
+func (r *Renderer) sample(key *Key) (*sample, error) {
+	id := sampleKey{id: key.Sample, vorbis: key.Vorbis}
+	if s, ok := r.samples[id]; ok {
+		return s, nil
+	}
+
+	var s *sample
+	if key.Vorbis {
+		if r.setup == nil {
+			setup, err := vorbis.LoadSetup(r.cache)
+			if err != nil {
+				return nil, err
+			}
+
+			r.setup = setup
+		}
+
+		decoded, err := vorbis.LoadSample(r.cache, key.Sample)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := r.setup.Decode(decoded)
+		if err != nil {
+			return nil, err
+		}
+
+		s = &sample{
+			rate:      decoded.SampleRate,
+			data:      data,
+			loopStart: decoded.LoopStart,
+			loopEnd:   decoded.LoopEnd,
+			pingPong:  decoded.PingPong,
+		}
+	} else {
+		effect, err := sound.Load(r.cache, key.Sample)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered := effect.Render()
+		s = &sample{
+			rate:      rendered.SampleRate,
+			data:      rendered.Samples,
+			loopStart: rendered.LoopStart,
+			loopEnd:   rendered.LoopEnd,
+		}
+	}
+
+	r.samples[id] = s
+	return s, nil
+}
+
+// timedEvent is an event of a track at the tick it occurs.
+type timedEvent struct {
+	tick  int
+	event *music.Event
+}
+
+// Render renders the given music Track into stereo audio at the SampleRate, playing the
+// notes of each channel through the patch of its program and bank. Keys the patches
+// leave out are silent, as are programs without a patch. May return an error.
+func (r *Renderer) Render(track *music.Track) (*Audio, error) {
+	if track.Division <= 0 {
+		return nil, music.ErrMalformed
+	}
+
+	var events []timedEvent
+	for _, trackEvents := range track.Tracks {
+		var tick int
+		for _, event := range trackEvents {
+			tick += event.Delta
+			events = append(events, timedEvent{tick: tick, event: event})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].tick < events[j].tick
+	})
+
+	m := newMixer(r)
+
+	// the time is kept in microseconds multiplied by the division, which the tempo
+	// converts ticks into exactly
+	var elapsed int64
+	var tick int
+	tempo := music.DefaultTempo
+
+	for _, timed := range events {
+		elapsed += int64(timed.tick-tick) * int64(tempo)
+		tick = timed.tick
+
+		m.advance(int(elapsed * SampleRate / (int64(track.Division) * 1000000)))
+
+		event := timed.event
+		if event.Status == music.Meta {
+			if len(event.Data) == 4 && event.Data[0] == music.MetaTempo {
+				tempo = int(event.Data[1])<<16 | int(event.Data[2])<<8 | int(event.Data[3])
+			}
+
+			continue
+		}
+
+		if err := m.handle(event); err != nil {
+			return nil, err
+		}
+	}
+
+	end := len(m.left) + maxTail
+	for m.playing() && len(m.left) < end {
+		m.advance(len(m.left) + SampleRate/ticksPerSecond)
+	}
+
+	return m.audio(), nil
+}
+
+// channel is the state of a channel of a track.
+type channel struct {
+	program int
+	bank    int
+
+	// the controllers of fourteen bits
+	modulation int
+	volume     int
+	pan        int
+	expression int
+	bend       int
+	bendRange  int
+
+	rpn     int
+	sustain bool
+}
+
+// reset resets the controllers of the channel.
+func (ch *channel) reset() {
+	ch.modulation = 0
+	ch.expression = defaultExpression
+	ch.bend = centre
+	ch.rpn = -1
+	ch.sustain = false
+}
+
+// patchId returns the id of the patch of the channel.
+func (ch *channel) patchId() int {
+	return ch.bank + ch.program
+}
+
+// mixer mixes the voices of the notes of a track.
+type mixer struct {
+	renderer *Renderer
+	channels [16]*channel
+	voices   []*voice
+
+	// left and right are the mixed samples of both sides, in 24 bits
+	left  []int
+	right []int
+
+	ticks int
+}
+
+// newMixer constructs a mixer that plays through the patches of the given Renderer.
+func newMixer(r *Renderer) *mixer {
+	m := &mixer{renderer: r}
+	for i := range m.channels {
+		ch := &channel{volume: defaultVolume, pan: centre, bendRange: defaultBendRange}
+		if i == drumChannel {
+			ch.bank = drumBank
+		}
+
+		ch.reset()
+		m.channels[i] = ch
+	}
+
+	return m
+}
+
+// advance mixes the voices up to the given amount of samples, updating them every tick.
+func (m *mixer) advance(samples int) {
+	for len(m.left) < samples {
+		next := (m.ticks + 1) * SampleRate / ticksPerSecond
+		if next > samples {
+			next = samples
+		}
+
+		start := len(m.left)
+		m.left = append(m.left, make([]int, next-start)...)
+		m.right = append(m.right, make([]int, next-start)...)
+
+		for _, v := range m.voices {
+			v.mix(m.left[start:], m.right[start:])
+		}
+
+		if next == (m.ticks+1)*SampleRate/ticksPerSecond {
+			m.ticks++
+			m.tick()
+		}
+	}
+}
+
+// tick advances the voices by a tick and removes those that have ended.
+func (m *mixer) tick() {
+	playing := m.voices[:0]
+	for _, v := range m.voices {
+		if !v.done {
+			v.tick()
+		}
+
+		if !v.done {
+			v.update(m.channels[v.channel])
+			playing = append(playing, v)
+		}
+	}
+
+	m.voices = playing
+}
+
+// playing returns whether any of the voices is still playing.
+func (m *mixer) playing() bool {
+	for _, v := range m.voices {
+		if !v.done {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handle applies the given event of a channel. May return an error.
+func (m *mixer) handle(event *music.Event) error {
+	ch := m.channels[event.Channel()]
+
+	switch event.Type() {
+	case music.NoteOn:
+		if event.Data[1] == 0 {
+			m.noteOff(event.Channel(), int(event.Data[0]))
+			return nil
+		}
+
+		return m.noteOn(event.Channel(), int(event.Data[0]), int(event.Data[1]))
+
+	case music.NoteOff:
+		m.noteOff(event.Channel(), int(event.Data[0]))
+
+	case music.ControlChange:
+		m.controlChange(event.Channel(), int(event.Data[0]), int(event.Data[1]))
+
+	case music.ProgramChange:
+		ch.program = int(event.Data[0])
+
+	case music.PitchBend:
+		ch.bend = int(event.Data[0]) | int(event.Data[1])<<7
+		m.updateChannel(event.Channel())
+	}
+
+	return nil
+}
+
+// noteOn starts a voice of the given key on the given channel, replacing the voice of
+// the key that is still playing. May return an error.
+func (m *mixer) noteOn(channel, key, velocity int) error {
+	m.noteOff(channel, key)
+
+	patch, err := m.renderer.patch(m.channels[channel].patchId())
+	if err != nil || patch == nil || patch.Keys[key] == nil {
+		return err
+	}
+
+	patchKey := patch.Keys[key]
+
+	s, err := m.renderer.sample(patchKey)
+	if err != nil {
+		return err
+	}
+
+	if patchKey.ExclusiveClass >= 0 {
+		for _, v := range m.voices {
+			if v.channel == channel && v.key.ExclusiveClass == patchKey.ExclusiveClass {
+				v.release()
+			}
+		}
+	}
+
+	v := newVoice(channel, key, velocity, patch, s)
+	v.update(m.channels[channel])
+
+	m.voices = append(m.voices, v)
+	return nil
+}
+
+// noteOff releases the voice of the given key on the given channel, or leaves it to
+// the release of the sustain pedal.
+func (m *mixer) noteOff(channel, key int) {
+	for _, v := range m.voices {
+		if v.channel != channel || v.note != key || v.released {
+			continue
+		}
+
+		if m.channels[channel].sustain {
+			v.sustained = true
+		} else {
+			v.release()
+		}
+	}
+}
+
+// controlChange changes the given controller of the given channel.
+func (m *mixer) controlChange(channel, controller, value int) {
+	ch := m.channels[channel]
+
+	switch controller {
+	case bankSelect:
+		ch.bank = value<<14 | ch.bank&^(0x7F<<14)
+	case bankSelectFine:
+		ch.bank = value<<7 | ch.bank&^(0x7F<<7)
+	case modulation:
+		ch.modulation = setCoarse(ch.modulation, value)
+	case modulation + fineOffset:
+		ch.modulation = setFine(ch.modulation, value)
+	case volume:
+		ch.volume = setCoarse(ch.volume, value)
+	case volume + fineOffset:
+		ch.volume = setFine(ch.volume, value)
+	case pan:
+		ch.pan = setCoarse(ch.pan, value)
+	case pan + fineOffset:
+		ch.pan = setFine(ch.pan, value)
+	case expression:
+		ch.expression = setCoarse(ch.expression, value)
+	case expression + fineOffset:
+		ch.expression = setFine(ch.expression, value)
+	case rpn:
+		ch.rpn = value<<7 | ch.rpn&0x7F
+	case rpnFine:
+		ch.rpn = ch.rpn&^0x7F | value
+	case dataEntry:
+		if ch.rpn == bendRangeRPN {
+			ch.bendRange = value << 7
+		}
+	case sustain:
+		ch.sustain = value >= 64
+		if !ch.sustain {
+			for _, v := range m.voices {
+				if v.channel == channel && v.sustained {
+					v.release()
+				}
+			}
+		}
+	case allSoundOff:
+		for _, v := range m.voices {
+			if v.channel == channel {
+				v.done = true
+			}
+		}
+	case resetControllers:
+		ch.reset()
+	case allNotesOff:
+		for _, v := range m.voices {
+			if v.channel == channel {
+				v.release()
+			}
+		}
+	}
+
+	m.updateChannel(channel)
+}
+
+// updateChannel updates the voices of the given channel after a change of its
+// controllers.
+func (m *mixer) updateChannel(channel int) {
+	for _, v := range m.voices {
+		if v.channel == channel && !v.done {
+			v.update(m.channels[channel])
+		}
+	}
+}
+
+// audio converts the mixed samples into stereo Audio of 16 bits.
+func (m *mixer) audio() *Audio {
+	samples := make([]int16, len(m.left)*2)
+	for i := range m.left {
+		samples[i*2] = int16(clamp(m.left[i]>>8, math.MinInt16, math.MaxInt16))
+		samples[i*2+1] = int16(clamp(m.right[i]>>8, math.MinInt16, math.MaxInt16))
+	}
+
+	return &Audio{SampleRate: SampleRate, Samples: samples}
+}
+
+// setCoarse sets the upper seven bits of the given controller of fourteen bits.
+func setCoarse(controller, value int) int {
+	return value<<7 | controller&0x7F
+}
+
+// setFine sets the lower seven bits of the given controller of fourteen bits.
+func setFine(controller, value int) int {
+	return controller&^0x7F | value
+}
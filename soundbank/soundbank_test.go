@@ -0,0 +1,217 @@
+package soundbank
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+	"github.com/sinoz/gokira/music"
+	"github.com/sinoz/gokira/sound"
+	"github.com/sinoz/gokira/vorbis"
+)
+
+// loadTestCache builds the fixture cache that holds patch 0, which plays the square wave
+// sound effect 1 for the keys 60 to 71 and the looped Vorbis sample 2 for the keys 72 to
+// 127, along with the sound effect, the sample and the setup of the sample.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	// the low and the high bytes of the pitch offsets of the keys, which accumulate
+	// from key to key
+	offsets := make([]byte, 256)
+	offsets[128] = 60
+	offsets[128+72] = 12
+
+	patch := buffer.NewWriter().
+		// no exclusive classes and the pans of two runs of keys
+		WriteInt8(0).WriteInt8(0).
+		WriteInt8(12).WriteInt8(0).WriteInt8(0).WriteInt8(-16).
+		// the articulations of two runs of keys, of which the first has a volume
+		// envelope of two points and a release envelope of a single point
+		WriteInt8(12).WriteInt8(0).
+		WriteInt8(2).WriteInt8(1).WriteInt8(0).WriteInt8(0).
+		WriteInt8(2).WriteInt8(0).
+		// the samples of three runs of keys and their pitch offsets
+		WriteInt8(60).WriteInt8(12).WriteInt8(0).
+		WriteBytes(offsets).
+		// the silence, the sound effect 1 and the looped Vorbis sample 2
+		WriteInt8(0).WriteInt8(1<<2 + 1).WriteInt8((2<<2 | 1 | 2) + 1).
+		// the volumes of the keys that have samples and of the patch
+		WriteInt8(99).WriteInt8(127).WriteInt8(127).
+		// the values and the positions of the envelopes
+		WriteInt8(64).WriteInt8(0).
+		WriteInt8(64).WriteInt8(32).
+		WriteInt8(9).
+		WriteInt8(99).
+		WriteInt8(60).WriteInt8(19).
+		// the decays, the scales of the envelopes and the depths of the vibratos
+		WriteInt8(0).WriteInt8(0).
+		WriteInt8(0).WriteInt8(0).
+		WriteInt8(0).WriteInt8(0)
+
+	square := buffer.NewWriter().
+		WriteInt8(1).WriteInt32(1000).WriteInt32(1000).WriteInt8(1).WriteInt16(0).WriteInt16(0).
+		WriteInt8(0).WriteInt32(0).WriteInt32(0).WriteInt8(1).WriteInt16(0).WriteInt16(65535).
+		WriteInt8(0).WriteInt8(0).WriteInt8(0).
+		WriteInt8(100).WriteInt8(64).WriteInt8(0).WriteInt8(0).
+		WriteInt8(0).WriteInt8(0).WriteInt16(100).WriteInt16(0).
+		WriteInt8(0).
+		WriteBytes(make([]byte, 9)).
+		WriteInt16(10).WriteInt16(90)
+
+	sample := cachetest.VorbisSample(22050, 500, 100, 400,
+		cachetest.VorbisPacket(false, false, false, []int{48, 56}, 1),
+		cachetest.VorbisPacket(true, false, false, []int{52, 44}, 2),
+		cachetest.VorbisPacket(false, false, false, []int{40, 60}, 3),
+		cachetest.VorbisPacket(false, false, false, nil, 0))
+
+	builder := cachetest.New()
+	builder.AddFile(sound.Archive, 1, square.Bytes())
+	builder.AddFile(vorbis.Archive, vorbis.SetupFolder, cachetest.VorbisSetup())
+	builder.AddFile(vorbis.Archive, 2, sample)
+	builder.AddFile(Archive, 0, patch.Bytes())
+
+	return builder.Build(t)
+}
+
+func TestLoad(t *testing.T) {
+	patch, err := Load(loadTestCache(t), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if patch.Volume != 128 || patch.Keys[59] != nil {
+		t.Fatalf("unexpected patch %+v", patch)
+	}
+
+	articulation := &Articulation{
+		VolumeEnvelope:  []Point{{0, 64}, {100, 0}},
+		ReleaseEnvelope: []Point{{0, 64}, {10, 0}},
+	}
+
+	// the volumes of the keys fade to half from key 60 to key 80
+	keys := map[int]*Key{
+		60:  {Sample: 1, PitchOffset: 60 << 8, Volume: 100, Pan: 64, ExclusiveClass: -1, Articulation: articulation},
+		70:  {Sample: 1, PitchOffset: 60 << 8, Volume: 75, Pan: 64, ExclusiveClass: -1, Articulation: articulation},
+		72:  {Sample: 2, Vorbis: true, Loop: true, PitchOffset: 72 << 8, Volume: 90, Pan: 0, ExclusiveClass: -1, Articulation: &Articulation{}},
+		127: {Sample: 2, Vorbis: true, Loop: true, PitchOffset: 72 << 8, Volume: 64, Pan: 0, ExclusiveClass: -1, Articulation: &Articulation{}},
+	}
+
+	for note, key := range keys {
+		if !reflect.DeepEqual(patch.Keys[note], key) {
+			t.Errorf("expected key %v to be %+v but got %+v", note, key, patch.Keys[note])
+		}
+	}
+
+	if patch.Keys[60].Articulation != patch.Keys[71].Articulation || patch.Keys[72].Articulation != patch.Keys[127].Articulation {
+		t.Error("expected the runs of keys to share their articulations")
+	}
+
+	if _, err := Decode(0, []byte{0, 0, 0, 0}); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	track := &music.Track{Division: 96, Tracks: [][]*music.Event{
+		{
+			{Delta: 0, Status: music.NoteOn, Data: []byte{60, 127}},
+			{Delta: 96, Status: music.NoteOff, Data: []byte{60, 0}},
+			{Delta: 0, Status: music.ProgramChange | 2, Data: []byte{5}},
+			{Delta: 0, Status: music.NoteOn | 2, Data: []byte{60, 127}},
+		},
+		{
+			{Delta: 192, Status: music.NoteOn | 1, Data: []byte{72, 100}},
+			{Delta: 96, Status: music.NoteOn | 1, Data: []byte{72, 0}},
+			{Delta: 0, Status: music.Meta, Data: []byte{music.MetaEndOfTrack}},
+		},
+	}}
+
+	audio, err := NewRenderer(loadTestCache(t)).Render(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last note ends at once as it has no release envelope
+	if audio.SampleRate != SampleRate || len(audio.Samples) != 2*3*SampleRate/2 {
+		t.Fatalf("unexpected audio of %v samples", len(audio.Samples))
+	}
+
+	left := func(i int) int16 { return audio.Samples[2*i] }
+	right := func(i int) int16 { return audio.Samples[2*i+1] }
+
+	// a full square wave at the full volume of the channel, the patch and the key
+	if left(0) != 30518 || right(0) != 30518 {
+		t.Errorf("unexpected first sample %v %v", left(0), right(0))
+	}
+
+	// the volume envelope fades the square wave out until the sound effect ends
+	for i := 3000; i < 3307; i++ {
+		if abs(int(left(i))) >= 30518 || left(i) != right(i) {
+			t.Fatalf("expected sample %v to fade out but got %v %v", i, left(i), right(i))
+		}
+	}
+
+	// the channel that plays a program without a patch is silent
+	for i := 3307; i < SampleRate; i++ {
+		if left(i) != 0 || right(i) != 0 {
+			t.Fatalf("expected silence at %v but got %v %v", i, left(i), right(i))
+		}
+	}
+
+	// the Vorbis sample plays on the left and repeats its loop of 300 samples
+	audible := false
+	for i := SampleRate; i < 3*SampleRate/2; i++ {
+		if right(i) != 0 {
+			t.Fatalf("expected silence on the right at %v but got %v", i, right(i))
+		}
+
+		if left(i) != 0 {
+			audible = true
+		}
+
+		if offset := i - SampleRate; offset >= 400 && left(i) != left(i-300) {
+			t.Fatalf("expected sample %v to repeat %v but got %v", i, left(i-300), left(i))
+		}
+	}
+
+	if !audible {
+		t.Error("expected the Vorbis sample to be audible")
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	audio := &Audio{SampleRate: SampleRate, Samples: []int16{1, -1, 256, -256}}
+
+	var buf bytes.Buffer
+	if err := audio.WriteWAV(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if len(data) != 44+8 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Fatalf("unexpected header %v", data[:16])
+	}
+
+	if channels := binary.LittleEndian.Uint16(data[22:]); channels != 2 {
+		t.Errorf("expected 2 channels but got %v", channels)
+	}
+
+	if rate := binary.LittleEndian.Uint32(data[28:]); rate != SampleRate*4 {
+		t.Errorf("expected a byte rate of %v but got %v", SampleRate*4, rate)
+	}
+
+	if !bytes.Equal(data[44:], []byte{1, 0, 0xFF, 0xFF, 0, 1, 0, 0xFF}) {
+		t.Errorf("unexpected samples %v", data[44:])
+	}
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
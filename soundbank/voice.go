@@ -0,0 +1,243 @@
+package soundbank
+
+import "math"
+
+const (
+	// pitchesPerOctave is the amount of steps of the pitch of an octave, in 256ths of a
+	// semitone.
+	pitchesPerOctave = 12 << 8
+
+	// middleC is the key that the envelopes are scaled around.
+	middleC = 60
+
+	// vibratoPeriod is the amount of steps of the phase of the vibrato of a cycle.
+	vibratoPeriod = 512
+
+	// decayHalfLife is the advance of the decay of a patch of a decay of one that halves
+	// the volume.
+	decayHalfLife = 51200
+
+	// fractionBits is the amount of bits of the position of a voice in its sample that
+	// lie behind the point.
+	fractionBits = 8
+)
+
+// voice is a key that plays on a channel.
+type voice struct {
+	channel int
+	note    int
+
+	key    *Key
+	sample *sample
+
+	// noteVolume is the volume of the note, out of the velocity and the volumes of the
+	// patch and the key.
+	noteVolume int
+
+	// position is the position in the sample in fixed point, step the advance of the
+	// position per sample and reverse whether a loop that plays back and forth plays
+	// backwards.
+	position int
+	step     int
+	reverse  bool
+
+	envelope  envelopeCursor
+	releasing envelopeCursor
+	decay     int
+
+	vibratoTicks int
+	vibratoPhase int
+
+	released  bool
+	sustained bool
+	done      bool
+
+	// left and right are the gains of the voice on either side.
+	left  int
+	right int
+}
+
+// envelopeCursor is a position in an envelope along with the point it has passed.
+type envelopeCursor struct {
+	position int
+	index    int
+}
+
+// advance advances the cursor through the given envelope by the given step and returns
+// whether the envelope ended at zero.
+func (c *envelopeCursor) advance(envelope []Point, step int) bool {
+	c.position += step
+	for c.index < len(envelope)-1 && c.position > envelope[c.index+1].Position<<8 {
+		c.index++
+	}
+
+	return c.index == len(envelope)-1 && envelope[c.index].Value == 0
+}
+
+// value returns the value of the given envelope at the cursor, interpolating between the
+// points around it.
+func (c *envelopeCursor) value(envelope []Point) int {
+	value := envelope[c.index].Value
+	if c.index < len(envelope)-1 {
+		start, end := envelope[c.index].Position<<8, envelope[c.index+1].Position<<8
+		value += (c.position - start) * (envelope[c.index+1].Value - value) / (end - start)
+	}
+
+	return value
+}
+
+// newVoice constructs a voice that plays the given key of the given Patch at the given
+// velocity on the given channel.
+func newVoice(channel, note, velocity int, patch *Patch, s *sample) *voice {
+	key := patch.Keys[note]
+	return &voice{
+		channel:    channel,
+		note:       note,
+		key:        key,
+		sample:     s,
+		noteVolume: (velocity*velocity*patch.Volume*key.Volume + 1024) >> 11,
+	}
+}
+
+// release releases the voice, which then fades out along its release envelope or ends
+// at once if it has none.
+func (v *voice) release() {
+	v.released = true
+	v.sustained = false
+
+	if v.key.Articulation.ReleaseEnvelope == nil {
+		v.done = true
+	}
+}
+
+// scaledStep returns the advance of an envelope per tick for the key of the voice,
+// which the given scale speeds up above the middle C and slows down below it.
+func (v *voice) scaledStep(scale int) int {
+	exponent := float64(scale) * float64((v.note-middleC)<<8) / (pitchesPerOctave * 64)
+	return int(envelopeStep * math.Pow(2, exponent))
+}
+
+// tick advances the envelopes and the vibrato of the voice by a tick.
+func (v *voice) tick() {
+	a := v.key.Articulation
+
+	if a.VolumeEnvelope != nil && v.envelope.advance(a.VolumeEnvelope, v.scaledStep(a.VolumeEnvelopeScale)) {
+		v.done = true
+	}
+
+	if a.Decay > 0 {
+		v.decay += v.scaledStep(a.DecayScale)
+	}
+
+	if v.released && a.ReleaseEnvelope != nil && v.releasing.advance(a.ReleaseEnvelope, v.scaledStep(a.ReleaseEnvelopeScale)) {
+		v.done = true
+	}
+
+	v.vibratoTicks++
+	v.vibratoPhase = (v.vibratoPhase + a.VibratoRate) % vibratoPeriod
+}
+
+// update updates the rate and the gains of the voice for the given state of its
+// channel.
+func (v *voice) update(ch *channel) {
+	a := v.key.Articulation
+
+	pitch := v.note<<8 - v.key.PitchOffset + (ch.bend-centre)*ch.bendRange>>12
+	if a.VibratoRate > 0 && (a.VibratoDepth > 0 || ch.modulation > 0) {
+		depth := a.VibratoDepth << 2
+		if delay := a.VibratoDelay << 1; v.vibratoTicks < delay {
+			depth = depth * v.vibratoTicks / delay
+		}
+
+		depth += ch.modulation >> 7
+		pitch += int(math.Sin(float64(v.vibratoPhase)*2*math.Pi/vibratoPeriod) * float64(depth))
+	}
+
+	rate := float64(v.sample.rate<<fractionBits) * math.Pow(2, float64(pitch)/pitchesPerOctave) / SampleRate
+	v.step = int(rate + 0.5)
+	if v.step < 1 {
+		v.step = 1
+	}
+
+	gain := (ch.volume*ch.expression + 4096) >> 13
+	gain = (gain*gain + 16384) >> 15
+	gain = (gain*v.noteVolume + 16384) >> 15
+
+	if a.Decay > 0 {
+		gain = int(float64(gain)*math.Pow(0.5, float64(a.Decay)*float64(v.decay)/decayHalfLife) + 0.5)
+	}
+
+	if a.VolumeEnvelope != nil {
+		gain = (gain*v.envelope.value(a.VolumeEnvelope) + 32) >> 6
+	}
+
+	if v.released && a.ReleaseEnvelope != nil {
+		gain = (gain*v.releasing.value(a.ReleaseEnvelope) + 32) >> 6
+	}
+
+	// the pan of the channel moves the pan of the key towards either side
+	var pan int
+	if ch.pan < centre {
+		pan = (ch.pan*v.key.Pan + 32) >> 6
+	} else {
+		pan = 2*centre - ((128-v.key.Pan)*(2*centre-ch.pan)+32)>>6
+	}
+
+	v.left = int(float64(gain)*math.Sqrt(float64(2*centre-pan)/centre) + 0.5)
+	v.right = int(float64(gain)*math.Sqrt(float64(pan)/centre) + 0.5)
+}
+
+// loops returns whether the voice repeats the loop of its sample.
+func (v *voice) loops() bool {
+	s := v.sample
+	return v.key.Loop && s.loopStart < s.loopEnd && s.loopEnd <= len(s.data)
+}
+
+// mix mixes the samples of the voice into the given samples of both sides, playing the
+// nearest sample of its position.
+func (v *voice) mix(left, right []int) {
+	s := v.sample
+	loops := v.loops()
+
+	start, end := s.loopStart<<fractionBits, s.loopEnd<<fractionBits
+
+	for i := range left {
+		if v.done {
+			return
+		}
+
+		index := v.position >> fractionBits
+		if index >= len(s.data) {
+			v.done = true
+			return
+		}
+
+		value := int(s.data[index])
+		left[i] += value * v.left
+		right[i] += value * v.right
+
+		if v.reverse {
+			v.position -= v.step
+		} else {
+			v.position += v.step
+		}
+
+		if !loops {
+			continue
+		}
+
+		// reflect or wrap the position back into the loop
+		for v.position >= end || (v.reverse && v.position < start) {
+			switch {
+			case !s.pingPong:
+				v.position = start + (v.position-end)%(end-start)
+			case v.position >= end:
+				v.position = 2*end - v.position - 1
+				v.reverse = true
+			default:
+				v.position = 2*start - v.position
+				v.reverse = false
+			}
+		}
+	}
+}
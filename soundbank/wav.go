@@ -0,0 +1,52 @@
+package soundbank
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// channelCount is the amount of channels of rendered Audio.
+const channelCount = 2
+
+// Audio is stereo pulse-code modulated audio of signed 16-bit samples.
+type Audio struct {
+	SampleRate int
+
+	// Samples are the samples of the left and the right side, interleaved.
+	Samples []int16
+}
+
+// WriteWAV writes the audio as a WAV file to the given writer. May return an error.
+func (audio *Audio) WriteWAV(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+
+	dataSize := len(audio.Samples) * 2
+	blockAlign := channelCount * 2
+
+	writeChunkHeader(buffered, "RIFF", 4+8+16+8+dataSize)
+	buffered.WriteString("WAVE")
+
+	writeChunkHeader(buffered, "fmt ", 16)
+	writeFields(buffered, []uint16{1, channelCount})
+	writeFields(buffered, []uint32{uint32(audio.SampleRate), uint32(audio.SampleRate * blockAlign)})
+	writeFields(buffered, []uint16{uint16(blockAlign), 16})
+
+	writeChunkHeader(buffered, "data", dataSize)
+	writeFields(buffered, audio.Samples)
+
+	return buffered.Flush()
+}
+
+// writeChunkHeader writes the header of a chunk of a RIFF file of the given type and
+// size. Errors are left to the flush of the buffered writer.
+func writeChunkHeader(w *bufio.Writer, chunkType string, size int) {
+	w.WriteString(chunkType)
+	writeFields(w, uint32(size))
+}
+
+// writeFields writes the given fields in little endian byte order. Errors are left to
+// the flush of the buffered writer.
+func writeFields(w *bufio.Writer, fields interface{}) {
+	binary.Write(w, binary.LittleEndian, fields)
+}
@@ -0,0 +1,182 @@
+package sprite
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/sinoz/gokira/buffer"
+)
+
+// MaxColors is the maximum amount of opaque colors in the palette of a sprite.
+const MaxColors = 255
+
+// ErrTooLarge is returned when a sprite has more frames or larger frames than its
+// encoding is able to describe.
+var ErrTooLarge = errors.New("sprite too large to encode")
+
+// FromImages constructs a Sprite of the given id from the given frames, which are each
+// drawn at the top left of a canvas that fits the largest of them.
+func FromImages(id int, images ...image.Image) *Sprite {
+	sprite := &Sprite{Id: id, Frames: make([]*Frame, len(images))}
+	for i, img := range images {
+		sprite.Frames[i] = &Frame{Image: img}
+
+		sprite.Width = max(sprite.Width, img.Bounds().Dx())
+		sprite.Height = max(sprite.Height, img.Bounds().Dy())
+	}
+
+	return sprite
+}
+
+// EncodeImages encodes the given frames into the payload of a sprite folder. May
+// return an error.
+func EncodeImages(images ...image.Image) ([]byte, error) {
+	return Encode(FromImages(0, images...))
+}
+
+// Encode encodes the given Sprite into the payload of a sprite folder that the client
+// is able to decode. The colors of the frames are quantized into a palette of at most
+// MaxColors colors, while fully transparent pixels are left out of it. Each frame is
+// stored in the pixel order that best suits compression, and with an alpha value
+// for each pixel only if it is translucent. May return an error.
+func Encode(sprite *Sprite) ([]byte, error) {
+	if len(sprite.Frames) > 0xFFFF || sprite.Width > 0xFFFF || sprite.Height > 0xFFFF {
+		return nil, ErrTooLarge
+	}
+
+	pixels := make([][]color.NRGBA, len(sprite.Frames))
+	histogram := newHistogram()
+
+	for i, frame := range sprite.Frames {
+		bounds := frame.Image.Bounds()
+		if bounds.Dx() > 0xFFFF || bounds.Dy() > 0xFFFF || frame.OffsetX > 0xFFFF || frame.OffsetY > 0xFFFF {
+			return nil, ErrTooLarge
+		}
+
+		pixels[i] = make([]color.NRGBA, 0, bounds.Dx()*bounds.Dy())
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				pixel := color.NRGBAModel.Convert(frame.Image.At(x, y)).(color.NRGBA)
+				if pixel.A != 0 {
+					histogram.add(rgbOf(pixel))
+				}
+
+				pixels[i] = append(pixels[i], pixel)
+			}
+		}
+	}
+
+	palette, lookup := histogram.quantize(MaxColors)
+
+	writer := buffer.NewWriter()
+	for i, frame := range sprite.Frames {
+		bounds := frame.Image.Bounds()
+		encodeFrame(writer, pixels[i], lookup, bounds.Dx(), bounds.Dy())
+	}
+
+	for _, rgb := range palette {
+		writer.WriteInt24(rgb)
+	}
+
+	writer.WriteInt16(sprite.Width)
+	writer.WriteInt16(sprite.Height)
+	_ = writer.WriteByte(byte(len(palette)))
+
+	for _, frame := range sprite.Frames {
+		writer.WriteInt16(frame.OffsetX)
+	}
+
+	for _, frame := range sprite.Frames {
+		writer.WriteInt16(frame.OffsetY)
+	}
+
+	for _, frame := range sprite.Frames {
+		writer.WriteInt16(frame.Image.Bounds().Dx())
+	}
+
+	for _, frame := range sprite.Frames {
+		writer.WriteInt16(frame.Image.Bounds().Dy())
+	}
+
+	writer.WriteInt16(len(sprite.Frames))
+
+	return writer.Bytes(), nil
+}
+
+// encodeFrame writes the flags and the palette indices of the given pixels of a frame,
+// followed by their alpha values if any of them is translucent.
+func encodeFrame(writer *buffer.Writer, pixels []color.NRGBA, lookup map[int]int, width, height int) {
+	indices := make([]byte, len(pixels))
+	alphas := make([]byte, len(pixels))
+
+	var flags byte
+	for i, pixel := range pixels {
+		if pixel.A != 0 {
+			indices[i] = byte(lookup[rgbOf(pixel)] + 1)
+		}
+
+		if pixel.A != 0 && pixel.A != 0xFF {
+			flags |= hasAlpha
+		}
+
+		alphas[i] = pixel.A
+	}
+
+	if transitions(indices, width, height, true) < transitions(indices, width, height, false) {
+		flags |= columnMajor
+	}
+
+	_ = writer.WriteByte(flags)
+	writer.WriteBytes(order(indices, width, height, flags&columnMajor != 0))
+
+	if flags&hasAlpha != 0 {
+		writer.WriteBytes(order(alphas, width, height, flags&columnMajor != 0))
+	}
+}
+
+// transitions counts the amount of times consecutive pixels differ in the specified
+// order, as the fewer there are, the better the pixels compress.
+func transitions(pixels []byte, width, height int, columnMajor bool) int {
+	ordered := order(pixels, width, height, columnMajor)
+
+	count := 0
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i] != ordered[i-1] {
+			count++
+		}
+	}
+
+	return count
+}
+
+// order returns the given pixels of a frame that are stored row by row in the specified
+// order.
+func order(pixels []byte, width, height int, columnMajor bool) []byte {
+	if !columnMajor {
+		return pixels
+	}
+
+	columns := make([]byte, len(pixels))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			columns[y+x*height] = pixels[x+y*width]
+		}
+	}
+
+	return columns
+}
+
+// rgbOf returns the 24-bit RGB value of the given color.
+func rgbOf(pixel color.NRGBA) int {
+	return int(pixel.R)<<16 | int(pixel.G)<<8 | int(pixel.B)
+}
+
+// max returns the largest of the given values.
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,133 @@
+package sprite
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	sprite, err := Decode(5, testSprite())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Encode(sprite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Decode(5, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Width != 4 || decoded.Height != 3 || len(decoded.Frames) != 2 {
+		t.Fatalf("unexpected sprite %+v", decoded)
+	}
+
+	for i, frame := range sprite.Frames {
+		if decoded.Frames[i].OffsetX != frame.OffsetX || decoded.Frames[i].OffsetY != frame.OffsetY {
+			t.Errorf("unexpected offsets of frame %v", i)
+		}
+
+		if !sameImage(frame.Image, decoded.Frames[i].Image) {
+			t.Errorf("unexpected pixels of frame %v", i)
+		}
+	}
+
+	if _, ok := decoded.Frames[0].Image.(*image.Paletted); !ok {
+		t.Error("expected an opaque frame to be encoded without alpha values")
+	}
+}
+
+func TestEncodeColumnMajor(t *testing.T) {
+	// vertical stripes compress better column by column
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x*50 + 10), A: 0xFF})
+		}
+	}
+
+	data, err := EncodeImages(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data[0] != columnMajor {
+		t.Errorf("expected column-major pixels but got flags %v", data[0])
+	}
+
+	decoded, err := Decode(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sameImage(img, decoded.Frames[0].Image) {
+		t.Error("unexpected pixels")
+	}
+}
+
+func TestEncodeQuantizes(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 8), B: 0x40, A: 0xFF})
+		}
+	}
+
+	img.SetNRGBA(0, 0, color.NRGBA{})
+
+	data, err := EncodeImages(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Decode(0, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded.Palette) != MaxColors+1 {
+		t.Errorf("expected a full palette but got %v colors", len(decoded.Palette))
+	}
+
+	frame := decoded.Frames[0].Image
+	if _, _, _, a := frame.At(0, 0).RGBA(); a != 0 {
+		t.Error("expected the transparent pixel to remain transparent")
+	}
+
+	got := color.NRGBAModel.Convert(frame.At(31, 31)).(color.NRGBA)
+	if diff(got.R, 248) > 16 || diff(got.G, 248) > 16 || got.A != 0xFF {
+		t.Errorf("expected a close color but got %v", got)
+	}
+}
+
+// sameImage returns whether the given images have equal pixels.
+func sameImage(a, b image.Image) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			ca := color.NRGBAModel.Convert(a.At(a.Bounds().Min.X+x, a.Bounds().Min.Y+y))
+			cb := color.NRGBAModel.Convert(b.At(b.Bounds().Min.X+x, b.Bounds().Min.Y+y))
+			if ca != cb {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// diff returns the absolute difference between the given values.
+func diff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+
+	return int(b - a)
+}
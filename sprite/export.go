@@ -0,0 +1,122 @@
+package sprite
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sinoz/gokira"
+)
+
+// Metadata is the JSON sidecar that accompanies the exported images of a Sprite.
+type Metadata struct {
+	Id     int             `json:"id"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Frames []FrameMetadata `json:"frames"`
+}
+
+// FrameMetadata describes the exported image of a single Frame.
+type FrameMetadata struct {
+	File    string `json:"file"`
+	OffsetX int    `json:"offsetX"`
+	OffsetY int    `json:"offsetY"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+// FrameFileName returns the name of the image file of the specified frame of a sprite.
+func FrameFileName(id, frame int) string {
+	return fmt.Sprintf("%d_%d.png", id, frame)
+}
+
+// MetadataFileName returns the name of the JSON sidecar of a sprite.
+func MetadataFileName(id int) string {
+	return fmt.Sprintf("%d.json", id)
+}
+
+// ExportAll exports every Sprite of the given Cache into the given directory. May
+// return an error.
+func ExportAll(cache *gokira.Cache, dir string) error {
+	manifest, err := cache.GetArchiveManifest(Archive)
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range manifest.FolderReferences {
+		if folder == nil {
+			continue
+		}
+
+		sprite, err := Load(cache, folder.Id)
+		if err != nil {
+			return fmt.Errorf("sprite %d: %v", folder.Id, err)
+		}
+
+		if err := sprite.Export(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Export writes each Frame of the Sprite as a PNG image into the given directory,
+// along with a JSON sidecar of their offsets. May return an error.
+func (sprite *Sprite) Export(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	metadata := sprite.Metadata()
+	for i, frame := range sprite.Frames {
+		if err := writePNG(filepath.Join(dir, metadata.Frames[i].File), frame); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, MetadataFileName(sprite.Id)), data, 0644)
+}
+
+// Metadata returns the Metadata of the exported images of the Sprite.
+func (sprite *Sprite) Metadata() *Metadata {
+	metadata := &Metadata{Id: sprite.Id, Width: sprite.Width, Height: sprite.Height}
+
+	metadata.Frames = make([]FrameMetadata, len(sprite.Frames))
+	for i, frame := range sprite.Frames {
+		bounds := frame.Image.Bounds()
+		metadata.Frames[i] = FrameMetadata{
+			File:    FrameFileName(sprite.Id, i),
+			OffsetX: frame.OffsetX,
+			OffsetY: frame.OffsetY,
+			Width:   bounds.Dx(),
+			Height:  bounds.Dy(),
+		}
+	}
+
+	return metadata
+}
+
+// writePNG writes the image of the given Frame to the file at the given path. May
+// return an error.
+func writePNG(path string, frame *Frame) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, frame.Image); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
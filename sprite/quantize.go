@@ -0,0 +1,133 @@
+package sprite
+
+import "sort"
+
+// histogram counts the occurrences of each color, in the order of their first
+// appearance.
+type histogram struct {
+	colors []int
+	counts map[int]int
+}
+
+// box is a group of colors that are represented by a single color of a palette.
+type box struct {
+	colors []int
+}
+
+// newHistogram constructs an empty histogram.
+func newHistogram() *histogram {
+	return &histogram{counts: make(map[int]int)}
+}
+
+// add counts an occurrence of the given color.
+func (h *histogram) add(rgb int) {
+	if h.counts[rgb] == 0 {
+		h.colors = append(h.colors, rgb)
+	}
+
+	h.counts[rgb]++
+}
+
+// quantize reduces the colors of the histogram to a palette of at most the given amount
+// of colors through median cut, and returns it along with the index within the palette
+// of each color. The colors are kept as is if there are few enough of them.
+func (h *histogram) quantize(limit int) ([]int, map[int]int) {
+	lookup := make(map[int]int, len(h.colors))
+
+	if len(h.colors) <= limit {
+		for i, rgb := range h.colors {
+			lookup[rgb] = i
+		}
+
+		return h.colors, lookup
+	}
+
+	boxes := []*box{{colors: h.colors}}
+	for len(boxes) < limit {
+		widest, channel, extent := -1, 0, 0
+		for i, b := range boxes {
+			if c, e := b.widestChannel(); e > extent {
+				widest, channel, extent = i, c, e
+			}
+		}
+
+		// every box holds a single color
+		if widest == -1 {
+			break
+		}
+
+		lower, upper := boxes[widest].split(channel, h.counts)
+		boxes[widest] = lower
+		boxes = append(boxes, upper)
+	}
+
+	palette := make([]int, len(boxes))
+	for i, b := range boxes {
+		palette[i] = b.average(h.counts)
+		for _, rgb := range b.colors {
+			lookup[rgb] = i
+		}
+	}
+
+	return palette, lookup
+}
+
+// widestChannel returns the shift of the color channel in which the colors of the box
+// differ the most, along with how much they differ.
+func (b *box) widestChannel() (int, int) {
+	channel, extent := 0, 0
+	for _, shift := range []int{16, 8, 0} {
+		low, high := 0xFF, 0
+		for _, rgb := range b.colors {
+			value := rgb >> uint(shift) & 0xFF
+			if value < low {
+				low = value
+			}
+
+			if value > high {
+				high = value
+			}
+		}
+
+		if high-low > extent {
+			channel, extent = shift, high-low
+		}
+	}
+
+	return channel, extent
+}
+
+// split divides the box in two at the weighted median of the given color channel.
+func (b *box) split(channel int, counts map[int]int) (*box, *box) {
+	colors := append([]int(nil), b.colors...)
+	sort.SliceStable(colors, func(i, j int) bool {
+		return colors[i]>>uint(channel)&0xFF < colors[j]>>uint(channel)&0xFF
+	})
+
+	total := 0
+	for _, rgb := range colors {
+		total += counts[rgb]
+	}
+
+	median, seen := 1, counts[colors[0]]
+	for median < len(colors)-1 && seen+counts[colors[median]] <= total/2 {
+		seen += counts[colors[median]]
+		median++
+	}
+
+	return &box{colors: colors[:median]}, &box{colors: colors[median:]}
+}
+
+// average returns the average of the colors of the box, weighted by their occurrences.
+func (b *box) average(counts map[int]int) int {
+	var red, green, blue, total int
+	for _, rgb := range b.colors {
+		count := counts[rgb]
+		red += (rgb >> 16 & 0xFF) * count
+		green += (rgb >> 8 & 0xFF) * count
+		blue += (rgb & 0xFF) * count
+		total += count
+	}
+
+	return (red/total)<<16 | (green/total)<<8 | blue/total
+}
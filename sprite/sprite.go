@@ -0,0 +1,250 @@
+// Package sprite decodes and encodes the groups of sprites that are stored within the
+// sprite archive of the cache.
+package sprite
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+// Archive is the archive that holds a folder for each group of sprites.
+const Archive = 8
+
+// ErrMalformed is returned when the data of a sprite group is inconsistent.
+var ErrMalformed = errors.New("malformed sprite data")
+
+const (
+	// columnMajor is the flag of a frame of which the pixels are stored column by
+	// column rather than row by row.
+	columnMajor = 1
+
+	// hasAlpha is the flag of a frame of which each pixel has an alpha value.
+	hasAlpha = 2
+)
+
+// Sprite is a group of frames that share a palette, such as the frames of an animated
+// icon or the glyphs of a font.
+type Sprite struct {
+	Id int
+
+	// Width and Height are the size of the canvas each frame is drawn onto.
+	Width  int
+	Height int
+
+	// Palette holds the 24-bit RGB colors the frames index. The first color is
+	// never used, as the index of zero marks a transparent pixel.
+	Palette []int
+
+	Frames []*Frame
+}
+
+// Frame is a single image of a Sprite, which is drawn onto the canvas of the sprite
+// at its offsets. The image is an *image.Paletted, or an *image.NRGBA if the frame
+// has an alpha value for each pixel.
+type Frame struct {
+	OffsetX int
+	OffsetY int
+	Image   image.Image
+}
+
+// Load decodes the Sprite of the specified id from the given Cache. May return an error.
+func Load(cache *gokira.Cache, id int) (*Sprite, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decode(id, folder.Data)
+}
+
+// LoadByName decodes the Sprite of the folder that is labelled with the given name, such
+// as "mod_icons". May return an error.
+func LoadByName(cache *gokira.Cache, name string) (*Sprite, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(cache, manifest.Id)
+}
+
+// Decode decodes a Sprite of the given id from the given data. The data ends with the
+// amount of frames, which is preceded by the palette and the dimensions of each frame.
+// The pixels of the frames lead the data. May return an error.
+func Decode(id int, data []byte) (*Sprite, error) {
+	itr := buffer.NewReader(data)
+
+	if err := itr.Seek(len(data) - 2); err != nil {
+		return nil, ErrMalformed
+	}
+
+	count, err := itr.ReadUInt16()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := itr.Seek(len(data) - 7 - int(count)*8); err != nil {
+		return nil, ErrMalformed
+	}
+
+	width, err := itr.ReadUInt16()
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := itr.ReadUInt16()
+	if err != nil {
+		return nil, err
+	}
+
+	paletteSize, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	sprite := &Sprite{Id: id, Width: int(width), Height: int(height), Frames: make([]*Frame, count)}
+
+	// the offsets and dimensions are stored as four consecutive lists
+	dimensions := make([][]int, 4)
+	for i := range dimensions {
+		dimensions[i] = make([]int, count)
+		for frame := range dimensions[i] {
+			value, err := itr.ReadUInt16()
+			if err != nil {
+				return nil, err
+			}
+
+			dimensions[i][frame] = int(value)
+		}
+	}
+
+	if err := itr.Seek(len(data) - 7 - int(count)*8 - int(paletteSize)*3); err != nil {
+		return nil, ErrMalformed
+	}
+
+	sprite.Palette = make([]int, int(paletteSize)+1)
+	for i := 1; i < len(sprite.Palette); i++ {
+		rgb, err := itr.ReadUInt24()
+		if err != nil {
+			return nil, err
+		}
+
+		// black is stored as zero, which the client reserves for transparency
+		if rgb == 0 {
+			rgb = 1
+		}
+
+		sprite.Palette[i] = int(rgb)
+	}
+
+	if err := itr.Seek(0); err != nil {
+		return nil, err
+	}
+
+	for frame := range sprite.Frames {
+		sprite.Frames[frame], err = decodeFrame(itr, sprite.Palette, dimensions[2][frame], dimensions[3][frame])
+		if err != nil {
+			return nil, err
+		}
+
+		sprite.Frames[frame].OffsetX = dimensions[0][frame]
+		sprite.Frames[frame].OffsetY = dimensions[1][frame]
+	}
+
+	return sprite, nil
+}
+
+// decodeFrame decodes the pixels of a frame of the given size. May return an error.
+func decodeFrame(itr *buffer.Reader, palette []int, width, height int) (*Frame, error) {
+	flags, err := itr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := readPixels(itr, width, height, flags&columnMajor != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indices {
+		if int(index) >= len(palette) {
+			return nil, ErrMalformed
+		}
+	}
+
+	bounds := image.Rect(0, 0, width, height)
+
+	if flags&hasAlpha == 0 {
+		img := image.NewPaletted(bounds, colorPalette(palette))
+		copy(img.Pix, indices)
+
+		return &Frame{Image: img}, nil
+	}
+
+	alphas, err := readPixels(itr, width, height, flags&columnMajor != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewNRGBA(bounds)
+	for i, index := range indices {
+		rgb := palette[index]
+		img.Pix[i*4] = uint8(rgb >> 16)
+		img.Pix[i*4+1] = uint8(rgb >> 8)
+		img.Pix[i*4+2] = uint8(rgb)
+		img.Pix[i*4+3] = alphas[i]
+	}
+
+	return &Frame{Image: img}, nil
+}
+
+// readPixels reads a byte for each pixel of a frame of the given size, in the order of
+// rows even if they are stored column by column. May return an error.
+func readPixels(itr *buffer.Reader, width, height int, columnMajor bool) ([]byte, error) {
+	pixels, err := itr.ReadBytes(width * height)
+	if err != nil || !columnMajor {
+		return pixels, err
+	}
+
+	rows := make([]byte, len(pixels))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			rows[x+y*width] = pixels[y+x*height]
+		}
+	}
+
+	return rows, nil
+}
+
+// colorPalette converts the given palette into a palette of which the first color
+// is transparent.
+func colorPalette(palette []int) color.Palette {
+	colors := make(color.Palette, len(palette))
+	colors[0] = color.NRGBA{}
+
+	for i := 1; i < len(palette); i++ {
+		colors[i] = color.NRGBA{R: uint8(palette[i] >> 16), G: uint8(palette[i] >> 8), B: uint8(palette[i]), A: 0xFF}
+	}
+
+	return colors
+}
+
+// Canvas draws the specified frame onto a transparent canvas of the size of the sprite,
+// at the offsets of the frame.
+func (sprite *Sprite) Canvas(frame int) *image.NRGBA {
+	canvas := image.NewNRGBA(image.Rect(0, 0, sprite.Width, sprite.Height))
+	f := sprite.Frames[frame]
+
+	bounds := f.Image.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			canvas.Set(f.OffsetX+x-bounds.Min.X, f.OffsetY+y-bounds.Min.Y, f.Image.At(x, y))
+		}
+	}
+
+	return canvas
+}
@@ -0,0 +1,140 @@
+package sprite
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSprite returns the data of a group of two frames that share a palette of red
+// and black. The first frame is stored row by row, the second one column by column
+// with an alpha value for each pixel.
+func testSprite() []byte {
+	data := []byte{
+		// a 2x1 frame of red and a transparent pixel
+		0, 1, 0,
+		// a 2x2 frame of which the columns are red and black
+		columnMajor | hasAlpha, 1, 1, 2, 2, 0xFF, 0x80, 0xFF, 0x40,
+		// the palette of red and black
+		0xFF, 0, 0, 0, 0, 0,
+		// the size of the canvas and the palette
+		0, 4, 0, 3, 2,
+		// the x and y offsets, widths and heights of the frames
+		0, 1, 0, 2,
+		0, 0, 0, 1,
+		0, 2, 0, 2,
+		0, 1, 0, 2,
+		// the amount of frames
+		0, 2,
+	}
+
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	sprite, err := Decode(5, testSprite())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sprite.Id != 5 || sprite.Width != 4 || sprite.Height != 3 || len(sprite.Frames) != 2 {
+		t.Fatalf("unexpected sprite %+v", sprite)
+	}
+
+	if len(sprite.Palette) != 3 || sprite.Palette[1] != 0xFF0000 || sprite.Palette[2] != 1 {
+		t.Errorf("unexpected palette %v", sprite.Palette)
+	}
+
+	first, ok := sprite.Frames[0].Image.(*image.Paletted)
+	if !ok {
+		t.Fatalf("expected a paletted image but got %T", sprite.Frames[0].Image)
+	}
+
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+	if first.At(0, 0) != red || first.At(1, 0) != (color.NRGBA{}) {
+		t.Errorf("unexpected pixels %v %v", first.At(0, 0), first.At(1, 0))
+	}
+
+	second, ok := sprite.Frames[1].Image.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("expected an NRGBA image but got %T", sprite.Frames[1].Image)
+	}
+
+	if sprite.Frames[1].OffsetX != 2 || sprite.Frames[1].OffsetY != 1 {
+		t.Errorf("unexpected offsets %+v", sprite.Frames[1])
+	}
+
+	if second.NRGBAAt(0, 1) != (color.NRGBA{R: 0xFF, A: 0x80}) || second.NRGBAAt(1, 0) != (color.NRGBA{B: 1, A: 0xFF}) {
+		t.Errorf("expected column-major pixels but got %v %v", second.NRGBAAt(0, 1), second.NRGBAAt(1, 0))
+	}
+
+	canvas := sprite.Canvas(1)
+	if canvas.Bounds().Dx() != 4 || canvas.NRGBAAt(2, 1) != red || canvas.NRGBAAt(0, 0).A != 0 {
+		t.Errorf("expected the frame to be drawn at its offsets")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	if _, err := Decode(0, []byte{0}); err != ErrMalformed {
+		t.Errorf("expected malformed data but got %v", err)
+	}
+
+	data := testSprite()
+	data[1] = 3
+	if _, err := Decode(0, data); err != ErrMalformed {
+		t.Errorf("expected an index outside of the palette to be rejected but got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	sprite, err := Decode(5, testSprite())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "sprite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := sprite.Export(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, MetadataFileName(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metadata.Frames) != 2 || metadata.Frames[1] != (FrameMetadata{File: "5_1.png", OffsetX: 2, OffsetY: 1, Width: 2, Height: 2}) {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	file, err := os.Open(filepath.Join(dir, metadata.Frames[1].File))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Errorf("unexpected image size %v", img.Bounds())
+	}
+}
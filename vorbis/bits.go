@@ -0,0 +1,59 @@
+package vorbis
+
+// bitReader reads values of up to 32 bits from packed data, least significant bit
+// first, the way Vorbis packs its fields. Reading beyond the end of the data yields
+// zero bits and marks the reader as exhausted rather than failing every read.
+type bitReader struct {
+	data      []byte
+	position  int
+	exhausted bool
+}
+
+// newBitReader constructs a bitReader that reads the given data.
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+// bit reads a single bit.
+func (r *bitReader) bit() uint32 {
+	index := r.position >> 3
+	if index >= len(r.data) {
+		r.exhausted = true
+		return 0
+	}
+
+	value := uint32(r.data[index]>>uint(r.position&7)) & 1
+	r.position++
+
+	return value
+}
+
+// bits reads an unsigned value of the given amount of bits.
+func (r *bitReader) bits(count int) uint32 {
+	var value uint32
+	for i := 0; i < count; i++ {
+		value |= r.bit() << uint(i)
+	}
+
+	return value
+}
+
+// int reads an unsigned value of the given amount of bits, as an int.
+func (r *bitReader) int(count int) int {
+	return int(r.bits(count))
+}
+
+// flag reads a single bit as a boolean.
+func (r *bitReader) flag() bool {
+	return r.bit() == 1
+}
+
+// ilog returns the amount of bits that are needed to represent the given value.
+func ilog(value int) int {
+	var bits int
+	for ; value > 0; value >>= 1 {
+		bits++
+	}
+
+	return bits
+}
@@ -0,0 +1,244 @@
+package vorbis
+
+import "math"
+
+// codebookSync is the pattern every codebook starts with.
+const codebookSync = 0x564342
+
+// codebook maps the Huffman codewords of a packet onto entries, and optionally the
+// entries onto vectors of values.
+type codebook struct {
+	dimensions int
+	entries    int
+
+	// tree holds a pair of children for each node of the Huffman tree, of which the
+	// root is the first. Children are the index of another node, the bitwise
+	// complement of an entry, or missing if they are zero.
+	tree []int32
+
+	// vectors are the values of each entry, or nil if the codebook has none.
+	vectors [][]float32
+}
+
+// readCodebook reads a codebook out of the setup. May return an error.
+func readCodebook(r *bitReader) (*codebook, error) {
+	if r.bits(24) != codebookSync {
+		return nil, ErrMalformed
+	}
+
+	book := &codebook{dimensions: r.int(16), entries: r.int(24)}
+	lengths := make([]int, book.entries)
+
+	if r.flag() {
+		// the lengths are ordered, listed as the amount of entries of each length
+		length := r.int(5) + 1
+		for entry := 0; entry < book.entries; length++ {
+			count := r.int(ilog(book.entries - entry))
+			if entry+count > book.entries || r.exhausted {
+				return nil, ErrMalformed
+			}
+
+			for i := 0; i < count; i++ {
+				lengths[entry] = length
+				entry++
+			}
+		}
+	} else {
+		sparse := r.flag()
+		for entry := range lengths {
+			if !sparse || r.flag() {
+				lengths[entry] = r.int(5) + 1
+			}
+		}
+	}
+
+	if err := book.build(lengths); err != nil {
+		return nil, err
+	}
+
+	lookupType := r.int(4)
+	if lookupType == 0 {
+		return book, nil
+	}
+
+	if lookupType > 2 || book.dimensions == 0 {
+		return nil, ErrMalformed
+	}
+
+	minimum := float32Unpack(r.bits(32))
+	delta := float32Unpack(r.bits(32))
+	valueBits := r.int(4) + 1
+	sequential := r.flag()
+
+	lookupValues := book.entries * book.dimensions
+	if lookupType == 1 {
+		lookupValues = lookup1Values(book.entries, book.dimensions)
+	}
+
+	multiplicands := make([]float32, lookupValues)
+	for i := range multiplicands {
+		multiplicands[i] = float32(r.bits(valueBits))
+	}
+
+	if r.exhausted {
+		return nil, ErrMalformed
+	}
+
+	book.vectors = make([][]float32, book.entries)
+	for entry := range book.vectors {
+		vector := make([]float32, book.dimensions)
+
+		var last float32
+		divisor := 1
+
+		for dimension := range vector {
+			offset := entry*book.dimensions + dimension
+			if lookupType == 1 {
+				offset = entry / divisor % lookupValues
+				divisor *= lookupValues
+			}
+
+			vector[dimension] = multiplicands[offset]*delta + minimum + last
+			if sequential {
+				last = vector[dimension]
+			}
+		}
+
+		book.vectors[entry] = vector
+	}
+
+	return book, nil
+}
+
+// build builds the Huffman tree of the codebook out of the lengths of the codewords of
+// its entries, of which those of zero are unused. Codewords are assigned in the order
+// of the entries, each the lowest codeword of its length that is available. May return
+// an error.
+func (book *codebook) build(lengths []int) error {
+	book.tree = []int32{0, 0}
+
+	// markers hold the next available codeword of each length
+	var markers [33]uint32
+
+	for entry, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		codeword := markers[length]
+		if length < 32 && codeword>>uint(length) != 0 {
+			return ErrMalformed
+		}
+
+		book.insert(codeword, length, entry)
+
+		for j := length; j > 0; j-- {
+			if markers[j]&1 != 0 {
+				if j == 1 {
+					markers[1]++
+				} else {
+					markers[j] = markers[j-1] << 1
+				}
+
+				break
+			}
+
+			markers[j]++
+		}
+
+		for j := length + 1; j < len(markers); j++ {
+			if markers[j]>>1 != codeword {
+				break
+			}
+
+			codeword = markers[j]
+			markers[j] = markers[j-1] << 1
+		}
+	}
+
+	return nil
+}
+
+// insert inserts the given entry into the Huffman tree, at the given codeword of the
+// given length of which the most significant bit is read first.
+func (book *codebook) insert(codeword uint32, length, entry int) {
+	node := 0
+	for i := length - 1; i > 0; i-- {
+		child := node*2 + int(codeword>>uint(i)&1)
+		if book.tree[child] <= 0 {
+			book.tree[child] = int32(len(book.tree) / 2)
+			book.tree = append(book.tree, 0, 0)
+		}
+
+		node = int(book.tree[child])
+	}
+
+	book.tree[node*2+int(codeword&1)] = ^int32(entry)
+}
+
+// decode reads a codeword out of the given reader and returns its entry, or -1 if the
+// codeword is not part of the codebook or the packet ends.
+func (book *codebook) decode(r *bitReader) int {
+	node := 0
+	for {
+		child := book.tree[node*2+int(r.bit())]
+		if r.exhausted || child == 0 {
+			return -1
+		}
+
+		if child < 0 {
+			return int(^child)
+		}
+
+		node = int(child)
+	}
+}
+
+// decodeVector reads a codeword out of the given reader and returns the vector of its
+// entry, or nil if the codeword is not part of the codebook or the packet ends.
+func (book *codebook) decodeVector(r *bitReader) []float32 {
+	entry := book.decode(r)
+	if entry < 0 || book.vectors == nil {
+		return nil
+	}
+
+	return book.vectors[entry]
+}
+
+// float32Unpack unpacks a floating point value in the format of Vorbis, which holds a
+// sign, a ten bit exponent and a 21 bit mantissa.
+func float32Unpack(value uint32) float32 {
+	mantissa := float64(value & 0x1FFFFF)
+	if value&0x80000000 != 0 {
+		mantissa = -mantissa
+	}
+
+	exponent := int(value&0x7FE00000) >> 21
+	return float32(math.Ldexp(mantissa, exponent-788))
+}
+
+// lookup1Values returns the greatest value of which the given power does not exceed the
+// given amount of entries.
+func lookup1Values(entries, dimensions int) int {
+	values := int(math.Floor(math.Pow(float64(entries), 1/float64(dimensions))))
+	for power(values+1, dimensions, entries) <= entries {
+		values++
+	}
+
+	for values > 0 && power(values, dimensions, entries) > entries {
+		values--
+	}
+
+	return values
+}
+
+// power returns the given base raised to the given exponent, or a value beyond the given
+// limit as soon as the result exceeds it.
+func power(base, exponent, limit int) int {
+	result := 1
+	for i := 0; i < exponent && result <= limit; i++ {
+		result *= base
+	}
+
+	return result
+}
@@ -0,0 +1,311 @@
+package vorbis
+
+import (
+	"math"
+	"sort"
+)
+
+// floorType is the only type of floor that is supported, floor 1.
+const floorType = 1
+
+// floorRanges are the ranges of the values of the floor for each multiplier.
+var floorRanges = [4]int{256, 128, 86, 64}
+
+// inverseDecibels maps the values of the floor onto amplitudes, spanning 140 decibels
+// over 256 steps.
+var inverseDecibels = func() [256]float32 {
+	var table [256]float32
+	for i := range table {
+		table[i] = float32(math.Pow(10, -7*float64(255-i)/256))
+	}
+
+	return table
+}()
+
+// floor describes the spectral envelope of a packet as a piecewise linear curve on a
+// logarithmic scale, of which the points are listed in the floor.
+type floor struct {
+	partitionClasses []int
+
+	classDimensions []int
+	classSubclasses []int
+	classMasterbook []int
+	subclassBooks   [][]int
+
+	multiplier int
+
+	// xs are the positions of the points of the curve, and order lists the indices of
+	// the points in the order of their positions.
+	xs    []int
+	order []int
+
+	// lowNeighbors and highNeighbors hold the indices of the points before each point
+	// that are nearest to it on either side.
+	lowNeighbors  []int
+	highNeighbors []int
+}
+
+// readFloor reads a floor out of the setup. May return an error.
+func readFloor(r *bitReader, codebooks []*codebook) (*floor, error) {
+	if r.int(16) != floorType {
+		return nil, ErrMalformed
+	}
+
+	f := &floor{partitionClasses: make([]int, r.int(5))}
+
+	classes := 0
+	for i := range f.partitionClasses {
+		f.partitionClasses[i] = r.int(4)
+		if f.partitionClasses[i] >= classes {
+			classes = f.partitionClasses[i] + 1
+		}
+	}
+
+	f.classDimensions = make([]int, classes)
+	f.classSubclasses = make([]int, classes)
+	f.classMasterbook = make([]int, classes)
+	f.subclassBooks = make([][]int, classes)
+
+	for class := 0; class < classes; class++ {
+		f.classDimensions[class] = r.int(3) + 1
+		f.classSubclasses[class] = r.int(2)
+
+		if f.classSubclasses[class] != 0 {
+			f.classMasterbook[class] = r.int(8)
+			if f.classMasterbook[class] >= len(codebooks) {
+				return nil, ErrMalformed
+			}
+		}
+
+		f.subclassBooks[class] = make([]int, 1<<uint(f.classSubclasses[class]))
+		for i := range f.subclassBooks[class] {
+			f.subclassBooks[class][i] = r.int(8) - 1
+			if f.subclassBooks[class][i] >= len(codebooks) {
+				return nil, ErrMalformed
+			}
+		}
+	}
+
+	f.multiplier = r.int(2) + 1
+	rangeBits := r.int(4)
+
+	f.xs = []int{0, 1 << uint(rangeBits)}
+	for _, class := range f.partitionClasses {
+		for i := 0; i < f.classDimensions[class]; i++ {
+			f.xs = append(f.xs, r.int(rangeBits))
+		}
+	}
+
+	f.order = make([]int, len(f.xs))
+	for i := range f.order {
+		f.order[i] = i
+	}
+
+	sort.SliceStable(f.order, func(i, j int) bool {
+		return f.xs[f.order[i]] < f.xs[f.order[j]]
+	})
+
+	f.lowNeighbors = make([]int, len(f.xs))
+	f.highNeighbors = make([]int, len(f.xs))
+
+	for i := 2; i < len(f.xs); i++ {
+		low, high := 0, 1
+		for j := 0; j < i; j++ {
+			if f.xs[j] < f.xs[i] && f.xs[j] > f.xs[low] {
+				low = j
+			}
+
+			if f.xs[j] > f.xs[i] && f.xs[j] < f.xs[high] {
+				high = j
+			}
+		}
+
+		f.lowNeighbors[i], f.highNeighbors[i] = low, high
+	}
+
+	return f, nil
+}
+
+// decode reads the values of the points of the floor out of the given packet. Returns
+// nil if the floor is unused for the packet, which is then silent.
+func (f *floor) decode(r *bitReader, codebooks []*codebook) []int {
+	if !r.flag() {
+		return nil
+	}
+
+	valueBits := ilog(floorRanges[f.multiplier-1] - 1)
+
+	ys := make([]int, len(f.xs))
+	ys[0], ys[1] = r.int(valueBits), r.int(valueBits)
+
+	offset := 2
+	for _, class := range f.partitionClasses {
+		dimensions := f.classDimensions[class]
+		subclassBits := uint(f.classSubclasses[class])
+
+		var value int
+		if subclassBits > 0 {
+			value = codebooks[f.classMasterbook[class]].decode(r)
+		}
+
+		for i := 0; i < dimensions; i++ {
+			book := f.subclassBooks[class][value&(1<<subclassBits-1)]
+			value >>= subclassBits
+
+			if book >= 0 {
+				ys[offset+i] = codebooks[book].decode(r)
+			}
+		}
+
+		offset += dimensions
+	}
+
+	if r.exhausted {
+		return nil
+	}
+
+	return ys
+}
+
+// synthesize renders the curve of the given values of the points of the floor into the
+// given spectrum, multiplying each of its values by the amplitude of the curve.
+func (f *floor) synthesize(ys []int, spectrum []float32) {
+	valueRange := floorRanges[f.multiplier-1]
+
+	final := make([]int, len(ys))
+	used := make([]bool, len(ys))
+
+	final[0], final[1] = ys[0], ys[1]
+	used[0], used[1] = true, true
+
+	// the values of the points other than the first two are relative to the point that
+	// the neighbors of the point predict
+	for i := 2; i < len(ys); i++ {
+		low, high := f.lowNeighbors[i], f.highNeighbors[i]
+		predicted := renderPoint(f.xs[low], final[low], f.xs[high], final[high], f.xs[i])
+
+		value := ys[i]
+		highRoom := valueRange - predicted
+		lowRoom := predicted
+
+		room := highRoom
+		if lowRoom < room {
+			room = lowRoom
+		}
+
+		room *= 2
+
+		if value == 0 {
+			final[i] = predicted
+			continue
+		}
+
+		used[low], used[high], used[i] = true, true, true
+
+		switch {
+		case value >= room && highRoom > lowRoom:
+			final[i] = value - lowRoom + predicted
+		case value >= room:
+			final[i] = predicted - value + highRoom - 1
+		case value%2 == 1:
+			final[i] = predicted - (value+1)/2
+		default:
+			final[i] = predicted + value/2
+		}
+	}
+
+	curve := make([]int, len(spectrum))
+
+	lowX, lowY := 0, final[0]*f.multiplier
+	highX, highY := 0, lowY
+
+	for _, i := range f.order[1:] {
+		if !used[i] {
+			continue
+		}
+
+		highX, highY = f.xs[i], final[i]*f.multiplier
+		renderLine(lowX, lowY, highX, highY, curve)
+		lowX, lowY = highX, highY
+	}
+
+	if highX < len(curve) {
+		renderLine(highX, highY, len(curve), highY, curve)
+	}
+
+	for i := range spectrum {
+		value := curve[i]
+		if value < 0 {
+			value = 0
+		} else if value > 255 {
+			value = 255
+		}
+
+		spectrum[i] *= inverseDecibels[value]
+	}
+}
+
+// renderPoint returns the value at the given position of the line between the given
+// points.
+func renderPoint(x0, y0, x1, y1, x int) int {
+	dy := y1 - y0
+	adx := x1 - x0
+
+	ady := dy
+	if ady < 0 {
+		ady = -ady
+	}
+
+	offset := ady * (x - x0) / adx
+	if dy < 0 {
+		return y0 - offset
+	}
+
+	return y0 + offset
+}
+
+// renderLine renders the line between the given points into the given values, up to
+// but excluding the second point, using integer arithmetic only.
+func renderLine(x0, y0, x1, y1 int, values []int) {
+	dy := y1 - y0
+	adx := x1 - x0
+	if adx <= 0 {
+		return
+	}
+
+	ady := dy
+	if ady < 0 {
+		ady = -ady
+	}
+
+	base := dy / adx
+
+	step := base + 1
+	if dy < 0 {
+		step = base - 1
+	}
+
+	absBase := base
+	if absBase < 0 {
+		absBase = -absBase
+	}
+
+	ady -= absBase * adx
+
+	y, err := y0, 0
+	if x0 < len(values) {
+		values[x0] = y
+	}
+
+	for x := x0 + 1; x < x1 && x < len(values); x++ {
+		err += ady
+		if err >= adx {
+			err -= adx
+			y += step
+		} else {
+			y += base
+		}
+
+		values[x] = y
+	}
+}
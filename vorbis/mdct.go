@@ -0,0 +1,140 @@
+package vorbis
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// imdct computes the inverse modified discrete cosine transform of a block size, which
+// turns the spectrum of a packet into a block of samples that overlaps its neighbors.
+// The transform folds a type IV discrete cosine transform, which in turn is computed
+// with a complex fast Fourier transform of an eighth of the block size.
+type imdct struct {
+	size int
+
+	// preTwiddles and postTwiddles rotate the values before and after the Fourier
+	// transform, and roots are the roots of unity of the Fourier transform.
+	preTwiddles  []complex128
+	postTwiddles []complex128
+	roots        []complex128
+
+	// reversed maps each index of the Fourier transform onto its bit reversal.
+	reversed []int
+}
+
+// newIMDCT constructs an imdct for blocks of the given size, which is a power of two of
+// at least eight.
+func newIMDCT(size int) *imdct {
+	half := size / 2
+	quarter := size / 4
+
+	m := &imdct{
+		size:         size,
+		preTwiddles:  make([]complex128, quarter),
+		postTwiddles: make([]complex128, quarter),
+		roots:        make([]complex128, quarter/2),
+		reversed:     make([]int, quarter),
+	}
+
+	for i := 0; i < quarter; i++ {
+		m.preTwiddles[i] = cmplx.Exp(complex(0, -math.Pi*float64(4*i+1)/float64(4*half)))
+		m.postTwiddles[i] = cmplx.Exp(complex(0, -math.Pi*float64(i)/float64(half)))
+	}
+
+	for i := range m.roots {
+		m.roots[i] = cmplx.Exp(complex(0, -2*math.Pi*float64(i)/float64(quarter)))
+	}
+
+	bits := uint(ilog(quarter) - 1)
+	for i := range m.reversed {
+		for bit := uint(0); bit < bits; bit++ {
+			m.reversed[i] |= (i >> bit & 1) << (bits - 1 - bit)
+		}
+	}
+
+	return m
+}
+
+// transform transforms the given spectrum of half the block size into the given block
+// of samples, as y[n] = sum of X[k] cos(2π/N (n + 1/2 + N/4)(k + 1/2)).
+func (m *imdct) transform(spectrum, block []float32) {
+	half := m.size / 2
+	quarter := m.size / 4
+
+	values := make([]complex128, quarter)
+	for i := range values {
+		value := complex(float64(spectrum[2*i]), float64(spectrum[half-1-2*i]))
+		values[m.reversed[i]] = value * m.preTwiddles[i]
+	}
+
+	m.fft(values)
+
+	// the values of the type IV cosine transform, of which the block is a fold
+	dct := make([]float64, half)
+	for i, value := range values {
+		value *= m.postTwiddles[i]
+		dct[2*i] = real(value)
+		dct[half-1-2*i] = -imag(value)
+	}
+
+	for i := range block {
+		switch index := i + half/2; {
+		case index < half:
+			block[i] = float32(dct[index])
+		case index < 2*half:
+			block[i] = float32(-dct[2*half-1-index])
+		default:
+			block[i] = float32(-dct[index-2*half])
+		}
+	}
+}
+
+// fft computes the fast Fourier transform of the given values in place, which must be
+// in bit reversed order.
+func (m *imdct) fft(values []complex128) {
+	for length := 2; length <= len(values); length <<= 1 {
+		stride := len(values) / length
+		for start := 0; start < len(values); start += length {
+			for i := 0; i < length/2; i++ {
+				even := values[start+i]
+				odd := values[start+i+length/2] * m.roots[i*stride]
+
+				values[start+i] = even + odd
+				values[start+i+length/2] = even - odd
+			}
+		}
+	}
+}
+
+// window returns the window of a block of the given size, of which the left and right
+// slopes span the given sizes of the neighboring blocks, so that the overlapping slopes
+// of neighbors add up to unity in power.
+func window(size, leftSize, rightSize int) []float32 {
+	values := make([]float32, size)
+
+	leftStart := size/4 - leftSize/4
+	leftEnd := size/4 + leftSize/4
+	rightStart := size*3/4 - rightSize/4
+	rightEnd := size*3/4 + rightSize/4
+
+	for i := leftStart; i < leftEnd; i++ {
+		values[i] = slope(float64(i-leftStart)+0.5, leftSize/2)
+	}
+
+	for i := leftEnd; i < rightStart; i++ {
+		values[i] = 1
+	}
+
+	for i := rightStart; i < rightEnd; i++ {
+		values[i] = slope(float64(rightEnd-i)-0.5, rightSize/2)
+	}
+
+	return values
+}
+
+// slope returns the value of the rising slope of a window at the given position of the
+// given length of the slope.
+func slope(position float64, length int) float32 {
+	x := math.Sin(position / float64(length) * math.Pi / 2)
+	return float32(math.Sin(math.Pi / 2 * x * x))
+}
@@ -0,0 +1,156 @@
+package vorbis
+
+// residuePasses is the amount of passes over the partitions of a residue.
+const residuePasses = 8
+
+// residue describes the fine structure of the spectrum of a packet that the floor
+// scales, as vectors of codebooks across partitions of the spectrum.
+type residue struct {
+	// kind is the type of the residue, which determines how the vectors interleave.
+	// As samples hold a single channel, the types 1 and 2 are alike.
+	kind int
+
+	begin         int
+	end           int
+	partitionSize int
+
+	classifications int
+	classbook       int
+
+	// books holds the codebook of each pass for each classification, or -1 if the
+	// pass skips partitions of that classification.
+	books [][residuePasses]int
+}
+
+// readResidue reads a residue out of the setup. May return an error.
+func readResidue(r *bitReader, codebooks []*codebook) (*residue, error) {
+	res := &residue{kind: r.int(16)}
+	if res.kind > 2 {
+		return nil, ErrMalformed
+	}
+
+	res.begin = r.int(24)
+	res.end = r.int(24)
+	res.partitionSize = r.int(24) + 1
+	res.classifications = r.int(6) + 1
+	res.classbook = r.int(8)
+
+	if res.classbook >= len(codebooks) || codebooks[res.classbook].dimensions == 0 {
+		return nil, ErrMalformed
+	}
+
+	cascades := make([]int, res.classifications)
+	for i := range cascades {
+		cascades[i] = r.int(3)
+		if r.flag() {
+			cascades[i] |= r.int(5) << 3
+		}
+	}
+
+	res.books = make([][residuePasses]int, res.classifications)
+	for i, cascade := range cascades {
+		for pass := 0; pass < residuePasses; pass++ {
+			res.books[i][pass] = -1
+			if cascade&(1<<uint(pass)) == 0 {
+				continue
+			}
+
+			book := r.int(8)
+			if book >= len(codebooks) || codebooks[book].vectors == nil {
+				return nil, ErrMalformed
+			}
+
+			res.books[i][pass] = book
+		}
+	}
+
+	return res, nil
+}
+
+// decode reads the residue out of the given packet and adds it to the given spectrum.
+// Decoding stops early if the packet ends, leaving the remainder of the spectrum as is.
+func (res *residue) decode(r *bitReader, codebooks []*codebook, spectrum []float32) {
+	begin, end := res.begin, res.end
+	if begin > len(spectrum) {
+		begin = len(spectrum)
+	}
+
+	if end > len(spectrum) {
+		end = len(spectrum)
+	}
+
+	if end <= begin {
+		return
+	}
+
+	classbook := codebooks[res.classbook]
+	partitions := (end - begin) / res.partitionSize
+	classifications := make([]int, partitions+classbook.dimensions)
+
+	for pass := 0; pass < residuePasses; pass++ {
+		for partition := 0; partition < partitions; {
+			if pass == 0 {
+				value := classbook.decode(r)
+				if value < 0 {
+					return
+				}
+
+				for i := classbook.dimensions - 1; i >= 0; i-- {
+					classifications[partition+i] = value % res.classifications
+					value /= res.classifications
+				}
+			}
+
+			for i := 0; i < classbook.dimensions && partition < partitions; i++ {
+				book := res.books[classifications[partition]][pass]
+				if book >= 0 {
+					offset := begin + partition*res.partitionSize
+					if !res.decodePartition(r, codebooks[book], spectrum[offset:offset+res.partitionSize]) {
+						return
+					}
+				}
+
+				partition++
+			}
+		}
+	}
+}
+
+// decodePartition reads the vectors of a partition out of the given packet and adds
+// them to the given values. Returns false if the packet ends.
+func (res *residue) decodePartition(r *bitReader, book *codebook, values []float32) bool {
+	if res.kind == 0 {
+		// the vectors interleave, each holding values that lie a step apart
+		step := len(values) / book.dimensions
+		for i := 0; i < step; i++ {
+			vector := book.decodeVector(r)
+			if vector == nil {
+				return false
+			}
+
+			for j, value := range vector {
+				values[i+j*step] += value
+			}
+		}
+
+		return true
+	}
+
+	for i := 0; i < len(values); {
+		vector := book.decodeVector(r)
+		if vector == nil {
+			return false
+		}
+
+		for _, value := range vector {
+			if i >= len(values) {
+				break
+			}
+
+			values[i] += value
+			i++
+		}
+	}
+
+	return true
+}
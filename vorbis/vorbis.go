@@ -0,0 +1,397 @@
+// Package vorbis decodes the samples of the cache that the instruments of the music are
+// made of, which are mono Vorbis audio streams that share a single setup and leave out
+// the headers and the framing of Ogg Vorbis files.
+package vorbis
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+)
+
+const (
+	// Archive is the archive that holds the setup in its first folder and a folder for
+	// each of the samples.
+	Archive = 14
+
+	// SetupFolder is the folder that holds the setup that every sample is decoded with.
+	SetupFolder = 0
+)
+
+// ErrMalformed is returned when the setup or a sample is malformed.
+var ErrMalformed = errors.New("vorbis sample is malformed")
+
+// Setup holds the codebooks and the configuration that every sample is decoded with, as
+// in the setup header of a Vorbis stream.
+type Setup struct {
+	// BlockSizes are the sizes of the short and the long blocks of the samples.
+	BlockSizes [2]int
+
+	codebooks []*codebook
+	floors    []*floor
+	residues  []*residue
+	mappings  []*mapping
+	modes     []*mode
+
+	transforms [2]*imdct
+}
+
+// mapping maps the channel of a packet onto the floor and the residue it is decoded
+// with. As samples hold a single channel, only the first submap is ever used.
+type mapping struct {
+	floor   int
+	residue int
+}
+
+// mode is the mode of a packet, which determines its block size and its mapping.
+type mode struct {
+	long    bool
+	mapping int
+}
+
+// Sample is a sample of the cache, a recording that an instrument plays at various
+// pitches.
+type Sample struct {
+	Id int
+
+	// SampleRate is the amount of samples per second of the sample.
+	SampleRate int
+
+	// SampleCount is the amount of samples that the sample decodes to.
+	SampleCount int
+
+	// LoopStart and LoopEnd are the range of samples that repeats while the sample is
+	// looped.
+	LoopStart int
+	LoopEnd   int
+
+	// PingPong indicates that the loop alternates between playing forwards and
+	// backwards rather than jumping back to its start.
+	PingPong bool
+
+	// Packets are the encoded audio packets of the sample.
+	Packets [][]byte
+}
+
+// LoadSetup decodes the Setup from the given Cache. May return an error.
+func LoadSetup(cache *gokira.Cache) (*Setup, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, SetupFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeSetup(folder.Data)
+}
+
+// DecodeSetup decodes a Setup from the given data, which starts with the exponents of
+// the block sizes, followed by the codebooks, the floors, the residues, the mappings and
+// the modes. May return an error.
+func DecodeSetup(data []byte) (*Setup, error) {
+	r := newBitReader(data)
+
+	setup := &Setup{}
+	setup.BlockSizes[0] = 1 << uint(r.int(4))
+	setup.BlockSizes[1] = 1 << uint(r.int(4))
+
+	if setup.BlockSizes[0] < 8 || setup.BlockSizes[0] > setup.BlockSizes[1] {
+		return nil, ErrMalformed
+	}
+
+	setup.codebooks = make([]*codebook, r.int(8)+1)
+	for i := range setup.codebooks {
+		book, err := readCodebook(r)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.codebooks[i] = book
+	}
+
+	// the time domain transforms are placeholders
+	for i := r.int(6) + 1; i > 0; i-- {
+		if r.int(16) != 0 {
+			return nil, ErrMalformed
+		}
+	}
+
+	setup.floors = make([]*floor, r.int(6)+1)
+	for i := range setup.floors {
+		f, err := readFloor(r, setup.codebooks)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.floors[i] = f
+	}
+
+	setup.residues = make([]*residue, r.int(6)+1)
+	for i := range setup.residues {
+		res, err := readResidue(r, setup.codebooks)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.residues[i] = res
+	}
+
+	setup.mappings = make([]*mapping, r.int(6)+1)
+	for i := range setup.mappings {
+		m, err := setup.readMapping(r)
+		if err != nil {
+			return nil, err
+		}
+
+		setup.mappings[i] = m
+	}
+
+	setup.modes = make([]*mode, r.int(6)+1)
+	for i := range setup.modes {
+		m := &mode{long: r.flag()}
+
+		// the window and the transform types are always zero
+		r.int(16)
+		r.int(16)
+
+		m.mapping = r.int(8)
+		if m.mapping >= len(setup.mappings) {
+			return nil, ErrMalformed
+		}
+
+		setup.modes[i] = m
+	}
+
+	if r.exhausted {
+		return nil, ErrMalformed
+	}
+
+	setup.transforms[0] = newIMDCT(setup.BlockSizes[0])
+	setup.transforms[1] = newIMDCT(setup.BlockSizes[1])
+
+	return setup, nil
+}
+
+// readMapping reads a mapping out of the setup. May return an error.
+func (setup *Setup) readMapping(r *bitReader) (*mapping, error) {
+	if r.int(16) != 0 {
+		return nil, ErrMalformed
+	}
+
+	submaps := 1
+	if r.flag() {
+		submaps = r.int(4) + 1
+	}
+
+	// a single channel has nothing to couple, leaving the angle and magnitude channels
+	// without any bits
+	if r.flag() {
+		r.int(8)
+	}
+
+	if r.int(2) != 0 {
+		return nil, ErrMalformed
+	}
+
+	if submaps > 1 {
+		r.int(4)
+	}
+
+	m := &mapping{}
+	for i := 0; i < submaps; i++ {
+		// the time domain transform is unused
+		r.int(8)
+
+		floor, residue := r.int(8), r.int(8)
+		if floor >= len(setup.floors) || residue >= len(setup.residues) {
+			return nil, ErrMalformed
+		}
+
+		if i == 0 {
+			m.floor, m.residue = floor, residue
+		}
+	}
+
+	return m, nil
+}
+
+// LoadSample loads the Sample of the specified id from the given Cache. May return an
+// error.
+func LoadSample(cache *gokira.Cache, id int) (*Sample, error) {
+	folder, err := cache.GetUnencryptedFolder(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeSample(id, folder.Data)
+}
+
+// DecodeSample decodes a Sample of the given id from the given data, which lists the
+// sample rate, the amount of samples and the loop, followed by the packets. A negative
+// loop end is the complement of a loop that plays back and forth. Each packet is
+// preceded by its length, as a sum of bytes that continues while they are 255. May
+// return an error.
+func DecodeSample(id int, data []byte) (*Sample, error) {
+	sample := &Sample{Id: id}
+
+	reader := buffer.NewReader(data)
+
+	var fields [5]int32
+	for i := range fields {
+		value, err := reader.ReadInt32()
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		fields[i] = value
+	}
+
+	sample.SampleRate = int(fields[0])
+	sample.SampleCount = int(fields[1])
+	sample.LoopStart = int(fields[2])
+	sample.LoopEnd = int(fields[3])
+
+	if sample.LoopEnd < 0 {
+		sample.LoopEnd = ^sample.LoopEnd
+		sample.PingPong = true
+	}
+
+	packetCount := int(fields[4])
+	if packetCount < 0 || sample.SampleCount < 0 {
+		return nil, ErrMalformed
+	}
+
+	sample.Packets = make([][]byte, packetCount)
+	for i := range sample.Packets {
+		var length int
+		for {
+			value, err := reader.ReadByte()
+			if err != nil {
+				return nil, ErrMalformed
+			}
+
+			length += int(value)
+			if value < 255 {
+				break
+			}
+		}
+
+		packet, err := reader.ReadBytes(length)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		sample.Packets[i] = packet
+	}
+
+	return sample, nil
+}
+
+// Decode decodes the packets of the given Sample into signed eight bit samples, the way
+// the client plays them. May return an error.
+func (setup *Setup) Decode(sample *Sample) ([]int8, error) {
+	output := make([]int8, 0, sample.SampleCount)
+
+	var previous []float32
+	for _, packet := range sample.Packets {
+		block, err := setup.decodePacket(packet)
+		if err != nil {
+			return nil, err
+		}
+
+		// the right half of the previous block overlaps the left half of this one,
+		// adding up to the samples between the centers of both blocks
+		if previous != nil {
+			count := len(previous)/4 + len(block)/4
+			for i := 0; i < count; i++ {
+				var value float32
+				if index := len(previous)/2 + i; index < len(previous) {
+					value += previous[index]
+				}
+
+				if index := i - len(previous)/4 + len(block)/4; index >= 0 && index < len(block) {
+					value += block[index]
+				}
+
+				output = append(output, toSample(value))
+			}
+		}
+
+		previous = block
+	}
+
+	if len(output) > sample.SampleCount {
+		output = output[:sample.SampleCount]
+	}
+
+	return output, nil
+}
+
+// decodePacket decodes the given packet into a block of windowed samples. May return an
+// error.
+func (setup *Setup) decodePacket(packet []byte) ([]float32, error) {
+	r := newBitReader(packet)
+	if r.flag() {
+		return nil, ErrMalformed
+	}
+
+	modeIndex := r.int(ilog(len(setup.modes) - 1))
+	if modeIndex >= len(setup.modes) {
+		return nil, ErrMalformed
+	}
+
+	m := setup.modes[modeIndex]
+
+	size := setup.BlockSizes[0]
+	leftSize, rightSize := size, size
+
+	if m.long {
+		size = setup.BlockSizes[1]
+		leftSize, rightSize = size, size
+
+		if !r.flag() {
+			leftSize = setup.BlockSizes[0]
+		}
+
+		if !r.flag() {
+			rightSize = setup.BlockSizes[0]
+		}
+	}
+
+	spectrum := make([]float32, size/2)
+	block := make([]float32, size)
+
+	mapping := setup.mappings[m.mapping]
+	f := setup.floors[mapping.floor]
+
+	// a packet without a floor is silent and holds no residue
+	if ys := f.decode(r, setup.codebooks); ys != nil {
+		setup.residues[mapping.residue].decode(r, setup.codebooks, spectrum)
+		f.synthesize(ys, spectrum)
+
+		transform := setup.transforms[0]
+		if m.long {
+			transform = setup.transforms[1]
+		}
+
+		transform.transform(spectrum, block)
+
+		for i, value := range window(size, leftSize, rightSize) {
+			block[i] *= value
+		}
+	}
+
+	return block, nil
+}
+
+// toSample converts the given amplitude into a signed eight bit sample, clamping it to
+// the range of the sample.
+func toSample(value float32) int8 {
+	sample := int(128 + value*128)
+	if sample < 0 {
+		sample = 0
+	} else if sample > 255 {
+		sample = 255
+	}
+
+	return int8(sample - 128)
+}
@@ -0,0 +1,190 @@
+package vorbis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// loadTestCache builds the fixture cache that holds the test setup and a sample that
+// loops back and forth, of a short, a long, another short and a silent packet.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	sample := cachetest.VorbisSample(22050, 500, 100, ^400,
+		cachetest.VorbisPacket(false, false, false, []int{48, 56}, 1),
+		cachetest.VorbisPacket(true, false, false, []int{52, 44}, 2),
+		cachetest.VorbisPacket(false, false, false, []int{40, 60}, 3),
+		cachetest.VorbisPacket(false, false, false, nil, 0))
+
+	builder := cachetest.New()
+	builder.AddFile(Archive, 0, cachetest.VorbisSetup())
+	builder.AddFile(Archive, 1, sample).Compressed = true
+
+	return builder.Build(t)
+}
+
+// packCodewords packs the given codewords of the given lengths the way a packet holds
+// them, most significant bit first.
+func packCodewords(codewords, lengths []int) []byte {
+	var data []byte
+	var position int
+
+	for i, codeword := range codewords {
+		for bit := lengths[i] - 1; bit >= 0; bit-- {
+			if position>>3 == len(data) {
+				data = append(data, 0)
+			}
+
+			data[position>>3] |= byte(codeword>>uint(bit)&1) << uint(position&7)
+			position++
+		}
+	}
+
+	return data
+}
+
+func TestCodebook(t *testing.T) {
+	// the example of the specification assigns the codewords 00, 0100, 0101, 0110, 0111,
+	// 10, 110 and 111 to these lengths
+	lengths := []int{2, 4, 4, 4, 4, 2, 3, 3}
+	codewords := []int{0, 4, 5, 6, 7, 2, 6, 7}
+
+	book := &codebook{dimensions: 1, entries: len(lengths)}
+	if err := book.build(lengths); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newBitReader(packCodewords(codewords, lengths))
+	for entry := range lengths {
+		if decoded := book.decode(r); decoded != entry {
+			t.Errorf("expected entry %v but got %v", entry, decoded)
+		}
+	}
+
+	if err := book.build([]int{1, 1, 1}); err != ErrMalformed {
+		t.Errorf("expected %v for an overspecified codebook but got %v", ErrMalformed, err)
+	}
+}
+
+func TestFloat32Unpack(t *testing.T) {
+	values := map[uint32]float32{
+		788<<21 | 1:              1,
+		0x80000000 | 788<<21 | 1: -1,
+		787<<21 | 3:              1.5,
+		(788+20)<<21 | 0x1FFFFF:  0x1FFFFF << 20,
+	}
+
+	for packed, expected := range values {
+		if value := float32Unpack(packed); value != expected {
+			t.Errorf("expected %v to unpack to %v but got %v", packed, expected, value)
+		}
+	}
+
+	for _, lookup := range [][3]int{{4, 1, 4}, {16, 2, 4}, {17, 2, 4}, {26, 3, 2}, {27, 3, 3}} {
+		if values := lookup1Values(lookup[0], lookup[1]); values != lookup[2] {
+			t.Errorf("expected %v lookup values for %v entries of %v dimensions but got %v", lookup[2], lookup[0], lookup[1], values)
+		}
+	}
+}
+
+func TestIMDCT(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{8, 64, 512} {
+		spectrum := make([]float32, size/2)
+		for i := range spectrum {
+			spectrum[i] = random.Float32()*2 - 1
+		}
+
+		block := make([]float32, size)
+		newIMDCT(size).transform(spectrum, block)
+
+		for n := range block {
+			var expected float64
+			for k, value := range spectrum {
+				expected += float64(value) * math.Cos(2*math.Pi/float64(size)*(float64(n)+0.5+float64(size)/4)*(float64(k)+0.5))
+			}
+
+			if math.Abs(expected-float64(block[n])) > 1e-3 {
+				t.Fatalf("expected sample %v of a block of %v to be %v but got %v", n, size, expected, block[n])
+			}
+		}
+	}
+
+	// overlapping slopes add up to unity in power
+	long, short := window(512, 256, 256), window(256, 256, 256)
+	for i := 0; i < 128; i++ {
+		if power := long[64+i]*long[64+i] + short[128+i]*short[128+i]; math.Abs(float64(power)-1) > 1e-5 {
+			t.Fatalf("expected a power of 1 at %v but got %v", i, power)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cache := loadTestCache(t)
+
+	setup, err := LoadSetup(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if setup.BlockSizes != [2]int{256, 512} || len(setup.codebooks) != 2 || len(setup.modes) != 2 {
+		t.Fatalf("unexpected setup %+v", setup)
+	}
+
+	sample, err := LoadSample(cache, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sample.SampleRate != 22050 || sample.SampleCount != 500 || sample.LoopStart != 100 || sample.LoopEnd != 400 || !sample.PingPong || len(sample.Packets) != 4 {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+
+	samples, err := setup.Decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != sample.SampleCount {
+		t.Fatalf("expected %v samples but got %v", sample.SampleCount, len(samples))
+	}
+
+	// decoded by a reference implementation of the specification, with a short, a long,
+	// another short and a silent packet
+	expected := map[int]int8{
+		0: 4, 1: -37, 63: -70, 64: -79, 100: -69, 191: -36, 192: 39, 250: 1,
+		300: 62, 319: -128, 383: -13, 384: 18, 447: -7, 450: -17, 499: -1,
+	}
+
+	for i, value := range expected {
+		if samples[i] != value {
+			t.Errorf("expected sample %v to be %v but got %v", i, value, samples[i])
+		}
+	}
+}
+
+func TestDecodeSample(t *testing.T) {
+	data := []byte{0, 0, 0x56, 0x22, 0, 0, 0, 10, 0, 0, 0, 0, 0, 0, 0, 10, 0, 0, 0, 2, 255, 45}
+	data = append(data, make([]byte, 300)...)
+	data = append(data, 1, 7)
+
+	sample, err := DecodeSample(3, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sample.SampleRate != 22050 || sample.PingPong || len(sample.Packets) != 2 || len(sample.Packets[0]) != 300 || sample.Packets[1][0] != 7 {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+
+	if _, err := DecodeSample(3, data[:len(data)-1]); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+
+	if _, err := DecodeSetup([]byte{0x98}); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+}
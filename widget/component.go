@@ -0,0 +1,221 @@
+package widget
+
+import "github.com/sinoz/gokira/buffer"
+
+// Component is a single element of an interface. Components are either decoded from the
+// legacy format, of which the behaviour is driven by the button type and by the legacy
+// client scripts, or from the if3 format, of which the behaviour is driven by client
+// scripts that are installed as listeners.
+type Component struct {
+	Id          int  `json:"id"`
+	If3         bool `json:"if3"`
+	Type        int  `json:"type"`
+	ContentType int  `json:"contentType,omitempty"`
+	ParentId    int  `json:"parentId"`
+	Hidden      bool `json:"hidden,omitempty"`
+
+	// X, Y, Width and Height are the raw position and size of the component, which
+	// are interpreted according to their modes. Legacy components are always laid out
+	// with absolute positions and sizes.
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	Width      int `json:"width"`
+	Height     int `json:"height"`
+	XMode      int `json:"xMode,omitempty"`
+	YMode      int `json:"yMode,omitempty"`
+	WidthMode  int `json:"widthMode,omitempty"`
+	HeightMode int `json:"heightMode,omitempty"`
+
+	// ScrollWidth and ScrollHeight are the size of the scrollable area of a layer.
+	ScrollWidth    int  `json:"scrollWidth,omitempty"`
+	ScrollHeight   int  `json:"scrollHeight,omitempty"`
+	NoClickThrough bool `json:"noClickThrough,omitempty"`
+
+	// SpriteId is the sprite of a sprite component, or -1. Legacy components show
+	// ActiveSpriteId instead while one of their conditions holds.
+	SpriteId       int  `json:"spriteId"`
+	ActiveSpriteId int  `json:"activeSpriteId,omitempty"`
+	SpriteAngle    int  `json:"spriteAngle,omitempty"`
+	SpriteTiling   bool `json:"spriteTiling,omitempty"`
+	Outline        int  `json:"outline,omitempty"`
+	ShadowColor    int  `json:"shadowColor,omitempty"`
+	FlipVertical   bool `json:"flipVertical,omitempty"`
+	FlipHorizontal bool `json:"flipHorizontal,omitempty"`
+
+	// Transparency ranges from 0 (opaque) to 255.
+	Transparency int `json:"transparency,omitempty"`
+
+	ModelType         int  `json:"modelType,omitempty"`
+	ModelId           int  `json:"modelId"`
+	ActiveModelId     int  `json:"activeModelId,omitempty"`
+	SequenceId        int  `json:"sequenceId"`
+	ActiveSequenceId  int  `json:"activeSequenceId,omitempty"`
+	ModelOffsetX      int  `json:"modelOffsetX,omitempty"`
+	ModelOffsetY      int  `json:"modelOffsetY,omitempty"`
+	ModelAngleX       int  `json:"modelAngleX,omitempty"`
+	ModelAngleY       int  `json:"modelAngleY,omitempty"`
+	ModelAngleZ       int  `json:"modelAngleZ,omitempty"`
+	ModelZoom         int  `json:"modelZoom,omitempty"`
+	ModelOrthographic bool `json:"modelOrthographic,omitempty"`
+
+	FontId         int    `json:"fontId"`
+	Text           string `json:"text,omitempty"`
+	ActiveText     string `json:"activeText,omitempty"`
+	LineHeight     int    `json:"lineHeight,omitempty"`
+	TextAlignmentX int    `json:"textAlignmentX,omitempty"`
+	TextAlignmentY int    `json:"textAlignmentY,omitempty"`
+	TextShadowed   bool   `json:"textShadowed,omitempty"`
+
+	// Color is the color of text, rectangles and lines. Legacy components use the
+	// active colors while one of their conditions holds and the mouse over colors
+	// while they are hovered.
+	Color                int  `json:"color,omitempty"`
+	ActiveColor          int  `json:"activeColor,omitempty"`
+	MouseOverColor       int  `json:"mouseOverColor,omitempty"`
+	ActiveMouseOverColor int  `json:"activeMouseOverColor,omitempty"`
+	Filled               bool `json:"filled,omitempty"`
+	LineWidth            int  `json:"lineWidth,omitempty"`
+	LineDirection        bool `json:"lineDirection,omitempty"`
+
+	// Flags are the flags of the server options of the component, such as which of
+	// its options are transmitted to the server.
+	Flags int `json:"flags,omitempty"`
+
+	// Name is the name the options of the component refer to, Actions are the options
+	// of if3 components and TargetVerb the option of spell-like components.
+	Name          string   `json:"name,omitempty"`
+	Actions       []string `json:"actions,omitempty"`
+	TargetVerb    string   `json:"targetVerb,omitempty"`
+	DragDeadZone  int      `json:"dragDeadZone,omitempty"`
+	DragDeadTime  int      `json:"dragDeadTime,omitempty"`
+	ScrollBar     bool     `json:"scrollBar,omitempty"`
+	SpellName     string   `json:"spellName,omitempty"`
+	ButtonType    int      `json:"buttonType,omitempty"`
+	ButtonText    string   `json:"buttonText,omitempty"`
+	ItemActions   []string `json:"itemActions,omitempty"`
+	PaddingX      int      `json:"paddingX,omitempty"`
+	PaddingY      int      `json:"paddingY,omitempty"`
+	MouseOverLink int      `json:"mouseOverLink,omitempty"`
+
+	// InventorySprites are the sprites drawn behind the slots of a legacy inventory,
+	// or -1, at the offsets of the slots.
+	InventorySprites []int `json:"inventorySprites,omitempty"`
+	InventoryOffsetX []int `json:"inventoryOffsetX,omitempty"`
+	InventoryOffsetY []int `json:"inventoryOffsetY,omitempty"`
+
+	// Comparisons and ComparisonValues are the conditions of legacy components, which
+	// compare the results of the legacy client scripts of Scripts against values.
+	Comparisons      []int   `json:"comparisons,omitempty"`
+	ComparisonValues []int   `json:"comparisonValues,omitempty"`
+	Scripts          [][]int `json:"scripts,omitempty"`
+
+	// Listeners are the client scripts that are invoked on events of the component,
+	// keyed by the name of the event such as "onClick". The transmit triggers list
+	// the variables, inventories and stats of which changes trigger the transmit
+	// listeners.
+	Listeners            map[string]*Listener `json:"listeners,omitempty"`
+	VarTransmitTriggers  []int                `json:"varTransmitTriggers,omitempty"`
+	InvTransmitTriggers  []int                `json:"invTransmitTriggers,omitempty"`
+	StatTransmitTriggers []int                `json:"statTransmitTriggers,omitempty"`
+
+	Children []*Component `json:"children,omitempty"`
+}
+
+// Listener is a client script that is installed as a listener of an event, along with
+// the arguments it is invoked with. Arguments are either an int or a string, of which
+// some ints are replaced by a property of the event, see the Event constants.
+type Listener struct {
+	ScriptId  int           `json:"scriptId"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+// The placeholders of the arguments of listeners that are replaced by a property of the
+// event when the listener is invoked.
+const (
+	EventMouseX    = -2147483647
+	EventMouseY    = -2147483646
+	EventComponent = -2147483645
+	EventOp        = -2147483644
+	EventIndex     = -2147483643
+	EventDragged   = -2147483642
+	EventKeyCode   = -2147483640
+	EventKeyChar   = -2147483639
+	EventOpBase    = "event_opbase"
+)
+
+// listeners are the names of the listeners of if3 components, in the order they are
+// encoded in.
+var listeners = []string{
+	"onLoad", "onMouseOver", "onMouseLeave", "onTargetLeave", "onTargetEnter",
+	"onVarTransmit", "onInvTransmit", "onStatTransmit", "onTimer", "onOp",
+	"onMouseRepeat", "onClick", "onClickRepeat", "onRelease", "onHold",
+	"onDrag", "onDragComplete", "onScrollWheel",
+}
+
+// DecodeComponent decodes a Component of the given packed id from the given data, which
+// is in the if3 format if it starts with a byte of 255 and in the legacy format if
+// not. May return an error.
+func DecodeComponent(id int, data []byte) (*Component, error) {
+	component := &Component{Id: id, ParentId: -1, SpriteId: -1, ActiveSpriteId: -1, ModelId: -1,
+		ActiveModelId: -1, SequenceId: -1, ActiveSequenceId: -1, FontId: -1, MouseOverLink: -1}
+
+	c := buffer.NewCursor(data)
+	if len(data) > 0 && data[0] == 0xFF {
+		component.decodeIf3(c)
+	} else {
+		component.decodeLegacy(c)
+	}
+
+	if c.Err() != nil {
+		return nil, c.Err()
+	}
+
+	return component, nil
+}
+
+// nullableU16 reads an unsigned 16-bit integer of which the maximum value denotes -1.
+func nullableU16(c *buffer.Cursor) int {
+	value := c.UInt16()
+	if value == 0xFFFF {
+		return -1
+	}
+
+	return value
+}
+
+// parent resolves the raw id of the parent of the component, which is the index of the
+// parent within the interface of the component.
+func (component *Component) parent(raw int) int {
+	if raw == 0xFFFF {
+		return -1
+	}
+
+	return component.Id&^0xFFFF | raw
+}
+
+// Options returns the options of the component that a player can click, which are the
+// actions of if3 components and the button text or item actions of legacy ones.
+func (component *Component) Options() []string {
+	var options []string
+	for _, action := range component.Actions {
+		if action != "" {
+			options = append(options, action)
+		}
+	}
+
+	if component.ButtonText != "" {
+		options = append(options, component.ButtonText)
+	}
+
+	for _, action := range component.ItemActions {
+		if action != "" {
+			options = append(options, action)
+		}
+	}
+
+	if component.TargetVerb != "" {
+		options = append(options, component.TargetVerb)
+	}
+
+	return options
+}
@@ -0,0 +1,155 @@
+package widget
+
+import "github.com/sinoz/gokira/buffer"
+
+// decodeIf3 decodes the fields of a component in the if3 format.
+func (component *Component) decodeIf3(c *buffer.Cursor) {
+	c.UInt8()
+
+	component.If3 = true
+	component.Type = c.UInt8()
+	component.ContentType = c.UInt16()
+	component.X = c.Int16()
+	component.Y = c.Int16()
+	component.Width = c.UInt16()
+
+	if component.Type == LineType {
+		component.Height = c.Int16()
+	} else {
+		component.Height = c.UInt16()
+	}
+
+	component.WidthMode = c.Int8()
+	component.HeightMode = c.Int8()
+	component.XMode = c.Int8()
+	component.YMode = c.Int8()
+	component.ParentId = component.parent(c.UInt16())
+	component.Hidden = c.Bool()
+
+	switch component.Type {
+	case LayerType:
+		component.ScrollWidth = c.UInt16()
+		component.ScrollHeight = c.UInt16()
+		component.NoClickThrough = c.Bool()
+	case SpriteType:
+		component.SpriteId = c.Int32()
+		component.SpriteAngle = c.UInt16()
+		component.SpriteTiling = c.Bool()
+		component.Transparency = c.UInt8()
+		component.Outline = c.UInt8()
+		component.ShadowColor = c.Int32()
+		component.FlipVertical = c.Bool()
+		component.FlipHorizontal = c.Bool()
+	case ModelType:
+		component.ModelType = 1
+		component.ModelId = nullableU16(c)
+		component.ModelOffsetX = c.Int16()
+		component.ModelOffsetY = c.Int16()
+		component.ModelAngleX = c.UInt16()
+		component.ModelAngleY = c.UInt16()
+		component.ModelAngleZ = c.UInt16()
+		component.ModelZoom = c.UInt16()
+		component.SequenceId = nullableU16(c)
+		component.ModelOrthographic = c.Bool()
+		c.UInt16()
+
+		// the model is scaled to the size of the component if it is not absolute
+		if component.WidthMode != AbsoluteSize {
+			c.UInt16()
+		}
+
+		if component.HeightMode != AbsoluteSize {
+			c.UInt16()
+		}
+	case TextType:
+		component.FontId = nullableU16(c)
+		component.Text = c.CString()
+		component.LineHeight = c.UInt8()
+		component.TextAlignmentX = c.UInt8()
+		component.TextAlignmentY = c.UInt8()
+		component.TextShadowed = c.Bool()
+		component.Color = c.Int32()
+	case RectangleType:
+		component.Color = c.Int32()
+		component.Filled = c.Bool()
+		component.Transparency = c.UInt8()
+	case LineType:
+		component.LineWidth = c.UInt8()
+		component.Color = c.Int32()
+		component.LineDirection = c.Bool()
+	}
+
+	component.Flags = c.UInt24()
+	component.Name = c.CString()
+
+	if count := c.UInt8(); count > 0 {
+		component.Actions = make([]string, count)
+		for i := range component.Actions {
+			component.Actions[i] = c.CString()
+		}
+	}
+
+	component.DragDeadZone = c.UInt8()
+	component.DragDeadTime = c.UInt8()
+	component.ScrollBar = c.Bool()
+	component.TargetVerb = c.CString()
+
+	for _, name := range listeners {
+		if listener := readListener(c); listener != nil {
+			if component.Listeners == nil {
+				component.Listeners = make(map[string]*Listener)
+			}
+
+			component.Listeners[name] = listener
+		}
+	}
+
+	component.VarTransmitTriggers = readTriggers(c)
+	component.InvTransmitTriggers = readTriggers(c)
+	component.StatTransmitTriggers = readTriggers(c)
+}
+
+// readListener reads a listener, which consists of the id of its script followed by
+// its arguments, or nil if there is none.
+func readListener(c *buffer.Cursor) *Listener {
+	count := c.UInt8()
+	if count == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, count)
+	for i := range values {
+		if c.UInt8() == 0 {
+			values[i] = c.Int32()
+		} else {
+			values[i] = c.CString()
+		}
+	}
+
+	listener := &Listener{Arguments: values[1:]}
+	if id, ok := values[0].(int); ok {
+		listener.ScriptId = id
+	}
+
+	if len(listener.Arguments) == 0 {
+		listener.Arguments = nil
+	}
+
+	return listener
+}
+
+// readTriggers reads the ids of which changes trigger a transmit listener, or nil if
+// there are none.
+func readTriggers(c *buffer.Cursor) []int {
+	count := c.UInt8()
+	if count == 0 {
+		return nil
+	}
+
+	triggers := make([]int, count)
+	for i := range triggers {
+		triggers[i] = c.Int32()
+	}
+
+	return triggers
+}
@@ -0,0 +1,47 @@
+package widget
+
+import "image"
+
+// Layout computes the bounds of the component within a parent of the given size, relative
+// to the top left corner of the parent, by interpreting the raw position and size of the
+// component according to their modes like the client does.
+func (component *Component) Layout(parentWidth, parentHeight int) image.Rectangle {
+	width := alignSize(component.Width, component.WidthMode, parentWidth)
+	height := alignSize(component.Height, component.HeightMode, parentHeight)
+
+	x := alignPosition(component.X, component.XMode, width, parentWidth)
+	y := alignPosition(component.Y, component.YMode, height, parentHeight)
+
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// alignSize interprets the given raw size according to the given mode of size.
+func alignSize(raw, mode, parent int) int {
+	switch mode {
+	case MinusSize:
+		return parent - raw
+	case ProportionalSize:
+		return raw * parent >> 14
+	default:
+		return raw
+	}
+}
+
+// alignPosition interprets the given raw offset of a component of the given size
+// according to the given mode of position.
+func alignPosition(raw, mode, size, parent int) int {
+	switch mode {
+	case AlignCenter:
+		return raw + (parent-size)/2
+	case AlignEnd:
+		return parent - size - raw
+	case ProportionalAlignStart:
+		return raw * parent >> 14
+	case ProportionalAlignCenter:
+		return (parent-size)/2 + (raw * parent >> 14)
+	case ProportionalAlignEnd:
+		return parent - size - (raw * parent >> 14)
+	default:
+		return raw
+	}
+}
@@ -0,0 +1,189 @@
+package widget
+
+import "github.com/sinoz/gokira/buffer"
+
+// The types of the buttons of legacy components.
+const (
+	OkButton       = 1
+	TargetButton   = 2
+	CloseButton    = 3
+	ToggleButton   = 4
+	SelectButton   = 5
+	ContinueButton = 6
+)
+
+// defaultButtonTexts are the texts of the buttons of legacy components that do not
+// specify a text of their own.
+var defaultButtonTexts = map[int]string{
+	OkButton:       "Ok",
+	ToggleButton:   "Select",
+	SelectButton:   "Select",
+	ContinueButton: "Continue",
+}
+
+// The flags that legacy components derive from their properties.
+const (
+	continueFlag      = 1
+	buttonFlag        = 1 << 22
+	itemSwappableFlag = 1 << 28
+	itemUsableFlag    = 1 << 30
+	itemMovableFlag   = 1 << 29
+	itemActionFlag    = -1 << 31
+)
+
+// decodeLegacy decodes the fields of a component in the legacy format.
+func (component *Component) decodeLegacy(c *buffer.Cursor) {
+	component.Type = c.UInt8()
+	component.ButtonType = c.UInt8()
+	component.ContentType = c.UInt16()
+	component.X = c.Int16()
+	component.Y = c.Int16()
+	component.Width = c.UInt16()
+	component.Height = c.UInt16()
+	component.Transparency = c.UInt8()
+	component.ParentId = component.parent(c.UInt16())
+	component.MouseOverLink = nullableU16(c)
+
+	if count := c.UInt8(); count > 0 {
+		component.Comparisons = make([]int, count)
+		component.ComparisonValues = make([]int, count)
+		for i := 0; i < count; i++ {
+			component.Comparisons[i] = c.UInt8()
+			component.ComparisonValues[i] = c.UInt16()
+		}
+	}
+
+	if count := c.UInt8(); count > 0 {
+		component.Scripts = make([][]int, count)
+		for i := range component.Scripts {
+			component.Scripts[i] = make([]int, c.UInt16())
+			for j := range component.Scripts[i] {
+				component.Scripts[i][j] = nullableU16(c)
+			}
+		}
+	}
+
+	switch component.Type {
+	case LayerType:
+		component.ScrollHeight = c.UInt16()
+		component.Hidden = c.Bool()
+	case 1:
+		c.UInt16()
+		c.UInt8()
+	case InventoryType:
+		component.decodeInventory(c)
+	case RectangleType:
+		component.Filled = c.Bool()
+	}
+
+	if component.Type == 1 || component.Type == TextType {
+		component.TextAlignmentX = c.UInt8()
+		component.TextAlignmentY = c.UInt8()
+		component.LineHeight = c.UInt8()
+		component.FontId = nullableU16(c)
+		component.TextShadowed = c.Bool()
+	}
+
+	if component.Type == TextType {
+		component.Text = c.CString()
+		component.ActiveText = c.CString()
+	}
+
+	if component.Type == 1 || component.Type == RectangleType || component.Type == TextType {
+		component.Color = c.Int32()
+	}
+
+	if component.Type == RectangleType || component.Type == TextType {
+		component.ActiveColor = c.Int32()
+		component.MouseOverColor = c.Int32()
+		component.ActiveMouseOverColor = c.Int32()
+	}
+
+	switch component.Type {
+	case SpriteType:
+		component.SpriteId = c.Int32()
+		component.ActiveSpriteId = c.Int32()
+	case ModelType:
+		component.ModelType = 1
+		component.ModelId = nullableU16(c)
+		component.ActiveModelId = nullableU16(c)
+		component.SequenceId = nullableU16(c)
+		component.ActiveSequenceId = nullableU16(c)
+		component.ModelZoom = c.UInt16()
+		component.ModelAngleX = c.UInt16()
+		component.ModelAngleY = c.UInt16()
+	case ItemListType:
+		component.TextAlignmentX = c.UInt8()
+		component.FontId = nullableU16(c)
+		component.TextShadowed = c.Bool()
+		component.Color = c.Int32()
+		component.PaddingX = c.Int16()
+		component.PaddingY = c.Int16()
+
+		if c.Bool() {
+			component.Flags |= itemUsableFlag
+		}
+
+		component.decodeItemActions(c)
+	case TooltipType:
+		component.Text = c.CString()
+	}
+
+	if component.ButtonType == TargetButton || component.Type == InventoryType {
+		component.TargetVerb = c.CString()
+		component.SpellName = c.CString()
+		component.Flags |= (c.UInt16() & 0x3F) << 11
+	}
+
+	switch component.ButtonType {
+	case OkButton, ToggleButton, SelectButton, ContinueButton:
+		component.ButtonText = c.CString()
+		if component.ButtonText == "" {
+			component.ButtonText = defaultButtonTexts[component.ButtonType]
+		}
+	}
+
+	switch component.ButtonType {
+	case OkButton, ToggleButton, SelectButton:
+		component.Flags |= buttonFlag
+	case ContinueButton:
+		component.Flags |= continueFlag
+	}
+}
+
+// decodeInventory decodes the fields of a legacy inventory.
+func (component *Component) decodeInventory(c *buffer.Cursor) {
+	for _, flag := range []int{itemSwappableFlag, itemUsableFlag, itemActionFlag, itemMovableFlag} {
+		if c.Bool() {
+			component.Flags |= flag
+		}
+	}
+
+	component.PaddingX = c.UInt8()
+	component.PaddingY = c.UInt8()
+
+	component.InventorySprites = make([]int, 20)
+	component.InventoryOffsetX = make([]int, 20)
+	component.InventoryOffsetY = make([]int, 20)
+	for i := range component.InventorySprites {
+		component.InventorySprites[i] = -1
+		if c.Bool() {
+			component.InventoryOffsetX[i] = c.Int16()
+			component.InventoryOffsetY[i] = c.Int16()
+			component.InventorySprites[i] = c.Int32()
+		}
+	}
+
+	component.decodeItemActions(c)
+}
+
+// decodeItemActions decodes the options of the items of a legacy inventory or item list.
+func (component *Component) decodeItemActions(c *buffer.Cursor) {
+	component.ItemActions = make([]string, 5)
+	for i := range component.ItemActions {
+		component.ItemActions[i] = c.CString()
+		if component.ItemActions[i] != "" {
+			component.Flags |= 1 << uint(i+23)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package widget decodes the interfaces of the client, which are trees of components
+// such as layers, sprites, text and models.
+package widget
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/sinoz/gokira"
+)
+
+// Archive is the archive that holds a folder for each interface, of which each pack
+// is a component.
+const Archive = 3
+
+// The types of components.
+const (
+	LayerType     = 0
+	InventoryType = 2
+	RectangleType = 3
+	TextType      = 4
+	SpriteType    = 5
+	ModelType     = 6
+	ItemListType  = 7
+	TooltipType   = 8
+	LineType      = 9
+)
+
+// The modes of the position and size of a component, which describe how its raw
+// position and size relate to its parent.
+const (
+	// AbsoluteSize sizes a component to its raw size, MinusSize to the size of its
+	// parent minus its raw size and ProportionalSize to its raw size in units of
+	// 1/16384th of the size of its parent.
+	AbsoluteSize     = 0
+	MinusSize        = 1
+	ProportionalSize = 2
+
+	// AlignStart positions a component at its raw offset from the left or top of
+	// its parent, AlignCenter from the center and AlignEnd from the right or bottom.
+	// The proportional modes express the offset in units of 1/16384th of the size
+	// of the parent.
+	AlignStart              = 0
+	AlignCenter             = 1
+	AlignEnd                = 2
+	ProportionalAlignStart  = 3
+	ProportionalAlignCenter = 4
+	ProportionalAlignEnd    = 5
+)
+
+// Interface is an interface along with its tree of components.
+type Interface struct {
+	Id int `json:"id"`
+
+	// Components are the components of the interface, indexed by their index within
+	// the interface. Roots are those without a parent, of which the others descend.
+	Components []*Component `json:"-"`
+	Roots      []*Component `json:"components"`
+}
+
+// Load decodes the Interface of the specified id from the given Cache. May return an error.
+func Load(cache *gokira.Cache, id int) (*Interface, error) {
+	packs, err := cache.GetFolderPacks(Archive, id)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make(map[int]*Component, len(packs))
+	for _, pack := range packs {
+		component, err := DecodeComponent(ComponentId(id, pack.Id), pack.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		components[pack.Id] = component
+	}
+
+	return NewInterface(id, components), nil
+}
+
+// NewInterface constructs an Interface of the given id from the given components,
+// which are keyed by their index within the interface, and links them into a tree.
+func NewInterface(id int, components map[int]*Component) *Interface {
+	size := 0
+	for index := range components {
+		if index >= size {
+			size = index + 1
+		}
+	}
+
+	iface := &Interface{Id: id, Components: make([]*Component, size)}
+	for index, component := range components {
+		iface.Components[index] = component
+		component.Children = nil
+	}
+
+	for _, component := range iface.Components {
+		if component == nil {
+			continue
+		}
+
+		parent := iface.Component(component.ParentId)
+		if parent == nil || parent == component {
+			iface.Roots = append(iface.Roots, component)
+		} else {
+			parent.Children = append(parent.Children, component)
+		}
+	}
+
+	return iface
+}
+
+// Component returns the Component of the given packed id, or nil if it is not part of
+// the Interface.
+func (iface *Interface) Component(id int) *Component {
+	interfaceId, index := SplitComponentId(id)
+	if id == -1 || interfaceId != iface.Id || index >= len(iface.Components) {
+		return nil
+	}
+
+	return iface.Components[index]
+}
+
+// Walk calls the given function for each component of the interface, parents before
+// their children and siblings in order of their index.
+func (iface *Interface) Walk(fn func(component *Component)) {
+	var walk func(components []*Component)
+	walk = func(components []*Component) {
+		for _, component := range components {
+			fn(component)
+			walk(component.Children)
+		}
+	}
+
+	walk(iface.Roots)
+}
+
+// WriteJSON writes the component tree of the Interface as indented JSON to the given
+// writer. May return an error.
+func (iface *Interface) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(iface)
+}
+
+// Clickable returns the ids of the components that have an option to click, along
+// with their options, which is what a server validates the clicks of buttons against.
+func (iface *Interface) Clickable() map[int][]string {
+	clickable := make(map[int][]string)
+	iface.Walk(func(component *Component) {
+		if options := component.Options(); len(options) > 0 {
+			clickable[component.Id] = options
+		}
+	})
+
+	return clickable
+}
+
+// ComponentId packs the given interface id and index of a component within it into the
+// id of the component.
+func ComponentId(interfaceId, index int) int {
+	return interfaceId<<16 | index
+}
+
+// SplitComponentId unpacks the given id of a component into the id of its interface
+// and its index within it.
+func SplitComponentId(id int) (int, int) {
+	return id >> 16, id & 0xFFFF
+}
@@ -0,0 +1,201 @@
+package widget
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// testIf3 encodes an if3 component of the given type, bounds and parent, of which the
+// given body holds the attributes of its type and the given listeners the arguments of
+// its listeners by their index.
+func testIf3(componentType, x, y, width, height, parent int, body []byte, actions []string, listeners map[int][]interface{}) []byte {
+	w := buffer.NewWriter().
+		WriteInt8(255).WriteInt8(componentType).WriteInt16(0).
+		WriteInt16(x).WriteInt16(y).WriteInt16(width).WriteInt16(height).
+		WriteInt32(0).WriteInt16(parent).WriteInt8(0).
+		WriteBytes(body).
+		WriteInt24(0).WriteCString("").WriteInt8(len(actions))
+
+	for _, action := range actions {
+		w.WriteCString(action)
+	}
+
+	w.WriteInt8(0).WriteInt8(0).WriteInt8(0).WriteCString("")
+
+	for i := 0; i < 18; i++ {
+		w.WriteInt8(len(listeners[i]))
+		for _, argument := range listeners[i] {
+			switch argument := argument.(type) {
+			case int:
+				w.WriteInt8(0).WriteInt32(argument)
+			case string:
+				w.WriteInt8(1).WriteCString(argument)
+			}
+		}
+	}
+
+	return w.WriteInt8(1).WriteInt32(101).WriteInt8(0).WriteInt8(0).Bytes()
+}
+
+func loadTestInterface(t *testing.T) *Interface {
+	layer := testIf3(0, 0, 0, 200, 100, 0xFFFF, []byte{0, 0, 1, 44, 0}, nil, nil)
+
+	spriteBody := buffer.NewWriter().
+		WriteInt32(535).WriteInt16(0).WriteInt8(0).WriteInt8(0).WriteInt8(0).
+		WriteInt32(0).WriteInt8(0).WriteInt8(0).Bytes()
+
+	sprite := testIf3(5, 10, 20, 32, 32, 0, spriteBody, []string{"Open", ""}, map[int][]interface{}{
+		11: {2100, -2147483645, "hello"},
+	})
+
+	legacy := buffer.NewWriter().
+		WriteInt8(4).WriteInt8(6).WriteInt16(0).
+		WriteInt16(5).WriteInt16(60).WriteInt16(190).WriteInt16(20).
+		WriteInt8(0).WriteInt16(0).WriteInt16(0xFFFF).
+		// a single comparison and a single legacy script
+		WriteInt8(1).WriteInt8(1).WriteInt16(3).
+		WriteInt8(1).WriteInt16(3).WriteInt16(5).WriteInt16(0xFFFF).WriteInt16(0).
+		// the alignment, the font and the shadow of the text
+		WriteInt8(1).WriteInt8(0).WriteInt8(12).WriteInt16(495).WriteInt8(1).
+		WriteCString("Click here to continue").WriteCString("").
+		WriteInt32(0x000080).WriteInt32(0).WriteInt32(0xFFFFFF).WriteInt32(0).
+		WriteCString("").Bytes()
+
+	builder := cachetest.New()
+	builder.Add(3, 162, map[int][]byte{0: layer, 1: sprite, 3: legacy})
+
+	cache := builder.Build(t)
+
+	iface, err := Load(cache, 162)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return iface
+}
+
+func TestLoad(t *testing.T) {
+	iface := loadTestInterface(t)
+
+	if len(iface.Components) != 4 || iface.Components[2] != nil {
+		t.Fatalf("expected room for 4 components but got %v", len(iface.Components))
+	}
+
+	if len(iface.Roots) != 1 || len(iface.Roots[0].Children) != 2 {
+		t.Fatalf("expected a single root with two children")
+	}
+
+	layer := iface.Roots[0]
+	if layer.Id != 162<<16 || !layer.If3 || layer.Type != LayerType || layer.ScrollHeight != 300 || layer.ParentId != -1 {
+		t.Errorf("unexpected layer %+v", layer)
+	}
+
+	if !reflect.DeepEqual(layer.VarTransmitTriggers, []int{101}) {
+		t.Errorf("unexpected triggers %v", layer.VarTransmitTriggers)
+	}
+}
+
+func TestDecodeIf3(t *testing.T) {
+	sprite := loadTestInterface(t).Component(ComponentId(162, 1))
+
+	if sprite.Type != SpriteType || sprite.SpriteId != 535 || sprite.X != 10 || sprite.Y != 20 || sprite.ParentId != 162<<16 {
+		t.Fatalf("unexpected sprite %+v", sprite)
+	}
+
+	listener := sprite.Listeners["onClick"]
+	if listener == nil || listener.ScriptId != 2100 || !reflect.DeepEqual(listener.Arguments, []interface{}{EventComponent, "hello"}) {
+		t.Errorf("unexpected listener %+v", listener)
+	}
+
+	if len(sprite.Listeners) != 1 {
+		t.Errorf("expected a single listener but got %v", len(sprite.Listeners))
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	text := loadTestInterface(t).Component(ComponentId(162, 3))
+
+	if text.If3 || text.Type != TextType || text.ButtonType != ContinueButton || text.FontId != 495 || !text.TextShadowed {
+		t.Fatalf("unexpected text %+v", text)
+	}
+
+	if text.Text != "Click here to continue" || text.Color != 0x80 || text.MouseOverColor != 0xFFFFFF {
+		t.Errorf("unexpected text properties %+v", text)
+	}
+
+	if !reflect.DeepEqual(text.Scripts, [][]int{{5, -1, 0}}) || !reflect.DeepEqual(text.ComparisonValues, []int{3}) {
+		t.Errorf("unexpected legacy scripts %v %v", text.Scripts, text.ComparisonValues)
+	}
+
+	if text.ButtonText != "Continue" || text.Flags&continueFlag == 0 {
+		t.Errorf("expected the default continue button but got %q", text.ButtonText)
+	}
+}
+
+func TestClickable(t *testing.T) {
+	clickable := loadTestInterface(t).Clickable()
+
+	expected := map[int][]string{
+		ComponentId(162, 1): {"Open"},
+		ComponentId(162, 3): {"Continue"},
+	}
+
+	if !reflect.DeepEqual(clickable, expected) {
+		t.Errorf("unexpected options %v", clickable)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := loadTestInterface(t).WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	var tree struct {
+		Id         int
+		Components []struct {
+			Id       int
+			Children []struct {
+				Id      int
+				Actions []string
+			}
+		}
+	}
+
+	if err := json.Unmarshal(out.Bytes(), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Id != 162 || len(tree.Components) != 1 || len(tree.Components[0].Children) != 2 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+
+	if tree.Components[0].Children[0].Actions[0] != "Open" {
+		t.Errorf("unexpected actions %v", tree.Components[0].Children[0].Actions)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		component *Component
+		expected  image.Rectangle
+	}{
+		{&Component{X: 5, Y: 6, Width: 10, Height: 20}, image.Rect(5, 6, 15, 26)},
+		{&Component{X: 5, Y: 6, Width: 10, Height: 8192, WidthMode: MinusSize, HeightMode: ProportionalSize}, image.Rect(5, 6, 195, 56)},
+		{&Component{X: 5, Y: 6, Width: 10, Height: 20, XMode: AlignCenter, YMode: AlignEnd}, image.Rect(100, 74, 110, 94)},
+		{&Component{X: 8192, Y: 4096, Width: 10, Height: 20, XMode: ProportionalAlignStart, YMode: ProportionalAlignCenter}, image.Rect(100, 65, 110, 85)},
+		{&Component{X: 8192, Y: 0, Width: 10, Height: 20, XMode: ProportionalAlignEnd}, image.Rect(90, 0, 100, 20)},
+	}
+
+	for _, test := range tests {
+		if bounds := test.component.Layout(200, 100); bounds != test.expected {
+			t.Errorf("expected %v but got %v for %+v", test.expected, bounds, test.component)
+		}
+	}
+}
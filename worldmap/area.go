@@ -0,0 +1,228 @@
+package worldmap
+
+import (
+	"image"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/mapdata"
+)
+
+// The types of the sections of an Area, which differ in the unit of the rectangles they
+// map from the game world onto the world map.
+const (
+	// ChunkSection maps a single chunk of eight by eight tiles.
+	ChunkSection = 0
+
+	// RegionRangeSection maps a rectangle of regions.
+	RegionRangeSection = 1
+
+	// RegionSection maps a single region.
+	RegionSection = 2
+
+	// ChunkRangeSection maps a rectangle of chunks within a single region.
+	ChunkRangeSection = 3
+)
+
+// ChunkSize is the width and the height of a chunk, in tiles.
+const ChunkSize = 8
+
+// Area is one of the maps the world map consists of, such as the surface of the game
+// world or a dungeon.
+type Area struct {
+	Id int
+
+	// InternalName labels the Composite of the area and Name is the name the world
+	// map lists the area by.
+	InternalName string
+	Name         string
+
+	// Origin is the position the world map is centred on when the area is opened, or
+	// a Coord of -1 if the area does not specify one.
+	Origin Coord
+
+	// BackgroundColor is the color that the world map is filled with outside of the
+	// sections of the area.
+	BackgroundColor int
+
+	// Main is whether the area is the surface of the game world, which the world map
+	// opens by default.
+	Main bool
+
+	// Zoom is the zoom level the area is opened at.
+	Zoom int
+
+	// Sections map the parts of the game world that the area shows onto the area.
+	Sections []*Section
+}
+
+// Section maps a rectangle of tiles of the game world onto a rectangle of the same size
+// on the world map, for a range of planes. Rectangles are in absolute tile coordinates
+// and exclude their maximum.
+type Section struct {
+	// Type is the type of the section, see ChunkSection and its siblings.
+	Type int
+
+	// MinPlane is the lowest plane of the section and Planes the amount of planes.
+	MinPlane int
+	Planes   int
+
+	// Source is the rectangle of the game world and Display that of the world map.
+	Source  image.Rectangle
+	Display image.Rectangle
+}
+
+// Contains returns whether the section maps the tile at the given coordinates.
+func (section *Section) Contains(c Coord) bool {
+	return c.Plane >= section.MinPlane && c.Plane < section.MinPlane+section.Planes &&
+		image.Pt(c.X, c.Y).In(section.Source)
+}
+
+// Project returns the position on the world map of the given tile, which the section
+// must contain. The plane of the position is relative to the MinPlane of the section.
+func (section *Section) Project(c Coord) Coord {
+	position := image.Pt(c.X, c.Y).Sub(section.Source.Min).Add(section.Display.Min)
+	return Coord{Plane: c.Plane - section.MinPlane, X: position.X, Y: position.Y}
+}
+
+// Project returns the position on the world map of the given tile of the game world, and
+// whether any of the sections of the area contains the tile.
+func (area *Area) Project(c Coord) (Coord, bool) {
+	for _, section := range area.Sections {
+		if section.Contains(c) {
+			return section.Project(c), true
+		}
+	}
+
+	return Coord{}, false
+}
+
+// Bounds returns the rectangle of the world map, in absolute tile coordinates, that the
+// sections of the area span.
+func (area *Area) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+	for _, section := range area.Sections {
+		bounds = bounds.Union(section.Display)
+	}
+
+	return bounds
+}
+
+// LoadAreas decodes every Area of the given Cache, indexed by id. May return an error.
+func LoadAreas(cache *gokira.Cache) ([]*Area, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, DetailsFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	packs, err := cache.GetFolderPacks(Archive, manifest.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var areas []*Area
+	for _, pack := range packs {
+		for len(areas) <= pack.Id {
+			areas = append(areas, nil)
+		}
+
+		if areas[pack.Id], err = DecodeArea(pack.Id, pack.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return areas, nil
+}
+
+// DecodeArea decodes an Area of the given id from the given data. May return an error.
+func DecodeArea(id int, data []byte) (*Area, error) {
+	c := buffer.NewCursor(data)
+
+	area := &Area{
+		Id:              id,
+		InternalName:    c.CString(),
+		Name:            c.CString(),
+		Origin:          UnpackCoord(c.Int32()),
+		BackgroundColor: c.Int32(),
+	}
+
+	c.UInt8()
+	area.Main = c.Bool()
+	area.Zoom = c.UInt8()
+
+	area.Sections = make([]*Section, c.UInt8())
+	for i := range area.Sections {
+		section, err := readSection(c)
+		if err != nil {
+			return nil, err
+		}
+
+		area.Sections[i] = section
+	}
+
+	if c.Err() != nil {
+		return nil, ErrMalformed
+	}
+
+	return area, nil
+}
+
+// readSection reads a Section, which starts with its type followed by its planes. The
+// rectangles are encoded in the unit of the type of the section. May return an error.
+func readSection(c *buffer.Cursor) (*Section, error) {
+	section := &Section{Type: c.UInt8(), MinPlane: c.UInt8(), Planes: c.UInt8()}
+
+	switch section.Type {
+	case ChunkSection:
+		section.Source = readChunk(c)
+		section.Display = readChunk(c)
+
+	case RegionRangeSection:
+		section.Source = regionRange(c.UInt16(), c.UInt16(), c.UInt16(), c.UInt16())
+		section.Display = regionRange(c.UInt16(), c.UInt16(), c.UInt16(), c.UInt16())
+
+	case RegionSection:
+		section.Source = readRegion(c)
+		section.Display = readRegion(c)
+
+	case ChunkRangeSection:
+		section.Source = readChunkRange(c)
+		section.Display = readChunkRange(c)
+
+	default:
+		return nil, ErrMalformed
+	}
+
+	return section, nil
+}
+
+// regionRange returns the rectangle of tiles that spans the regions from the given
+// minimum to the given maximum, both inclusive.
+func regionRange(minX, minY, maxX, maxY int) image.Rectangle {
+	return image.Rect(minX*mapdata.Size, minY*mapdata.Size, (maxX+1)*mapdata.Size, (maxY+1)*mapdata.Size)
+}
+
+// readRegion reads the rectangle of tiles of a single region.
+func readRegion(c *buffer.Cursor) image.Rectangle {
+	x, y := c.UInt16(), c.UInt16()
+	return regionRange(x, y, x, y)
+}
+
+// readChunk reads the rectangle of tiles of a single chunk, which is encoded as the
+// region and the chunk within it along the x axis followed by those along the y axis.
+func readChunk(c *buffer.Cursor) image.Rectangle {
+	x := c.UInt16()*mapdata.Size + c.UInt8()*ChunkSize
+	y := c.UInt16()*mapdata.Size + c.UInt8()*ChunkSize
+
+	return image.Rect(x, y, x+ChunkSize, y+ChunkSize)
+}
+
+// readChunkRange reads the rectangle of tiles of a range of chunks within a region, which
+// is encoded as the region and the first and the last chunk along the x axis followed by
+// those along the y axis.
+func readChunkRange(c *buffer.Cursor) image.Rectangle {
+	regionX, minX, maxX := c.UInt16()*mapdata.Size, c.UInt8(), c.UInt8()
+	regionY, minY, maxY := c.UInt16()*mapdata.Size, c.UInt8(), c.UInt8()
+
+	return image.Rect(regionX+minX*ChunkSize, regionY+minY*ChunkSize, regionX+(maxX+1)*ChunkSize, regionY+(maxY+1)*ChunkSize)
+}
@@ -0,0 +1,155 @@
+package worldmap
+
+import (
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/config"
+)
+
+// Composite describes what an Area is composed of: the map squares and the zones of
+// the terrain the world map draws and the elements that are placed on top of it.
+type Composite struct {
+	MapSquares []*MapSquare
+	Zones      []*Zone
+	Elements   []*Element
+}
+
+// MapSquare places the terrain of a region onto the world map. The squares are in
+// region coordinates.
+type MapSquare struct {
+	MinPlane int
+	Planes   int
+
+	DisplayX int
+	DisplayY int
+	SourceX  int
+	SourceY  int
+
+	// GroupId and FileId locate the terrain within the GeographyArchive, or are -1.
+	GroupId int
+	FileId  int
+}
+
+// Zone places the terrain of a chunk onto the world map, in the chunk of the given
+// square in region coordinates.
+type Zone struct {
+	MapSquare
+
+	DisplayZoneX int
+	DisplayZoneY int
+	SourceZoneX  int
+	SourceZoneY  int
+}
+
+// Element is a map function or a label that is placed on the world map.
+type Element struct {
+	// AreaTypeId is the id of the config.AreaType that describes the icon and the
+	// label of the element.
+	AreaTypeId int
+
+	// Position is the tile of the game world the element is placed at, which the
+	// Area projects onto the world map.
+	Position Coord
+
+	// Members is whether the element is only shown on members worlds.
+	Members bool
+}
+
+// LoadComposite decodes the Composite of the given Area from the given Cache. May return
+// an error.
+func LoadComposite(cache *gokira.Cache, area *Area) (*Composite, error) {
+	data, err := loadPack(cache, CompositeMapFolder, area.InternalName)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeComposite(data)
+}
+
+// DecodeComposite decodes a Composite from the given data, which lists the map squares,
+// the zones and the elements, each list preceded by its length. May return an error.
+func DecodeComposite(data []byte) (*Composite, error) {
+	c := buffer.NewCursor(data)
+
+	composite := &Composite{MapSquares: make([]*MapSquare, c.UInt16())}
+	for i := range composite.MapSquares {
+		composite.MapSquares[i] = readMapSquare(c)
+	}
+
+	composite.Zones = make([]*Zone, c.UInt16())
+	for i := range composite.Zones {
+		zone := &Zone{MapSquare: MapSquare{MinPlane: c.UInt8(), Planes: c.UInt8()}}
+
+		zone.DisplayX, zone.DisplayY = c.UInt16(), c.UInt16()
+		zone.SourceX, zone.SourceY = c.UInt16(), c.UInt16()
+		zone.DisplayZoneX, zone.DisplayZoneY = c.UInt8(), c.UInt8()
+		zone.SourceZoneX, zone.SourceZoneY = c.UInt8(), c.UInt8()
+		zone.GroupId, zone.FileId = c.NullableBigSmart(), c.NullableBigSmart()
+
+		composite.Zones[i] = zone
+	}
+
+	composite.Elements = make([]*Element, c.UInt16())
+	for i := range composite.Elements {
+		composite.Elements[i] = &Element{AreaTypeId: c.NullableBigSmart(), Position: UnpackCoord(c.Int32()), Members: c.Bool()}
+	}
+
+	if c.Err() != nil || c.IsReadable() {
+		return nil, ErrMalformed
+	}
+
+	return composite, nil
+}
+
+// readMapSquare reads a MapSquare.
+func readMapSquare(c *buffer.Cursor) *MapSquare {
+	return &MapSquare{
+		MinPlane: c.UInt8(),
+		Planes:   c.UInt8(),
+		DisplayX: c.UInt16(),
+		DisplayY: c.UInt16(),
+		SourceX:  c.UInt16(),
+		SourceY:  c.UInt16(),
+		GroupId:  c.NullableBigSmart(),
+		FileId:   c.NullableBigSmart(),
+	}
+}
+
+// Marker is an Element placed on the world map, along with the AreaType that describes
+// how it is drawn.
+type Marker struct {
+	Element *Element
+
+	// Type is the config.AreaType of the element, or nil if it does not exist.
+	Type *config.AreaType
+
+	// Position is the position of the element on the world map.
+	Position Coord
+}
+
+// Markers places each of the elements of the given Composite onto the world map of the
+// given Area, along with their config.AreaType out of the given area types indexed by id.
+// Elements that lie outside of the sections of the area are left out, as are the
+// elements that are only shown on members worlds unless members is set.
+func Markers(area *Area, composite *Composite, areaTypes []*config.AreaType, members bool) []*Marker {
+	var markers []*Marker
+	for _, element := range composite.Elements {
+		if element.Members && !members {
+			continue
+		}
+
+		position, ok := area.Project(element.Position)
+		if !ok {
+			continue
+		}
+
+		marker := &Marker{Element: element, Position: position}
+		if element.AreaTypeId >= 0 && element.AreaTypeId < len(areaTypes) {
+			marker.Type = areaTypes[element.AreaTypeId]
+		}
+
+		markers = append(markers, marker)
+	}
+
+	return markers
+}
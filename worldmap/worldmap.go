@@ -0,0 +1,89 @@
+// Package worldmap decodes the world map of newer caches: the areas the world map is
+// divided into, such as the surface and the dungeons, and the elements that mark map
+// functions and labels within them. The geography and the composite textures that the
+// client draws the map itself from are not decoded, see the minimap package instead.
+package worldmap
+
+import (
+	"errors"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/crypto"
+)
+
+const (
+	// Archive is the archive that holds the definitions of the world map.
+	Archive = 19
+
+	// GeographyArchive and GroundArchive hold the terrain of the world map that the
+	// map squares and zones of a Composite refer to.
+	GeographyArchive = 18
+	GroundArchive    = 20
+)
+
+// The names of the folders of the Archive.
+const (
+	// DetailsFolder holds an Area for each area, indexed by the id of the area.
+	DetailsFolder = "details"
+
+	// CompositeMapFolder holds a Composite for each area, labelled with the internal
+	// name of the area.
+	CompositeMapFolder = "compositemap"
+
+	// CompositeTextureFolder holds the textures of the areas, which are not decoded.
+	CompositeTextureFolder = "compositetexture"
+)
+
+// ErrMalformed is returned when the world map is malformed.
+var ErrMalformed = errors.New("world map is malformed")
+
+// Coord is an absolute position in the game world.
+type Coord struct {
+	Plane int
+	X     int
+	Y     int
+}
+
+// UnpackCoord unpacks a Coord from the given packed coordinates, which hold the plane
+// in their upper bits followed by 14 bits for each of X and Y. The packed value of -1
+// unpacks to a Coord of which every field is -1.
+func UnpackCoord(packed int) Coord {
+	if packed == -1 {
+		return Coord{Plane: -1, X: -1, Y: -1}
+	}
+
+	return Coord{Plane: packed >> 28 & 0x3, X: packed >> 14 & 0x3FFF, Y: packed & 0x3FFF}
+}
+
+// Pack packs the Coord into the format of UnpackCoord.
+func (c Coord) Pack() int {
+	if c.Plane == -1 {
+		return -1
+	}
+
+	return c.Plane<<28 | c.X<<14 | c.Y
+}
+
+// loadPack returns the data of the pack that is labelled with the given name within the
+// folder of the Archive that is labelled with the given folder name. Returns
+// gokira.ErrLabelNotFound if either of them does not exist. May return an error.
+func loadPack(cache *gokira.Cache, folderName, packName string) ([]byte, error) {
+	manifest, err := cache.GetFolderManifestByName(Archive, folderName)
+	if err != nil {
+		return nil, err
+	}
+
+	packs, err := cache.GetFolderPacks(Archive, manifest.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := uint32(crypto.Djb2(packName))
+	for i, reference := range manifest.PackReferences {
+		if reference.LabelHash == hash && i < len(packs) {
+			return packs[i].Data, nil
+		}
+	}
+
+	return nil, gokira.ErrLabelNotFound
+}
@@ -0,0 +1,212 @@
+package worldmap
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/sinoz/gokira"
+	"github.com/sinoz/gokira/buffer"
+	"github.com/sinoz/gokira/config"
+	"github.com/sinoz/gokira/crypto"
+	"github.com/sinoz/gokira/internal/cachetest"
+)
+
+// testCoord encodes the given position as a Coord.
+func testCoord(plane, x, y int) int {
+	return plane<<28 | x<<14 | y
+}
+
+// testArea encodes an area of the given attributes and sections.
+func testArea(internalName, name string, origin int, main bool, zoom int, sections ...[]byte) []byte {
+	w := buffer.NewWriter().
+		WriteCString(internalName).WriteCString(name).
+		WriteInt32(origin).WriteInt32(0x112233).WriteInt8(0).
+		WriteBool(main).WriteInt8(zoom).WriteInt8(len(sections))
+
+	for _, section := range sections {
+		w.WriteBytes(section)
+	}
+
+	return w.Bytes()
+}
+
+// loadTestCache builds the fixture cache that holds the surface area and a dungeon
+// area along with the composite of the surface.
+func loadTestCache(t *testing.T) *gokira.Cache {
+	surface := testArea("main", "Gielinor Surface", testCoord(0, 3232, 3232), true, 37,
+		// the regions 48, 48 to 52, 52
+		buffer.NewWriter().WriteInt8(RegionRangeSection).WriteInt8(0).WriteInt8(4).
+			WriteInt16(48).WriteInt16(48).WriteInt16(52).WriteInt16(52).
+			WriteInt16(48).WriteInt16(48).WriteInt16(52).WriteInt16(52).Bytes(),
+		buffer.NewWriter().WriteInt8(ChunkSection).WriteInt8(0).WriteInt8(1).
+			WriteInt16(40).WriteInt8(1).WriteInt16(150).WriteInt8(2).
+			WriteInt16(40).WriteInt8(3).WriteInt16(50).WriteInt8(4).Bytes())
+
+	dungeon := testArea("dungeon", "Dungeon", -1, false, 50,
+		buffer.NewWriter().WriteInt8(RegionSection).WriteInt8(1).WriteInt8(2).
+			WriteInt16(45).WriteInt16(150).WriteInt16(45).WriteInt16(50).Bytes(),
+		buffer.NewWriter().WriteInt8(ChunkRangeSection).WriteInt8(0).WriteInt8(1).
+			WriteInt16(46).WriteInt8(0).WriteInt8(3).WriteInt16(150).WriteInt8(2).WriteInt8(5).
+			WriteInt16(47).WriteInt8(0).WriteInt8(3).WriteInt16(51).WriteInt8(2).WriteInt8(5).Bytes())
+
+	composite := buffer.NewWriter().
+		// a map square
+		WriteInt16(1).WriteInt8(0).WriteInt8(4).
+		WriteInt16(50).WriteInt16(50).WriteInt16(50).WriteInt16(50).WriteInt16(3).WriteInt16(7).
+		// a zone of which the group and the file are absent
+		WriteInt16(1).WriteInt8(0).WriteInt8(1).
+		WriteInt16(40).WriteInt16(50).WriteInt16(40).WriteInt16(150).
+		WriteInt8(3).WriteInt8(4).WriteInt8(1).WriteInt8(2).WriteInt16(0x7FFF).WriteInt16(0x7FFF).
+		// the element markers
+		WriteInt16(3).
+		WriteInt16(12).WriteInt32(testCoord(0, 3222, 3218)).WriteInt8(0).
+		WriteInt16(13).WriteInt32(testCoord(0, 3230, 3230)).WriteInt8(1).
+		WriteInt16(14).WriteInt32(testCoord(0, 1000, 1000)).WriteInt8(0).
+		Bytes()
+
+	builder := cachetest.New()
+	builder.Add(19, 0, map[int][]byte{0: surface, 1: dungeon}).Named("details")
+
+	composites := builder.Add(19, 1, map[int][]byte{0: make([]byte, 6), 1: composite}).Named("compositemap")
+	composites.PackNameHashes = map[int]int{0: crypto.Djb2("dungeon"), 1: crypto.Djb2("main")}
+
+	return builder.Build(t)
+}
+
+func TestLoadAreas(t *testing.T) {
+	areas, err := LoadAreas(loadTestCache(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(areas) != 2 {
+		t.Fatalf("expected 2 areas but got %v", len(areas))
+	}
+
+	main, dungeon := areas[0], areas[1]
+	if main.InternalName != "main" || main.Name != "Gielinor Surface" || !main.Main || main.Zoom != 37 {
+		t.Errorf("unexpected area %+v", main)
+	}
+
+	if main.Origin != (Coord{Plane: 0, X: 3232, Y: 3232}) || main.BackgroundColor != 0x112233 {
+		t.Errorf("unexpected area %+v", main)
+	}
+
+	if dungeon.Main || dungeon.Origin != (Coord{Plane: -1, X: -1, Y: -1}) || len(dungeon.Sections) != 2 {
+		t.Errorf("unexpected area %+v", dungeon)
+	}
+
+	expected := &Section{Type: RegionRangeSection, MinPlane: 0, Planes: 4, Source: image.Rect(3072, 3072, 3392, 3392), Display: image.Rect(3072, 3072, 3392, 3392)}
+	if !reflect.DeepEqual(main.Sections[0], expected) {
+		t.Errorf("expected section %+v but got %+v", expected, main.Sections[0])
+	}
+
+	if bounds := main.Bounds(); bounds != image.Rect(2584, 3072, 3392, 3392) {
+		t.Errorf("unexpected bounds %v", bounds)
+	}
+
+	projections := []struct {
+		area      *Area
+		position  Coord
+		projected Coord
+		ok        bool
+	}{
+		{main, Coord{0, 3222, 3218}, Coord{0, 3222, 3218}, true},
+		{main, Coord{0, 2570, 9620}, Coord{0, 2586, 3236}, true},
+		{main, Coord{1, 2570, 9620}, Coord{}, false},
+		{main, Coord{0, 1000, 1000}, Coord{}, false},
+		{dungeon, Coord{1, 2885, 9607}, Coord{0, 2885, 3207}, true},
+		{dungeon, Coord{0, 2885, 9607}, Coord{}, false},
+		{dungeon, Coord{0, 2950, 9620}, Coord{0, 3014, 3284}, true},
+		{dungeon, Coord{0, 2980, 9620}, Coord{}, false},
+	}
+
+	for _, projection := range projections {
+		projected, ok := projection.area.Project(projection.position)
+		if projected != projection.projected || ok != projection.ok {
+			t.Errorf("expected %v of %v to project to %v, %v but got %v, %v", projection.position, projection.area.Name, projection.projected, projection.ok, projected, ok)
+		}
+	}
+}
+
+func TestLoadComposite(t *testing.T) {
+	cache := loadTestCache(t)
+
+	areas, err := LoadAreas(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	composite, err := LoadComposite(cache, areas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	square := &MapSquare{MinPlane: 0, Planes: 4, DisplayX: 50, DisplayY: 50, SourceX: 50, SourceY: 50, GroupId: 3, FileId: 7}
+	if len(composite.MapSquares) != 1 || !reflect.DeepEqual(composite.MapSquares[0], square) {
+		t.Errorf("unexpected map squares %+v", composite.MapSquares)
+	}
+
+	zone := &Zone{
+		MapSquare:    MapSquare{MinPlane: 0, Planes: 1, DisplayX: 40, DisplayY: 50, SourceX: 40, SourceY: 150, GroupId: -1, FileId: -1},
+		DisplayZoneX: 3,
+		DisplayZoneY: 4,
+		SourceZoneX:  1,
+		SourceZoneY:  2,
+	}
+
+	if len(composite.Zones) != 1 || !reflect.DeepEqual(composite.Zones[0], zone) {
+		t.Errorf("unexpected zones %+v", composite.Zones)
+	}
+
+	if len(composite.Elements) != 3 || composite.Elements[1].AreaTypeId != 13 || !composite.Elements[1].Members {
+		t.Errorf("unexpected elements %+v", composite.Elements)
+	}
+
+	areaTypes := make([]*config.AreaType, 13)
+	areaTypes[12] = &config.AreaType{Id: 12, Name: "Bank"}
+
+	markers := Markers(areas[0], composite, areaTypes, false)
+	if len(markers) != 1 || markers[0].Type != areaTypes[12] || markers[0].Position != (Coord{0, 3222, 3218}) {
+		t.Errorf("unexpected markers %+v", markers)
+	}
+
+	if markers := Markers(areas[0], composite, areaTypes, true); len(markers) != 2 || markers[1].Type != nil {
+		t.Errorf("unexpected markers %+v", markers)
+	}
+
+	dungeon, err := LoadComposite(cache, areas[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dungeon.MapSquares) != 0 || len(dungeon.Zones) != 0 || len(dungeon.Elements) != 0 {
+		t.Errorf("unexpected composite %+v", dungeon)
+	}
+
+	if _, err := LoadComposite(cache, &Area{InternalName: "unknown"}); err != gokira.ErrLabelNotFound {
+		t.Errorf("expected %v but got %v", gokira.ErrLabelNotFound, err)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	if _, err := DecodeComposite([]byte{0, 0, 0, 0, 0, 0, 1}); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+
+	if _, err := DecodeArea(0, []byte{'a', 0, 'b', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 4, 0, 1}); err != ErrMalformed {
+		t.Errorf("expected %v but got %v", ErrMalformed, err)
+	}
+}
+
+func TestCoord(t *testing.T) {
+	c := Coord{Plane: 2, X: 3222, Y: 9618}
+	if unpacked := UnpackCoord(c.Pack()); unpacked != c {
+		t.Errorf("expected %v but got %v", c, unpacked)
+	}
+
+	if packed := UnpackCoord(-1).Pack(); packed != -1 {
+		t.Errorf("expected -1 but got %v", packed)
+	}
+}